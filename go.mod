@@ -4,6 +4,8 @@ go 1.13
 
 require (
 	github.com/bytecodealliance/wasmtime-go v0.17.0
+	github.com/cockroachdb/pebble v0.0.0-20200916222308-4e219a90ba5b
+	github.com/dgraph-io/badger/v2 v2.0.3
 	github.com/iotaledger/goshimmer v0.1.4-0.20200702153554-9e76d374009a
 	github.com/iotaledger/hive.go v0.0.0-20200625105326-310ea88f1337
 	github.com/labstack/echo v3.3.10+incompatible
@@ -17,6 +19,7 @@ require (
 	github.com/stretchr/testify v1.6.1
 	github.com/urfave/cli/v2 v2.2.0
 	go.dedis.ch/kyber/v3 v3.0.12
+	go.etcd.io/bbolt v1.3.5
 	go.nanomsg.org/mangos/v3 v3.0.1
 	go.uber.org/atomic v1.6.0
 	golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9
@@ -8,7 +8,9 @@ import (
 	"github.com/iotaledger/wasp/plugins/config"
 	"github.com/iotaledger/wasp/plugins/database"
 	"github.com/iotaledger/wasp/plugins/dispatcher"
+	"github.com/iotaledger/wasp/plugins/dkg"
 	"github.com/iotaledger/wasp/plugins/gracefulshutdown"
+	"github.com/iotaledger/wasp/plugins/keystore"
 	"github.com/iotaledger/wasp/plugins/logger"
 	"github.com/iotaledger/wasp/plugins/nodeconn"
 	"github.com/iotaledger/wasp/plugins/peering"
@@ -27,7 +29,9 @@ var PLUGINS = node.Plugins(
 	webapi.Plugin,
 	cli.Plugin,
 	database.Plugin,
+	keystore.Plugin,
 	peering.Plugin,
+	dkg.Plugin,
 	nodeconn.Plugin,
 	dispatcher.Plugin,
 	committees.Plugin,
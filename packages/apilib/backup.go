@@ -0,0 +1,46 @@
+package apilib
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/iotaledger/wasp/plugins/webapi/admapi"
+	"github.com/iotaledger/wasp/plugins/webapi/misc"
+)
+
+// Backup requests the backup archive of the registry (if withRegistry) and partitions of the smart contracts.
+// Empty list of addresses means all smart contracts of the node
+func Backup(host string, addrs []string, withRegistry bool) ([]byte, error) {
+	data, err := json.Marshal(&admapi.BackupRequest{
+		Addresses: addrs,
+		Registry:  withRegistry,
+	})
+	if err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("http://%s/adm/backup", host)
+	resp, err := http.Post(url, "application/json", bytes.NewBuffer(data))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("response status %d", resp.StatusCode)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Header.Get("Content-Type") != "application/octet-stream" {
+		var bresp misc.SimpleResponse
+		if err = json.Unmarshal(body, &bresp); err != nil {
+			return nil, err
+		}
+		return nil, errors.New(bresp.Error)
+	}
+	return body, nil
+}
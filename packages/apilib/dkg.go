@@ -1,8 +1,9 @@
 package apilib
 
 import (
-	"bytes"
+	"fmt"
 	"math/rand"
+	"sync"
 
 	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
 	"github.com/iotaledger/wasp/packages/tcrypto"
@@ -10,72 +11,168 @@ import (
 	"github.com/pkg/errors"
 )
 
-func GenerateNewDistributedKeySet(nodes []string, n, t uint16) (*address.Address, error) {
-	if len(nodes) != int(n) {
+// RunDistributedKeyGeneration triggers peer-to-peer distributed key generation in nodes.
+// hosts are web API locations of nodes, peeringHosts are their peering locations in the same order.
+// Nodes exchange shares directly, the caller only receives the address of the new key set
+func RunDistributedKeyGeneration(hosts []string, peeringHosts []string, t uint16) (*address.Address, error) {
+	if len(hosts) != len(peeringHosts) {
 		return nil, errors.New("wrong params")
 	}
-	if err := tcrypto.ValidateDKSParams(t, n, 0); err != nil {
+	if err := tcrypto.ValidateDKSParams(t, uint16(len(hosts)), 0); err != nil {
 		return nil, err
 	}
-	// temporary numeric id during DKG
-	params := dkgapi.NewDKSRequest{
-		TmpId: rand.Int(),
-		N:     n,
-		T:     t,
+	params := dkgapi.RunDKGRequest{
+		TmpId:         rand.Int(),
+		PeerLocations: peeringHosts,
+		T:             t,
 	}
-	// generate new key shares
-	// results in the matrix
-	priSharesMatrix := make([][]string, params.N)
-	for i, host := range nodes {
-		par := params
-		par.Index = uint16(i)
-		resp, err := callNewKey(host, par)
+	addrs := make([]*address.Address, len(hosts))
+	errs := make([]error, len(hosts))
+	var wg sync.WaitGroup
+	for i, host := range hosts {
+		wg.Add(1)
+		go func(i int, host string) {
+			defer wg.Done()
+			addrs[i], errs[i] = callRunDKG(host, params)
+		}(i, host)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("%s: %v", hosts[i], err)
 		}
-		if len(resp.PriShares) != int(params.N) {
-			return nil, errors.New("apilib: len(resp.PriShares) != int(params.N)")
+		if *addrs[i] != *addrs[0] {
+			return nil, errors.New("distributed key generation returned different addresses from different nodes")
 		}
-		priSharesMatrix[i] = resp.PriShares
 	}
+	if addrs[0].Version() != address.VersionBLS {
+		return nil, errors.New("distributed key generation returned non-BLS address")
+	}
+	return addrs[0], nil
+}
 
-	// aggregate private shares
-	pubShares := make([]string, params.N)
-	priSharesCol := make([]string, params.N)
-	for col, host := range nodes {
-		for row := range nodes {
-			priSharesCol[row] = priSharesMatrix[row][col]
+// ReshareDistributedKeySet triggers peer-to-peer resharing of the existing key set to the new committee
+// with quorum t. oldHosts and newHosts are web API locations of old and new nodes, oldPeeringHosts and
+// newPeeringHosts are their peering locations in the same order. Old nodes must contain at least quorum
+// of the old committee. Old nodes deal shares directly to new nodes, the caller doesn't see private shares.
+// The address of the key set remains the same. New nodes switch to the reshared key set when the committee
+// is activated with new nodes. After that, old key shares must be erased with EraseRetiredDKShares
+func ReshareDistributedKeySet(addr *address.Address, oldHosts, oldPeeringHosts, newHosts, newPeeringHosts []string, t uint16) error {
+	if len(oldHosts) != len(oldPeeringHosts) || len(newHosts) != len(newPeeringHosts) || len(oldHosts) == 0 {
+		return errors.New("wrong params")
+	}
+	if err := tcrypto.ValidateDKSParams(t, uint16(len(newHosts)), 0); err != nil {
+		return err
+	}
+	params := dkgapi.RunReshareRequest{
+		TmpId:            rand.Int(),
+		Address:          addr.String(),
+		OldPeerLocations: oldPeeringHosts,
+		NewPeerLocations: newPeeringHosts,
+		T:                t,
+	}
+	// a node may be both old and new. The API is called once for it
+	hosts := make([]string, 0, len(oldHosts)+len(newHosts))
+	isNew := make(map[string]bool)
+	for _, host := range newHosts {
+		hosts = append(hosts, host)
+		isNew[host] = true
+	}
+	for _, host := range oldHosts {
+		if !isNew[host] {
+			hosts = append(hosts, host)
 		}
-		resp, err := callAggregate(host, dkgapi.AggregateDKSRequest{
-			TmpId:     params.TmpId,
-			Index:     uint16(col),
-			PriShares: priSharesCol,
-		})
+	}
+	pubKeysHashes := make([]string, len(hosts))
+	errs := make([]error, len(hosts))
+	var wg sync.WaitGroup
+	for i, host := range hosts {
+		wg.Add(1)
+		go func(i int, host string) {
+			defer wg.Done()
+			pubKeysHashes[i], errs[i] = callRunReshare(host, params)
+		}(i, host)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
 		if err != nil {
-			return nil, err
+			return fmt.Errorf("%s: %v", hosts[i], err)
 		}
-		pubShares[col] = resp.PubShare
 	}
+	for i := range newHosts {
+		if pubKeysHashes[i] != pubKeysHashes[0] {
+			return errors.New("resharing returned different public shares from different nodes")
+		}
+	}
+	return nil
+}
 
-	// commit keys
-	var addrRet *address.Address
-	for _, host := range nodes {
-		addr, err := callCommit(host, dkgapi.CommitDKSRequest{
-			TmpId:     params.TmpId,
-			PubShares: pubShares,
-		})
+// EraseRetiredDKShares erases key shares of the address on old nodes which are not in the new committee.
+// oldHosts and newHosts are web API locations of old and new nodes, newPeeringHosts are peering locations
+// of new nodes in the same order. Must be called after new nodes have switched to the reshared key set,
+// i.e. after the committee was activated with new nodes. Otherwise nothing is erased.
+// Returns the number of erased key shares
+func EraseRetiredDKShares(addr *address.Address, oldHosts, newHosts, newPeeringHosts []string) (int, error) {
+	if len(newHosts) != len(newPeeringHosts) || len(newHosts) == 0 {
+		return 0, errors.New("wrong params")
+	}
+	isNew := make(map[string]bool)
+	for _, host := range newHosts {
+		isNew[host] = true
+	}
+	retired := make([]string, 0, len(oldHosts))
+	for _, host := range oldHosts {
+		if !isNew[host] {
+			retired = append(retired, host)
+		}
+	}
+	if len(retired) == 0 {
+		return 0, nil
+	}
+	// new nodes must be in the new key set. Old nodes still hold the old one
+	newInfo := GetPublicKeyInfo(newHosts, addr)
+	for i, info := range newInfo {
+		if info.Err != "" {
+			return 0, fmt.Errorf("%s: %s", newHosts[i], info.Err)
+		}
+		if int(info.N) != len(newHosts) || int(info.Index) != i || !equalStrings(info.PubKeys, newInfo[0].PubKeys) {
+			return 0, fmt.Errorf("%s hasn't switched to the reshared key set", newHosts[i])
+		}
+	}
+	for i, info := range GetPublicKeyInfo(retired, addr) {
+		if info.Err == "" && equalStrings(info.PubKeys, newInfo[0].PubKeys) {
+			return 0, fmt.Errorf("%s holds the key share of the new key set", retired[i])
+		}
+	}
+	params := dkgapi.EraseRetiredDKShareRequest{
+		Address:          addr.String(),
+		NewPeerLocations: newPeeringHosts,
+	}
+	numErased := 0
+	for _, host := range retired {
+		erased, err := callEraseRetiredDKShare(host, params)
 		if err != nil {
-			return nil, err
+			return numErased, fmt.Errorf("%s: %v", host, err)
 		}
-		if addrRet != nil && !bytes.Equal(addrRet.Bytes(), addr.Bytes()) {
-			return nil, errors.New("key commit returned different addresses from different nodes")
+		if erased {
+			numErased++
 		}
-		if addr.Version() != address.VersionBLS {
-			return nil, errors.New("key commit returned non-BLS address")
+	}
+	return numErased, nil
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
 		}
-		addrRet = addr
 	}
-	return addrRet, nil
+	return true
 }
 
 // retrieves public info about key with specific address
@@ -90,14 +187,18 @@ func GetPublicKeyInfo(nodes []string, address *address.Address) []*dkgapi.GetPub
 	return ret
 }
 
-func ExportDKShare(node string, address *address.Address) (string, error) {
+// ExportDKShare returns the DKShare of the node. If passphrase is not empty, the blob is encrypted with it
+func ExportDKShare(node string, address *address.Address, passphrase string) (string, error) {
 	return callExportDKShare(node, dkgapi.ExportDKShareRequest{
-		Address: address.String(),
+		Address:    address.String(),
+		Passphrase: passphrase,
 	})
 }
 
-func ImportDKShare(node string, base58blob string) error {
+// ImportDKShare imports the DKShare to the node. Passphrase is needed if the blob is encrypted
+func ImportDKShare(node string, base58blob string, passphrase string) error {
 	return callImportDKShare(node, dkgapi.ImportDKShareRequest{
-		Blob: base58blob,
+		Blob:       base58blob,
+		Passphrase: passphrase,
 	})
 }
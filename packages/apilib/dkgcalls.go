@@ -11,59 +11,17 @@ import (
 	"github.com/pkg/errors"
 )
 
-func callNewKey(netLoc string, params dkgapi.NewDKSRequest) (*dkgapi.NewDKSResponse, error) {
+func callRunDKG(netLoc string, params dkgapi.RunDKGRequest) (*address.Address, error) {
 	data, err := json.Marshal(params)
 	if err != nil {
 		return nil, err
 	}
-	url := fmt.Sprintf("http://%s/adm/newdks", netLoc)
+	url := fmt.Sprintf("http://%s/adm/rundkg", netLoc)
 	resp, err := http.Post(url, "application/json", bytes.NewBuffer(data))
 	if err != nil {
 		return nil, err
 	}
-	result := &dkgapi.NewDKSResponse{}
-	err = json.NewDecoder(resp.Body).Decode(&result)
-	if err != nil {
-		return nil, err
-	}
-	if result.Err == "" {
-		return result, nil
-	}
-	return nil, errors.New(result.Err)
-}
-
-func callAggregate(netLoc string, params dkgapi.AggregateDKSRequest) (*dkgapi.AggregateDKSResponse, error) {
-	data, err := json.Marshal(params)
-	if err != nil {
-		return nil, err
-	}
-	url := fmt.Sprintf("http://%s/adm/aggregatedks", netLoc)
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(data))
-	if err != nil {
-		return nil, err
-	}
-	result := &dkgapi.AggregateDKSResponse{}
-	err = json.NewDecoder(resp.Body).Decode(&result)
-	if err != nil {
-		return nil, err
-	}
-	if result.Err == "" {
-		return result, nil
-	}
-	return nil, errors.New(result.Err)
-}
-
-func callCommit(netloc string, params dkgapi.CommitDKSRequest) (*address.Address, error) {
-	data, err := json.Marshal(params)
-	if err != nil {
-		return nil, err
-	}
-	url := fmt.Sprintf("http://%s/adm/commitdks", netloc)
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(data))
-	if err != nil {
-		return nil, err
-	}
-	result := &dkgapi.CommitDKSResponse{}
+	result := &dkgapi.RunDKGResponse{}
 	err = json.NewDecoder(resp.Body).Decode(&result)
 	if err != nil {
 		return nil, err
@@ -128,3 +86,45 @@ func callImportDKShare(netLoc string, params dkgapi.ImportDKShareRequest) error
 	}
 	return err
 }
+
+func callRunReshare(netLoc string, params dkgapi.RunReshareRequest) (string, error) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return "", err
+	}
+	url := fmt.Sprintf("http://%s/adm/runreshare", netLoc)
+	resp, err := http.Post(url, "application/json", bytes.NewBuffer(data))
+	if err != nil {
+		return "", err
+	}
+	result := &dkgapi.RunReshareResponse{}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return "", err
+	}
+	if result.Err == "" {
+		return result.PubKeysHash, nil
+	}
+	return "", errors.New(result.Err)
+}
+
+func callEraseRetiredDKShare(netLoc string, params dkgapi.EraseRetiredDKShareRequest) (bool, error) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return false, err
+	}
+	url := fmt.Sprintf("http://%s/adm/eraseretireddkshare", netLoc)
+	resp, err := http.Post(url, "application/json", bytes.NewBuffer(data))
+	if err != nil {
+		return false, err
+	}
+	result := &dkgapi.EraseRetiredDKShareResponse{}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return false, err
+	}
+	if result.Err == "" {
+		return result.Erased, nil
+	}
+	return false, errors.New(result.Err)
+}
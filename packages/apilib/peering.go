@@ -0,0 +1,85 @@
+package apilib
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/iotaledger/wasp/plugins/webapi/admapi"
+	"github.com/iotaledger/wasp/plugins/webapi/misc"
+)
+
+// GetPeeringIdentity returns network location and public key of the node in the peering network
+func GetPeeringIdentity(host string) (*admapi.TrustedPeerJsonable, error) {
+	resp, err := http.Get(fmt.Sprintf("http://%s/adm/getpeeringidentity", host))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	ret := &admapi.TrustedPeerJsonable{}
+	if err = json.NewDecoder(resp.Body).Decode(ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// PutTrustedPeers adds peers to the allow-list of the node
+func PutTrustedPeers(host string, peers []*admapi.TrustedPeerJsonable) error {
+	data, err := json.Marshal(peers)
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("http://%s/adm/puttrustedpeers", host)
+	resp, err := http.Post(url, "application/json", bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var result misc.SimpleResponse
+	if err = json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+	if result.Error != "" {
+		return errors.New(result.Error)
+	}
+	return nil
+}
+
+// GetPeers returns state of connections of the node with its peers
+func GetPeers(host string) (*admapi.GetPeersResponse, error) {
+	resp, err := http.Get(fmt.Sprintf("http://%s/adm/peers", host))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	ret := &admapi.GetPeersResponse{}
+	if err = json.NewDecoder(resp.Body).Decode(ret); err != nil {
+		return nil, fmt.Errorf("response status %d: %v", resp.StatusCode, err)
+	}
+	if ret.Err != "" {
+		return nil, errors.New(ret.Err)
+	}
+	return ret, nil
+}
+
+// TrustEachOther collects identities of the nodes and makes each node trust all others
+func TrustEachOther(hosts []string) error {
+	peers := make([]*admapi.TrustedPeerJsonable, len(hosts))
+	for i, host := range hosts {
+		var err error
+		if peers[i], err = GetPeeringIdentity(host); err != nil {
+			return fmt.Errorf("GetPeeringIdentity(%s): %v", host, err)
+		}
+	}
+	for _, host := range hosts {
+		if err := PutTrustedPeers(host, peers); err != nil {
+			return fmt.Errorf("PutTrustedPeers(%s): %v", host, err)
+		}
+	}
+	return nil
+}
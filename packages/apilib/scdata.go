@@ -14,13 +14,17 @@ import (
 
 // PutSCData calls node to write BootupData record
 func PutSCData(host string, bd registry.BootupData) error {
-	data, err := json.Marshal(&admapi.BootupDataJsonable{
+	req := &admapi.BootupDataJsonable{
 		Address:        bd.Address.String(),
 		OwnerAddress:   bd.OwnerAddress.String(),
 		Color:          bd.Color.String(),
 		CommitteeNodes: bd.CommitteeNodes,
 		AccessNodes:    bd.AccessNodes,
-	})
+	}
+	if *bd.StateAddress() != bd.Address {
+		req.OriginAddress = bd.OriginAddress.String()
+	}
+	data, err := json.Marshal(req)
 	if err != nil {
 		return err
 	}
@@ -74,6 +78,16 @@ func GetSCData(host string, addr *address.Address) (*registry.BootupData, bool,
 	if ret.Address, err = address.FromBase58(dresp.Address); err != nil {
 		return nil, false, err
 	}
+	if dresp.OriginAddress != "" {
+		if ret.OriginAddress, err = address.FromBase58(dresp.OriginAddress); err != nil {
+			return nil, false, err
+		}
+	}
+	if dresp.RotatedTo != "" {
+		if ret.RotatedTo, err = address.FromBase58(dresp.RotatedTo); err != nil {
+			return nil, false, err
+		}
+	}
 
 	return ret, true, nil
 }
@@ -28,3 +28,20 @@ func DumpSCState(host string, scAddress string) (*admapi.DumpSCStateResponse, er
 	}
 	return &result, nil
 }
+
+func GetPeerFaults(host string, scAddress string) (*admapi.PeerFaultsResponse, error) {
+	url := fmt.Sprintf("http://%s/adm/peerfaults/%s", host, scAddress)
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	var result admapi.PeerFaultsResponse
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return nil, fmt.Errorf("response status %d: %v", resp.StatusCode, err)
+	}
+	if result.Err != "" {
+		return nil, errors.New(result.Err)
+	}
+	return &result, nil
+}
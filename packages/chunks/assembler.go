@@ -0,0 +1,118 @@
+// Package chunks reassembles messages chopped into chunks by chopper.ChopData of Goshimmer.
+// Wasp nodes chop large peering messages the same way as the WaspConn protocol does
+package chunks
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/iotaledger/wasp/packages/util"
+)
+
+// size of the chunk header written by chopper.ChopData: message id, number of chunks, sequence number, data size
+const chunkHeaderSize = 4 + 1 + 1 + 2
+
+// ErrTooLarge is returned when unfinished messages would exceed the size limit of the assembler
+var ErrTooLarge = errors.New("message too large")
+
+// Assembler reassembles chopped messages. It belongs to one connection, so message ids of different senders
+// don't mix. The total size of unfinished messages is limited and they are dropped when they expire
+type Assembler struct {
+	maxChunkDataSize int
+	maxSize          int
+	ttl              time.Duration
+
+	mutex      sync.Mutex
+	reserved   int
+	inProgress map[uint32]*chunkedMsg
+}
+
+type chunkedMsg struct {
+	chunks      [][]byte
+	numReceived int
+	expires     time.Time
+}
+
+// NewAssembler creates the assembler of chunks produced by chopper.ChopData with the maximum piece size pieceSize.
+// Unfinished messages take at most maxSize bytes and are dropped after ttl
+func NewAssembler(pieceSize, maxSize int, ttl time.Duration) *Assembler {
+	return &Assembler{
+		maxChunkDataSize: pieceSize - chunkHeaderSize,
+		maxSize:          maxSize,
+		ttl:              ttl,
+		inProgress:       make(map[uint32]*chunkedMsg),
+	}
+}
+
+// Incoming returns the reassembled message when the last chunk is received, otherwise nil
+func (a *Assembler) Incoming(data []byte, now time.Time) ([]byte, error) {
+	rdr := bytes.NewReader(data)
+	var msgId uint32
+	if err := util.ReadUint32(rdr, &msgId); err != nil {
+		return nil, err
+	}
+	numChunks, err := util.ReadByte(rdr)
+	if err != nil {
+		return nil, err
+	}
+	seqNum, err := util.ReadByte(rdr)
+	if err != nil {
+		return nil, err
+	}
+	var size uint16
+	if err = util.ReadUint16(rdr, &size); err != nil {
+		return nil, err
+	}
+	switch {
+	case int(size) != rdr.Len():
+		return nil, fmt.Errorf("wrong data chunk length")
+	case seqNum >= numChunks:
+		return nil, fmt.Errorf("wrong data chunk seq number")
+	case seqNum < numChunks-1 && int(size) != a.maxChunkDataSize, int(size) > a.maxChunkDataSize:
+		return nil, fmt.Errorf("wrong data chunk length")
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	for id, msg := range a.inProgress {
+		if now.After(msg.expires) {
+			a.remove(id)
+		}
+	}
+	msg, ok := a.inProgress[msgId]
+	if !ok {
+		if a.reserved+int(numChunks)*a.maxChunkDataSize > a.maxSize {
+			return nil, ErrTooLarge
+		}
+		msg = &chunkedMsg{
+			chunks:  make([][]byte, numChunks),
+			expires: now.Add(a.ttl),
+		}
+		a.inProgress[msgId] = msg
+		a.reserved += int(numChunks) * a.maxChunkDataSize
+	}
+	if len(msg.chunks) != int(numChunks) {
+		a.remove(msgId)
+		return nil, fmt.Errorf("inconsistent number of data chunks")
+	}
+	if msg.chunks[seqNum] != nil {
+		return nil, fmt.Errorf("repeating seq number")
+	}
+	msg.chunks[seqNum] = make([]byte, size)
+	copy(msg.chunks[seqNum], data[len(data)-int(size):])
+	msg.numReceived++
+	if msg.numReceived < len(msg.chunks) {
+		return nil, nil
+	}
+	a.remove(msgId)
+	return bytes.Join(msg.chunks, nil), nil
+}
+
+func (a *Assembler) remove(msgId uint32) {
+	a.reserved -= len(a.inProgress[msgId].chunks) * a.maxChunkDataSize
+	delete(a.inProgress, msgId)
+}
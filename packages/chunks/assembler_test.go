@@ -0,0 +1,106 @@
+package chunks
+
+import (
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/iotaledger/goshimmer/dapps/waspconn/packages/chopper"
+	"github.com/iotaledger/hive.go/netutil/buffconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testPieceSize = buffconn.MaxMessageSize - 9
+	testTTL       = time.Minute
+)
+
+func chop(t *testing.T, size int) ([]byte, [][]byte) {
+	data := make([]byte, size)
+	_, _ = rand.Read(data)
+	chunks, ok := chopper.ChopData(data, testPieceSize)
+	require.True(t, ok)
+	return data, chunks
+}
+
+// setMsgId replaces the message id, as each sender numbers chopped messages with its own counter
+func setMsgId(chunks [][]byte, msgId uint32) {
+	for _, chunk := range chunks {
+		chunk[0], chunk[1], chunk[2], chunk[3] = byte(msgId), byte(msgId>>8), byte(msgId>>16), byte(msgId>>24)
+	}
+}
+
+func TestAssembler(t *testing.T) {
+	now := time.Now()
+	a := NewAssembler(testPieceSize, 100000, testTTL)
+
+	data1, chunks1 := chop(t, 10000)
+	data2, chunks2 := chop(t, 20000)
+	// interleaved
+	var res1, res2 []byte
+	for i := 0; i < len(chunks2); i++ {
+		if i < len(chunks1) {
+			ret, err := a.Incoming(chunks1[i], now)
+			require.NoError(t, err)
+			if ret != nil {
+				res1 = ret
+			}
+		}
+		ret, err := a.Incoming(chunks2[i], now)
+		require.NoError(t, err)
+		if ret != nil {
+			res2 = ret
+		}
+	}
+	assert.Equal(t, data1, res1)
+	assert.Equal(t, data2, res2)
+	assert.Empty(t, a.inProgress)
+	assert.Zero(t, a.reserved)
+
+	// repeating chunk
+	_, chunks := chop(t, 10000)
+	_, err := a.Incoming(chunks[0], now)
+	require.NoError(t, err)
+	_, err = a.Incoming(chunks[0], now)
+	assert.Error(t, err)
+
+	// unfinished messages can't exceed the limit
+	_, chunks = chop(t, 95000)
+	_, err = a.Incoming(chunks[0], now)
+	assert.Equal(t, ErrTooLarge, err)
+
+	// until they expire
+	_, err = a.Incoming(chunks[0], now.Add(testTTL+time.Second))
+	require.NoError(t, err)
+	assert.Len(t, a.inProgress, 1)
+
+	// truncated chunk
+	_, err = a.Incoming(chunks[1][:100], now)
+	assert.Error(t, err)
+}
+
+// messages of two senders with the same message id don't mix, each connection has its own assembler
+func TestAssemblerPerConnection(t *testing.T) {
+	now := time.Now()
+	data1, chunks1 := chop(t, 10000)
+	data2, chunks2 := chop(t, 10000)
+	setMsgId(chunks1, 7)
+	setMsgId(chunks2, 7)
+	a1 := NewAssembler(testPieceSize, 100000, testTTL)
+	a2 := NewAssembler(testPieceSize, 100000, testTTL)
+
+	for i := range chunks1 {
+		res1, err := a1.Incoming(chunks1[i], now)
+		require.NoError(t, err)
+		res2, err := a2.Incoming(chunks2[i], now)
+		require.NoError(t, err)
+		if i < len(chunks1)-1 {
+			assert.Nil(t, res1)
+			assert.Nil(t, res2)
+			continue
+		}
+		assert.Equal(t, data1, res1)
+		assert.Equal(t, data2, res2)
+	}
+}
@@ -0,0 +1,169 @@
+// Package adversary implements the byzantine committee member for testing.
+// It wraps the committee seen by the state manager and by the operator of the node
+// and tampers outgoing messages. The simulator tests use it directly, the Wasp node only
+// when built with the 'byzantine' tag (see commiteeimpl)
+package adversary
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/iotaledger/wasp/packages/committee"
+	"github.com/iotaledger/wasp/packages/tcrypto"
+	"github.com/iotaledger/wasp/packages/util"
+)
+
+// Behavior is a set of misbehaviors of the byzantine committee member
+type Behavior uint
+
+const (
+	// BadSigShares sends garbage instead of signature shares in SignedHashMsg
+	BadSigShares Behavior = 1 << iota
+	// WrongEssence signs the essence different from the calculated one while reporting the right essence hash
+	WrongEssence
+	// Equivocate sends different StartProcessingBatchMsg to different peers when the node is the leader
+	Equivocate
+	// LieStateIndex announces the state index one ahead of the real one in all messages
+	LieStateIndex
+)
+
+var behaviorNames = map[string]Behavior{
+	"badsigshares":  BadSigShares,
+	"wrongessence":  WrongEssence,
+	"equivocate":    Equivocate,
+	"liestateindex": LieStateIndex,
+}
+
+// ParseBehavior parses comma separated list of behavior names
+func ParseBehavior(s string) (Behavior, error) {
+	var ret Behavior
+	for _, name := range strings.Split(s, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		b, ok := behaviorNames[name]
+		if !ok {
+			return 0, fmt.Errorf("unknown byzantine behavior '%s'", name)
+		}
+		ret |= b
+	}
+	return ret, nil
+}
+
+func (b Behavior) String() string {
+	names := make([]string, 0, len(behaviorNames))
+	for name, v := range behaviorNames {
+		if b&v != 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// adversary wraps the committee seen by the state manager and by the operator of the node
+// and tampers outgoing messages. Incoming messages are dispatched as usual
+type adversary struct {
+	committee.Committee
+	behavior Behavior
+	dkshare  *tcrypto.DKShare
+}
+
+// New wraps the committee with the byzantine behavior
+func New(c committee.Committee, dkshare *tcrypto.DKShare, behavior Behavior) committee.Committee {
+	return &adversary{
+		Committee: c,
+		behavior:  behavior,
+		dkshare:   dkshare,
+	}
+}
+
+// the message is not addressed to a particular peer
+const anyPeer = ^uint16(0)
+
+func (a *adversary) SendMsg(targetPeerIndex uint16, msgType byte, msgData []byte) error {
+	return a.Committee.SendMsg(targetPeerIndex, msgType, a.tamper(targetPeerIndex, msgType, msgData))
+}
+
+func (a *adversary) SendMsgInSequence(msgType byte, msgData []byte, seqIndex uint16, seq []uint16) (uint16, error) {
+	return a.Committee.SendMsgInSequence(msgType, a.tamper(anyPeer, msgType, msgData), seqIndex, seq)
+}
+
+func (a *adversary) SendMsgToCommitteePeers(msgType byte, msgData []byte) (uint16, int64) {
+	if a.behavior&Equivocate == 0 || msgType != committee.MsgStartProcessingRequest {
+		return a.Committee.SendMsgToCommitteePeers(msgType, a.tamper(anyPeer, msgType, msgData))
+	}
+	// each peer receives own version of the message
+	ts := a.Environment().Now().UnixNano()
+	numSent := uint16(0)
+	for i := uint16(0); i < a.Size(); i++ {
+		if i == a.OwnPeerIndex() {
+			continue
+		}
+		if a.SendMsg(i, msgType, msgData) == nil {
+			numSent++
+		}
+	}
+	return numSent, ts
+}
+
+func (a *adversary) tamper(target uint16, msgType byte, msgData []byte) []byte {
+	ret := make([]byte, len(msgData))
+	copy(ret, msgData)
+
+	switch msgType {
+	case committee.MsgSignedHash:
+		ret = a.tamperSignedHash(ret)
+	case committee.MsgStartProcessingRequest:
+		if target != anyPeer && target%2 == 1 {
+			ret = a.tamperStartProcessing(ret)
+		}
+	}
+	if a.behavior&LieStateIndex != 0 && msgType != committee.MsgTestTrace && len(ret) >= 4 {
+		// all committee messages start with the state index
+		stateIndex := util.Uint32From4Bytes(ret[:4])
+		copy(ret[:4], util.Uint32To4Bytes(stateIndex+1))
+	}
+	return ret
+}
+
+func (a *adversary) tamperSignedHash(data []byte) []byte {
+	if a.behavior&(BadSigShares|WrongEssence) == 0 {
+		return data
+	}
+	msg := &committee.SignedHashMsg{}
+	if err := msg.Read(bytes.NewReader(data)); err != nil {
+		return data
+	}
+	if a.behavior&WrongEssence != 0 {
+		wrongEssence := append(msg.EssenceHash[:], []byte("byzantine")...)
+		if sigShare, err := a.dkshare.SignShare(wrongEssence); err == nil {
+			msg.SigShare = sigShare
+		}
+	}
+	if a.behavior&BadSigShares != 0 && len(msg.SigShare) > 2 {
+		// keep the index, spoil the signature
+		for i := 2; i < len(msg.SigShare); i++ {
+			msg.SigShare[i] ^= 0xFF
+		}
+	}
+	return util.MustBytes(msg)
+}
+
+func (a *adversary) tamperStartProcessing(data []byte) []byte {
+	if a.behavior&Equivocate == 0 {
+		return data
+	}
+	msg := &committee.StartProcessingBatchMsg{}
+	if err := msg.Read(bytes.NewReader(data)); err != nil {
+		return data
+	}
+	if len(msg.RequestIds) > 1 {
+		msg.RequestIds = msg.RequestIds[:len(msg.RequestIds)-1]
+	}
+	msg.RewardAddress[len(msg.RewardAddress)-1] ^= 0xFF
+	return util.MustBytes(msg)
+}
@@ -0,0 +1,34 @@
+//go:build byzantine
+// +build byzantine
+
+package commiteeimpl
+
+// Byzantine committee member for testing. Compiled only with the 'byzantine' build tag.
+// The Wasp node built with the tag misbehaves in all its committees
+// if the environment variable WASP_BYZANTINE lists the behaviors, for example
+//    WASP_BYZANTINE=badsigshares,equivocate
+
+import (
+	"os"
+
+	"github.com/iotaledger/hive.go/logger"
+	"github.com/iotaledger/wasp/packages/committee"
+	"github.com/iotaledger/wasp/packages/committee/adversary"
+	"github.com/iotaledger/wasp/packages/tcrypto"
+)
+
+const envByzantine = "WASP_BYZANTINE"
+
+func withAdversary(c committee.Committee, dkshare *tcrypto.DKShare, log *logger.Logger) committee.Committee {
+	s := os.Getenv(envByzantine)
+	if s == "" {
+		return c
+	}
+	behavior, err := adversary.ParseBehavior(s)
+	if err != nil {
+		log.Errorf("%s: %v", envByzantine, err)
+		return c
+	}
+	log.Warnf("BYZANTINE COMMITTEE MEMBER: %s", behavior.String())
+	return adversary.New(c, dkshare, behavior)
+}
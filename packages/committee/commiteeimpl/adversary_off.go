@@ -0,0 +1,15 @@
+//go:build !byzantine
+// +build !byzantine
+
+package commiteeimpl
+
+import (
+	"github.com/iotaledger/hive.go/logger"
+	"github.com/iotaledger/wasp/packages/committee"
+	"github.com/iotaledger/wasp/packages/tcrypto"
+)
+
+// withAdversary returns the committee as is. Byzantine behavior exists only in the build with the 'byzantine' tag
+func withAdversary(c committee.Committee, _ *tcrypto.DKShare, _ *logger.Logger) committee.Committee {
+	return c
+}
@@ -8,7 +8,9 @@ import (
 	"github.com/iotaledger/wasp/packages/committee/consensus"
 	"github.com/iotaledger/wasp/packages/committee/statemgr"
 	"github.com/iotaledger/wasp/packages/registry"
+	"github.com/iotaledger/wasp/packages/tcrypto"
 	"github.com/iotaledger/wasp/packages/util"
+	"github.com/iotaledger/wasp/plugins/config"
 	"github.com/iotaledger/wasp/plugins/peering"
 	"go.uber.org/atomic"
 	"sync"
@@ -17,6 +19,10 @@ import (
 
 const (
 	timerTickPeriod = 100 * time.Millisecond
+	// messages from peers waiting for the signature check
+	inboundQueueSize = 1000
+	// drops of messages from peers are logged once per dropLogEvery dropped messages
+	dropLogEvery = 100
 )
 
 type committeeObj struct {
@@ -30,28 +36,54 @@ type committeeObj struct {
 	address      address.Address
 	ownerAddress address.Address
 	color        balance.Color
-	peers        []*peering.Peer
+	stateAddress address.Address
+	peers        []peering.Peer
 	size         uint16
 	ownIndex     uint16
 	chMsg        chan interface{}
+	chPeerMsg    chan *peering.PeerMessage
+	chDismissed  chan struct{}
+	numDropped   atomic.Uint64
+	env          committee.Environment
 	stateMgr     committee.StateManager
 	operator     committee.Operator
+	faults       *committee.PeerFaults
+	signer       *msgSigner
+	transport    peering.Transport
 	log          *logger.Logger
 }
 
 func newCommitteeObj(bootupData *registry.BootupData, log *logger.Logger) committee.Committee {
+	transport := peering.GetTransport()
+	return newCommitteeWithTransport(bootupData, newNodeEnvironment(bootupData, transport), transport, peering.GetIdentity(), log)
+}
+
+// newCommitteeWithTransport creates the committee object in the environment with the peering transport.
+// Messages are signed with the identity and checked with keys of peers it trusts
+func newCommitteeWithTransport(bootupData *registry.BootupData, env committee.Environment, transport peering.Transport, identity peering.Identity, log *logger.Logger) committee.Committee {
 	log.Debugw("creating committee", "addr", bootupData.Address.String())
 
 	addr := bootupData.Address
+	myNetworkId := transport.MyNetworkId()
 	if util.ContainsDuplicates(bootupData.CommitteeNodes) ||
 		util.ContainsDuplicates(bootupData.AccessNodes) ||
 		util.IntersectsLists(bootupData.CommitteeNodes, bootupData.AccessNodes) ||
-		util.ContainsInList(peering.MyNetworkId(), bootupData.AccessNodes) {
+		util.ContainsInList(myNetworkId, bootupData.AccessNodes) {
 
 		log.Errorf("can't create committee object for %s: bootup data contains duplicate node addresses", addr.String())
 		return nil
 	}
-	dkshare, keyExists, err := registry.GetDKShare(&bootupData.Address)
+	// the key set may be reshared to the new committee. The node switches to the new key share
+	// when the committee is activated with the new committee nodes
+	switched, err := env.SwitchToResharedDKShare(&addr, bootupData.CommitteeNodes, myNetworkId)
+	if err != nil {
+		log.Error(err)
+		return nil
+	}
+	if switched {
+		log.Infof("switched to the reshared key share for the address %s", addr.String())
+	}
+	dkshare, keyExists, err := env.GetDKShare(&bootupData.Address)
 	if err != nil {
 		log.Error(err)
 		return nil
@@ -67,9 +99,9 @@ func newCommitteeObj(bootupData *registry.BootupData, log *logger.Logger) commit
 			return nil
 		}
 	} else {
-		if !iAmInTheCommittee(bootupData.CommitteeNodes, dkshare.N, dkshare.Index) {
+		if !iAmInTheCommittee(myNetworkId, bootupData.CommitteeNodes, dkshare.N, dkshare.Index) {
 			log.Errorf("bootup data inconsistency: the own node %s is not in the committee for %s: %+v",
-				peering.MyNetworkId(), addr.String(), bootupData.CommitteeNodes)
+				myNetworkId, addr.String(), bootupData.CommitteeNodes)
 			return nil
 		}
 		// check for owner address. It is mandatory for the committee node
@@ -78,49 +110,113 @@ func newCommitteeObj(bootupData *registry.BootupData, log *logger.Logger) commit
 			log.Errorf("undefined owner address for the committee node. Dismiss. Addr = %s", addr.String())
 			return nil
 		}
+		if !checkTrustedPeers(&addr, env, identity, myNetworkId, bootupData.CommitteeNodes, log) {
+			return nil
+		}
+	}
+	if !checkTrustedPeers(&addr, env, identity, myNetworkId, bootupData.AccessNodes, log) {
+		return nil
+	}
+	epoch, err := env.NextMsgEpoch()
+	if err != nil {
+		log.Error(err)
+		return nil
 	}
+	ret := newPeeredCommittee(bootupData, dkshare, epoch, env, transport, identity, log)
 
+	var cmt committee.Committee = ret
+	if keyExists {
+		cmt = withAdversary(ret, dkshare, ret.log)
+	}
+	partition := partitionKey{node: myNetworkId, addr: ret.stateAddress}
+	go func() {
+		for {
+			select {
+			case msg := <-ret.chMsg:
+				ret.dispatchMessage(msg)
+			case <-ret.chDismissed:
+				releasePartition(partition, ret)
+				return
+			}
+		}
+	}()
+	// the queue stays closed until the state manager and the operator are ready
+	acquirePartition(partition, ret, func() {
+		ret.stateMgr = statemgr.New(cmt, ret.log)
+		if keyExists {
+			ret.operator = consensus.NewOperator(cmt, dkshare, ret.log)
+		}
+	})
+
+	return ret
+}
+
+// newPeeredCommittee creates the committee object with peers of the bootup data and starts checking
+// messages from them. dkshare is the key share of the committee node, nil on the access node.
+// Messages to peers are signed with the epoch (see registry.NextMsgEpoch)
+func newPeeredCommittee(bootupData *registry.BootupData, dkshare *tcrypto.DKShare, epoch uint64, env committee.Environment, transport peering.Transport, identity peering.Identity, log *logger.Logger) *committeeObj {
+	myNetworkId := transport.MyNetworkId()
 	ret := &committeeObj{
 		chMsg:        make(chan interface{}, 100),
+		chPeerMsg:    make(chan *peering.PeerMessage, inboundQueueSize),
+		chDismissed:  make(chan struct{}),
 		address:      bootupData.Address,
 		ownerAddress: bootupData.OwnerAddress,
 		color:        bootupData.Color,
-		peers:        make([]*peering.Peer, 0),
+		stateAddress: *bootupData.StateAddress(),
+		env:          env,
+		peers:        make([]peering.Peer, 0),
+		transport:    transport,
 		log:          log.Named(util.Short(bootupData.Address.String())),
 	}
-	if keyExists {
+	if dkshare != nil {
 		ret.ownIndex = dkshare.Index
 		ret.size = dkshare.N
 
 		for _, remoteLocation := range bootupData.CommitteeNodes {
-			ret.peers = append(ret.peers, peering.UsePeer(remoteLocation))
+			ret.peers = append(ret.peers, transport.UsePeer(remoteLocation))
 		}
+		ret.signer = newMsgSigner(bootupData.Address, myNetworkId, bootupData.CommitteeNodes, bootupData.AccessNodes, identity, epoch)
+	} else {
+		ret.signer = newMsgSigner(bootupData.Address, myNetworkId, nil, bootupData.AccessNodes, identity, epoch)
 	}
+	// access peers follow committee peers, so peer indices are the same as in the signer
 	for _, remoteLocation := range bootupData.AccessNodes {
-		p := peering.UsePeer(remoteLocation)
-		if p != nil {
-			ret.peers = append(ret.peers, p)
-		}
+		ret.peers = append(ret.peers, transport.UsePeer(remoteLocation))
 	}
+	ret.faults = committee.NewPeerFaults(ret.size)
+	go ret.runInbound()
+	return ret
+}
 
-	ret.stateMgr = statemgr.New(ret, ret.log)
-	if keyExists {
-		ret.operator = consensus.NewOperator(ret, dkshare, ret.log)
+// checkTrustedPeers checks if all peers of the committee are in the trusted peer list.
+// Untrusted peers are refused by the peering. By default the committee is activated with a warning and
+// untrusted peers stay disconnected until they are added to the list.
+// With peering.requireTrustedPeers=true the committee is not activated
+func checkTrustedPeers(addr *address.Address, env committee.Environment, identity peering.Identity, myNetworkId string, locations []string, log *logger.Logger) bool {
+	err := peering.CheckTrusted(identity, myNetworkId, locations...)
+	if err == nil {
+		return true
 	}
-	go func() {
-		for msg := range ret.chMsg {
-			ret.dispatchMessage(msg)
-		}
-	}()
-
-	return ret
+	hint := "add peers to the trusted list with 'adm/puttrustedpeers'"
+	if trusted, errList := env.GetTrustedPeers(); errList == nil && len(trusted) == 0 {
+		hint = "the trusted peer list is empty, as on nodes upgraded from the version without authenticated peering: " + hint
+	}
+	if config.Node.GetBool(peering.CfgPeeringRequireTrustedPeers) {
+		log.Errorf("can't create committee object for %s: %v. Fix: %s, or set %s to false to activate the committee anyway",
+			addr.String(), err, hint, peering.CfgPeeringRequireTrustedPeers)
+		return false
+	}
+	log.Warnf("committee for %s is activated with untrusted peers: %v. Untrusted peers stay disconnected. Fix: %s",
+		addr.String(), err, hint)
+	return true
 }
 
 // iAmInTheCommittee checks if netLocations makes sense
-func iAmInTheCommittee(committeeNodes []string, n, index uint16) bool {
+func iAmInTheCommittee(myNetworkId string, committeeNodes []string, n, index uint16) bool {
 	if len(committeeNodes) != int(n) {
 		return false
 	}
 	// check for duplicates
-	return committeeNodes[index] == peering.MyNetworkId()
+	return committeeNodes[index] == myNetworkId
 }
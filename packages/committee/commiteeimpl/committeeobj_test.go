@@ -0,0 +1,227 @@
+package commiteeimpl
+
+import (
+	"testing"
+	"time"
+
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	valuetransaction "github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/transaction"
+	"github.com/iotaledger/hive.go/events"
+	"github.com/iotaledger/hive.go/kvstore"
+	"github.com/iotaledger/hive.go/kvstore/mapdb"
+	"github.com/iotaledger/hive.go/logger"
+	"github.com/iotaledger/wasp/packages/committee"
+	"github.com/iotaledger/wasp/packages/registry"
+	"github.com/iotaledger/wasp/packages/tcrypto"
+	"github.com/iotaledger/wasp/packages/vm"
+	"github.com/iotaledger/wasp/plugins/peering"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+)
+
+// testEnvironment is the environment of the node in the test: the database and the registry are in memory,
+// the connection to the node and the VM do nothing. Published messages are recorded
+type testEnvironment struct {
+	store     kvstore.KVStore
+	dkshare   *tcrypto.DKShare
+	epoch     atomic.Uint64
+	published chan string
+}
+
+func newTestEnvironment(dkshare *tcrypto.DKShare) *testEnvironment {
+	return &testEnvironment{
+		store:     mapdb.NewMapDB(),
+		dkshare:   dkshare,
+		published: make(chan string, 100),
+	}
+}
+
+func (env *testEnvironment) Now() time.Time {
+	return time.Now()
+}
+
+func (env *testEnvironment) ClockDrifts() bool {
+	return false
+}
+
+func (env *testEnvironment) Partition(addr *address.Address) kvstore.KVStore {
+	return env.store.WithRealm(addr[:])
+}
+
+func (env *testEnvironment) GetDKShare(addr *address.Address) (*tcrypto.DKShare, bool, error) {
+	if env.dkshare == nil || *addr != *env.dkshare.Address {
+		return nil, false, nil
+	}
+	return env.dkshare, true, nil
+}
+
+func (env *testEnvironment) SwitchToResharedDKShare(_ *address.Address, _ []string, _ string) (bool, error) {
+	return false, nil
+}
+
+func (env *testEnvironment) NextMsgEpoch() (uint64, error) {
+	return env.epoch.Inc(), nil
+}
+
+func (env *testEnvironment) MarkRotated(_, _ *address.Address) error {
+	return nil
+}
+
+func (env *testEnvironment) GetRewardAddress(_ *address.Address) address.Address {
+	return address.Address{}
+}
+
+func (env *testEnvironment) GetTrustedPeers() ([]*registry.TrustedPeer, error) {
+	return nil, nil
+}
+
+func (env *testEnvironment) RequestOutputs(_ *address.Address) error {
+	return nil
+}
+
+func (env *testEnvironment) RequestTransaction(_ *valuetransaction.ID) error {
+	return nil
+}
+
+func (env *testEnvironment) PostTransaction(_ *valuetransaction.Transaction) error {
+	return nil
+}
+
+func (env *testEnvironment) RunComputationsAsync(_ *vm.VMTask) error {
+	return nil
+}
+
+func (env *testEnvironment) Publish(msgType string, _ ...string) {
+	select {
+	case env.published <- msgType:
+	default:
+	}
+}
+
+// waitPublished waits until the message of the type is published in the environment
+func (env *testEnvironment) waitPublished(t *testing.T, msgType string) {
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case m := <-env.published:
+			if m == msgType {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("'%s' is not published", msgType)
+		}
+	}
+}
+
+func newMemTransport(t *testing.T, network *peering.MemNetwork, loc string) peering.Transport {
+	transport, err := network.NewTransport(loc)
+	require.NoError(t, err)
+	shutdown := make(chan struct{})
+	go transport.Run(shutdown)
+	t.Cleanup(func() { close(shutdown) })
+	return transport
+}
+
+// committee objects of nodes running in one process are activated with the state manager and the operator,
+// each in the environment of its node
+func TestCommitteeInEnvironment(t *testing.T) {
+	network := peering.NewMemNetwork()
+	locations := []string{"node0:4000", "node1:4000", "node2:4000"}
+	identities, err := peering.NewStaticIdentities(locations...)
+	require.NoError(t, err)
+	dkshares, err := tcrypto.NewDKSharesInProcess(3, 3, nil)
+	require.NoError(t, err)
+	bootupData := &registry.BootupData{
+		Address:        *dkshares[0].Address,
+		OwnerAddress:   address.Random(),
+		CommitteeNodes: locations,
+	}
+
+	// the node without the key share in its registry is refused
+	c := newCommitteeWithTransport(bootupData, newTestEnvironment(nil), newMemTransport(t, network, "other:4000"),
+		identities[0], logger.NewExampleLogger("other"))
+	assert.Nil(t, c)
+
+	envs := make([]*testEnvironment, len(locations))
+	cmts := make([]committee.Committee, len(locations))
+	for i, loc := range locations {
+		envs[i] = newTestEnvironment(dkshares[i])
+		cmts[i] = newCommitteeWithTransport(bootupData, envs[i], newMemTransport(t, network, loc), identities[i], logger.NewExampleLogger(loc))
+		require.NotNil(t, cmts[i])
+	}
+	for _, env := range envs {
+		env.waitPublished(t, "active_committee")
+		// each run of the committee takes the next epoch from the registry of its node
+		assert.EqualValues(t, 1, env.epoch.Load())
+	}
+	for i, c := range cmts {
+		c.Dismiss()
+		envs[i].waitPublished(t, "dismissed_committee")
+	}
+}
+
+// committee objects of nodes running in one process exchange signed messages over their transports
+// of the in-memory network, each signing with its own identity
+func TestCommitteeOverMemNetwork(t *testing.T) {
+	network := peering.NewMemNetwork()
+	locations := []string{"node0:4000", "node1:4000", "node2:4000"}
+	identities, err := peering.NewStaticIdentities(locations...)
+	require.NoError(t, err)
+	dkshares, err := tcrypto.NewDKSharesInProcess(3, 3, nil)
+	require.NoError(t, err)
+	bootupData := &registry.BootupData{
+		Address:        *dkshares[0].Address,
+		CommitteeNodes: locations,
+	}
+
+	newTransport := func(loc string) peering.Transport {
+		return newMemTransport(t, network, loc)
+	}
+	cmts := make([]*committeeObj, len(locations))
+	for i, loc := range locations {
+		transport := newTransport(loc)
+		c := newPeeredCommittee(bootupData, dkshares[i], 1, newTestEnvironment(dkshares[i]), transport, identities[i], logger.NewExampleLogger(loc))
+		t.Cleanup(func() { close(c.chDismissed) })
+		// the queue is opened without the state manager and the operator
+		c.isOpenQueue.Store(true)
+		transport.Events().MessageReceived.Attach(events.NewClosure(func(msg *peering.PeerMessage) {
+			c.ReceiveMessage(msg)
+		}))
+		cmts[i] = c
+	}
+	receive := func(c *committeeObj) *peering.PeerMessage {
+		select {
+		case msg := <-c.chMsg:
+			return msg.(*peering.PeerMessage)
+		case <-time.After(5 * time.Second):
+			t.Fatal("message not received")
+		}
+		return nil
+	}
+	data := []byte("committee message")
+
+	sent, ts := cmts[0].SendMsgToCommitteePeers(peering.FirstCommitteeMsgCode, data)
+	assert.EqualValues(t, 2, sent)
+	for _, c := range cmts[1:] {
+		msg := receive(c)
+		assert.EqualValues(t, 0, msg.SenderIndex)
+		assert.Equal(t, ts, msg.Timestamp)
+		assert.Equal(t, data, msg.MsgData)
+	}
+
+	// the node with the identity not trusted by the committee claims to be node 2
+	intruderIdentities, err := peering.NewStaticIdentities("intruder:4000")
+	require.NoError(t, err)
+	intruder := newMsgSigner(bootupData.Address, "intruder:4000", locations, nil, intruderIdentities[0], 1)
+	msg, err := intruder.signedMessage(2, 1, time.Now().UnixNano(), peering.FirstCommitteeMsgCode, []byte("forged"))
+	require.NoError(t, err)
+	require.NoError(t, newTransport("intruder:4000").UsePeer(locations[1]).SendMsg(msg))
+
+	// messages are delivered in the order they are sent: the forged message is rejected before this one
+	require.NoError(t, cmts[2].SendMsg(1, peering.FirstCommitteeMsgCode, data))
+	msg = receive(cmts[1])
+	assert.EqualValues(t, 2, msg.SenderIndex)
+	assert.Equal(t, data, msg.MsgData)
+	assert.Len(t, cmts[1].chMsg, 0)
+}
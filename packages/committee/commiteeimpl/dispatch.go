@@ -2,6 +2,8 @@ package commiteeimpl
 
 import (
 	"bytes"
+
+	"github.com/iotaledger/hive.go/logger"
 	"github.com/iotaledger/wasp/packages/committee"
 	"github.com/iotaledger/wasp/packages/vm"
 	"github.com/iotaledger/wasp/plugins/peering"
@@ -11,7 +13,49 @@ func (c *committeeObj) dispatchMessage(msg interface{}) {
 	if !c.isOpenQueue.Load() {
 		return
 	}
+	if msgt, ok := msg.(*peering.PeerMessage); ok && msgt.MsgType == committee.MsgTestTrace {
+		c.processTestTraceMsg(msgt)
+		return
+	}
+	if msgt, ok := msg.(*committee.StateTransitionMsg); ok && c.checkRotation(msgt.StateTransaction) {
+		return
+	}
+	newDispatcher(c.stateMgr, c.operator, c.log).dispatchMessage(msg)
+}
+
+func (c *committeeObj) processTestTraceMsg(msg *peering.PeerMessage) {
+	msgt := &committee.TestTraceMsg{}
+	if err := msgt.Read(bytes.NewReader(msg.MsgData)); err != nil {
+		c.log.Error(err)
+		return
+	}
+	msgt.SenderIndex = msg.SenderIndex
+	c.testTrace(msgt)
+}
+
+// dispatcher routes messages of the committee to the state manager and to the consensus operator.
+// The operator is nil for access nodes
+type dispatcher struct {
+	stateMgr committee.StateManager
+	operator committee.Operator
+	log      *logger.Logger
+}
+
+func newDispatcher(stateMgr committee.StateManager, operator committee.Operator, log *logger.Logger) *dispatcher {
+	return &dispatcher{
+		stateMgr: stateMgr,
+		operator: operator,
+		log:      log,
+	}
+}
+
+// DispatchMessage routes the message to the state manager or to the consensus operator
+// the same way as the committee of the node does it. Used by the committee simulator
+func DispatchMessage(msg interface{}, stateMgr committee.StateManager, operator committee.Operator, log *logger.Logger) {
+	newDispatcher(stateMgr, operator, log).dispatchMessage(msg)
+}
 
+func (c *dispatcher) dispatchMessage(msg interface{}) {
 	switch msgt := msg.(type) {
 
 	case *peering.PeerMessage:
@@ -69,7 +113,7 @@ func (c *committeeObj) dispatchMessage(msg interface{}) {
 	}
 }
 
-func (c *committeeObj) processPeerMessage(msg *peering.PeerMessage) {
+func (c *dispatcher) processPeerMessage(msg *peering.PeerMessage) {
 
 	rdr := bytes.NewReader(msg.MsgData)
 
@@ -130,19 +174,19 @@ func (c *committeeObj) processPeerMessage(msg *peering.PeerMessage) {
 
 		c.stateMgr.EventGetBatchMsg(msgt)
 
-	case committee.MsgBatchHeader:
-		msgt := &committee.BatchHeaderMsg{}
+	case committee.MsgGetBatches:
+		msgt := &committee.GetBatchesMsg{}
 		if err := msgt.Read(rdr); err != nil {
 			c.log.Error(err)
 			return
 		}
-		c.stateMgr.EvidenceStateIndex(msgt.StateIndex)
 
 		msgt.SenderIndex = msg.SenderIndex
-		c.stateMgr.EventBatchHeaderMsg(msgt)
 
-	case committee.MsgStateUpdate:
-		msgt := &committee.StateUpdateMsg{}
+		c.stateMgr.EventGetBatchesMsg(msgt)
+
+	case committee.MsgBatchHeader:
+		msgt := &committee.BatchHeaderMsg{}
 		if err := msgt.Read(rdr); err != nil {
 			c.log.Error(err)
 			return
@@ -150,17 +194,18 @@ func (c *committeeObj) processPeerMessage(msg *peering.PeerMessage) {
 		c.stateMgr.EvidenceStateIndex(msgt.StateIndex)
 
 		msgt.SenderIndex = msg.SenderIndex
-		c.stateMgr.EventStateUpdateMsg(msgt)
+		c.stateMgr.EventBatchHeaderMsg(msgt)
 
-	case committee.MsgTestTrace:
-		msgt := &committee.TestTraceMsg{}
+	case committee.MsgStateUpdate:
+		msgt := &committee.StateUpdateMsg{}
 		if err := msgt.Read(rdr); err != nil {
 			c.log.Error(err)
 			return
 		}
+		c.stateMgr.EvidenceStateIndex(msgt.StateIndex)
 
 		msgt.SenderIndex = msg.SenderIndex
-		c.testTrace(msgt)
+		c.stateMgr.EventStateUpdateMsg(msgt)
 
 	default:
 		c.log.Errorf("processPeerMessage: wrong msg type")
@@ -0,0 +1,94 @@
+package commiteeimpl
+
+import (
+	"time"
+
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	valuetransaction "github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/transaction"
+	"github.com/iotaledger/hive.go/kvstore"
+	"github.com/iotaledger/wasp/packages/committee"
+	"github.com/iotaledger/wasp/packages/registry"
+	"github.com/iotaledger/wasp/packages/tcrypto"
+	"github.com/iotaledger/wasp/packages/vm"
+	"github.com/iotaledger/wasp/plugins/database"
+	"github.com/iotaledger/wasp/plugins/nodeconn"
+	"github.com/iotaledger/wasp/plugins/peering"
+	"github.com/iotaledger/wasp/plugins/publisher"
+	"github.com/iotaledger/wasp/plugins/runvm"
+)
+
+// nodeEnvironment is the environment of committees running in the Wasp node.
+// The state is kept in the partition of the state address. It differs from the address of the committee
+// if the smart contract was rotated to the committee
+type nodeEnvironment struct {
+	address      address.Address
+	stateAddress address.Address
+	transport    peering.Transport
+}
+
+func newNodeEnvironment(bootupData *registry.BootupData, transport peering.Transport) committee.Environment {
+	return nodeEnvironment{
+		address:      bootupData.Address,
+		stateAddress: *bootupData.StateAddress(),
+		transport:    transport,
+	}
+}
+
+func (nodeEnvironment) Now() time.Time {
+	return time.Now()
+}
+
+func (env nodeEnvironment) ClockDrifts() bool {
+	return env.transport.OwnClockDrifts()
+}
+
+func (env nodeEnvironment) Partition(addr *address.Address) kvstore.KVStore {
+	if *addr == env.address {
+		return database.GetPartition(&env.stateAddress)
+	}
+	return database.GetPartition(addr)
+}
+
+func (nodeEnvironment) GetDKShare(addr *address.Address) (*tcrypto.DKShare, bool, error) {
+	return registry.GetDKShare(addr)
+}
+
+func (nodeEnvironment) SwitchToResharedDKShare(addr *address.Address, committeeNodes []string, ownLocation string) (bool, error) {
+	return registry.SwitchToResharedDKShare(addr, committeeNodes, ownLocation)
+}
+
+func (nodeEnvironment) NextMsgEpoch() (uint64, error) {
+	return registry.NextMsgEpoch()
+}
+
+func (nodeEnvironment) MarkRotated(addr, newAddr *address.Address) error {
+	return registry.MarkRotated(addr, newAddr)
+}
+
+func (nodeEnvironment) GetRewardAddress(scaddr *address.Address) address.Address {
+	return registry.GetRewardAddress(scaddr)
+}
+
+func (nodeEnvironment) GetTrustedPeers() ([]*registry.TrustedPeer, error) {
+	return registry.GetTrustedPeers()
+}
+
+func (nodeEnvironment) RequestOutputs(addr *address.Address) error {
+	return nodeconn.RequestOutputsFromNode(addr)
+}
+
+func (nodeEnvironment) RequestTransaction(txid *valuetransaction.ID) error {
+	return nodeconn.RequestTransactionFromNode(txid)
+}
+
+func (nodeEnvironment) PostTransaction(tx *valuetransaction.Transaction) error {
+	return nodeconn.PostTransactionToNode(tx)
+}
+
+func (nodeEnvironment) RunComputationsAsync(ctx *vm.VMTask) error {
+	return runvm.RunComputationsAsync(ctx)
+}
+
+func (nodeEnvironment) Publish(msgType string, parts ...string) {
+	publisher.Publish(msgType, parts...)
+}
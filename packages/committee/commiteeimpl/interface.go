@@ -7,7 +7,6 @@ import (
 	"github.com/iotaledger/wasp/packages/committee"
 	"github.com/iotaledger/wasp/packages/util"
 	"github.com/iotaledger/wasp/plugins/peering"
-	"github.com/iotaledger/wasp/plugins/publisher"
 	"time"
 )
 
@@ -52,7 +51,7 @@ func (c *committeeObj) checkReady() bool {
 
 		c.log.Debugf("committee now is fully initialized")
 
-		publisher.Publish("active_committee", c.address.String())
+		c.env.Publish("active_committee", c.address.String())
 	}
 	return c.isReadyConsensus && c.isReadyStateManager
 }
@@ -75,16 +74,17 @@ func (c *committeeObj) Dismiss() {
 		c.isOpenQueue.Store(false)
 		c.dismissed.Store(true)
 
-		close(c.chMsg)
+		// chMsg is not closed: senders may still be waiting for it. The queue consumer stops on chDismissed
+		close(c.chDismissed)
 
 		for _, pa := range c.peers {
 			if pa != nil {
-				peering.StopUsingPeer(pa.PeeringId())
+				c.transport.StopUsingPeer(pa.RemoteLocation())
 			}
 		}
 	})
 
-	publisher.Publish("dismissed_committee", c.address.String())
+	c.env.Publish("dismissed_committee", c.address.String())
 }
 
 func (c *committeeObj) IsDismissed() bool {
@@ -107,18 +107,81 @@ func (c *committeeObj) Size() uint16 {
 	return c.size
 }
 
+func (c *committeeObj) Environment() committee.Environment {
+	return c.env
+}
+
+// ReceiveMessage puts the message into the queue. It doesn't block, so the state manager and the operator
+// can send messages to the committee from the queue goroutine and the peering delivers messages to other
+// committees while the queue is full.
+// Messages from peers go to the bounded inbound queue of the committee. They are dropped and counted
+// when it is full. Other messages wait in goroutines until the queue has room
 func (c *committeeObj) ReceiveMessage(msg interface{}) {
+	if !c.isOpenQueue.Load() {
+		return
+	}
+	if msgt, ok := msg.(*peering.PeerMessage); ok {
+		select {
+		case c.chPeerMsg <- msgt:
+		default:
+			if n := c.numDropped.Inc(); n%dropLogEvery == 1 {
+				c.log.Warnf("inbound queue is full: dropped message type %d from peer %d, %d dropped in total",
+					msgt.MsgType, msgt.SenderIndex, n)
+			}
+		}
+		return
+	}
+	select {
+	case c.chMsg <- msg:
+	default:
+		go c.receiveMessageWait(msg)
+	}
+}
+
+// runInbound checks signatures of messages from peers and moves them to the queue in the order of arrival.
+// It is the only goroutine waiting for the queue on behalf of peers
+func (c *committeeObj) runInbound() {
+	for {
+		select {
+		case msg := <-c.chPeerMsg:
+			if err := c.signer.open(msg); err != nil {
+				c.log.Warnf("rejected message type %d from peer %d: %v", msg.MsgType, msg.SenderIndex, err)
+				continue
+			}
+			c.receivePeerMessage(msg)
+		case <-c.chDismissed:
+			return
+		}
+	}
+}
+
+func (c *committeeObj) receivePeerMessage(msg *peering.PeerMessage) {
+	for c.isOpenQueue.Load() {
+		select {
+		case c.chMsg <- msg:
+			return
+		case <-c.chDismissed:
+			return
+		case <-time.After(500 * time.Millisecond):
+			c.log.Warnf("timeout on ReceiveMessage type %d from peer %d. Will be repeated", msg.MsgType, msg.SenderIndex)
+		}
+	}
+}
+
+func (c *committeeObj) receiveMessageWait(msg interface{}) {
 	if c.isOpenQueue.Load() {
 		select {
 		case c.chMsg <- msg:
+		case <-c.chDismissed:
 		case <-time.After(500 * time.Millisecond):
 			c.log.Warnf("timeout on ReceiveMessage type '%T'. Will be repeated", msg)
-			go c.ReceiveMessage(msg)
+			go c.receiveMessageWait(msg)
 		}
 	}
 }
 
 // sends message to peer by index. It can be both committee peer or access peer
+// The message is signed for the target peer
 func (c *committeeObj) SendMsg(targetPeerIndex uint16, msgType byte, msgData []byte) error {
 	if int(targetPeerIndex) >= len(c.peers) {
 		return fmt.Errorf("SendMsg: wrong peer index")
@@ -127,23 +190,33 @@ func (c *committeeObj) SendMsg(targetPeerIndex uint16, msgType byte, msgData []b
 	if peer == nil {
 		return fmt.Errorf("SendMsg: wrong peer")
 	}
-	msg := &peering.PeerMessage{
-		Address:     c.address,
-		SenderIndex: c.ownIndex,
-		MsgType:     msgType,
-		MsgData:     msgData,
+	ts := c.signer.nextTimestamp()
+	msg, err := c.signer.signedMessage(c.ownIndex, targetPeerIndex, ts, msgType, msgData)
+	if err != nil {
+		return err
 	}
 	return peer.SendMsg(msg)
 }
 
+// sends message to all committee peers. Each peer receives the message signed for it,
+// all with the same timestamp, which is returned
 func (c *committeeObj) SendMsgToCommitteePeers(msgType byte, msgData []byte) (uint16, int64) {
-	msg := &peering.PeerMessage{
-		Address:     c.address,
-		SenderIndex: c.ownIndex,
-		MsgType:     msgType,
-		MsgData:     msgData,
+	ts := c.signer.nextTimestamp()
+	ret := uint16(0)
+	for i, peer := range c.committeePeers() {
+		if peer == nil {
+			continue
+		}
+		msg, err := c.signer.signedMessage(c.ownIndex, uint16(i), ts, msgType, msgData)
+		if err != nil {
+			c.log.Errorf("failed to sign message to peer %d: %v", i, err)
+			continue
+		}
+		if err := peer.SendMsg(msg); err == nil {
+			ret++
+		}
 	}
-	return peering.SendMsgToPeers(msg, c.committeePeers()...)
+	return ret, ts
 }
 
 // sends message to the peer seq[seqIndex]. If receives error, seqIndex = (seqIndex+1) % size and repeats
@@ -175,6 +248,9 @@ func (c *committeeObj) IsAlivePeer(peerIndex uint16) bool {
 	if peerIndex == c.ownIndex {
 		return true
 	}
+	if c.peers[peerIndex] == nil {
+		return false
+	}
 	ret, _ := c.peers[peerIndex].IsAlive()
 	return ret
 }
@@ -183,11 +259,26 @@ func (c *committeeObj) OwnPeerIndex() uint16 {
 	return c.ownIndex
 }
 
+// RecordPeerFault increments the fault counter of the committee peer and publishes the 'peer_fault' event through the environment
+func (c *committeeObj) RecordPeerFault(peerIndex uint16, reason string) {
+	if peerIndex >= c.size || peerIndex == c.ownIndex {
+		return
+	}
+	count := c.faults.Inc(peerIndex)
+	c.log.Warnf("peer #%d fault '%s', total %d", peerIndex, reason, count)
+	c.env.Publish("peer_fault", c.address.String(), fmt.Sprintf("%d", peerIndex), fmt.Sprintf("%d", count), reason)
+}
+
+// PeerFaults returns fault counters of committee peers, indexed by the peer index
+func (c *committeeObj) PeerFaults() []uint32 {
+	return c.faults.Counters()
+}
+
 func (c *committeeObj) NumPeers() uint16 {
 	return uint16(len(c.peers))
 }
 
 // first N peers are committee peers, the rest are access peers in any
-func (c *committeeObj) committeePeers() []*peering.Peer {
+func (c *committeeObj) committeePeers() []peering.Peer {
 	return c.peers[:c.size]
 }
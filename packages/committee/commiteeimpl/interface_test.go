@@ -0,0 +1,60 @@
+package commiteeimpl
+
+import (
+	"testing"
+	"time"
+
+	"github.com/iotaledger/hive.go/logger"
+	"github.com/iotaledger/wasp/packages/committee"
+	"github.com/iotaledger/wasp/plugins/peering"
+	"github.com/stretchr/testify/assert"
+)
+
+// messages from peers don't block the peering when the inbound queue is full
+func TestReceivePeerMessageFullQueue(t *testing.T) {
+	c := &committeeObj{
+		chMsg:       make(chan interface{}, 1),
+		chPeerMsg:   make(chan *peering.PeerMessage, 2),
+		chDismissed: make(chan struct{}),
+		env:         newTestEnvironment(nil),
+		log:         logger.NewExampleLogger("committee"),
+	}
+	c.isOpenQueue.Store(true)
+	for i := 0; i < 5; i++ {
+		c.ReceiveMessage(&peering.PeerMessage{MsgType: peering.FirstCommitteeMsgCode})
+	}
+	assert.Len(t, c.chPeerMsg, 2)
+	assert.EqualValues(t, 3, c.numDropped.Load())
+
+	// not accepted after the committee is dismissed
+	c.isOpenQueue.Store(false)
+	<-c.chPeerMsg
+	c.ReceiveMessage(&peering.PeerMessage{MsgType: peering.FirstCommitteeMsgCode})
+	assert.Len(t, c.chPeerMsg, 1)
+}
+
+// the committee is dismissed while the message from the peer waits for the full queue
+func TestDismissWhileReceiving(t *testing.T) {
+	c := &committeeObj{
+		chMsg:       make(chan interface{}),
+		chPeerMsg:   make(chan *peering.PeerMessage, 1),
+		chDismissed: make(chan struct{}),
+		env:         newTestEnvironment(nil),
+		log:         logger.NewExampleLogger("committee"),
+	}
+	c.isOpenQueue.Store(true)
+	done := make(chan struct{})
+	go func() {
+		c.receivePeerMessage(&peering.PeerMessage{MsgType: peering.FirstCommitteeMsgCode})
+		close(done)
+	}()
+
+	c.Dismiss()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("receivePeerMessage didn't stop after Dismiss")
+	}
+	assert.True(t, c.IsDismissed())
+	c.ReceiveMessage(committee.TimerTick(0))
+}
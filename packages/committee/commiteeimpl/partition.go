@@ -0,0 +1,84 @@
+package commiteeimpl
+
+import (
+	"sync"
+
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+)
+
+// The state of the smart contract is kept in the partition of its origin address. After the rotation
+// the old and the new committees of the smart contract run in the node at the same time, so only one of them
+// owns the partition. The committee starts the state manager and the operator when it acquires the partition.
+// The partition is released when the message loop of the owner stops after the committee is dismissed.
+// Nodes running in one process have own databases, so partitions are told apart by the network location of the node
+
+type partitionKey struct {
+	node string
+	addr address.Address
+}
+
+type partitionWaiter struct {
+	c     *committeeObj
+	start func()
+}
+
+type partitionOwner struct {
+	owner   *committeeObj
+	waiting []partitionWaiter
+}
+
+var (
+	partitionOwnersMutex sync.Mutex
+	partitionOwners      = make(map[partitionKey]*partitionOwner)
+)
+
+// acquirePartition calls start if the partition is free, otherwise start is called when the partition is released
+func acquirePartition(key partitionKey, c *committeeObj, start func()) {
+	partitionOwnersMutex.Lock()
+	po, ok := partitionOwners[key]
+	if !ok {
+		partitionOwners[key] = &partitionOwner{owner: c}
+		partitionOwnersMutex.Unlock()
+		start()
+		return
+	}
+	po.waiting = append(po.waiting, partitionWaiter{c: c, start: start})
+	ownerAddr := po.owner.address
+	partitionOwnersMutex.Unlock()
+
+	c.log.Infof("state partition %s is used by the committee %s. Committee will start when it is released",
+		key.addr.String(), ownerAddr.String())
+}
+
+// releasePartition passes the partition to the next waiting committee, if any.
+// The committee which is still waiting for the partition is removed from the queue
+func releasePartition(key partitionKey, c *committeeObj) {
+	partitionOwnersMutex.Lock()
+	po, ok := partitionOwners[key]
+	if !ok {
+		partitionOwnersMutex.Unlock()
+		return
+	}
+	if po.owner != c {
+		for i := range po.waiting {
+			if po.waiting[i].c == c {
+				po.waiting = append(po.waiting[:i], po.waiting[i+1:]...)
+				break
+			}
+		}
+		partitionOwnersMutex.Unlock()
+		return
+	}
+	if len(po.waiting) == 0 {
+		delete(partitionOwners, key)
+		partitionOwnersMutex.Unlock()
+		return
+	}
+	next := po.waiting[0]
+	po.waiting = po.waiting[1:]
+	po.owner = next.c
+	partitionOwnersMutex.Unlock()
+
+	next.c.log.Infof("state partition %s is released. Committee starts", key.addr.String())
+	next.start()
+}
@@ -0,0 +1,46 @@
+package commiteeimpl
+
+import (
+	"testing"
+
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	"github.com/iotaledger/hive.go/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPartitionOwnership(t *testing.T) {
+	log := logger.NewExampleLogger("partition")
+	stateAddr := address.Random()
+	key := partitionKey{node: "node:4000", addr: stateAddr}
+	oldCmt := &committeeObj{address: stateAddr, log: log}
+	newCmt := &committeeObj{address: address.Random(), log: log}
+	otherCmt := &committeeObj{address: address.Random(), log: log}
+
+	var started []*committeeObj
+	starter := func(c *committeeObj) func() {
+		return func() { started = append(started, c) }
+	}
+	acquirePartition(key, oldCmt, starter(oldCmt))
+	acquirePartition(key, newCmt, starter(newCmt))
+	acquirePartition(key, otherCmt, starter(otherCmt))
+	assert.Equal(t, []*committeeObj{oldCmt}, started)
+
+	// the committee of another node in the process has own partition
+	otherNodeCmt := &committeeObj{address: stateAddr, log: log}
+	otherNodeKey := partitionKey{node: "other:4000", addr: stateAddr}
+	acquirePartition(otherNodeKey, otherNodeCmt, starter(otherNodeCmt))
+	assert.Equal(t, []*committeeObj{oldCmt, otherNodeCmt}, started)
+	releasePartition(otherNodeKey, otherNodeCmt)
+	started = started[:1]
+
+	// the waiting committee is dismissed before it starts
+	releasePartition(key, otherCmt)
+	assert.Equal(t, []*committeeObj{oldCmt}, started)
+
+	releasePartition(key, oldCmt)
+	assert.Equal(t, []*committeeObj{oldCmt, newCmt}, started)
+
+	releasePartition(key, newCmt)
+	_, ok := partitionOwners[key]
+	assert.False(t, ok)
+}
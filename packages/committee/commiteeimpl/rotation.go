@@ -0,0 +1,33 @@
+package commiteeimpl
+
+import (
+	valuetransaction "github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/transaction"
+	"github.com/iotaledger/wasp/packages/sctransaction"
+)
+
+// checkRotation dismisses the committee if the state transaction moved the smart contract token
+// from the address of the committee to another address, i.e. the smart contract was rotated to the new committee.
+// The bootup record is marked as rotated, so the committee is not activated again
+func (c *committeeObj) checkRotation(stateTx *sctransaction.Transaction) bool {
+	newAddr, ok, err := stateTx.StateAddress()
+	if err != nil || !ok || newAddr == c.address {
+		return false
+	}
+	spent := false
+	stateTx.Inputs().ForEach(func(oid valuetransaction.OutputID) bool {
+		spent = oid.Address() == c.address
+		return !spent
+	})
+	if !spent {
+		// the state transaction of another committee, before the rotation to this one
+		return false
+	}
+	c.log.Infof("smart contract was rotated to the committee %s", newAddr.String())
+
+	if err := c.env.MarkRotated(&c.address, &newAddr); err != nil {
+		c.log.Errorf("failed to mark the bootup record as rotated: %v", err)
+	}
+	c.env.Publish("rotated_committee", c.address.String(), newAddr.String())
+	c.Dismiss()
+	return true
+}
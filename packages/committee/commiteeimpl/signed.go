@@ -0,0 +1,297 @@
+package commiteeimpl
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	"github.com/iotaledger/wasp/packages/util"
+	"github.com/iotaledger/wasp/plugins/peering"
+)
+
+// Committee messages are wrapped into the signed envelope:
+// Target     string16, network location of the receiver
+// Epoch      8 bytes, epoch of the sender's committee object, grows with each restart of the sender
+// Seq        8 bytes, sequence number of the message from the sender to the target within the epoch
+// Timestamp  8 bytes, time of the message
+// MsgData    bytes32
+// Signature  bytes16
+// The signature covers the committee address, the sender index, the message type
+// and the envelope without signature.
+// Messages are signed with the node identity key and checked with the trusted public key of the network location
+// of the sender index. The key share of the committee only signs state transactions: it controls funds of
+// the smart contract and its signatures are much more expensive to check.
+// The target detects forwarding of the message to another node, the epoch and the sequence number detect replay
+// of the message. The timestamp is not used for the replay detection, so clocks of nodes may differ
+
+// seqWindow is how many sequence numbers back messages of the sender are accepted if they were not seen before.
+// Messages of the same sender may be reordered when sent concurrently
+const seqWindow = 1000
+
+var (
+	errWrongTarget   = errors.New("message is addressed to another node")
+	errBadSignature  = errors.New("invalid signature")
+	errReplayedMsg   = errors.New("replayed message")
+	errUnknownSender = errors.New("unknown sender")
+)
+
+type signedMsg struct {
+	Target    string
+	Epoch     uint64
+	Seq       uint64
+	Timestamp int64
+	MsgData   []byte
+	Signature []byte
+}
+
+func (m *signedMsg) writeEssence(w *bytes.Buffer, addr *address.Address, senderIndex uint16, msgType byte) {
+	w.Write(addr.Bytes())
+	_ = util.WriteUint16(w, senderIndex)
+	_ = util.WriteByte(w, msgType)
+	m.writeEnvelope(w)
+}
+
+func (m *signedMsg) writeEnvelope(w *bytes.Buffer) {
+	_ = util.WriteString16(w, m.Target)
+	_ = util.WriteUint64(w, m.Epoch)
+	_ = util.WriteUint64(w, m.Seq)
+	_ = util.WriteInt64(w, m.Timestamp)
+	_ = util.WriteBytes32(w, m.MsgData)
+}
+
+func (m *signedMsg) essence(addr *address.Address, senderIndex uint16, msgType byte) []byte {
+	var buf bytes.Buffer
+	m.writeEssence(&buf, addr, senderIndex, msgType)
+	return buf.Bytes()
+}
+
+func (m *signedMsg) bytes() []byte {
+	var buf bytes.Buffer
+	m.writeEnvelope(&buf)
+	_ = util.WriteBytes16(&buf, m.Signature)
+	return buf.Bytes()
+}
+
+func readSignedMsg(data []byte) (*signedMsg, error) {
+	rdr := bytes.NewReader(data)
+	ret := &signedMsg{}
+	var err error
+	if ret.Target, err = util.ReadString16(rdr); err != nil {
+		return nil, err
+	}
+	if err = util.ReadUint64(rdr, &ret.Epoch); err != nil {
+		return nil, err
+	}
+	if err = util.ReadUint64(rdr, &ret.Seq); err != nil {
+		return nil, err
+	}
+	if err = util.ReadInt64(rdr, &ret.Timestamp); err != nil {
+		return nil, err
+	}
+	if ret.MsgData, err = util.ReadBytes32(rdr); err != nil {
+		return nil, err
+	}
+	if ret.Signature, err = util.ReadBytes16(rdr); err != nil {
+		return nil, err
+	}
+	if rdr.Len() != 0 {
+		return nil, errors.New("unexpected bytes after the signed message")
+	}
+	return ret, nil
+}
+
+// signMsg signs the envelope of the committee message and returns its bytes
+func signMsg(sign func([]byte) []byte, addr *address.Address, senderIndex uint16, msgType byte, msg *signedMsg) []byte {
+	msg.Signature = sign(msg.essence(addr, senderIndex, msgType))
+	return msg.bytes()
+}
+
+// openSignedMsg checks the envelope received by the node 'me' and returns its contents
+func openSignedMsg(msg *peering.PeerMessage, me string, verify func(data, sig []byte) bool) (*signedMsg, error) {
+	ret, err := readSignedMsg(msg.MsgData)
+	if err != nil {
+		return nil, err
+	}
+	if ret.Target != me {
+		return nil, errWrongTarget
+	}
+	if !verify(ret.essence(&msg.Address, msg.SenderIndex, msg.MsgType), ret.Signature) {
+		return nil, errBadSignature
+	}
+	return ret, nil
+}
+
+// replayFilter remembers sequence numbers of messages received from one sender within the window.
+// Messages of previous epochs are rejected, the new epoch starts the new sequence
+type replayFilter struct {
+	epoch uint64
+	last  uint64
+	seen  map[uint64]struct{}
+}
+
+func (f *replayFilter) accept(epoch uint64, seq uint64) bool {
+	if epoch < f.epoch {
+		return false
+	}
+	if f.seen == nil || epoch > f.epoch {
+		f.epoch = epoch
+		f.last = 0
+		f.seen = make(map[uint64]struct{})
+	}
+	if seq+seqWindow <= f.last {
+		return false
+	}
+	if _, ok := f.seen[seq]; ok {
+		return false
+	}
+	f.seen[seq] = struct{}{}
+	if seq > f.last {
+		f.last = seq
+		for s := range f.seen {
+			if s+seqWindow <= f.last {
+				delete(f.seen, s)
+			}
+		}
+	}
+	return true
+}
+
+// msgSigner keeps state of signing and verification of committee messages for the committee object
+type msgSigner struct {
+	sync.Mutex
+	address address.Address
+	// network locations of committee nodes followed by access nodes, in the order of peer indices
+	peerLocations []string
+	me            string
+	// signs messages and provides trusted keys of network locations
+	identity peering.Identity
+	// the version of the allow-list the cached keys are taken from
+	peerKeysVer   uint64
+	peerKeys      map[uint16]ed25519.PublicKey
+	epoch         uint64
+	seqs          map[string]uint64
+	lastTimestamp int64
+	filters       map[uint16]*replayFilter
+}
+
+// newMsgSigner creates the signer with the identity of the node and the epoch of the committee object
+// (see registry.NextMsgEpoch)
+func newMsgSigner(addr address.Address, me string, committeeNodes, accessNodes []string, identity peering.Identity, epoch uint64) *msgSigner {
+	peerLocations := make([]string, 0, len(committeeNodes)+len(accessNodes))
+	peerLocations = append(peerLocations, committeeNodes...)
+	peerLocations = append(peerLocations, accessNodes...)
+	return &msgSigner{
+		address:       addr,
+		peerLocations: peerLocations,
+		me:            me,
+		identity:      identity,
+		peerKeys:      make(map[uint16]ed25519.PublicKey),
+		epoch:         epoch,
+		seqs:          make(map[string]uint64),
+		filters:       make(map[uint16]*replayFilter),
+	}
+}
+
+// nextTimestamp returns current time, but strictly greater than the previous returned value
+func (s *msgSigner) nextTimestamp() int64 {
+	s.Lock()
+	defer s.Unlock()
+
+	ts := time.Now().UnixNano()
+	if ts <= s.lastTimestamp {
+		ts = s.lastTimestamp + 1
+	}
+	s.lastTimestamp = ts
+	return ts
+}
+
+func (s *msgSigner) signedMessage(senderIndex uint16, targetIndex uint16, ts int64, msgType byte, msgData []byte) (*peering.PeerMessage, error) {
+	if int(targetIndex) >= len(s.peerLocations) {
+		return nil, errUnknownSender
+	}
+	target := s.peerLocations[targetIndex]
+
+	s.Lock()
+	s.seqs[target]++
+	msg := &signedMsg{
+		Target:    target,
+		Epoch:     s.epoch,
+		Seq:       s.seqs[target],
+		Timestamp: ts,
+		MsgData:   msgData,
+	}
+	s.Unlock()
+
+	return &peering.PeerMessage{
+		Address:     s.address,
+		SenderIndex: senderIndex,
+		MsgType:     msgType,
+		MsgData:     signMsg(s.identity.Sign, &s.address, senderIndex, msgType, msg),
+	}, nil
+}
+
+// peerKey returns trusted public key of the node with the peer index.
+// Cached keys are dropped when the allow-list changes
+func (s *msgSigner) peerKey(index uint16) (ed25519.PublicKey, error) {
+	if ver := s.identity.TrustedPeersVersion(); ver != s.peerKeysVer {
+		s.peerKeys = make(map[uint16]ed25519.PublicKey)
+		s.peerKeysVer = ver
+	}
+	if key, ok := s.peerKeys[index]; ok {
+		return key, nil
+	}
+	if int(index) >= len(s.peerLocations) {
+		return nil, errUnknownSender
+	}
+	key, ok, err := s.identity.TrustedPeerKey(s.peerLocations[index])
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is not trusted", errUnknownSender, s.peerLocations[index])
+	}
+	s.peerKeys[index] = key
+	return key, nil
+}
+
+// verifier returns the function checking signatures of the sender
+func (s *msgSigner) verifier(senderIndex uint16) (func(data, sig []byte) bool, error) {
+	key, err := s.peerKey(senderIndex)
+	if err != nil {
+		return nil, err
+	}
+	return func(data, sig []byte) bool {
+		return ed25519.Verify(key, data, sig)
+	}, nil
+}
+
+// open checks the signed envelope of the received message. It replaces data and timestamp
+// of the message with the signed ones
+func (s *msgSigner) open(msg *peering.PeerMessage) error {
+	s.Lock()
+	defer s.Unlock()
+
+	verify, err := s.verifier(msg.SenderIndex)
+	if err != nil {
+		return err
+	}
+	signed, err := openSignedMsg(msg, s.me, verify)
+	if err != nil {
+		return err
+	}
+	f, ok := s.filters[msg.SenderIndex]
+	if !ok {
+		f = &replayFilter{}
+		s.filters[msg.SenderIndex] = f
+	}
+	if !f.accept(signed.Epoch, signed.Seq) {
+		return errReplayedMsg
+	}
+	msg.MsgData = signed.MsgData
+	msg.Timestamp = signed.Timestamp
+	return nil
+}
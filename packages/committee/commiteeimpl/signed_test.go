@@ -0,0 +1,181 @@
+package commiteeimpl
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	"github.com/iotaledger/wasp/plugins/peering"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignedMsg(t *testing.T) {
+	pubKey, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signWith := func(key ed25519.PrivateKey) func([]byte) []byte {
+		return func(data []byte) []byte { return ed25519.Sign(key, data) }
+	}
+	verify := func(data, sig []byte) bool { return ed25519.Verify(pubKey, data, sig) }
+
+	addr := address.Random()
+	ts := time.Now().UnixNano()
+	data := []byte("committee message")
+	msg := func(senderIndex uint16, target string) *peering.PeerMessage {
+		env := &signedMsg{Target: target, Epoch: 1, Seq: 5, Timestamp: ts, MsgData: data}
+		return &peering.PeerMessage{
+			Address:     addr,
+			SenderIndex: senderIndex,
+			MsgType:     peering.FirstCommitteeMsgCode,
+			MsgData:     signMsg(signWith(key), &addr, senderIndex, peering.FirstCommitteeMsgCode, env),
+		}
+	}
+
+	signed, err := openSignedMsg(msg(1, "node2:4000"), "node2:4000", verify)
+	require.NoError(t, err)
+	assert.Equal(t, data, signed.MsgData)
+	assert.Equal(t, ts, signed.Timestamp)
+	assert.EqualValues(t, 1, signed.Epoch)
+	assert.EqualValues(t, 5, signed.Seq)
+
+	// forwarded to another node
+	_, err = openSignedMsg(msg(1, "node2:4000"), "node3:4000", verify)
+	assert.Equal(t, errWrongTarget, err)
+
+	// sent with another sender index
+	m := msg(1, "node2:4000")
+	m.SenderIndex = 0
+	_, err = openSignedMsg(m, "node2:4000", verify)
+	assert.Equal(t, errBadSignature, err)
+
+	// message type changed on the way
+	m = msg(1, "node2:4000")
+	m.MsgType++
+	_, err = openSignedMsg(m, "node2:4000", verify)
+	assert.Equal(t, errBadSignature, err)
+
+	// signed by another key
+	m = msg(1, "node2:4000")
+	m.MsgData = signMsg(signWith(otherKey), &addr, 1, m.MsgType,
+		&signedMsg{Target: "node2:4000", Epoch: 1, Seq: 5, Timestamp: ts, MsgData: data})
+	_, err = openSignedMsg(m, "node2:4000", verify)
+	assert.Equal(t, errBadSignature, err)
+
+	// unsigned
+	m = msg(1, "node2:4000")
+	m.MsgData = data
+	_, err = openSignedMsg(m, "node2:4000", verify)
+	assert.Error(t, err)
+}
+
+func TestReplayFilter(t *testing.T) {
+	f := &replayFilter{}
+
+	assert.True(t, f.accept(10, 1))
+	assert.False(t, f.accept(10, 1))
+	assert.True(t, f.accept(10, 3))
+	// reordered but not seen
+	assert.True(t, f.accept(10, 2))
+	assert.False(t, f.accept(10, 2))
+
+	// too old
+	assert.True(t, f.accept(10, seqWindow+3))
+	assert.Len(t, f.seen, 1)
+	assert.False(t, f.accept(10, 3))
+
+	// the sender restarted
+	assert.True(t, f.accept(11, 1))
+	assert.True(t, f.accept(11, 2))
+	// messages of the previous epoch are replayed
+	assert.False(t, f.accept(10, seqWindow+4))
+}
+
+// testIdentity is the identity with the allow-list changed by the test
+type testIdentity struct {
+	me      string
+	trusted map[string]ed25519.PrivateKey
+	ver     *uint64
+}
+
+func (id *testIdentity) Sign(data []byte) []byte {
+	return ed25519.Sign(id.trusted[id.me], data)
+}
+
+func (id *testIdentity) TrustedPeerKey(loc string) (ed25519.PublicKey, bool, error) {
+	key, ok := id.trusted[loc]
+	if !ok {
+		return nil, false, nil
+	}
+	return key.Public().(ed25519.PublicKey), true, nil
+}
+
+func (id *testIdentity) TrustedPeersVersion() uint64 {
+	return *id.ver
+}
+
+// committee and access nodes sign messages with identity keys. Cached identity keys are dropped
+// when the allow-list changes. The restarted node signs messages with the next epoch
+func TestMsgSigner(t *testing.T) {
+	committeeNodes := []string{"node0:4000", "node1:4000"}
+	accessNodes := []string{"access:4000"}
+	addr := address.Random()
+
+	trustedVer := uint64(0)
+	trusted := make(map[string]ed25519.PrivateKey)
+	for _, loc := range append(append([]string{}, committeeNodes...), accessNodes...) {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		trusted[loc] = key
+	}
+	newSigner := func(me string, epoch uint64) *msgSigner {
+		return newMsgSigner(addr, me, committeeNodes, accessNodes, &testIdentity{me, trusted, &trustedVer}, epoch)
+	}
+	node0 := newSigner(committeeNodes[0], 1)
+	node1 := newSigner(committeeNodes[1], 1)
+	access := newSigner(accessNodes[0], 1)
+	data := []byte("committee message")
+
+	// committee node to committee node
+	msg, err := node0.signedMessage(0, 1, time.Now().UnixNano(), peering.FirstCommitteeMsgCode, data)
+	require.NoError(t, err)
+	require.NoError(t, node1.open(msg))
+	assert.Equal(t, data, msg.MsgData)
+
+	// the key of node 0 doesn't pass as node 1
+	msg, err = node0.signedMessage(1, 1, time.Now().UnixNano(), peering.FirstCommitteeMsgCode, data)
+	require.NoError(t, err)
+	assert.Equal(t, errBadSignature, node1.open(msg))
+
+	// access node to committee node
+	msg, err = access.signedMessage(2, 1, time.Now().UnixNano(), peering.FirstCommitteeMsgCode, data)
+	require.NoError(t, err)
+	require.NoError(t, node1.open(msg))
+
+	// committee node to access node
+	msg, err = node1.signedMessage(1, 2, time.Now().UnixNano(), peering.FirstCommitteeMsgCode, data)
+	require.NoError(t, err)
+	require.NoError(t, access.open(msg))
+
+	// node 0 restarts with the next epoch and the sequence starts again, whatever the clock shows
+	old, err := node0.signedMessage(0, 1, time.Now().UnixNano(), peering.FirstCommitteeMsgCode, data)
+	require.NoError(t, err)
+	node0 = newSigner(committeeNodes[0], 2)
+	msg, err = node0.signedMessage(0, 1, time.Now().Add(-time.Hour).UnixNano(), peering.FirstCommitteeMsgCode, data)
+	require.NoError(t, err)
+	require.NoError(t, node1.open(msg))
+	// messages of the previous run are rejected
+	assert.Equal(t, errReplayedMsg, node1.open(old))
+
+	// the identity key of the access node is replaced after the message is signed with the old key
+	msg, err = access.signedMessage(2, 1, time.Now().UnixNano(), peering.FirstCommitteeMsgCode, data)
+	require.NoError(t, err)
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	trusted[accessNodes[0]] = key
+	trustedVer++
+	assert.Equal(t, errBadSignature, node1.open(msg))
+}
@@ -1,10 +1,15 @@
 package committee
 
 import (
+	"time"
+
 	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
 	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/balance"
+	valuetransaction "github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/transaction"
+	"github.com/iotaledger/hive.go/kvstore"
 	"github.com/iotaledger/hive.go/logger"
 	"github.com/iotaledger/wasp/packages/registry"
+	"github.com/iotaledger/wasp/packages/tcrypto"
 	"github.com/iotaledger/wasp/packages/vm"
 )
 
@@ -21,6 +26,9 @@ type Committee interface {
 	IsAlivePeer(peerIndex uint16) bool
 	ReceiveMessage(msg interface{})
 	InitTestRound()
+	Environment() Environment
+	RecordPeerFault(peerIndex uint16, reason string)
+	PeerFaults() []uint32
 	//
 	SetReadyStateManager()
 	SetReadyConsensus()
@@ -31,6 +39,7 @@ type Committee interface {
 type StateManager interface {
 	EvidenceStateIndex(idx uint32)
 	EventGetBatchMsg(msg *GetBatchMsg)
+	EventGetBatchesMsg(msg *GetBatchesMsg)
 	EventBatchHeaderMsg(msg *BatchHeaderMsg)
 	EventStateUpdateMsg(msg *StateUpdateMsg)
 	EventStateTransactionMsg(msg StateTransactionMsg)
@@ -50,6 +59,29 @@ type Operator interface {
 	EventTimerMsg(msg TimerTick)
 }
 
+// Environment is everything outside of the committee the committee object, the state manager and the consensus
+// operator depend on: time, database and registry of the node, connection to the node, VM and the event publisher.
+// ClockDrifts is true when clocks of peers show that the own clock probably drifts.
+// The node uses the real ones, the simulator and tests replace them with deterministic stand-ins
+type Environment interface {
+	Now() time.Time
+	ClockDrifts() bool
+	Partition(addr *address.Address) kvstore.KVStore
+	// registry of the node (see package registry)
+	GetDKShare(addr *address.Address) (*tcrypto.DKShare, bool, error)
+	SwitchToResharedDKShare(addr *address.Address, committeeNodes []string, ownLocation string) (bool, error)
+	NextMsgEpoch() (uint64, error)
+	MarkRotated(addr, newAddr *address.Address) error
+	GetRewardAddress(scaddr *address.Address) address.Address
+	GetTrustedPeers() ([]*registry.TrustedPeer, error)
+	// connection to the node, VM and the publisher
+	RequestOutputs(addr *address.Address) error
+	RequestTransaction(txid *valuetransaction.ID) error
+	PostTransaction(tx *valuetransaction.Transaction) error
+	RunComputationsAsync(ctx *vm.VMTask) error
+	Publish(msgType string, parts ...string)
+}
+
 var ConstructorNew func(bootupData *registry.BootupData, log *logger.Logger) Committee
 
 func New(bootupData *registry.BootupData, log *logger.Logger) Committee {
@@ -6,12 +6,12 @@ import (
 	"github.com/iotaledger/wasp/packages/state"
 	"github.com/iotaledger/wasp/packages/util"
 	"github.com/iotaledger/wasp/packages/vm"
-	"github.com/iotaledger/wasp/plugins/nodeconn"
 	"time"
 )
 
 func (op *operator) takeAction() {
 	op.requestOutputsIfNeeded()
+	op.checkBatchVMTime()
 	if op.iAmCurrentLeader() {
 		op.startProcessingIfNeeded()
 	}
@@ -29,10 +29,11 @@ func (op *operator) rotateLeaderIfNeeded() {
 	if !op.leaderRotationDeadlineSet {
 		return
 	}
-	if op.leaderRotationDeadline.After(time.Now()) {
+	if op.leaderRotationDeadline.After(op.env.Now()) {
 		return
 	}
 	prevlead, _ := op.currentLeader()
+	op.rollbackPipeline()
 	leader := op.moveToNextLeader()
 	op.log.Infof("LEADER ROTATED #%d --> #%d", prevlead, leader)
 	op.sendRequestNotificationsToLeader(nil)
@@ -42,8 +43,15 @@ func (op *operator) startProcessingIfNeeded() {
 	if !op.synchronized {
 		return
 	}
-	if op.leaderStatus != nil {
-		// request already selected and calculations initialized
+	// the batch starts on the current state or, if the previous batch is finalized
+	// and awaits confirmation, on the state calculated by it
+	base, ok := op.nextBatchBase()
+	if !ok {
+		return
+	}
+	if op.env.Now().UnixNano() <= base.state.Timestamp() {
+		// peers would reject the timestamp. The clock of the node is behind the clock of the previous leader
+		op.log.Debugf("own clock is behind the timestamp of the state. Not proposing the batch")
 		return
 	}
 
@@ -56,10 +64,15 @@ func (op *operator) startProcessingIfNeeded() {
 	reqIds := takeIds(reqs)
 	reqIdsStr := idsShortStr(reqIds)
 	op.log.Debugw("requests selected to process",
-		"stateIdx", op.stateTx.MustState().StateIndex(),
+		"stateIdx", base.state.StateIndex(),
+		"pipelined", base.pipelined,
 		"batch", reqIdsStr,
 	)
 	rewardAddress := op.getRewardAddress()
+	balances := op.balances
+	if base.pipelined {
+		balances = balancesAfter(op.balances, op.committee.Address(), op.leaderStatus.resultTx.Transaction)
+	}
 
 	// send to subordinate the request to process the batch
 	msgData := util.MustBytes(&committee.StartProcessingBatchMsg{
@@ -67,9 +80,11 @@ func (op *operator) startProcessingIfNeeded() {
 			// timestamp is set by SendMsgToCommitteePeers
 			StateIndex: op.stateTx.MustState().StateIndex(),
 		},
-		RewardAddress: rewardAddress,
-		Balances:      op.balances,
-		RequestIds:    reqIds,
+		RewardAddress:  rewardAddress,
+		Balances:       balances,
+		RequestIds:     reqIds,
+		StateHash:      base.state.Hash(),
+		BaseSignatures: base.signatures(),
 	})
 
 	numSucc, ts := op.committee.SendMsgToCommitteePeers(committee.MsgStartProcessingRequest, msgData)
@@ -84,10 +99,12 @@ func (op *operator) startProcessingIfNeeded() {
 
 	batchHash := vm.BatchHash(reqIds, ts)
 	op.leaderStatus = &leaderStatus{
+		base:          base,
 		reqs:          reqs,
 		batchHash:     batchHash,
-		balances:      op.balances,
+		balances:      balances,
 		timestamp:     ts,
+		vmDeadline:    op.env.Now().Add(op.params.maxBatchVMTime),
 		signedResults: make([]*signedResult, op.committee.Size()),
 	}
 	op.log.Debugw("runCalculationsAsync leader",
@@ -97,9 +114,10 @@ func (op *operator) startProcessingIfNeeded() {
 	)
 	// process the batch on own side
 	op.runCalculationsAsync(runCalculationsParams{
+		base:            base,
 		requests:        reqs,
 		leaderPeerIndex: op.committee.OwnPeerIndex(),
-		balances:        op.balances,
+		balances:        balances,
 		timestamp:       ts,
 		rewardAddress:   rewardAddress,
 	})
@@ -115,8 +133,7 @@ func (op *operator) checkQuorum() bool {
 
 	// collect signature shares available
 	mainHash := op.leaderStatus.signedResults[op.committee.OwnPeerIndex()].essenceHash
-	sigShares := make([][]byte, 0, op.committee.Size())
-	contributingPeers := make([]uint16, 0, op.size())
+	numShares := 0
 	for i := range op.leaderStatus.signedResults {
 		if op.leaderStatus.signedResults[i] == nil {
 			continue
@@ -126,26 +143,16 @@ func (op *operator) checkQuorum() bool {
 			op.leaderStatus.signedResults[i] = nil // ignoring
 			continue
 		}
-		err := op.dkshare.VerifySigShare(op.leaderStatus.resultTx.EssenceBytes(), op.leaderStatus.signedResults[i].sigShare)
-		if err != nil {
-			op.log.Warnf("wrong signature from peer #%d: %v", i, err)
-			op.leaderStatus.signedResults[i] = nil // ignoring
-			continue
-		}
-
-		sigShare := op.leaderStatus.signedResults[i].sigShare
-		idx, _ := sigShare.Index()
-		sigShares = append(sigShares, sigShare)
-		contributingPeers = append(contributingPeers, uint16(idx))
+		numShares++
 	}
 
-	if len(sigShares) < int(op.quorum()) {
+	if numShares < int(op.quorum()) {
 		return false
 	}
 	// quorum detected
-	err := op.aggregateSigShares(sigShares)
+	contributingPeers, err := op.aggregateSigShares()
 	if err != nil {
-		op.log.Errorf("aggregateSigShares returned: %v", err)
+		op.log.Warnf("aggregateSigShares returned: %v", err)
 		return false
 	}
 
@@ -159,24 +166,55 @@ func (op *operator) checkQuorum() bool {
 	op.log.Infof("FINALIZED RESULT. txid: %s, state index: #%d, state hash: %s, contributors: %+v",
 		op.leaderStatus.resultTx.ID().String(), stateIndex, sh.String(), contributingPeers)
 	op.leaderStatus.finalized = true
+	op.growBatchSizeCap()
 
-	if err = nodeconn.PostTransactionToNode(op.leaderStatus.resultTx.Transaction); err != nil {
+	if err = op.env.PostTransaction(op.leaderStatus.resultTx.Transaction); err != nil {
 		op.log.Warnf("PostTransactionToNode failed: %v", err)
 		return false
 	}
 	return true
 }
 
+// checkBatchVMTime abandons the batch if the leader didn't calculate it within maxBatchVMTime of the smart contract.
+// Next batches are twice smaller until the batch is finalized in time
+func (op *operator) checkBatchVMTime() {
+	if op.leaderStatus == nil || op.leaderStatus.resultTx != nil {
+		return
+	}
+	if op.env.Now().Before(op.leaderStatus.vmDeadline) {
+		return
+	}
+	batchSize := len(op.leaderStatus.reqs)
+	op.log.Warnf("batch of %d requests wasn't calculated in %v. Abandoned", batchSize, op.params.maxBatchVMTime)
+	op.batchSizeCap = batchSize / 2
+	if op.batchSizeCap == 0 {
+		op.batchSizeCap = 1
+	}
+	op.leaderStatus = nil
+}
+
+func (op *operator) growBatchSizeCap() {
+	if op.batchSizeCap == 0 {
+		return
+	}
+	op.batchSizeCap *= 2
+	if op.batchSizeCap >= op.params.maxBatchSize {
+		op.batchSizeCap = 0
+	}
+}
+
 // sets new currentState transaction and initializes respective variables
 func (op *operator) setNewState(stateTx *sctransaction.Transaction, variableState state.VirtualState, synchronized bool) {
 	op.stateTx = stateTx
 	op.currentState = variableState
 	op.synchronized = synchronized
+	op.params = op.readConsensusParams()
 
-	op.requestBalancesDeadline = time.Now()
+	op.requestBalancesDeadline = op.env.Now()
 	op.requestOutputsIfNeeded()
 
 	op.resetLeader(stateTx.ID().Bytes())
+	op.adjustPipeline()
 
 	op.adjustNotifications()
 }
@@ -187,11 +225,11 @@ func (op *operator) requestOutputsIfNeeded() {
 	if !op.synchronized {
 		return
 	}
-	if op.balances != nil && op.requestBalancesDeadline.After(time.Now()) {
+	if op.balances != nil && op.requestBalancesDeadline.After(op.env.Now()) {
 		return
 	}
-	if err := nodeconn.RequestOutputsFromNode(op.committee.Address()); err != nil {
+	if err := op.env.RequestOutputs(op.committee.Address()); err != nil {
 		op.log.Debugf("RequestOutputsFromNode failed: %v", err)
 	}
-	op.requestBalancesDeadline = time.Now().Add(requestBalancesPeriod)
+	op.requestBalancesDeadline = op.env.Now().Add(requestBalancesPeriod)
 }
@@ -1,6 +1,7 @@
 package consensus
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
@@ -8,7 +9,6 @@ import (
 	"github.com/iotaledger/wasp/packages/util"
 	"github.com/iotaledger/wasp/packages/vm"
 	"github.com/iotaledger/wasp/packages/vm/processor"
-	"github.com/iotaledger/wasp/plugins/publisher"
 )
 
 func (op *operator) EventProcessorReady(msg committee.ProcessorIsReady) {
@@ -56,7 +56,7 @@ func (op *operator) EventStateTransitionMsg(msg *committee.StateTransitionMsg) {
 				op.committee.ReceiveMessage(committee.ProcessorIsReady{
 					ProgramHash: progHashStr,
 				})
-				publisher.Publish("vmready", op.committee.Address().String(), progHashStr)
+				op.env.Publish("vmready", op.committee.Address().String(), progHashStr)
 			} else {
 				op.log.Warn("failed to load processor")
 			}
@@ -69,7 +69,7 @@ func (op *operator) EventStateTransitionMsg(msg *committee.StateTransitionMsg) {
 func (op *operator) EventBalancesMsg(reqMsg committee.BalancesMsg) {
 	op.log.Debugf("EventBalancesMsg: balances arrived\n%s", util.BalancesToString(reqMsg.Balances))
 	op.balances = reqMsg.Balances
-	op.requestBalancesDeadline = time.Now().Add(requestBalancesPeriod)
+	op.requestBalancesDeadline = op.env.Now().Add(requestBalancesPeriod)
 
 	op.takeAction()
 }
@@ -92,11 +92,16 @@ func (op *operator) EventRequestMsg(reqMsg committee.RequestMsg) {
 	req, newRequest := op.requestFromMsg(reqMsg)
 
 	if newRequest {
-		publisher.Publish("request_in",
+		op.env.Publish("request_in",
 			op.committee.Address().String(),
 			reqMsg.Transaction.ID().String(),
 			fmt.Sprintf("%d", reqMsg.Index),
 		)
+		op.limitBacklog()
+		if _, ok := op.requests[req.reqId]; !ok {
+			// dropped
+			return
+		}
 	}
 
 	op.sendRequestNotificationsToLeader([]*request{req})
@@ -127,11 +132,28 @@ func (op *operator) EventStartProcessingBatchMsg(msg *committee.StartProcessingB
 		"batch hash", bh.String(),
 		"reqIds", idsShortStr(msg.RequestIds),
 	)
-	stateIndex, ok := op.stateIndex()
-	if !ok || msg.StateIndex != stateIndex {
+	// the batch is calculated on the current state or on the speculative state, if pipelined
+	base, err := op.batchBaseByHash(msg.StateHash, msg.BaseSignatures)
+	if errors.Is(err, errBaseSignature) {
+		op.log.Warnf("batch proposed by the leader #%d rejected: %v", msg.SenderIndex, err)
+		op.committee.RecordPeerFault(msg.SenderIndex, committee.FaultBadBaseSignature)
+		return
+	}
+	if err != nil {
 		op.log.Debugf("EventStartProcessingBatchMsg: batch out of context")
 		return
 	}
+	if err := checkBatchTimestamp(msg.Timestamp, base.state.Timestamp(), op.env.Now(), op.params.maxClockDeviation); err != nil {
+		if errors.Is(err, errClockDeviation) && op.env.ClockDrifts() {
+			// the leader may be honest, it is the own clock which is wrong
+			op.log.Warnf("batch proposed by the leader #%d rejected: %v. Own clock probably drifts, the leader is not blamed",
+				msg.SenderIndex, err)
+			return
+		}
+		op.log.Warnf("batch proposed by the leader #%d rejected: %v", msg.SenderIndex, err)
+		op.committee.RecordPeerFault(msg.SenderIndex, committee.FaultBadTimestamp)
+		return
+	}
 
 	numOrig := len(msg.RequestIds)
 	reqs := op.takeFromIds(msg.RequestIds)
@@ -139,6 +161,12 @@ func (op *operator) EventStartProcessingBatchMsg(msg *committee.StartProcessingB
 		op.log.Debugf("node can't process the batch: some requests are already processed")
 		return
 	}
+	for _, req := range reqs {
+		if base.contains(&req.reqId) {
+			op.log.Warnf("node can't process the batch: request %s is in the previous batch", req.reqId.Short())
+			return
+		}
+	}
 	reqs = op.filterNotReadyYet(reqs)
 	if len(reqs) != numOrig {
 		op.log.Debugf("node is not ready to process the batch")
@@ -146,6 +174,7 @@ func (op *operator) EventStartProcessingBatchMsg(msg *committee.StartProcessingB
 	}
 	// start async calculation
 	op.runCalculationsAsync(runCalculationsParams{
+		base:            base,
 		requests:        reqs,
 		timestamp:       msg.Timestamp,
 		balances:        msg.Balances,
@@ -154,11 +183,27 @@ func (op *operator) EventStartProcessingBatchMsg(msg *committee.StartProcessingB
 	})
 }
 
+var errClockDeviation = errors.New("timestamp deviates from the clock of the node")
+
+// checkBatchTimestamp checks the timestamp proposed by the leader. It becomes the timestamp of the new state,
+// so it must be after the timestamp of the previous state and close to the own clock.
+// Otherwise the leader with the wrong clock could move the time of the smart contract
+func checkBatchTimestamp(ts, prevTs int64, now time.Time, maxDeviation time.Duration) error {
+	if ts <= prevTs {
+		return fmt.Errorf("timestamp %d is not after the timestamp of the previous state %d", ts, prevTs)
+	}
+	if deviation := time.Duration(ts - now.UnixNano()); deviation > maxDeviation || deviation < -maxDeviation {
+		return fmt.Errorf("%w by %v", errClockDeviation, deviation)
+	}
+	return nil
+}
+
 func (op *operator) EventResultCalculated(ctx *vm.VMTask) {
 	op.log.Debugf("eventResultCalculated")
 
-	// check if result belongs to context
-	if ctx.ResultBatch.StateIndex() != op.mustStateIndex()+1 {
+	// check if result belongs to context: the batch is calculated on the current state or pipelined on top of it
+	resultIndex := ctx.ResultBatch.StateIndex()
+	if resultIndex != op.mustStateIndex()+1 && resultIndex != op.mustStateIndex()+2 {
 		// out of context. ignore
 		return
 	}
@@ -167,18 +212,25 @@ func (op *operator) EventResultCalculated(ctx *vm.VMTask) {
 		"stateIndex", op.mustStateIndex(),
 	)
 
+	if ctx.LeaderPeerIndex == op.committee.OwnPeerIndex() && !op.isOwnBatch(ctx) {
+		// the leader abandoned the batch
+		op.log.Debugf("eventResultCalculated: result of the abandoned batch ignored")
+		return
+	}
+
 	// inform currentState manager about new result batch
-	go func() {
-		op.committee.ReceiveMessage(committee.PendingBatchMsg{
-			Batch: ctx.ResultBatch,
-		})
-	}()
+	op.committee.ReceiveMessage(committee.PendingBatchMsg{
+		Batch: ctx.ResultBatch,
+	})
+	// the next batch may be pipelined on top of the result
+	op.saveSpeculativeState(ctx)
 
 	// save own result or send to the leader
 	if ctx.LeaderPeerIndex == op.committee.OwnPeerIndex() {
 		op.saveOwnResult(ctx)
 	} else {
 		op.sendResultToTheLeader(ctx)
+		op.setBatchSigned(ctx.VirtualState.Hash())
 	}
 
 	op.takeAction()
@@ -196,12 +248,14 @@ func (op *operator) EventSignedHashMsg(msg *committee.SignedHashMsg) {
 		// shouldn't be
 		return
 	}
-	if stateIndex, ok := op.stateIndex(); !ok || msg.StateIndex != stateIndex {
+	// the pipelined batch is signed in the context of the state before its base
+	baseIndex := op.leaderStatus.base.state.StateIndex()
+	if msg.StateIndex != baseIndex && msg.StateIndex+1 != baseIndex {
 		// out of context
 		return
 	}
 	if msg.BatchHash != op.leaderStatus.batchHash {
-		op.log.Errorf("EventSignedHashMsg: msg.BatchHash != op.leaderStatus.batchHash")
+		op.log.Debugf("EventSignedHashMsg: msg.BatchHash != op.leaderStatus.batchHash")
 		return
 	}
 	if op.leaderStatus.signedResults[msg.SenderIndex] != nil {
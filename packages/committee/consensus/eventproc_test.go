@@ -0,0 +1,33 @@
+package consensus
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckBatchTimestamp(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	prevTs := now.Add(-time.Minute).UnixNano()
+	const maxDeviation = 5 * time.Second
+
+	assert.NoError(t, checkBatchTimestamp(now.UnixNano(), prevTs, now, maxDeviation))
+	// sent a bit ago or by the leader with the clock a bit ahead
+	assert.NoError(t, checkBatchTimestamp(now.Add(-time.Second).UnixNano(), prevTs, now, maxDeviation))
+	assert.NoError(t, checkBatchTimestamp(now.Add(maxDeviation).UnixNano(), prevTs, now, maxDeviation))
+
+	// leader's clock too far ahead or behind. Or the own clock, then the leader is not blamed
+	err := checkBatchTimestamp(now.Add(time.Hour).UnixNano(), prevTs, now, maxDeviation)
+	assert.True(t, errors.Is(err, errClockDeviation))
+	err = checkBatchTimestamp(now.Add(-maxDeviation-time.Millisecond).UnixNano(), prevTs, now, maxDeviation)
+	assert.True(t, errors.Is(err, errClockDeviation))
+
+	// time of the smart contract can't go back or stay
+	err = checkBatchTimestamp(prevTs, prevTs, now, time.Hour)
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, errClockDeviation))
+	assert.Error(t, checkBatchTimestamp(prevTs-1, prevTs, now, time.Hour))
+	assert.NoError(t, checkBatchTimestamp(prevTs+1, prevTs, now, time.Hour))
+}
@@ -1,7 +1,8 @@
 package consensus
 
 import (
-	"time"
+	valuetransaction "github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/transaction"
+	"github.com/iotaledger/wasp/packages/util"
 )
 
 func (op *operator) currentLeader() (uint16, bool) {
@@ -14,8 +15,6 @@ func (op *operator) iAmCurrentLeader() bool {
 	return ok && op.committee.OwnPeerIndex() == idx
 }
 
-const leaderRotationPeriod = 3 * time.Second
-
 func (op *operator) moveToNextLeader() uint16 {
 	op.peerPermutation.Next()
 	ret := op.moveToFirstAliveLeader()
@@ -25,11 +24,15 @@ func (op *operator) moveToNextLeader() uint16 {
 
 func (op *operator) resetLeader(seedBytes []byte) {
 	op.peerPermutation.Shuffle(seedBytes)
-	op.leaderStatus = nil
 	op.moveToFirstAliveLeader()
 	op.leaderRotationDeadlineSet = false
 }
 
+// firstLeaderOf returns the first leader of the state anchored by the transaction
+func (op *operator) firstLeaderOf(txid valuetransaction.ID) uint16 {
+	return util.NewPermutation16(op.committee.Size(), txid.Bytes()).Current()
+}
+
 // select leader first in the permutation which is alive
 // then sets deadline if itself is not the leader
 func (op *operator) moveToFirstAliveLeader() uint16 {
@@ -52,5 +55,5 @@ func (op *operator) setLeaderRotationDeadline() {
 		return
 	}
 	op.leaderRotationDeadlineSet = true
-	op.leaderRotationDeadline = time.Now().Add(leaderRotationPeriod)
+	op.leaderRotationDeadline = op.env.Now().Add(op.params.leaderTimeout)
 }
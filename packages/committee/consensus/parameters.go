@@ -0,0 +1,93 @@
+package consensus
+
+import (
+	"time"
+
+	"github.com/iotaledger/wasp/packages/table"
+	"github.com/iotaledger/wasp/packages/vm/vmconst"
+	"github.com/iotaledger/wasp/plugins/config"
+	flag "github.com/spf13/pflag"
+)
+
+const (
+	CfgStarvationStates  = "consensus.starvationstates"
+	CfgMaxClockDeviation = "consensus.maxclockdeviation"
+
+	DefaultStarvationStates  = 10
+	DefaultMaxClockDeviation = 5000
+)
+
+// protocol defaults of consensus parameters not set in the state of the smart contract.
+// They are the same on every node, so nodes with different configuration reach the same decisions
+const (
+	DefaultMaxBatchSize   = 100
+	DefaultMaxBatchVMTime = 10000
+	DefaultLeaderTimeout  = 3000
+	DefaultMaxBacklog     = 10000
+)
+
+func init() {
+	flag.Int(CfgStarvationStates, DefaultStarvationStates,
+		"number of state transitions after which the waiting request is taken to the batch before requests paying higher rewards")
+	flag.Int(CfgMaxClockDeviation, DefaultMaxClockDeviation,
+		"milliseconds the timestamp of the batch proposed by the leader may deviate from the clock of the node")
+}
+
+// not set or not positive values mean default
+func configuredInt(name string, def int) int {
+	if ret := config.Node.GetInt(name); ret > 0 {
+		return ret
+	}
+	return def
+}
+
+// consensusParams of the smart contract. The owner can set them in the state with the builtin request,
+// the protocol defaults are used for parameters not set in the state
+type consensusParams struct {
+	maxBatchSize   int
+	maxBatchVMTime time.Duration
+	leaderTimeout  time.Duration
+	maxBacklog     int
+	// not set in the state, always the configuration of the node
+	maxClockDeviation time.Duration
+}
+
+func defaultConsensusParams() consensusParams {
+	return consensusParams{
+		maxBatchSize:   DefaultMaxBatchSize,
+		maxBatchVMTime: DefaultMaxBatchVMTime * time.Millisecond,
+		leaderTimeout:  DefaultLeaderTimeout * time.Millisecond,
+		maxBacklog:     DefaultMaxBacklog,
+		maxClockDeviation: time.Duration(configuredInt(CfgMaxClockDeviation, DefaultMaxClockDeviation)) *
+			time.Millisecond,
+	}
+}
+
+// readConsensusParams reads parameters of the current state
+func (op *operator) readConsensusParams() consensusParams {
+	ret := op.defaultParams
+	if op.currentState == nil {
+		return ret
+	}
+	get := func(name string) (int64, bool) {
+		v, ok, err := op.currentState.Variables().Codec().GetInt64(table.Key(name))
+		if err != nil {
+			op.log.Warnf("wrong consensus parameter %s in the state: %v", name, err)
+			return 0, false
+		}
+		return v, ok && v > 0
+	}
+	if v, ok := get(vmconst.VarNameMaxBatchSize); ok {
+		ret.maxBatchSize = int(v)
+	}
+	if v, ok := get(vmconst.VarNameMaxBatchVMTime); ok {
+		ret.maxBatchVMTime = time.Duration(v) * time.Millisecond
+	}
+	if v, ok := get(vmconst.VarNameLeaderTimeout); ok {
+		ret.leaderTimeout = time.Duration(v) * time.Millisecond
+	}
+	if v, ok := get(vmconst.VarNameMaxBacklog); ok {
+		ret.maxBacklog = int(v)
+	}
+	return ret
+}
@@ -0,0 +1,31 @@
+package consensus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/iotaledger/wasp/packages/vm/vmconst"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadConsensusParams(t *testing.T) {
+	op := newTestOperator(1, 0, 0)
+	op.defaultParams = defaultConsensusParams()
+	assert.Equal(t, consensusParams{
+		maxBatchSize:      DefaultMaxBatchSize,
+		maxBatchVMTime:    DefaultMaxBatchVMTime * time.Millisecond,
+		leaderTimeout:     DefaultLeaderTimeout * time.Millisecond,
+		maxBacklog:        DefaultMaxBacklog,
+		maxClockDeviation: DefaultMaxClockDeviation * time.Millisecond,
+	}, op.readConsensusParams())
+
+	vars := op.currentState.Variables().Codec()
+	vars.SetInt64(vmconst.VarNameMaxBatchSize, 5)
+	vars.SetInt64(vmconst.VarNameLeaderTimeout, 500)
+	params := op.readConsensusParams()
+	assert.Equal(t, 5, params.maxBatchSize)
+	assert.Equal(t, 500*time.Millisecond, params.leaderTimeout)
+	assert.Equal(t, op.defaultParams.maxBatchVMTime, params.maxBatchVMTime)
+	assert.Equal(t, op.defaultParams.maxBacklog, params.maxBacklog)
+	assert.Equal(t, op.defaultParams.maxClockDeviation, params.maxClockDeviation)
+}
@@ -0,0 +1,251 @@
+package consensus
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/balance"
+	valuetransaction "github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/transaction"
+	"github.com/iotaledger/wasp/packages/hashing"
+	"github.com/iotaledger/wasp/packages/sctransaction"
+	"github.com/iotaledger/wasp/packages/state"
+	"github.com/iotaledger/wasp/packages/vm"
+	"github.com/iotaledger/wasp/packages/vm/vmconst"
+)
+
+// Pipelining. The leader doesn't wait for the confirmation of the finalized batch N: it starts batch N+1
+// on top of the state calculated by batch N. Each node keeps states it calculated as speculative states
+// until the state transition, so it is able to calculate the pipelined batch too.
+// If batch N is not confirmed, the pipelined batch is rolled back on the state transition to another state
+// or when the leader is rotated
+
+// speculativeState is the result of the batch calculated by the node on top of the current state
+type speculativeState struct {
+	state    state.VirtualState
+	resultTx *sctransaction.Transaction
+	reqIds   []sctransaction.RequestId
+}
+
+// batchBase is the state the batch is calculated on
+type batchBase struct {
+	state state.VirtualState
+	// signed state transaction anchoring the state. The entropy of the batch is its ID, which depends
+	// on the threshold signature, so it is not known to anybody, the leader included, before the quorum
+	// signs the transaction. It is the same for the pipelined base and for the same base when confirmed
+	stateTx *sctransaction.Transaction
+	// requests of the not confirmed batch. Not empty only for the pipelined base
+	reqIds    []sctransaction.RequestId
+	pipelined bool
+}
+
+func (b *batchBase) entropy() hashing.HashValue {
+	return (hashing.HashValue)(b.stateTx.ID())
+}
+
+// signatures of the state transaction, sent to peers with the pipelined batch
+func (b *batchBase) signatures() []byte {
+	if !b.pipelined {
+		return nil
+	}
+	return b.stateTx.SignatureBytes()
+}
+
+func (b *batchBase) contains(reqId *sctransaction.RequestId) bool {
+	for i := range b.reqIds {
+		if b.reqIds[i] == *reqId {
+			return true
+		}
+	}
+	return false
+}
+
+func (op *operator) currentBase() *batchBase {
+	return &batchBase{
+		state:   op.currentState,
+		stateTx: op.stateTx,
+	}
+}
+
+var (
+	errBaseOutOfContext = errors.New("the state is neither the current state nor the speculative state on top of it")
+	errBaseSignature    = errors.New("invalid signature of the state transaction awaiting confirmation")
+)
+
+// batchBaseByHash returns the current state or the speculative state on top of it.
+// The speculative state is anchored by the transaction calculated by the node, which awaits confirmation.
+// The signatures of the transaction must be provided by the leader
+func (op *operator) batchBaseByHash(stateHash hashing.HashValue, signatures []byte) (*batchBase, error) {
+	if op.currentState == nil {
+		return nil, errBaseOutOfContext
+	}
+	if op.currentState.Hash() == stateHash {
+		return op.currentBase(), nil
+	}
+	spec, ok := op.speculativeStates[stateHash]
+	if !ok || spec.state.StateIndex() != op.mustStateIndex()+1 {
+		// only one batch ahead of the current state
+		return nil, errBaseOutOfContext
+	}
+	stateTx, err := withSignatures(spec.resultTx, op.committee.Address(), signatures)
+	if err != nil {
+		return nil, err
+	}
+	return &batchBase{
+		state:     spec.state,
+		stateTx:   stateTx,
+		reqIds:    spec.reqIds,
+		pipelined: true,
+	}, nil
+}
+
+// withSignatures returns the transaction signed with the signature of the address taken from signatures
+func withSignatures(tx *sctransaction.Transaction, addr *address.Address, signatures []byte) (*sctransaction.Transaction, error) {
+	sigs, _, err := valuetransaction.SignaturesFromBytes(signatures)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errBaseSignature, err)
+	}
+	sig, ok := sigs.Get(*addr)
+	if !ok || !sig.IsValid(tx.EssenceBytes()) {
+		return nil, errBaseSignature
+	}
+	// parsed transaction caches its bytes, so it is parsed with the signature rather than signed after parsing
+	signed := valuetransaction.NewSignatures()
+	signed.Add(*addr, sig)
+	data := make([]byte, 0, len(tx.EssenceBytes())+len(signed.Bytes()))
+	data = append(data, tx.EssenceBytes()...)
+	data = append(data, signed.Bytes()...)
+	return sctransaction.NewFromBytes(data)
+}
+
+// saveSpeculativeState keeps the state calculated by the VM until the state transition
+func (op *operator) saveSpeculativeState(result *vm.VMTask) {
+	nextState := result.VirtualState.Clone()
+	if err := nextState.ApplyBatch(result.ResultBatch); err != nil {
+		op.log.Errorf("saveSpeculativeState: %v", err)
+		return
+	}
+	reqIds := make([]sctransaction.RequestId, len(result.Requests))
+	for i := range reqIds {
+		reqIds[i] = *result.Requests[i].RequestId()
+	}
+	op.speculativeStates[nextState.Hash()] = &speculativeState{
+		state:    nextState,
+		resultTx: result.ResultTransaction,
+		reqIds:   reqIds,
+	}
+}
+
+// cleanSpeculativeStates removes speculative states which can't be used after the state transition
+func (op *operator) cleanSpeculativeStates() {
+	for h, spec := range op.speculativeStates {
+		if spec.state.StateIndex() <= op.mustStateIndex() {
+			delete(op.speculativeStates, h)
+		}
+	}
+}
+
+// nextBatchBase returns the state the leader can start the next batch on
+func (op *operator) nextBatchBase() (*batchBase, bool) {
+	if op.leaderStatus == nil {
+		if op.awaitsSignedBatch() {
+			// another leader has pipelined the batch on top of the current state
+			return nil, false
+		}
+		if !op.ownsState(op.stateTx) {
+			// the smart contract is rotated away or not yet rotated to the committee
+			return nil, false
+		}
+		return op.currentBase(), true
+	}
+	if !op.leaderStatus.finalized || op.leaderStatus.base.pipelined {
+		// the batch is in progress or the pipeline is full
+		return nil, false
+	}
+	if !op.ownsState(op.leaderStatus.resultTx) {
+		// the finalized batch rotates the smart contract to another committee
+		return nil, false
+	}
+	if op.firstLeaderOf(op.leaderStatus.resultTx.ID()) != op.committee.OwnPeerIndex() {
+		// another node leads on the next state. Pipelining must not change the order of leaders
+		return nil, false
+	}
+	// the finalized transaction is signed by the quorum
+	resultTx := op.leaderStatus.resultTx
+	ret, err := op.batchBaseByHash(resultTx.MustState().StateHash(), resultTx.SignatureBytes())
+	if err != nil || !ret.pipelined {
+		return nil, false
+	}
+	return ret, true
+}
+
+// setBatchSigned records that the node signed the result of the batch of another leader calculated on the state.
+// The node doesn't start own batch on the same state until the signed batch is confirmed or the leader times out.
+// It prevents the next leader from conflicting with the batch pipelined on top of the new state
+func (op *operator) setBatchSigned(stateHash hashing.HashValue) {
+	op.signedBaseHash = stateHash
+	op.signedBaseDeadline = op.env.Now().Add(op.params.leaderTimeout)
+}
+
+func (op *operator) awaitsSignedBatch() bool {
+	if op.signedBaseDeadline.IsZero() || !op.env.Now().Before(op.signedBaseDeadline) {
+		return false
+	}
+	return op.signedBaseHash == op.currentState.Hash()
+}
+
+// adjustPipeline is called upon state transition. The leader keeps the pipelined batch if it was calculated
+// on top of the new state, otherwise the batch is rolled back
+func (op *operator) adjustPipeline() {
+	op.cleanSpeculativeStates()
+	if op.leaderStatus == nil {
+		return
+	}
+	if op.leaderStatus.base.pipelined && op.leaderStatus.base.state.Hash() == op.currentState.Hash() {
+		op.log.Debugf("pipelined batch continues on the confirmed state #%d", op.mustStateIndex())
+		op.leaderStatus.base.pipelined = false
+		return
+	}
+	op.leaderStatus = nil
+}
+
+// rollbackPipeline discards the pipelined batch and speculative states
+func (op *operator) rollbackPipeline() {
+	if op.leaderStatus != nil && op.leaderStatus.base.pipelined {
+		op.log.Infof("pipelined batch rolled back: previous batch wasn't confirmed in time")
+		op.leaderStatus = nil
+	}
+	op.speculativeStates = make(map[hashing.HashValue]*speculativeState)
+	op.signedBaseDeadline = time.Time{}
+}
+
+// sameProgramHash checks if the program hash in the state is the same as in the current state,
+// i.e. the processor loaded for the current state can process the batch on top of the state
+func (op *operator) sameProgramHash(vs state.VirtualState) bool {
+	h1, ok1 := op.getProgramHash()
+	h2, ok2, err := vs.Variables().Codec().GetHashValue(vmconst.VarNameProgramHash)
+	if err != nil || ok1 != ok2 {
+		return false
+	}
+	return !ok1 || *h1 == *h2
+}
+
+// balancesAfter returns outputs of the address after the transaction: outputs consumed by the transaction
+// are removed and outputs of the transaction to the address are added
+func balancesAfter(balances map[valuetransaction.ID][]*balance.Balance, addr *address.Address, tx *valuetransaction.Transaction) map[valuetransaction.ID][]*balance.Balance {
+	ret := make(map[valuetransaction.ID][]*balance.Balance, len(balances)+1)
+	for txid, bals := range balances {
+		ret[txid] = bals
+	}
+	tx.Inputs().ForEach(func(outputId valuetransaction.OutputID) bool {
+		if outputId.Address() == *addr {
+			delete(ret, outputId.TransactionID())
+		}
+		return true
+	})
+	if bals, ok := tx.Outputs().Get(*addr); ok {
+		ret[tx.ID()] = bals.([]*balance.Balance)
+	}
+	return ret
+}
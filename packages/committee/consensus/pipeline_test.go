@@ -0,0 +1,89 @@
+package consensus
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address/signaturescheme"
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/balance"
+	valuetransaction "github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/transaction"
+	"github.com/iotaledger/wasp/packages/hashing"
+	"github.com/iotaledger/wasp/packages/sctransaction"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBalancesAfter(t *testing.T) {
+	other := address.Random()
+	consumed := valuetransaction.ID{1}
+	kept := valuetransaction.ID{2}
+	balances := map[valuetransaction.ID][]*balance.Balance{
+		consumed: {balance.New(balance.ColorIOTA, 5)},
+		kept:     {balance.New(balance.ColorIOTA, 7)},
+	}
+	tx := valuetransaction.New(
+		valuetransaction.NewInputs(
+			valuetransaction.NewOutputID(testAddress, consumed),
+			valuetransaction.NewOutputID(other, kept),
+		),
+		valuetransaction.NewOutputs(map[address.Address][]*balance.Balance{
+			testAddress: {balance.New(balance.ColorIOTA, 4)},
+			other:       {balance.New(balance.ColorIOTA, 1)},
+		}),
+	)
+	ret := balancesAfter(balances, &testAddress, tx)
+	assert.Len(t, ret, 2)
+	assert.Equal(t, balances[kept], ret[kept])
+	assert.EqualValues(t, 4, ret[tx.ID()][0].Value)
+
+	// the same when the ledger already knows the transaction
+	assert.Equal(t, ret, balancesAfter(ret, &testAddress, tx))
+	// the original is not changed
+	assert.Len(t, balances, 2)
+	assert.Contains(t, balances, consumed)
+}
+
+// the entropy of the batch pipelined on the unconfirmed transaction depends on its threshold signature:
+// the leader can't calculate it before the quorum signs the transaction and can't replace the signature
+func TestEntropyUnpredictable(t *testing.T) {
+	op, shares := newTestLeader(t)
+	tx := op.leaderStatus.resultTx
+	addr := *op.dkshare.Address
+	essence := tx.EssenceBytes()
+	// all the leader knows alone
+	essenceHash := *hashing.HashData(essence)
+
+	// the own share is not enough to sign
+	receive(op, shares[0], nil, nil, nil)
+	_, err := op.aggregateSigShares()
+	assert.Error(t, err)
+
+	// signatures not produced by the quorum are rejected by peers
+	pubKey, err := op.dkshare.PubKeyMaster.MarshalBinary()
+	assert.NoError(t, err)
+	forged := []signaturescheme.Signature{
+		signaturescheme.NewBLSSignature(pubKey, shares[0][2:]),
+		signaturescheme.RandBLS().Sign(essence),
+	}
+	for _, sig := range forged {
+		sigs := valuetransaction.NewSignatures()
+		sigs.Add(sig.Address(), sig)
+		_, err = withSignatures(tx, &addr, sigs.Bytes())
+		assert.True(t, errors.Is(err, errBaseSignature))
+	}
+
+	receive(op, nil, shares[1], shares[2], shares[3])
+	_, err = op.aggregateSigShares()
+	assert.NoError(t, err)
+
+	// the peer calculated the same transaction and receives signatures from the leader
+	peerTx, err := withSignatures(tx, &addr, tx.SignatureBytes())
+	assert.NoError(t, err)
+	pipelined := &batchBase{stateTx: peerTx, pipelined: true}
+	assert.NotEqual(t, essenceHash, pipelined.entropy())
+
+	// the entropy is the same when the transaction is confirmed
+	confirmed, err := sctransaction.NewFromBytes(tx.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, (hashing.HashValue)(confirmed.ID()), pipelined.entropy())
+}
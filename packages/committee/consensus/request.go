@@ -1,13 +1,15 @@
 package consensus
 
 import (
+	"bytes"
+	"sort"
+
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/balance"
 	valuetransaction "github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/transaction"
 	"github.com/iotaledger/wasp/packages/committee"
 	"github.com/iotaledger/wasp/packages/sctransaction"
 	"github.com/iotaledger/wasp/packages/state"
 	"github.com/iotaledger/wasp/packages/util"
-	"sort"
-	"time"
 )
 
 // check if the request message is well formed
@@ -58,15 +60,13 @@ func (op *operator) requestFromMsg(reqMsg committee.RequestMsg) (*request, bool)
 	if ok {
 		newreq := ret.reqTx == nil
 		if newreq {
-			ret.reqTx = reqMsg.Transaction
-			ret.whenMsgReceived = time.Now()
+			op.setRequestTx(ret, reqMsg.Transaction)
 		}
 		return ret, newreq
 	}
 	if !ok {
 		ret = op.newRequest(reqId)
-		ret.whenMsgReceived = time.Now()
-		ret.reqTx = reqMsg.Transaction
+		op.setRequestTx(ret, reqMsg.Transaction)
 		op.requests[reqId] = ret
 	}
 	ret.notifications[op.peerIndex()] = true
@@ -74,25 +74,42 @@ func (op *operator) requestFromMsg(reqMsg committee.RequestMsg) (*request, bool)
 	return ret, true
 }
 
+func (op *operator) setRequestTx(req *request, tx *sctransaction.Transaction) {
+	req.reqTx = tx
+	req.whenMsgReceived = op.env.Now()
+	req.stateIndexReceived, _ = op.stateIndex()
+
+	// iotas sent to the smart contract by the transaction are shared equally by all its requests
+	addr := op.committee.Address()
+	if numReqs := len(tx.RequestsToAddress(addr)); numReqs > 0 {
+		req.reward = sctransaction.OutputValueOfColor(tx, *addr, balance.ColorIOTA) / int64(numReqs)
+	}
+}
+
 func (req *request) requestCode() sctransaction.RequestCode {
 	return req.reqTx.Requests()[req.reqId.Index()].RequestCode()
 }
 
 // selectRequestsToProcess select requests to process in the batch by counting votes of notification messages
-// first it selects candidates with >= quorum 'seen' votes and sorts by num votes
+// first it selects candidates with >= quorum 'seen' votes and sorts them by priority (see sortByPriority),
+// starving requests first (see starvingFirst)
 // then it selects maximum number of requests which has been seen by at least quorum of common peers
-// the requests are sorted by arrival time
 // only requests in "full batches" are selected, it means request is in the selection together with ALL other requests
 // from the same request transaction, or it is not selected
+// the number of requests in the batch is limited by maxBatchSize of the smart contract.
+// The selected batch is ordered by priority only
 func (op *operator) selectRequestsToProcess() []*request {
 	candidates := op.requestMessagesSeenQuorumTimes()
 	if len(candidates) == 0 {
 		return nil
 	}
 	candidates = op.filterNotReadyYet(candidates)
+	candidates = op.filterInLeaderBatch(candidates)
 	if len(candidates) == 0 {
 		return nil
 	}
+	op.sortByPriority(candidates)
+	candidates = op.starvingFirst(candidates)
 
 	ret := []*request{candidates[0]}
 	intersection := make([]bool, op.size())
@@ -120,9 +137,131 @@ func (op *operator) selectRequestsToProcess() []*request {
 			before, after, util.BalancesToString(op.balances))
 	}
 
-	sort.Slice(ret, func(i, j int) bool {
-		return ret[i].whenMsgReceived.Before(ret[j].whenMsgReceived)
+	ret = op.limitBatchSize(ret)
+	// the batch is ordered in the same way on every node
+	op.sortByPriority(ret)
+	return ret
+}
+
+// sortByPriority orders requests in the same way on every node:
+// requests paying more reward go first, then by request id
+func (op *operator) sortByPriority(reqs []*request) {
+	sort.SliceStable(reqs, func(i, j int) bool {
+		if reqs[i].reward != reqs[j].reward {
+			return reqs[i].reward > reqs[j].reward
+		}
+		return bytes.Compare(reqs[i].reqId[:], reqs[j].reqId[:]) < 0
+	})
+}
+
+// starvingFirst moves requests waiting more than starvationStates state transitions to the front, the oldest first.
+// The order of other requests is kept. The wait is measured from the moment the node has seen the request,
+// so the result differs between nodes: it is used only to decide which requests to take, never to order the batch
+func (op *operator) starvingFirst(reqs []*request) []*request {
+	curStateIndex, _ := op.stateIndex()
+	starving := make([]*request, 0)
+	rest := make([]*request, 0, len(reqs))
+	for _, req := range reqs {
+		if curStateIndex >= req.stateIndexReceived+op.starvationStates {
+			starving = append(starving, req)
+		} else {
+			rest = append(rest, req)
+		}
+	}
+	sort.SliceStable(starving, func(i, j int) bool {
+		return starving[i].stateIndexReceived < starving[j].stateIndexReceived
 	})
+	return append(starving, rest...)
+}
+
+// maxBatchSize is the parameter of the smart contract, reduced after the batch wasn't calculated in time
+func (op *operator) maxBatchSize() int {
+	if op.batchSizeCap > 0 && op.batchSizeCap < op.params.maxBatchSize {
+		return op.batchSizeCap
+	}
+	return op.params.maxBatchSize
+}
+
+// limitBatchSize takes requests in the order of priority until the batch is full.
+// User defined requests of the same transaction are taken all or none.
+// The transaction with more requests than maxBatchSize is taken alone, otherwise it would never be processed
+func (op *operator) limitBatchSize(reqs []*request) []*request {
+	maxBatchSize := op.maxBatchSize()
+	if len(reqs) <= maxBatchSize {
+		return reqs
+	}
+	numInTx := make(map[valuetransaction.ID]int)
+	for _, req := range reqs {
+		if req.requestCode().IsUserDefined() {
+			numInTx[req.reqTx.ID()]++
+		}
+	}
+	taken := make(map[valuetransaction.ID]bool)
+	ret := make([]*request, 0, maxBatchSize)
+	reserved := 0
+	for _, req := range reqs {
+		if !req.requestCode().IsUserDefined() {
+			if reserved < maxBatchSize {
+				ret = append(ret, req)
+				reserved++
+			}
+			continue
+		}
+		txid := req.reqTx.ID()
+		isTaken, decided := taken[txid]
+		if !decided {
+			isTaken = reserved+numInTx[txid] <= maxBatchSize || reserved == 0
+			taken[txid] = isTaken
+			if isTaken {
+				reserved += numInTx[txid]
+			}
+		}
+		if isTaken {
+			ret = append(ret, req)
+		}
+	}
+	return ret
+}
+
+// limitBacklog drops requests with the lowest priority when the number of known request messages
+// exceeds maxBacklog of the smart contract. Dropped requests can't be processed by the node until
+// the request message is received again
+func (op *operator) limitBacklog() {
+	reqs := op.requestMsgList()
+	if len(reqs) <= op.params.maxBacklog {
+		return
+	}
+	op.sortByPriority(reqs)
+	reqs = op.starvingFirst(reqs)
+	for _, req := range reqs[op.params.maxBacklog:] {
+		if op.leaderStatus != nil && op.isInLeaderBatch(req) {
+			continue
+		}
+		op.log.Warnf("backlog is full: request %s dropped", req.reqId.Short())
+		delete(op.requests, req.reqId)
+	}
+}
+
+func (op *operator) isInLeaderBatch(req *request) bool {
+	for _, r := range op.leaderStatus.reqs {
+		if r == req {
+			return true
+		}
+	}
+	return false
+}
+
+// filterInLeaderBatch removes requests of the leader's batch, which awaits confirmation when the next batch is pipelined
+func (op *operator) filterInLeaderBatch(reqs []*request) []*request {
+	if op.leaderStatus == nil {
+		return reqs
+	}
+	ret := reqs[:0] // same underlying array, different slice
+	for _, req := range reqs {
+		if !op.isInLeaderBatch(req) {
+			ret = append(ret, req)
+		}
+	}
 	return ret
 }
 
@@ -157,7 +296,7 @@ func (op *operator) requestMessagesSeenQuorumTimes() []*request {
 
 func (op *operator) isRequestProcessed(reqid *sctransaction.RequestId) bool {
 	addr := op.committee.Address()
-	processed, err := state.IsRequestCompleted(addr, reqid)
+	processed, err := state.IsRequestCompletedInPartition(op.env.Partition(addr), reqid)
 	if err != nil {
 		panic(err)
 	}
@@ -169,7 +308,7 @@ func (op *operator) deleteCompletedRequests() error {
 	toDelete := make([]*sctransaction.RequestId, 0)
 
 	for _, req := range op.requests {
-		if completed, err := state.IsRequestCompleted(op.committee.Address(), &req.reqId); err != nil {
+		if completed, err := state.IsRequestCompletedInPartition(op.env.Partition(op.committee.Address()), &req.reqId); err != nil {
 			return err
 		} else {
 			if completed {
@@ -218,8 +357,9 @@ func (op *operator) filterNotCompletePackages(reqs []*request) []*request {
 	}
 	ret := reqs[:0] // same underlying array, different slice
 	for _, req := range reqs {
-		st := reqstats[req.reqTx.ID()]
-		if st.numOfRequestsInTheList != st.totalNumOfRequestsInTx {
+		st, ok := reqstats[req.reqTx.ID()]
+		if ok && st.numOfRequestsInTheList != st.totalNumOfRequestsInTx {
+			// builtin requests are not counted
 			continue
 		}
 		ret = append(ret, req)
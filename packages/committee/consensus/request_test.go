@@ -0,0 +1,129 @@
+package consensus
+
+import (
+	"bytes"
+	"sort"
+	"testing"
+
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/balance"
+	valuetransaction "github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/transaction"
+	"github.com/iotaledger/hive.go/kvstore"
+	"github.com/iotaledger/hive.go/kvstore/mapdb"
+	"github.com/iotaledger/wasp/packages/sctransaction"
+	"github.com/iotaledger/wasp/packages/state"
+	"github.com/iotaledger/wasp/packages/vm/vmconst"
+	"github.com/stretchr/testify/assert"
+)
+
+var testAddress = address.Random()
+
+// newTestRequests returns records of requests of the same request transaction
+func newTestRequests(txIndex byte, code sctransaction.RequestCode, numReqs int, reward int64) []*request {
+	vtx := valuetransaction.New(
+		valuetransaction.NewInputs(valuetransaction.NewOutputID(testAddress, valuetransaction.ID{txIndex})),
+		valuetransaction.NewOutputs(map[address.Address][]*balance.Balance{
+			testAddress: {balance.New(balance.ColorIOTA, 1)},
+		}),
+	)
+	blocks := make([]*sctransaction.RequestBlock, numReqs)
+	for i := range blocks {
+		blocks[i] = sctransaction.NewRequestBlock(testAddress, code)
+	}
+	tx, err := sctransaction.NewTransaction(vtx, nil, blocks)
+	if err != nil {
+		panic(err)
+	}
+	ret := make([]*request, numReqs)
+	for i := range ret {
+		ret[i] = &request{
+			reqId:  sctransaction.NewRequestId(tx.ID(), uint16(i)),
+			reqTx:  tx,
+			reward: reward,
+		}
+	}
+	return ret
+}
+
+func newTestOperator(stateIndex uint32, maxBatchSize int, starvationStates uint32) *operator {
+	vs := state.NewEmptyVirtualStateInPartition(&testAddress, func(*address.Address) kvstore.KVStore {
+		return mapdb.NewMapDB()
+	})
+	vs.ApplyStateIndex(stateIndex)
+	return &operator{
+		currentState:     vs,
+		params:           consensusParams{maxBatchSize: maxBatchSize},
+		starvationStates: starvationStates,
+	}
+}
+
+func TestSortByPriority(t *testing.T) {
+	op := newTestOperator(20, 100, 10)
+
+	cheap := newTestRequests(1, 1, 1, 10)[0]
+	rich := newTestRequests(2, 1, 1, 1000)[0]
+	sameReward := newTestRequests(5, 1, 2, 10)
+	for _, req := range []*request{cheap, rich, sameReward[0], sameReward[1]} {
+		req.stateIndexReceived = 15
+	}
+	// the moment the request was seen doesn't change the order
+	old := newTestRequests(3, 1, 1, 0)[0]
+	old.stateIndexReceived = 3
+
+	reqs := []*request{cheap, sameReward[1], old, rich, sameReward[0]}
+	op.sortByPriority(reqs)
+
+	// requests with the same reward are ordered by request id
+	middle := []*request{cheap, sameReward[0], sameReward[1]}
+	sort.Slice(middle, func(i, j int) bool {
+		return bytes.Compare(middle[i].reqId[:], middle[j].reqId[:]) < 0
+	})
+	assert.Equal(t, append(append([]*request{rich}, middle...), old), reqs)
+
+	// the same order on the other node regardless of the initial order and of when the requests were seen
+	reqs1 := []*request{old, sameReward[0], rich, cheap, sameReward[1]}
+	for _, req := range reqs1 {
+		req.stateIndexReceived = 19
+	}
+	op.sortByPriority(reqs1)
+	assert.Equal(t, reqs, reqs1)
+}
+
+func TestStarvingFirst(t *testing.T) {
+	op := newTestOperator(20, 100, 10)
+
+	rich := newTestRequests(1, 1, 1, 1000)[0]
+	rich.stateIndexReceived = 15
+	cheap := newTestRequests(2, 1, 1, 10)[0]
+	cheap.stateIndexReceived = 15
+	starving := newTestRequests(3, 1, 1, 0)[0]
+	starving.stateIndexReceived = 5
+	older := newTestRequests(4, 1, 1, 0)[0]
+	older.stateIndexReceived = 3
+
+	reqs := []*request{rich, cheap, starving, older}
+	assert.Equal(t, []*request{older, starving, rich, cheap}, op.starvingFirst(reqs))
+}
+
+func TestLimitBatchSize(t *testing.T) {
+	op := newTestOperator(0, 4, 10)
+
+	tx3 := newTestRequests(1, 1, 3, 100)
+	tx2 := newTestRequests(2, 1, 2, 50)
+	builtin := newTestRequests(3, vmconst.RequestCodeNOP, 1, 0)[0]
+	tx1 := newTestRequests(4, 1, 1, 10)[0]
+
+	reqs := append(append([]*request{}, tx3...), tx2...)
+	reqs = append(reqs, builtin, tx1)
+	// the second transaction doesn't fit, requests with lower priority fill the batch
+	assert.Equal(t, []*request{tx3[0], tx3[1], tx3[2], builtin}, op.limitBatchSize(reqs))
+
+	// all requests of the big transaction are taken alone
+	big := newTestRequests(5, 1, 6, 1000)
+	reqs = append(append([]*request{}, big...), tx1)
+	assert.Equal(t, big, op.limitBatchSize(reqs))
+
+	// nothing to limit
+	reqs = append(append([]*request{}, tx2...), tx1)
+	assert.Equal(t, reqs, op.limitBatchSize(reqs))
+}
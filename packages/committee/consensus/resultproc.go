@@ -10,10 +10,10 @@ import (
 	"github.com/iotaledger/wasp/packages/sctransaction"
 	"github.com/iotaledger/wasp/packages/util"
 	"github.com/iotaledger/wasp/packages/vm"
-	"github.com/iotaledger/wasp/plugins/runvm"
 )
 
 type runCalculationsParams struct {
+	base            *batchBase
 	requests        []*request
 	leaderPeerIndex uint16
 	balances        map[valuetransaction.ID][]*balance.Balance
@@ -27,6 +27,10 @@ func (op *operator) runCalculationsAsync(par runCalculationsParams) {
 		op.log.Debugf("runCalculationsAsync: variable currentState is not known")
 		return
 	}
+	if par.base.pipelined && !op.sameProgramHash(par.base.state) {
+		op.log.Debugf("runCalculationsAsync: program hash is changed by the previous batch. Can't pipeline")
+		return
+	}
 	numReqs := len(par.requests)
 	if len(op.filterNotReadyYet(par.requests)) != numReqs {
 		op.log.Errorf("runCalculationsAsync: inconsistency: some requests not ready yet")
@@ -42,14 +46,14 @@ func (op *operator) runCalculationsAsync(par runCalculationsParams) {
 		ProgramHash:     progHash,
 		Address:         *op.committee.Address(),
 		Color:           *op.committee.Color(),
-		Entropy:         (hashing.HashValue)(op.stateTx.ID()),
+		Entropy:         par.base.entropy(),
 		Balances:        par.balances,
 		OwnerAddress:    *op.committee.OwnerAddress(),
 		RewardAddress:   par.rewardAddress,
-		MinimumReward:   op.getMinimumReward(),
+		MinimumReward:   op.getMinimumReward(par.base.state),
 		Requests:        takeRefs(par.requests),
 		Timestamp:       par.timestamp,
-		VirtualState:    op.currentState,
+		VirtualState:    par.base.state,
 		Log:             op.log,
 	}
 	ctx.OnFinish = func(err error) {
@@ -59,7 +63,7 @@ func (op *operator) runCalculationsAsync(par runCalculationsParams) {
 		}
 		op.committee.ReceiveMessage(ctx)
 	}
-	if err := runvm.RunComputationsAsync(ctx); err != nil {
+	if err := op.env.RunComputationsAsync(ctx); err != nil {
 		op.log.Errorf("RunComputationsAsync: %v", err)
 	}
 }
@@ -103,6 +107,18 @@ func (op *operator) sendResultToTheLeader(result *vm.VMTask) {
 	}
 }
 
+// isOwnBatch checks if the result is calculated for the current batch of the leader
+func (op *operator) isOwnBatch(result *vm.VMTask) bool {
+	if op.leaderStatus == nil || op.leaderStatus.resultTx != nil {
+		return false
+	}
+	reqids := make([]sctransaction.RequestId, len(result.Requests))
+	for i := range reqids {
+		reqids[i] = *result.Requests[i].RequestId()
+	}
+	return vm.BatchHash(reqids, result.Timestamp) == op.leaderStatus.batchHash
+}
+
 func (op *operator) saveOwnResult(result *vm.VMTask) {
 	sigShare, err := op.dkshare.SignShare(result.ResultTransaction.EssenceBytes())
 	if err != nil {
@@ -138,15 +154,37 @@ func (op *operator) saveOwnResult(result *vm.VMTask) {
 	}
 }
 
-func (op *operator) aggregateSigShares(sigShares [][]byte) error {
+// aggregateSigShares verifies signature shares collected by the leader, each against the public key of the sender.
+// Invalid shares are excluded and counted as faults of the sender, the final signature is recovered from the valid ones.
+// Returns indices of contributing peers
+func (op *operator) aggregateSigShares() ([]uint16, error) {
 	resTx := op.leaderStatus.resultTx
+	essence := resTx.EssenceBytes()
 
-	finalSignature, err := op.dkshare.RecoverFullSignature(sigShares, resTx.EssenceBytes())
+	sigShares := make([][]byte, 0, op.size())
+	contributingPeers := make([]uint16, 0, op.size())
+	for i, sr := range op.leaderStatus.signedResults {
+		if sr == nil {
+			continue
+		}
+		if err := op.dkshare.VerifySigShareOfPeer(uint16(i), essence, sr.sigShare); err != nil {
+			op.log.Warnf("invalid signature share from peer #%d: %v", i, err)
+			op.committee.RecordPeerFault(uint16(i), committee.FaultInvalidSigShare)
+			op.leaderStatus.signedResults[i] = nil // ignoring
+			continue
+		}
+		sigShares = append(sigShares, sr.sigShare)
+		contributingPeers = append(contributingPeers, uint16(i))
+	}
+	if len(sigShares) < int(op.dkshare.T) {
+		return nil, fmt.Errorf("not enough valid signature shares: %d, need %d", len(sigShares), op.dkshare.T)
+	}
+	finalSignature, err := op.dkshare.RecoverFullSignature(sigShares, essence)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if err := resTx.PutSignature(finalSignature); err != nil {
-		return fmt.Errorf("something wrong while aggregating final signature: %v", err)
+		return nil, fmt.Errorf("something wrong while aggregating final signature: %v", err)
 	}
-	return nil
+	return contributingPeers, nil
 }
@@ -0,0 +1,131 @@
+package consensus
+
+import (
+	"testing"
+
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/balance"
+	valuetransaction "github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/transaction"
+	"github.com/iotaledger/hive.go/logger"
+	"github.com/iotaledger/wasp/packages/committee"
+	"github.com/iotaledger/wasp/packages/sctransaction"
+	"github.com/iotaledger/wasp/packages/tcrypto"
+	"github.com/iotaledger/wasp/packages/tcrypto/tbdn"
+	"github.com/stretchr/testify/assert"
+)
+
+// testCommittee records peer faults, the rest is not used by aggregateSigShares
+type testCommittee struct {
+	committee.Committee
+	faults *committee.PeerFaults
+}
+
+func (c *testCommittee) RecordPeerFault(peerIndex uint16, _ string) {
+	c.faults.Inc(peerIndex)
+}
+
+func (c *testCommittee) PeerFaults() []uint32 {
+	return c.faults.Counters()
+}
+
+func (c *testCommittee) Size() uint16 {
+	return 4
+}
+
+// newTestLeader returns the leader with the result transaction to sign and signature shares of all peers
+func newTestLeader(t *testing.T) (*operator, []tbdn.SigShare) {
+	dkshares, err := tcrypto.NewDKSharesInProcess(3, 4, nil)
+	assert.NoError(t, err)
+
+	addr := *dkshares[0].Address
+	vtx := valuetransaction.New(
+		valuetransaction.NewInputs(valuetransaction.NewOutputID(addr, valuetransaction.ID{})),
+		valuetransaction.NewOutputs(map[address.Address][]*balance.Balance{
+			addr: {balance.New(balance.ColorIOTA, 1)},
+		}),
+	)
+	tx, err := sctransaction.NewTransaction(vtx, nil, []*sctransaction.RequestBlock{
+		sctransaction.NewRequestBlock(addr, sctransaction.RequestCode(1)),
+	})
+	assert.NoError(t, err)
+
+	shares := make([]tbdn.SigShare, len(dkshares))
+	for i, ks := range dkshares {
+		shares[i], err = ks.SignShare(tx.EssenceBytes())
+		assert.NoError(t, err)
+	}
+	op := &operator{
+		committee:    &testCommittee{faults: committee.NewPeerFaults(4)},
+		dkshare:      dkshares[0],
+		leaderStatus: &leaderStatus{resultTx: tx},
+		log:          logger.NewExampleLogger("test"),
+	}
+	return op, shares
+}
+
+// receive puts signature shares to the leader, indexed by the sender
+func receive(op *operator, shares ...tbdn.SigShare) {
+	op.leaderStatus.signedResults = make([]*signedResult, len(shares))
+	for i, s := range shares {
+		if s != nil {
+			op.leaderStatus.signedResults[i] = &signedResult{sigShare: s}
+		}
+	}
+}
+
+func TestAggregateSigShares(t *testing.T) {
+	op, shares := newTestLeader(t)
+	receive(op, nil, shares[1], shares[2], shares[3])
+	contributors, err := op.aggregateSigShares()
+	assert.NoError(t, err)
+	assert.Equal(t, []uint16{1, 2, 3}, contributors)
+	assert.True(t, op.leaderStatus.resultTx.SignaturesValid())
+	assert.Equal(t, []uint32{0, 0, 0, 0}, op.committee.PeerFaults())
+}
+
+func TestAggregateSigSharesExcludesBadShares(t *testing.T) {
+	op, shares := newTestLeader(t)
+
+	// spoiled signature, the index is right
+	bad := make(tbdn.SigShare, len(shares[1]))
+	copy(bad, shares[1])
+	for i := 2; i < len(bad); i++ {
+		bad[i] ^= 0xFF
+	}
+	receive(op, shares[0], bad, shares[2], shares[3])
+	contributors, err := op.aggregateSigShares()
+	assert.NoError(t, err)
+	assert.Equal(t, []uint16{0, 2, 3}, contributors)
+	assert.True(t, op.leaderStatus.resultTx.SignaturesValid())
+	assert.Nil(t, op.leaderStatus.signedResults[1])
+	assert.Equal(t, []uint32{0, 1, 0, 0}, op.committee.PeerFaults())
+}
+
+func TestAggregateSigSharesRejectsBadShares(t *testing.T) {
+	op, shares := newTestLeader(t)
+
+	// valid signature of the different essence
+	wrongEssence, err := op.dkshare.SignShare([]byte("different essence"))
+	assert.NoError(t, err)
+	receive(op, wrongEssence, shares[1], shares[2])
+	_, err = op.aggregateSigShares()
+	assert.Error(t, err)
+
+	// share of the peer #1 sent by the peer #2
+	receive(op, shares[0], shares[1], shares[1])
+	_, err = op.aggregateSigShares()
+	assert.Error(t, err)
+
+	// garbage
+	receive(op, shares[0], shares[1], tbdn.SigShare{0})
+	_, err = op.aggregateSigShares()
+	assert.Error(t, err)
+
+	// less than quorum
+	receive(op, shares[0], nil, shares[2])
+	_, err = op.aggregateSigShares()
+	assert.Error(t, err)
+
+	assert.False(t, op.leaderStatus.resultTx.SignaturesValid())
+	assert.Equal(t, []uint32{1, 0, 2, 0}, op.committee.PeerFaults())
+}
@@ -0,0 +1,14 @@
+package consensus
+
+import "github.com/iotaledger/wasp/packages/sctransaction"
+
+// Committee rotation. The request RequestCodeRotateCommittee moves the smart contract token and balances
+// to the address of the new committee. Both committees follow the same state, but only the committee
+// which holds the smart contract token runs batches: the old one after the rotation is dismissed,
+// the new one stays passive until the rotation
+
+// ownsState checks if the state transaction keeps the smart contract token at the address of the committee
+func (op *operator) ownsState(stateTx *sctransaction.Transaction) bool {
+	addr, ok, err := stateTx.StateAddress()
+	return err == nil && ok && addr == *op.committee.Address()
+}
@@ -9,7 +9,6 @@ import (
 	"github.com/iotaledger/hive.go/logger"
 	"github.com/iotaledger/wasp/packages/committee"
 	"github.com/iotaledger/wasp/packages/hashing"
-	"github.com/iotaledger/wasp/packages/registry"
 	"github.com/iotaledger/wasp/packages/sctransaction"
 	"github.com/iotaledger/wasp/packages/state"
 	"github.com/iotaledger/wasp/packages/tcrypto"
@@ -20,6 +19,7 @@ import (
 
 type operator struct {
 	committee committee.Committee
+	env       committee.Environment
 	dkshare   *tcrypto.DKShare
 	//currentState
 	currentState state.VirtualState
@@ -42,14 +42,30 @@ type operator struct {
 
 	leaderStatus *leaderStatus
 
+	// states calculated on top of the current state, not confirmed yet. Key is the hash of the state
+	speculativeStates map[hashing.HashValue]*speculativeState
+	// the batch of another leader on top of the state with the hash was signed by the node
+	signedBaseHash     hashing.HashValue
+	signedBaseDeadline time.Time
+
+	// consensus parameters of the current state and the protocol defaults
+	params        consensusParams
+	defaultParams consensusParams
+	// the batch size is reduced after the batch wasn't calculated in time. 0 means no limit
+	batchSizeCap int
+	// request selection policy of the leader
+	starvationStates uint32
+
 	log *logger.Logger
 }
 
 type leaderStatus struct {
+	base          *batchBase
 	reqs          []*request
 	batch         state.Batch
 	batchHash     hashing.HashValue
 	timestamp     int64
+	vmDeadline    time.Time
 	balances      map[valuetransaction.ID][]*balance.Balance
 	resultTx      *sctransaction.Transaction
 	finalized     bool
@@ -80,6 +96,12 @@ type request struct {
 	// time when request message was received by the operator
 	whenMsgReceived time.Time
 
+	// state index when request message was received by the operator
+	stateIndexReceived uint32
+
+	// iotas paid to the smart contract address by the request
+	reward int64
+
 	// notification vector for the current currentState
 	notifications []bool
 
@@ -89,12 +111,18 @@ type request struct {
 func NewOperator(committee committee.Committee, dkshare *tcrypto.DKShare, log *logger.Logger) *operator {
 	defer committee.SetReadyConsensus()
 
+	defaultParams := defaultConsensusParams()
 	return &operator{
-		committee:       committee,
-		dkshare:         dkshare,
-		requests:        make(map[sctransaction.RequestId]*request),
-		peerPermutation: util.NewPermutation16(committee.Size(), nil),
-		log:             log.Named("c"),
+		committee:         committee,
+		env:               committee.Environment(),
+		dkshare:           dkshare,
+		requests:          make(map[sctransaction.RequestId]*request),
+		speculativeStates: make(map[hashing.HashValue]*speculativeState),
+		peerPermutation:   util.NewPermutation16(committee.Size(), nil),
+		params:            defaultParams,
+		defaultParams:     defaultParams,
+		starvationStates:  uint32(configuredInt(CfgStarvationStates, DefaultStarvationStates)),
+		log:               log.Named("c"),
 	}
 }
 
@@ -137,14 +165,11 @@ func (op *operator) getProgramHash() (*hashing.HashValue, bool) {
 }
 
 func (op *operator) getRewardAddress() address.Address {
-	return registry.GetRewardAddress(op.committee.Address())
+	return op.env.GetRewardAddress(op.committee.Address())
 }
 
-func (op *operator) getMinimumReward() int64 {
-	if _, ok := op.stateIndex(); !ok {
-		return 0
-	}
-	vt, ok, err := op.currentState.Variables().Codec().GetInt64(vmconst.VarNameMinimumReward)
+func (op *operator) getMinimumReward(vs state.VirtualState) int64 {
+	vt, ok, err := vs.Variables().Codec().GetInt64(vmconst.VarNameMinimumReward)
 	if err != nil {
 		panic(err)
 	}
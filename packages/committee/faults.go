@@ -0,0 +1,45 @@
+package committee
+
+import "sync"
+
+// reasons of peer faults, published in the 'peer_fault' message
+const (
+	FaultInvalidSigShare  = "invalid_sigshare"
+	FaultBadTimestamp     = "bad_timestamp"
+	FaultBadBaseSignature = "bad_base_signature"
+)
+
+// PeerFaults counts faults of committee peers detected by the node, for example invalid signature shares.
+// The counters are kept in memory and start from zero when the committee is created
+type PeerFaults struct {
+	mutex    sync.Mutex
+	counters []uint32
+}
+
+func NewPeerFaults(size uint16) *PeerFaults {
+	return &PeerFaults{
+		counters: make([]uint32, size),
+	}
+}
+
+// Inc increments the counter of the peer and returns the new value
+func (pf *PeerFaults) Inc(peerIndex uint16) uint32 {
+	pf.mutex.Lock()
+	defer pf.mutex.Unlock()
+
+	if int(peerIndex) >= len(pf.counters) {
+		return 0
+	}
+	pf.counters[peerIndex]++
+	return pf.counters[peerIndex]
+}
+
+// Counters returns the copy of the counters, indexed by the peer index
+func (pf *PeerFaults) Counters() []uint32 {
+	pf.mutex.Lock()
+	defer pf.mutex.Unlock()
+
+	ret := make([]uint32, len(pf.counters))
+	copy(ret, pf.counters)
+	return ret
+}
@@ -65,6 +65,12 @@ func (msg *StartProcessingBatchMsg) Write(w io.Writer) error {
 	if err := waspconn.WriteBalances(w, msg.Balances); err != nil {
 		return err
 	}
+	if _, err := w.Write(msg.StateHash[:]); err != nil {
+		return err
+	}
+	if err := util.WriteBytes16(w, msg.BaseSignatures); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -89,6 +95,12 @@ func (msg *StartProcessingBatchMsg) Read(r io.Reader) error {
 	if msg.Balances, err = waspconn.ReadBalances(r); err != nil {
 		return err
 	}
+	if err := util.ReadHashValue(r, &msg.StateHash); err != nil {
+		return err
+	}
+	if msg.BaseSignatures, err = util.ReadBytes16(r); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -142,6 +154,20 @@ func (msg *GetBatchMsg) Read(r io.Reader) error {
 	return util.ReadUint32(r, &msg.StateIndex)
 }
 
+func (msg *GetBatchesMsg) Write(w io.Writer) error {
+	if err := util.WriteUint32(w, msg.StateIndex); err != nil {
+		return err
+	}
+	return util.WriteUint32(w, msg.ToIndex)
+}
+
+func (msg *GetBatchesMsg) Read(r io.Reader) error {
+	if err := util.ReadUint32(r, &msg.StateIndex); err != nil {
+		return err
+	}
+	return util.ReadUint32(r, &msg.ToIndex)
+}
+
 func (msg *BatchHeaderMsg) Write(w io.Writer) error {
 	if err := util.WriteUint32(w, msg.StateIndex); err != nil {
 		return err
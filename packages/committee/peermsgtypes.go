@@ -19,6 +19,7 @@ const (
 	MsgStateUpdate            = 4 + peering.FirstCommitteeMsgCode
 	MsgBatchHeader            = 5 + peering.FirstCommitteeMsgCode
 	MsgTestTrace              = 6 + peering.FirstCommitteeMsgCode
+	MsgGetBatches             = 7 + peering.FirstCommitteeMsgCode
 )
 
 type TimerTick int
@@ -53,6 +54,12 @@ type StartProcessingBatchMsg struct {
 	RewardAddress address.Address
 	// balances/outputs
 	Balances map[valuetransaction.ID][]*balance.Balance
+	// hash of the state the batch is calculated on. It is the current state or, when the batch
+	// is pipelined, the state after the previous batch which awaits confirmation
+	StateHash hashing.HashValue
+	// signatures of the state transaction of the pipelined batch, which awaits confirmation.
+	// The entropy of the batch is derived from the signed transaction. Empty if the batch is not pipelined
+	BaseSignatures []byte
 }
 
 // after calculations the result peer responds to the start processing msg
@@ -76,6 +83,14 @@ type GetBatchMsg struct {
 	PeerMsgHeader
 }
 
+// request range of batches from peer. Used to catch up when the node is many states behind.
+// StateIndex of the header is the first state index of the range
+type GetBatchesMsg struct {
+	PeerMsgHeader
+	// last state index of the range, inclusive
+	ToIndex uint32
+}
+
 // the header of the batch message sent by peers in the process of syncing
 // it is sent as a first message while syncing a batch
 type BatchHeaderMsg struct {
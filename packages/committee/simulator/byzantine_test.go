@@ -0,0 +1,150 @@
+package simulator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/iotaledger/hive.go/logger"
+	"github.com/iotaledger/wasp/packages/committee"
+	"github.com/iotaledger/wasp/packages/committee/adversary"
+	"github.com/iotaledger/wasp/packages/tcrypto"
+	"github.com/stretchr/testify/assert"
+)
+
+const byzantineNode = 3
+
+func honestReached(sim *Simulator, stateIndex uint32) func() bool {
+	return func() bool {
+		for i := uint16(0); i < sim.cfg.N; i++ {
+			if i == byzantineNode {
+				continue
+			}
+			idx, ok := sim.Node(i).StateIndex()
+			if !ok || idx < stateIndex {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// leaderSpy counts rounds in which the wrapped node was the leader.
+// The node fails as the leader if noLead is set
+type leaderSpy struct {
+	committee.Committee
+	numLed int
+	noLead bool
+}
+
+func (s *leaderSpy) SendMsgToCommitteePeers(msgType byte, msgData []byte) (uint16, int64) {
+	if msgType == committee.MsgStartProcessingRequest {
+		s.numLed++
+		if s.noLead {
+			return 0, s.Environment().Now().UnixNano()
+		}
+	}
+	return s.Committee.SendMsgToCommitteePeers(msgType, msgData)
+}
+
+func newByzantineSimulator(t *testing.T, behavior adversary.Behavior, seed int64) (*Simulator, *leaderSpy) {
+	spy := &leaderSpy{}
+	cfg := DefaultConfig(4, seed)
+	cfg.WrapCommittee = func(index uint16, node committee.Committee, dkshare *tcrypto.DKShare) committee.Committee {
+		if index != byzantineNode {
+			return node
+		}
+		spy.Committee = adversary.New(node, dkshare, behavior)
+		return spy
+	}
+	sim, err := New(cfg, logger.NewExampleLogger("sim"))
+	assert.NoError(t, err)
+	assert.True(t, sim.RunUntil(allReached(sim, 0), 30*time.Second))
+	return sim, spy
+}
+
+// runByzantine runs the committee of 4 with one byzantine node. The honest quorum of 3 must progress,
+// including the states in which the byzantine node is the leader
+func runByzantine(t *testing.T, behavior adversary.Behavior, seed int64) {
+	sim, spy := newByzantineSimulator(t, behavior, seed)
+
+	const numStates = 10
+	for i := uint32(1); i <= numStates; i++ {
+		_, err := sim.PostRequests(requestCode, 1)
+		assert.NoError(t, err)
+		if !assert.True(t, sim.RunUntil(honestReached(sim, i), 5*time.Minute), "state #%d not reached", i) {
+			return
+		}
+	}
+	assert.True(t, spy.numLed > 0, "byzantine node was never the leader")
+}
+
+// shares of the byzantine node are needed for the quorum while one honest node is cut off.
+// They must be rejected, so the committee can't progress until the honest node is back
+func runBadShares(t *testing.T, behavior adversary.Behavior, seed int64) {
+	sim, spy := newByzantineSimulator(t, behavior, seed)
+	// the byzantine leader would finalize the result with its own share, which is valid
+	spy.noLead = true
+
+	const cutOff = 2
+	for i := uint16(0); i < 4; i++ {
+		if i != cutOff {
+			sim.SetLinkDown(cutOff, i, true)
+		}
+	}
+	_, err := sim.PostRequests(requestCode, 1)
+	assert.NoError(t, err)
+	sim.RunFor(time.Minute)
+	for i := uint16(0); i < 4; i++ {
+		idx, _ := sim.Node(i).StateIndex()
+		assert.EqualValues(t, 0, idx)
+	}
+	// honest leaders blame the byzantine node only
+	numFaults := uint32(0)
+	for i := uint16(0); i < 4; i++ {
+		for peer, n := range sim.Node(i).PeerFaults() {
+			if peer == byzantineNode {
+				numFaults += n
+			} else {
+				assert.EqualValues(t, 0, n, "node #%d blames honest peer #%d", i, peer)
+			}
+		}
+	}
+	assert.True(t, numFaults > 0, "invalid signature shares were not detected")
+
+	for i := uint16(0); i < 4; i++ {
+		sim.SetLinkDown(cutOff, i, false)
+	}
+	assert.True(t, sim.RunUntil(honestReached(sim, 1), 5*time.Minute))
+}
+
+func TestByzantineBadSigShares(t *testing.T) {
+	runByzantine(t, adversary.BadSigShares, 1)
+	runBadShares(t, adversary.BadSigShares, 1)
+}
+
+func TestByzantineWrongEssence(t *testing.T) {
+	runByzantine(t, adversary.WrongEssence, 2)
+	runBadShares(t, adversary.WrongEssence, 2)
+}
+
+func TestByzantineEquivocate(t *testing.T) {
+	runByzantine(t, adversary.Equivocate, 3)
+}
+
+func TestByzantineLieStateIndex(t *testing.T) {
+	runByzantine(t, adversary.LieStateIndex, 6)
+}
+
+func TestByzantineAll(t *testing.T) {
+	runByzantine(t, adversary.BadSigShares|adversary.Equivocate|adversary.LieStateIndex, 5)
+}
+
+func TestParseBehavior(t *testing.T) {
+	b, err := adversary.ParseBehavior("equivocate, BadSigShares")
+	assert.NoError(t, err)
+	assert.Equal(t, adversary.Equivocate|adversary.BadSigShares, b)
+	assert.Equal(t, "badsigshares,equivocate", b.String())
+
+	_, err = adversary.ParseBehavior("crash")
+	assert.Error(t, err)
+}
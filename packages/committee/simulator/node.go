@@ -0,0 +1,334 @@
+package simulator
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/balance"
+	valuetransaction "github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/transaction"
+	"github.com/iotaledger/hive.go/kvstore"
+	"github.com/iotaledger/hive.go/logger"
+	"github.com/iotaledger/wasp/packages/committee"
+	"github.com/iotaledger/wasp/packages/committee/commiteeimpl"
+	"github.com/iotaledger/wasp/packages/registry"
+	"github.com/iotaledger/wasp/packages/state"
+	"github.com/iotaledger/wasp/packages/tcrypto"
+	"github.com/iotaledger/wasp/packages/util"
+	"github.com/iotaledger/wasp/packages/vm"
+	"github.com/iotaledger/wasp/plugins/peering"
+	"github.com/iotaledger/wasp/plugins/runvm"
+)
+
+const timerTickPeriod = 100 * time.Millisecond
+
+// Node is the simulated committee node. It implements committee.Committee and committee.Environment
+// on top of the simulator: the virtual clock, the scheduler instead of the network and the mocked tangle
+type Node struct {
+	sim     *Simulator
+	index   uint16
+	store   kvstore.KVStore
+	dkshare *tcrypto.DKShare
+	log     *logger.Logger
+
+	stateMgr committee.StateManager
+	operator committee.Operator
+	faults   *committee.PeerFaults
+
+	mutexReady          sync.Mutex
+	isReadyStateManager bool
+	isReadyConsensus    bool
+	chReady             chan struct{}
+
+	isOpenQueue bool
+	dismissed   bool
+	down        bool
+}
+
+func newNode(sim *Simulator, index uint16, store kvstore.KVStore, dkshare *tcrypto.DKShare) *Node {
+	return &Node{
+		sim:     sim,
+		index:   index,
+		store:   store,
+		dkshare: dkshare,
+		log:     sim.log.Named(fmt.Sprintf("#%d", index)),
+		faults:  committee.NewPeerFaults(sim.cfg.N),
+		chReady: make(chan struct{}),
+	}
+}
+
+// Index is the peer index of the node in the committee
+func (n *Node) Index() uint16 {
+	return n.index
+}
+
+// StateIndex returns index of the solid state stored in the database of the node
+func (n *Node) StateIndex() (uint32, bool) {
+	vs, _, ok, err := state.LoadSolidStateFromPartition(&n.sim.address, n.Partition)
+	if err != nil || !ok {
+		return 0, false
+	}
+	return vs.StateIndex(), true
+}
+
+// implements committee.Committee
+
+func (n *Node) Address() *address.Address {
+	return &n.sim.address
+}
+
+func (n *Node) OwnerAddress() *address.Address {
+	return &n.sim.ownerAddress
+}
+
+func (n *Node) Color() *balance.Color {
+	return &n.sim.color
+}
+
+func (n *Node) Size() uint16 {
+	return n.sim.cfg.N
+}
+
+func (n *Node) OwnPeerIndex() uint16 {
+	return n.index
+}
+
+func (n *Node) NumPeers() uint16 {
+	return n.sim.cfg.N
+}
+
+func (n *Node) SendMsg(targetPeerIndex uint16, msgType byte, msgData []byte) error {
+	if targetPeerIndex >= n.sim.cfg.N || targetPeerIndex == n.index {
+		return fmt.Errorf("SendMsg: wrong peer index")
+	}
+	if !n.IsAlivePeer(targetPeerIndex) {
+		return fmt.Errorf("SendMsg: peer %d is not connected", targetPeerIndex)
+	}
+	n.sim.sendPeerMessage(n.index, targetPeerIndex, msgType, msgData)
+	return nil
+}
+
+func (n *Node) SendMsgToCommitteePeers(msgType byte, msgData []byte) (uint16, int64) {
+	ts := n.Now().UnixNano()
+	numSent := uint16(0)
+	for i := uint16(0); i < n.sim.cfg.N; i++ {
+		if i == n.index {
+			continue
+		}
+		if n.SendMsg(i, msgType, msgData) == nil {
+			numSent++
+		}
+	}
+	return numSent, ts
+}
+
+func (n *Node) SendMsgInSequence(msgType byte, msgData []byte, seqIndex uint16, seq []uint16) (uint16, error) {
+	if len(seq) != int(n.Size()) || seqIndex >= n.Size() || !util.ValidPermutation(seq) {
+		return 0, fmt.Errorf("SendMsgInSequence: wrong params")
+	}
+	numAttempts := uint16(0)
+	for ; numAttempts < n.Size(); seqIndex = (seqIndex + 1) % n.Size() {
+		if seq[seqIndex] >= n.Size() {
+			return 0, fmt.Errorf("SendMsgInSequence: wrong params")
+		}
+		if err := n.SendMsg(seq[seqIndex], msgType, msgData); err == nil {
+			return seqIndex, nil
+		}
+		numAttempts++
+	}
+	return 0, fmt.Errorf("failed to send")
+}
+
+func (n *Node) IsAlivePeer(peerIndex uint16) bool {
+	if peerIndex >= n.sim.cfg.N {
+		return false
+	}
+	if peerIndex == n.index {
+		return true
+	}
+	return n.sim.isConnected(n.index, peerIndex)
+}
+
+// ReceiveMessage schedules the message for the node at the current virtual time.
+// Results of the VM are delayed by the configured VM delay
+func (n *Node) ReceiveMessage(msg interface{}) {
+	if !n.isOpen() {
+		return
+	}
+	var delay time.Duration
+	if _, ok := msg.(*vm.VMTask); ok {
+		delay = n.sim.cfg.VMDelay
+	}
+	n.sim.schedule(n.index, msg, delay, "")
+}
+
+func (n *Node) InitTestRound() {
+	n.log.Debugf("InitTestRound is not supported by the simulator")
+}
+
+func (n *Node) Environment() committee.Environment {
+	return n
+}
+
+func (n *Node) RecordPeerFault(peerIndex uint16, reason string) {
+	if peerIndex >= n.sim.cfg.N || peerIndex == n.index {
+		return
+	}
+	count := n.faults.Inc(peerIndex)
+	n.log.Warnf("peer #%d fault '%s', total %d", peerIndex, reason, count)
+	n.Publish("peer_fault", n.sim.address.String(), fmt.Sprintf("%d", peerIndex), fmt.Sprintf("%d", count), reason)
+}
+
+func (n *Node) PeerFaults() []uint32 {
+	return n.faults.Counters()
+}
+
+func (n *Node) SetReadyStateManager() {
+	n.mutexReady.Lock()
+	defer n.mutexReady.Unlock()
+
+	n.isReadyStateManager = true
+	n.checkReady()
+}
+
+func (n *Node) SetReadyConsensus() {
+	n.mutexReady.Lock()
+	defer n.mutexReady.Unlock()
+
+	n.isReadyConsensus = true
+	n.checkReady()
+}
+
+func (n *Node) checkReady() {
+	if n.isOpenQueue || !n.isReadyStateManager || !n.isReadyConsensus {
+		return
+	}
+	n.isOpenQueue = true
+	close(n.chReady)
+}
+
+func (n *Node) isOpen() bool {
+	n.mutexReady.Lock()
+	defer n.mutexReady.Unlock()
+
+	return n.isOpenQueue && !n.dismissed
+}
+
+func (n *Node) Dismiss() {
+	n.mutexReady.Lock()
+	defer n.mutexReady.Unlock()
+
+	n.log.Infof("Dismiss committee for %s", n.sim.address.String())
+	n.dismissed = true
+}
+
+func (n *Node) IsDismissed() bool {
+	n.mutexReady.Lock()
+	defer n.mutexReady.Unlock()
+
+	return n.dismissed
+}
+
+// implements committee.Environment
+
+func (n *Node) Now() time.Time {
+	return n.sim.Now()
+}
+
+// ClockDrifts is false: all nodes of the simulator use the clock of the simulator
+func (n *Node) ClockDrifts() bool {
+	return false
+}
+
+func (n *Node) Partition(addr *address.Address) kvstore.KVStore {
+	return n.store.WithRealm(addr[:])
+}
+
+func (n *Node) GetDKShare(addr *address.Address) (*tcrypto.DKShare, bool, error) {
+	if n.dkshare == nil || *addr != *n.dkshare.Address {
+		return nil, false, nil
+	}
+	return n.dkshare, true, nil
+}
+
+// SwitchToResharedDKShare is false: the simulator doesn't reshare key sets
+func (n *Node) SwitchToResharedDKShare(_ *address.Address, _ []string, _ string) (bool, error) {
+	return false, nil
+}
+
+// NextMsgEpoch is constant: nodes of the simulator are not restarted
+func (n *Node) NextMsgEpoch() (uint64, error) {
+	return 1, nil
+}
+
+func (n *Node) MarkRotated(_, _ *address.Address) error {
+	return nil
+}
+
+// GetRewardAddress is the nil address: no rewards are collected
+func (n *Node) GetRewardAddress(_ *address.Address) address.Address {
+	return address.Address{}
+}
+
+func (n *Node) GetTrustedPeers() ([]*registry.TrustedPeer, error) {
+	return nil, nil
+}
+
+func (n *Node) RequestOutputs(addr *address.Address) error {
+	n.sim.requestOutputs(n, addr)
+	return nil
+}
+
+func (n *Node) RequestTransaction(txid *valuetransaction.ID) error {
+	n.sim.requestTransaction(n, txid)
+	return nil
+}
+
+func (n *Node) PostTransaction(tx *valuetransaction.Transaction) error {
+	if err := n.sim.PostTransaction(tx); err != nil {
+		// the node connection doesn't report rejection of the transaction to the committee
+		n.log.Debugf("transaction %s was rejected by the ledger: %v", tx.ID().String(), err)
+	}
+	return nil
+}
+
+// RunComputationsAsync runs the VM in the scheduler goroutine. The result is delivered after the VM delay
+func (n *Node) RunComputationsAsync(ctx *vm.VMTask) error {
+	return runvm.RunComputations(ctx)
+}
+
+func (n *Node) Publish(msgType string, parts ...string) {
+	n.sim.publish(n.index, msgType, parts...)
+}
+
+// dispatch delivers the event to the node. Messages to the node which is down are lost
+func (n *Node) dispatch(msg interface{}) {
+	if tick, ok := msg.(committee.TimerTick); ok {
+		n.sim.schedule(n.index, tick+1, timerTickPeriod, "")
+	}
+	if n.isDown() || !n.isOpen() {
+		return
+	}
+	if n.sim.processLedgerEvent(n, msg) {
+		return
+	}
+	commiteeimpl.DispatchMessage(msg, n.stateMgr, n.operator, n.log)
+}
+
+func (n *Node) isDown() bool {
+	n.sim.mutex.Lock()
+	defer n.sim.mutex.Unlock()
+
+	return n.down
+}
+
+func newPeerMessage(n *Node, msgType byte, msgData []byte) *peering.PeerMessage {
+	return &peering.PeerMessage{
+		Address:     n.sim.address,
+		SenderIndex: n.index,
+		Timestamp:   n.Now().UnixNano(),
+		MsgType:     msgType,
+		MsgData:     msgData,
+	}
+}
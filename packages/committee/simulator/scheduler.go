@@ -0,0 +1,91 @@
+package simulator
+
+import (
+	"container/heap"
+	"time"
+)
+
+// event is a message scheduled for delivery to the node at the virtual time
+type event struct {
+	at     time.Time
+	key    string // tie breaker for events scheduled at the same time independently of insertion order
+	seq    uint64
+	target uint16
+	msg    interface{}
+}
+
+// eventQueue is a priority queue of events ordered by (time, key, insertion sequence)
+type eventQueue []*event
+
+func (q eventQueue) Len() int {
+	return len(q)
+}
+
+func (q eventQueue) Less(i, j int) bool {
+	if !q[i].at.Equal(q[j].at) {
+		return q[i].at.Before(q[j].at)
+	}
+	if q[i].key != q[j].key {
+		return q[i].key < q[j].key
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q eventQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+}
+
+func (q *eventQueue) Push(x interface{}) {
+	*q = append(*q, x.(*event))
+}
+
+func (q *eventQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	ret := old[n-1]
+	old[n-1] = nil
+	*q = old[:n-1]
+	return ret
+}
+
+// schedule puts the message for the target node into the queue with the delay from the current virtual time
+func (sim *Simulator) schedule(target uint16, msg interface{}, delay time.Duration, key string) {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+
+	sim.seq++
+	heap.Push(&sim.queue, &event{
+		at:     sim.now.Add(delay),
+		key:    key,
+		seq:    sim.seq,
+		target: target,
+		msg:    msg,
+	})
+}
+
+// nextEvent takes the earliest event from the queue and advances the virtual clock to its time.
+// The clock advances at least by 1ns with each event, so timestamps taken by handlers are unique
+func (sim *Simulator) nextEvent(limit time.Time) (*event, bool) {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+
+	if len(sim.queue) == 0 || sim.queue[0].at.After(limit) {
+		return nil, false
+	}
+	ev := heap.Pop(&sim.queue).(*event)
+	if ev.at.After(sim.now) {
+		sim.now = ev.at
+	} else {
+		sim.now = sim.now.Add(time.Nanosecond)
+	}
+	return ev, true
+}
+
+// networkDelay returns random delay of the peer message in the configured range.
+// Random delays reorder messages
+func (sim *Simulator) networkDelay() time.Duration {
+	if sim.cfg.MaxDelay <= sim.cfg.MinDelay {
+		return sim.cfg.MinDelay
+	}
+	return sim.cfg.MinDelay + time.Duration(sim.rnd.Int63n(int64(sim.cfg.MaxDelay-sim.cfg.MinDelay)))
+}
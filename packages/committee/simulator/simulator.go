@@ -0,0 +1,375 @@
+// simulator package runs the whole committee in one process on the virtual time.
+// Consensus operators and state managers of all nodes are the real ones. The network is replaced by the
+// controllable message scheduler (delays, reordering, drops, duplicates, partitions),
+// the goshimmer node by the mocked tangle on top of utxodb. Everything is driven from one goroutine,
+// so the run is reproducible from the seed
+package simulator
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address/signaturescheme"
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/balance"
+	"github.com/iotaledger/goshimmer/dapps/waspconn/packages/utxodb"
+	"github.com/iotaledger/hive.go/kvstore/mapdb"
+	"github.com/iotaledger/hive.go/logger"
+	"github.com/iotaledger/wasp/packages/committee"
+	"github.com/iotaledger/wasp/packages/committee/consensus"
+	"github.com/iotaledger/wasp/packages/committee/statemgr"
+	"github.com/iotaledger/wasp/packages/hashing"
+	"github.com/iotaledger/wasp/packages/sctransaction"
+	"github.com/iotaledger/wasp/packages/sctransaction/origin"
+	"github.com/iotaledger/wasp/packages/sctransaction/txbuilder"
+	"github.com/iotaledger/wasp/packages/tcrypto"
+	"github.com/iotaledger/wasp/packages/vm/examples/vmnil"
+	"github.com/iotaledger/wasp/packages/vm/processor"
+	"go.dedis.ch/kyber/v3/util/random"
+)
+
+// Config of the simulated committee and of the simulated network
+type Config struct {
+	// size of the committee and the quorum
+	N uint16
+	T uint16
+	// seed of all random choices: keys, delays and faults
+	Seed int64
+	// delay of peer messages is random in the range [MinDelay, MaxDelay)
+	MinDelay time.Duration
+	MaxDelay time.Duration
+	// probability for the peer message to be lost
+	DropRate float64
+	// probability for the peer message to be delivered twice
+	DuplicateRate float64
+	// time after which posted transaction is confirmed and delivered to nodes
+	ConfirmationDelay time.Duration
+	// time in which the ledger responds to queries of the node
+	LedgerDelay time.Duration
+	// time the VM takes to calculate the result
+	VMDelay time.Duration
+	// how long New waits for each node to load its state. The state manager loads it in the background,
+	// outside of the virtual time, so this is the real time. Zero means no limit
+	NodeReadyTimeout time.Duration
+	// index of the utxodb address which owns the smart contract and sends requests
+	OwnerIndex int
+	// optional wrapper of the node seen by its state manager and operator, for example a byzantine adversary.
+	// Returns the node itself for honest nodes
+	WrapCommittee func(index uint16, node committee.Committee, dkshare *tcrypto.DKShare) committee.Committee
+}
+
+// DefaultConfig is the committee of n nodes with quorum floor(2n/3)+1 on a reliable network
+func DefaultConfig(n uint16, seed int64) Config {
+	return Config{
+		N:                 n,
+		T:                 n*2/3 + 1,
+		Seed:              seed,
+		MinDelay:          5 * time.Millisecond,
+		MaxDelay:          50 * time.Millisecond,
+		ConfirmationDelay: 500 * time.Millisecond,
+		LedgerDelay:       20 * time.Millisecond,
+		VMDelay:           10 * time.Millisecond,
+		NodeReadyTimeout:  5 * time.Second,
+		OwnerIndex:        1,
+	}
+}
+
+// Simulator of the committee
+type Simulator struct {
+	cfg   Config
+	log   *logger.Logger
+	rnd   *rand.Rand
+	nodes []*Node
+
+	address        address.Address
+	ownerAddress   address.Address
+	ownerSigScheme signaturescheme.SignatureScheme
+	color          balance.Color
+
+	mutex    sync.Mutex
+	start    time.Time
+	now      time.Time
+	seq      uint64
+	queue    eventQueue
+	linkDown map[[2]uint16]bool
+	trace    []string
+}
+
+// virtual time starts always at the same moment
+var startTime = time.Unix(1600000000, 0)
+
+// New creates the committee of the smart contract with the new origin transaction in the fresh utxodb ledger.
+// utxodb is global, so simulators must not run in parallel
+func New(cfg Config, log *logger.Logger) (*Simulator, error) {
+	if err := tcrypto.ValidateDKSParams(cfg.T, cfg.N, 0); err != nil {
+		return nil, err
+	}
+	if cfg.OwnerIndex == 0 {
+		cfg.OwnerIndex = 1
+	}
+	ret := &Simulator{
+		cfg:      cfg,
+		log:      log,
+		rnd:      rand.New(rand.NewSource(cfg.Seed)),
+		start:    startTime,
+		now:      startTime,
+		linkDown: make(map[[2]uint16]bool),
+	}
+	utxodb.Init()
+	ret.ownerAddress = utxodb.GetAddress(cfg.OwnerIndex)
+	ret.ownerSigScheme = utxodb.GetSigScheme(ret.ownerAddress)
+
+	if err := loadProcessor(vmnil.ProgramHash); err != nil {
+		return nil, err
+	}
+	dkshares, err := tcrypto.NewDKSharesInProcess(cfg.T, cfg.N, random.New(rand.New(rand.NewSource(cfg.Seed))))
+	if err != nil {
+		return nil, err
+	}
+	ret.address = *dkshares[0].Address
+
+	progHash, err := hashing.HashValueFromBase58(vmnil.ProgramHash)
+	if err != nil {
+		return nil, err
+	}
+	originTx, err := origin.NewOriginTransaction(origin.NewOriginTransactionParams{
+		Address:              ret.address,
+		OwnerSignatureScheme: ret.ownerSigScheme,
+		AllInputs:            utxodb.GetAddressOutputs(ret.ownerAddress),
+		ProgramHash:          progHash,
+	})
+	if err != nil {
+		return nil, err
+	}
+	ret.color = (balance.Color)(originTx.ID())
+
+	ret.nodes = make([]*Node, cfg.N)
+	for i := range ret.nodes {
+		node := newNode(ret, uint16(i), mapdb.NewMapDB(), dkshares[i])
+		ret.nodes[i] = node
+		var cmt committee.Committee = node
+		if cfg.WrapCommittee != nil {
+			cmt = cfg.WrapCommittee(uint16(i), node, dkshares[i])
+		}
+		node.stateMgr = statemgr.New(cmt, node.log)
+		node.operator = consensus.NewOperator(cmt, dkshares[i], node.log)
+		var timeout <-chan time.Time
+		if cfg.NodeReadyTimeout > 0 {
+			timeout = time.After(cfg.NodeReadyTimeout)
+		}
+		select {
+		case <-node.chReady:
+		case <-timeout:
+			return nil, fmt.Errorf("node #%d failed to start in %v", i, cfg.NodeReadyTimeout)
+		}
+		ret.schedule(uint16(i), committee.TimerTick(0), timerTickPeriod, "")
+	}
+	if err := ret.PostTransaction(originTx.Transaction); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// loadProcessor makes the processor available before the run, otherwise the operator would load it
+// in the background and the run would not be reproducible
+func loadProcessor(progHash string) error {
+	if processor.CheckProcessor(progHash) {
+		return nil
+	}
+	chErr := make(chan error)
+	processor.LoadProcessorAsync(progHash, func(err error) {
+		chErr <- err
+	})
+	return <-chErr
+}
+
+// Address of the smart contract
+func (sim *Simulator) Address() *address.Address {
+	return &sim.address
+}
+
+// Color of the smart contract
+func (sim *Simulator) Color() *balance.Color {
+	return &sim.color
+}
+
+// Node returns the node by the peer index
+func (sim *Simulator) Node(index uint16) *Node {
+	return sim.nodes[index]
+}
+
+// Now is the current virtual time
+func (sim *Simulator) Now() time.Time {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+
+	return sim.now
+}
+
+// Elapsed is the virtual time since the start of the simulation
+func (sim *Simulator) Elapsed() time.Duration {
+	return sim.Now().Sub(sim.start)
+}
+
+// PostRequests posts the transaction with numRequests requests with the code to the smart contract
+// from the owner address. The program of the smart contract is the nil processor, it accepts any code
+func (sim *Simulator) PostRequests(code sctransaction.RequestCode, numRequests int) (*sctransaction.Transaction, error) {
+	blocks := make([]*sctransaction.RequestBlock, numRequests)
+	for i := range blocks {
+		blocks[i] = sctransaction.NewRequestBlock(sim.address, code)
+	}
+	return sim.PostRequestBlocks(blocks...)
+}
+
+// PostRequestBlocks posts the request transaction with the request blocks, signed by the owner of the smart contract
+func (sim *Simulator) PostRequestBlocks(blocks ...*sctransaction.RequestBlock) (*sctransaction.Transaction, error) {
+	txb, err := txbuilder.NewFromOutputBalances(utxodb.GetAddressOutputs(sim.ownerAddress))
+	if err != nil {
+		return nil, err
+	}
+	for _, blk := range blocks {
+		if err = txb.AddRequestBlock(blk); err != nil {
+			return nil, err
+		}
+	}
+	tx, err := txb.Build(false)
+	if err != nil {
+		return nil, err
+	}
+	tx.Sign(sim.ownerSigScheme)
+	if err = sim.PostTransaction(tx.Transaction); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// Step delivers the next event. Returns false if there are no more events
+func (sim *Simulator) Step() bool {
+	return sim.step(sim.start.Add(1<<62 - 1))
+}
+
+func (sim *Simulator) step(limit time.Time) bool {
+	ev, ok := sim.nextEvent(limit)
+	if !ok {
+		return false
+	}
+	sim.nodes[ev.target].dispatch(ev.msg)
+	return true
+}
+
+// RunFor delivers all events in the next d of the virtual time
+func (sim *Simulator) RunFor(d time.Duration) {
+	limit := sim.Now().Add(d)
+	for sim.step(limit) {
+	}
+	sim.advanceTo(limit)
+}
+
+// RunUntil delivers events until the condition becomes true or the timeout of the virtual time expires.
+// Returns the condition
+func (sim *Simulator) RunUntil(cond func() bool, timeout time.Duration) bool {
+	limit := sim.Now().Add(timeout)
+	for !cond() {
+		if !sim.step(limit) {
+			sim.advanceTo(limit)
+			return cond()
+		}
+	}
+	return true
+}
+
+func (sim *Simulator) advanceTo(t time.Time) {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+
+	if t.After(sim.now) {
+		sim.now = t
+	}
+}
+
+// SetLinkDown cuts or restores the connection between two nodes in both directions
+func (sim *Simulator) SetLinkDown(i, j uint16, down bool) {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+
+	sim.linkDown[linkKey(i, j)] = down
+}
+
+// SetNodeDown stops or restarts the node. The node which is down loses all messages delivered to it,
+// including the timer ticks and confirmations of transactions. Its state remains in the database
+func (sim *Simulator) SetNodeDown(index uint16, down bool) {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+
+	sim.nodes[index].down = down
+}
+
+// SetNetworkFaults changes probabilities of lost and duplicated peer messages
+func (sim *Simulator) SetNetworkFaults(dropRate, duplicateRate float64) {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+
+	sim.cfg.DropRate = dropRate
+	sim.cfg.DuplicateRate = duplicateRate
+}
+
+func linkKey(i, j uint16) [2]uint16 {
+	if i > j {
+		i, j = j, i
+	}
+	return [2]uint16{i, j}
+}
+
+func (sim *Simulator) isConnected(from, to uint16) bool {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+
+	return !sim.nodes[from].down && !sim.nodes[to].down && !sim.linkDown[linkKey(from, to)]
+}
+
+// sendPeerMessage applies faults of the network to the message and schedules it for the target node
+func (sim *Simulator) sendPeerMessage(from, to uint16, msgType byte, msgData []byte) {
+	sim.mutex.Lock()
+	dropRate, duplicateRate := sim.cfg.DropRate, sim.cfg.DuplicateRate
+	sim.mutex.Unlock()
+
+	if sim.rnd.Float64() < dropRate {
+		sim.log.Debugf("dropped message type %d #%d -> #%d", msgType, from, to)
+		return
+	}
+	copies := 1
+	if sim.rnd.Float64() < duplicateRate {
+		copies = 2
+	}
+	data := make([]byte, len(msgData))
+	copy(data, msgData)
+	for i := 0; i < copies; i++ {
+		sim.schedule(to, newPeerMessage(sim.nodes[from], msgType, data), sim.networkDelay(), "")
+	}
+}
+
+// publish records the published message in the trace of the run
+func (sim *Simulator) publish(index uint16, msgType string, parts ...string) {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+
+	sim.trace = append(sim.trace, fmt.Sprintf("%v #%d %s %s", sim.now.Sub(sim.start), index, msgType, strings.Join(parts, " ")))
+}
+
+// Trace returns all messages published by nodes with the virtual time and the node index.
+// Runs with the same seed produce the same trace
+func (sim *Simulator) Trace() []string {
+	sim.mutex.Lock()
+	defer sim.mutex.Unlock()
+
+	ret := make([]string, len(sim.trace))
+	copy(ret, sim.trace)
+	return ret
+}
+
+// make sure the simulated node is a complete committee
+var _ committee.Committee = &Node{}
+var _ committee.Environment = &Node{}
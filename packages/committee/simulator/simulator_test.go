@@ -0,0 +1,225 @@
+package simulator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/iotaledger/hive.go/logger"
+	"github.com/iotaledger/wasp/packages/sctransaction"
+	"github.com/iotaledger/wasp/packages/table"
+	"github.com/iotaledger/wasp/packages/vm/vmconst"
+	"github.com/stretchr/testify/assert"
+)
+
+// user defined request code. Requests with builtin codes may be processed before the init request
+const requestCode = sctransaction.RequestCode(1)
+
+func allReached(sim *Simulator, stateIndex uint32) func() bool {
+	return func() bool {
+		for i := uint16(0); i < sim.cfg.N; i++ {
+			idx, ok := sim.Node(i).StateIndex()
+			if !ok || idx < stateIndex {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// runRequests starts the committee, posts numTx request transactions and waits until all nodes reach the state
+func runRequests(t *testing.T, cfg Config, numTx int) *Simulator {
+	sim, err := New(cfg, logger.NewExampleLogger("sim"))
+	assert.NoError(t, err)
+
+	// origin
+	assert.True(t, sim.RunUntil(allReached(sim, 0), 30*time.Second))
+
+	for i := 0; i < numTx; i++ {
+		_, err = sim.PostRequests(requestCode, 2)
+		assert.NoError(t, err)
+		sim.RunFor(time.Second)
+	}
+	assert.True(t, sim.RunUntil(allReached(sim, 1), 2*time.Minute))
+	return sim
+}
+
+func TestCommitteeProgress(t *testing.T) {
+	sim := runRequests(t, DefaultConfig(4, 1), 3)
+
+	// after all requests are processed nodes stay in the same state
+	sim.RunFor(time.Minute)
+	idx, _ := sim.Node(0).StateIndex()
+	sim.RunFor(time.Minute)
+	for i := uint16(0); i < 4; i++ {
+		idx1, ok := sim.Node(i).StateIndex()
+		assert.True(t, ok)
+		assert.Equal(t, idx, idx1)
+	}
+}
+
+func TestDeterminism(t *testing.T) {
+	cfg := DefaultConfig(4, 42)
+	cfg.DuplicateRate = 0.1
+	trace1 := runRequests(t, cfg, 2).Trace()
+	trace2 := runRequests(t, cfg, 2).Trace()
+	assert.NotEmpty(t, trace1)
+	assert.Equal(t, trace1, trace2)
+}
+
+func TestFaultyNetwork(t *testing.T) {
+	cfg := DefaultConfig(4, 7)
+	cfg.DropRate = 0.05
+	cfg.DuplicateRate = 0.05
+	cfg.MaxDelay = 300 * time.Millisecond
+	sim := runRequests(t, cfg, 3)
+
+	// the network gets worse
+	sim.SetNetworkFaults(0.2, 0.1)
+	_, err := sim.PostRequests(requestCode, 2)
+	assert.NoError(t, err)
+	idx, _ := sim.Node(0).StateIndex()
+	assert.True(t, sim.RunUntil(allReached(sim, idx+1), 5*time.Minute))
+}
+
+func TestNodeDown(t *testing.T) {
+	sim, err := New(DefaultConfig(4, 3), logger.NewExampleLogger("sim"))
+	assert.NoError(t, err)
+	assert.True(t, sim.RunUntil(allReached(sim, 0), 30*time.Second))
+
+	// quorum of 3 makes progress without the node
+	sim.SetNodeDown(3, true)
+	_, err = sim.PostRequests(requestCode, 1)
+	assert.NoError(t, err)
+	reached := func() bool {
+		for i := uint16(0); i < 3; i++ {
+			if idx, _ := sim.Node(i).StateIndex(); idx < 1 {
+				return false
+			}
+		}
+		return true
+	}
+	assert.True(t, sim.RunUntil(reached, 2*time.Minute))
+	idx, _ := sim.Node(3).StateIndex()
+	assert.EqualValues(t, 0, idx)
+
+	// the node syncs after the restart
+	sim.SetNodeDown(3, false)
+	_, err = sim.PostRequests(requestCode, 1)
+	assert.NoError(t, err)
+	assert.True(t, sim.RunUntil(allReached(sim, 2), 2*time.Minute))
+}
+
+func TestWrongConfig(t *testing.T) {
+	_, err := New(Config{N: 4, T: 5}, logger.NewExampleLogger("sim"))
+	assert.Error(t, err)
+}
+
+func TestConsensusParams(t *testing.T) {
+	sim := runRequests(t, DefaultConfig(4, 5), 1)
+	sim.RunFor(time.Minute)
+
+	// the owner limits the batch to 1 request
+	setMaxBatchSize(t, sim, 1)
+
+	// each request is processed in own batch
+	idx, _ := sim.Node(0).StateIndex()
+	for i := 0; i < 3; i++ {
+		_, err := sim.PostRequests(requestCode, 1)
+		assert.NoError(t, err)
+	}
+	assert.True(t, sim.RunUntil(allReached(sim, idx+3), 2*time.Minute))
+	sim.RunFor(time.Minute)
+	idx1, _ := sim.Node(0).StateIndex()
+	assert.Equal(t, idx+3, idx1)
+}
+
+// setMaxBatchSize sets the consensus parameter by the request of the owner
+func setMaxBatchSize(t *testing.T, sim *Simulator, maxBatchSize int64) {
+	blk := sctransaction.NewRequestBlock(*sim.Address(), vmconst.RequestCodeSetConsensusParams)
+	args := table.NewMemTable()
+	args.Codec().SetInt64(vmconst.VarNameMaxBatchSize, maxBatchSize)
+	blk.SetArgs(args)
+	_, err := sim.PostRequestBlocks(blk)
+	assert.NoError(t, err)
+	idx, _ := sim.Node(0).StateIndex()
+	assert.True(t, sim.RunUntil(allReached(sim, idx+1), 2*time.Minute))
+}
+
+// runBatches posts numReqs requests, each processed in own batch, and returns the time until all nodes
+// reach the last state
+func runBatches(t *testing.T, sim *Simulator, numReqs int) time.Duration {
+	setMaxBatchSize(t, sim, 1)
+	sim.RunFor(time.Minute)
+
+	idx, _ := sim.Node(0).StateIndex()
+	start := sim.Elapsed()
+	for i := 0; i < numReqs; i++ {
+		_, err := sim.PostRequests(requestCode, 1)
+		assert.NoError(t, err)
+	}
+	assert.True(t, sim.RunUntil(allReached(sim, idx+uint32(numReqs)), 5*time.Minute))
+	ret := sim.Elapsed() - start
+
+	// all nodes are in the same state
+	sim.RunFor(time.Minute)
+	for i := uint16(0); i < sim.cfg.N; i++ {
+		idx1, _ := sim.Node(i).StateIndex()
+		assert.Equal(t, idx+uint32(numReqs), idx1)
+	}
+	return ret
+}
+
+func TestPipelining(t *testing.T) {
+	const numReqs = 6
+	cfg := DefaultConfig(4, 11)
+	cfg.ConfirmationDelay = 2 * time.Second
+	sim := runRequests(t, cfg, 1)
+
+	// the next batch is calculated while the previous one awaits confirmation,
+	// so the committee needs less confirmations than batches
+	elapsed := runBatches(t, sim, numReqs)
+	t.Logf("%d batches in %v", numReqs, elapsed)
+	assert.True(t, elapsed < (numReqs+1)*cfg.ConfirmationDelay, "%d batches in %v", numReqs, elapsed)
+}
+
+func TestPipeliningSlowConfirmation(t *testing.T) {
+	cfg := DefaultConfig(4, 12)
+	// the leader times out before the batch is confirmed. Pipelined batches are rolled back
+	cfg.ConfirmationDelay = 5 * time.Second
+	sim := runRequests(t, cfg, 1)
+	runBatches(t, sim, 4)
+}
+
+func TestCatchUp(t *testing.T) {
+	const numReqs = 40
+	sim := runRequests(t, DefaultConfig(4, 13), 1)
+	setMaxBatchSize(t, sim, 1)
+	sim.RunFor(time.Minute)
+
+	// the node falls many states behind
+	idx, _ := sim.Node(0).StateIndex()
+	sim.SetNodeDown(3, true)
+	for i := 0; i < numReqs; i++ {
+		_, err := sim.PostRequests(requestCode, 1)
+		assert.NoError(t, err)
+	}
+	reached := func() bool {
+		for i := uint16(0); i < 3; i++ {
+			if idx1, _ := sim.Node(i).StateIndex(); idx1 < idx+numReqs {
+				return false
+			}
+		}
+		return true
+	}
+	assert.True(t, sim.RunUntil(reached, 5*time.Minute))
+
+	// batches are synced in ranges from several peers, state transactions are requested in advance
+	sim.SetNodeDown(3, false)
+	_, err := sim.PostRequests(requestCode, 1)
+	assert.NoError(t, err)
+	start := sim.Elapsed()
+	assert.True(t, sim.RunUntil(allReached(sim, idx+numReqs+1), 2*time.Minute))
+	elapsed := sim.Elapsed() - start
+	t.Logf("synced %d states in %v", numReqs+1, elapsed)
+	assert.True(t, elapsed < 10*time.Second, "synced %d states in %v", numReqs+1, elapsed)
+}
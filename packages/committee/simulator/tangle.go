@@ -0,0 +1,122 @@
+package simulator
+
+import (
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	valuetransaction "github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/transaction"
+	"github.com/iotaledger/goshimmer/dapps/waspconn/packages/utxodb"
+	"github.com/iotaledger/goshimmer/dapps/waspconn/packages/waspconn"
+	"github.com/iotaledger/wasp/packages/committee"
+	"github.com/iotaledger/wasp/packages/sctransaction"
+)
+
+// the mocked tangle is the utxodb ledger. Confirmations and responses to the queries of nodes
+// are delivered through the event queue the same way the node connection delivers them
+
+// confirmedTx is delivered to every node when the transaction is confirmed
+type confirmedTx struct {
+	tx *sctransaction.Transaction
+}
+
+// outputsQuery is the response to the RequestOutputs call of the node
+type outputsQuery struct {
+	addr address.Address
+}
+
+// txQuery is the response to the RequestTransaction call of the node
+type txQuery struct {
+	txid valuetransaction.ID
+}
+
+// PostTransaction adds the value transaction to the ledger.
+// After the confirmation delay all nodes receive the address update, as from the node connection
+func (sim *Simulator) PostTransaction(vtx *valuetransaction.Transaction) error {
+	if err := utxodb.AddTransaction(vtx); err != nil {
+		return err
+	}
+	tx, err := sctransaction.ParseValueTransaction(vtx)
+	if err != nil {
+		// not a smart contract transaction, nothing to dispatch
+		return nil
+	}
+	sim.log.Debugf("posted transaction %s", tx.ID().String())
+	for i := range sim.nodes {
+		sim.schedule(uint16(i), confirmedTx{tx: tx}, sim.cfg.ConfirmationDelay, tx.ID().String())
+	}
+	return nil
+}
+
+func (sim *Simulator) requestOutputs(node *Node, addr *address.Address) {
+	sim.schedule(node.index, outputsQuery{addr: *addr}, sim.cfg.LedgerDelay, addr.String())
+}
+
+func (sim *Simulator) requestTransaction(node *Node, txid *valuetransaction.ID) {
+	sim.schedule(node.index, txQuery{txid: *txid}, sim.cfg.LedgerDelay, txid.String())
+}
+
+// processLedgerEvent handles events of the mocked tangle. Returns false if the event is not a ledger event
+func (sim *Simulator) processLedgerEvent(node *Node, msg interface{}) bool {
+	switch msgt := msg.(type) {
+	case confirmedTx:
+		sim.dispatchAddressUpdate(node, msgt.tx)
+
+	case outputsQuery:
+		outs := utxodb.GetAddressOutputs(msgt.addr)
+		node.ReceiveMessage(committee.BalancesMsg{Balances: waspconn.OutputsToBalances(outs)})
+
+	case txQuery:
+		vtx, ok := utxodb.GetTransaction(msgt.txid)
+		if !ok {
+			node.log.Warnf("transaction %s not found in the ledger", msgt.txid.String())
+			return true
+		}
+		tx, err := sctransaction.ParseValueTransaction(vtx)
+		if err != nil {
+			return true
+		}
+		if stateAddr, ok, err := tx.StateAddress(); err != nil || !ok || stateAddr != sim.address {
+			return true
+		}
+		if _, err := tx.ValidateBlocks(&sim.address); err != nil {
+			node.log.Errorf("invalid transaction %s ignored: %v", tx.ID().String(), err)
+			return true
+		}
+		node.ReceiveMessage(committee.StateTransactionMsg{Transaction: tx})
+
+	default:
+		return false
+	}
+	return true
+}
+
+// dispatchAddressUpdate mirrors the dispatcher plugin: balances first, then state transaction, then requests
+func (sim *Simulator) dispatchAddressUpdate(node *Node, tx *sctransaction.Transaction) {
+	if _, err := tx.ValidateBlocks(&sim.address); err != nil {
+		node.log.Warnf("invalid transaction %s ignored: %v", tx.ID().String(), err)
+		return
+	}
+	isState := false
+	if stateAddr, ok, err := tx.StateAddress(); err == nil && ok && stateAddr == sim.address {
+		isState = true
+	}
+	requestMsgs := make([]committee.RequestMsg, 0, len(tx.Requests()))
+	for i, reqBlk := range tx.Requests() {
+		if reqBlk.Address() == sim.address {
+			requestMsgs = append(requestMsgs, committee.RequestMsg{
+				Transaction: tx,
+				Index:       uint16(i),
+			})
+		}
+	}
+	if !isState && len(requestMsgs) == 0 {
+		return
+	}
+	outs := utxodb.GetAddressOutputs(sim.address)
+	node.ReceiveMessage(committee.BalancesMsg{Balances: waspconn.OutputsToBalances(outs)})
+
+	if isState {
+		node.ReceiveMessage(committee.StateTransactionMsg{Transaction: tx})
+	}
+	for _, reqMsg := range requestMsgs {
+		node.ReceiveMessage(reqMsg)
+	}
+}
@@ -7,14 +7,15 @@ import (
 	"github.com/iotaledger/wasp/packages/hashing"
 	"github.com/iotaledger/wasp/packages/state"
 	"github.com/iotaledger/wasp/packages/util"
-	"github.com/iotaledger/wasp/plugins/nodeconn"
-	"github.com/iotaledger/wasp/plugins/publisher"
 	"strconv"
 	"time"
 )
 
 func (sm *stateManager) takeAction() {
 	if sm.checkStateApproval() {
+		// the pipelined batch may be approved right after
+		for sm.checkStateApproval() {
+		}
 		return
 	}
 	sm.requestStateTransactionIfNeeded()
@@ -94,10 +95,10 @@ func (sm *stateManager) checkStateApproval() bool {
 	sm.nextStateTransaction = nil
 	sm.pendingBatches = make(map[hashing.HashValue]*pendingBatch) // clear pending batches
 	sm.permutation.Shuffle(varStateHash.Bytes())
-	sm.syncMessageDeadline = time.Now() // if not synced then immediately
+	sm.syncMessageDeadline = sm.env.Now() // if not synced then immediately
 
 	// publish state transition
-	publisher.Publish("state",
+	sm.env.Publish("state",
 		sm.committee.Address().String(),
 		strconv.Itoa(int(sm.solidState.StateIndex())),
 		strconv.Itoa(int(pending.batch.Size())),
@@ -107,7 +108,7 @@ func (sm *stateManager) checkStateApproval() bool {
 	)
 	// publish processed requests
 	for i, reqid := range pending.batch.RequestIds() {
-		publisher.Publish("request_out",
+		sm.env.Publish("request_out",
 			sm.committee.Address().String(),
 			reqid.TransactionId().String(),
 			fmt.Sprintf("%d", reqid.Index()),
@@ -117,45 +118,166 @@ func (sm *stateManager) checkStateApproval() bool {
 		)
 	}
 
-	go func() {
-		sm.committee.ReceiveMessage(&committee.StateTransitionMsg{
-			VariableState:    sm.solidState,
-			StateTransaction: saveTx,
-			Synchronized:     sm.isSynchronized(),
-		})
-	}()
+	sm.committee.ReceiveMessage(&committee.StateTransitionMsg{
+		VariableState:    sm.solidState,
+		StateTransaction: saveTx,
+		Synchronized:     sm.isSynchronized(),
+	})
+
+	sm.usePipelined()
+	sm.useSynced()
 	return true
 }
 
-const periodBetweenSyncMessages = 1 * time.Second
+const maxPipelinedBatches = 16
+
+// pipelineBatch keeps the batch calculated on top of the not yet confirmed state until the state transition
+func (sm *stateManager) pipelineBatch(batch state.Batch) {
+	if len(sm.pipelinedBatches) >= maxPipelinedBatches {
+		sm.pipelinedBatches = sm.pipelinedBatches[1:]
+	}
+	sm.pipelinedBatches = append(sm.pipelinedBatches, batch)
+}
+
+// usePipelined adds pipelined batches and the state transaction to the next state transition
+func (sm *stateManager) usePipelined() {
+	batches := sm.pipelinedBatches
+	sm.pipelinedBatches = nil
+	for _, batch := range batches {
+		sm.addPendingBatch(batch)
+	}
+	tx := sm.pipelinedTransaction
+	sm.pipelinedTransaction = nil
+	if tx == nil {
+		return
+	}
+	switch tx.MustState().StateIndex() {
+	case sm.solidState.StateIndex() + 1:
+		sm.nextStateTransaction = tx
+	case sm.solidState.StateIndex() + 2:
+		sm.pipelinedTransaction = tx
+	}
+}
+
+// useSynced adds the batch synced ahead and its state transaction to the next state transition.
+// The batch is verified as any other pending batch: the state transaction must approve the resulting state
+func (sm *stateManager) useSynced() {
+	next := sm.solidState.StateIndex() + 1
+	for stateIndex := range sm.syncedBatches {
+		if stateIndex < next {
+			delete(sm.syncedBatches, stateIndex)
+		}
+	}
+	for stateIndex := range sm.syncedAhead {
+		if stateIndex < next {
+			delete(sm.syncedAhead, stateIndex)
+		}
+	}
+	for stateIndex := range sm.syncedAheadTransactions {
+		if stateIndex < next {
+			delete(sm.syncedAheadTransactions, stateIndex)
+		}
+	}
+	batch, ok := sm.syncedAhead[next]
+	if !ok {
+		return
+	}
+	delete(sm.syncedAhead, next)
+	if tx, ok := sm.syncedAheadTransactions[next]; ok && sm.nextStateTransaction == nil {
+		sm.nextStateTransaction = tx
+	}
+	delete(sm.syncedAheadTransactions, next)
+	sm.addPendingBatch(batch)
+}
+
+const (
+	periodBetweenSyncMessages = 1 * time.Second
+	// the period between queries of the origin transaction grows up to the limit
+	maxPeriodBetweenOriginRequests = 1 * time.Minute
+	// number of states after the solid state which are synced at once.
+	// The range is requested in chunks of maxBatchesPerRequest from different peers
+	syncWindow = 4 * maxBatchesPerRequest
+)
+
+// isSyncedIndex return true if the batch with the state index is expected while syncing
+func (sm *stateManager) isSyncedIndex(stateIndex uint32) bool {
+	if sm.solidState == nil {
+		return false
+	}
+	return stateIndex > sm.solidState.StateIndex() && stateIndex <= sm.solidState.StateIndex()+syncWindow
+}
 
 func (sm *stateManager) requestStateUpdateFromPeerIfNeeded() {
-	if sm.isSynchronized() || sm.solidState == nil {
+	if sm.isSynchronized() {
 		// state is synced, no need for more info
-		// or it is in the pre-origin state, the 0 batch is deterministically known
 		return
 	}
 	// not synced
-	if !sm.syncMessageDeadline.Before(time.Now()) {
+	if !sm.syncMessageDeadline.Before(sm.env.Now()) {
 		// not time yet for the next message
 		return
 	}
-	// it is time to ask for the next state update to next peer in the permutation
-	stateIndex := uint32(0)
-	if sm.solidState != nil {
-		stateIndex = sm.solidState.StateIndex() + 1
+	if sm.solidState == nil {
+		// pre-origin state while the smart contract is evidenced in later states: the node has joined
+		// the committee of the running smart contract, e.g. after rotation. The 0 batch is deterministically
+		// known, so the sync starts from the origin transaction
+		originTxId := (valuetransaction.ID)(*sm.committee.Color())
+		sm.log.Debugf("query origin transaction from the node. txid = %s", originTxId.String())
+		_ = sm.env.RequestTransaction(&originTxId)
+		// the origin transaction may not be confirmed yet or may not exist at all. Back off
+		if sm.originRequestPeriod < periodBetweenSyncMessages {
+			sm.originRequestPeriod = periodBetweenSyncMessages
+		} else if sm.originRequestPeriod < maxPeriodBetweenOriginRequests {
+			sm.originRequestPeriod *= 2
+			if sm.originRequestPeriod > maxPeriodBetweenOriginRequests {
+				sm.originRequestPeriod = maxPeriodBetweenOriginRequests
+			}
+		}
+		sm.syncMessageDeadline = sm.env.Now().Add(sm.originRequestPeriod)
+		return
 	}
-	data := util.MustBytes(&committee.GetBatchMsg{
+	// it is time to ask for missing batches. The range is split into chunks, each chunk
+	// is requested from the next peer in the permutation. The batch for the next state is always
+	// requested again: the one received before may be wrong
+	from := sm.solidState.StateIndex() + 1
+	to := sm.largestEvidencedStateIndex
+	if to > sm.solidState.StateIndex()+syncWindow {
+		to = sm.solidState.StateIndex() + syncWindow
+	}
+	for chunkFrom := from; chunkFrom <= to; chunkFrom += maxBatchesPerRequest {
+		chunkTo := chunkFrom + maxBatchesPerRequest - 1
+		if chunkTo > to {
+			chunkTo = to
+		}
+		first := chunkFrom
+		for ; first <= chunkTo; first++ {
+			if _, ok := sm.syncedAhead[first]; !ok {
+				break
+			}
+		}
+		if first > chunkTo {
+			// the whole chunk is synced
+			continue
+		}
+		sm.requestBatches(first, chunkTo)
+	}
+	sm.syncMessageDeadline = sm.env.Now().Add(periodBetweenSyncMessages)
+}
+
+// sends the request for the range of batches to the next peer in the permutation until the first without error
+func (sm *stateManager) requestBatches(from, to uint32) {
+	sm.log.Debugf("requesting batches #%d - #%d", from, to)
+
+	data := util.MustBytes(&committee.GetBatchesMsg{
 		PeerMsgHeader: committee.PeerMsgHeader{
-			StateIndex: stateIndex,
+			StateIndex: from,
 		},
+		ToIndex: to,
 	})
-	// send messages until first without error
 	for i := uint16(0); i < sm.committee.Size(); i++ {
-		if err := sm.committee.SendMsg(sm.permutation.Next(), committee.MsgGetBatch, data); err == nil {
-			break
+		if err := sm.committee.SendMsg(sm.permutation.Next(), committee.MsgGetBatches, data); err == nil {
+			return
 		}
-		sm.syncMessageDeadline = time.Now().Add(periodBetweenSyncMessages)
 	}
 }
 
@@ -176,7 +298,7 @@ func (sm *stateManager) EvidenceStateIndex(stateIndex uint32) {
 	}
 	switch {
 	case !sm.isSynchronized() && wasSynchronized:
-		sm.syncMessageDeadline = time.Now()
+		sm.syncMessageDeadline = sm.env.Now()
 		sm.log.Debugf("NOT SYNCED: current state index: %d, largest evidenced index: %d",
 			currStateIndex, sm.largestEvidencedStateIndex)
 	case sm.isSynchronized() && !wasSynchronized:
@@ -203,6 +325,11 @@ func (sm *stateManager) addPendingBatch(batch state.Batch) bool {
 	)
 
 	if sm.solidStateValid {
+		if batch.StateIndex() == sm.solidState.StateIndex()+2 {
+			// pipelined batch, used after the next state transition
+			sm.pipelineBatch(batch)
+			return false
+		}
 		if batch.StateIndex() != sm.solidState.StateIndex()+1 {
 			// if current state is validated, only interested in the batches of state updates for the next state
 			return false
@@ -256,7 +383,8 @@ func (sm *stateManager) addPendingBatch(batch state.Batch) bool {
 		"approving tx", pb.batch.StateTransactionId().String(),
 	)
 	// request approving transaction from the node. It may also come without request
-	if batch.StateTransactionId() != niltxid {
+	if batch.StateTransactionId() != niltxid &&
+		(sm.nextStateTransaction == nil || sm.nextStateTransaction.ID() != batch.StateTransactionId()) {
 		sm.requestStateTransaction(pb)
 	}
 	return true
@@ -264,7 +392,7 @@ func (sm *stateManager) addPendingBatch(batch state.Batch) bool {
 
 func (sm *stateManager) createStateToApprove() state.VirtualState {
 	if sm.solidState == nil {
-		return state.NewEmptyVirtualState(sm.committee.Address())
+		return state.NewEmptyVirtualStateInPartition(sm.committee.Address(), sm.env.Partition)
 	}
 	return sm.solidState.Clone()
 }
@@ -277,7 +405,7 @@ func (sm *stateManager) requestStateTransactionIfNeeded() {
 		return
 	}
 	for _, pb := range sm.pendingBatches {
-		if pb.batch.StateTransactionId() != niltxid && pb.stateTransactionRequestDeadline.Before(time.Now()) {
+		if pb.batch.StateTransactionId() != niltxid && pb.stateTransactionRequestDeadline.Before(sm.env.Now()) {
 			sm.requestStateTransaction(pb)
 		}
 	}
@@ -286,6 +414,6 @@ func (sm *stateManager) requestStateTransactionIfNeeded() {
 func (sm *stateManager) requestStateTransaction(pb *pendingBatch) {
 	txid := pb.batch.StateTransactionId()
 	sm.log.Debugf("query transaction from the node. txid = %s", txid.String())
-	_ = nodeconn.RequestTransactionFromNode(&txid)
-	pb.stateTransactionRequestDeadline = time.Now().Add(stateTransactionRequestTimeout)
+	_ = sm.env.RequestTransaction(&txid)
+	pb.stateTransactionRequestDeadline = sm.env.Now().Add(stateTransactionRequestTimeout)
 }
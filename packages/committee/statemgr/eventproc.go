@@ -12,42 +12,64 @@ func (sm *stateManager) EventGetBatchMsg(msg *committee.GetBatchMsg) {
 		"sender index", msg.SenderIndex,
 		"state index", msg.StateIndex,
 	)
+	sm.sendBatch(msg.SenderIndex, msg.StateIndex)
+}
+
+// maximum number of batches sent in response to one GetBatchesMsg
+const maxBatchesPerRequest = 16
+
+// respond to the range sync request. Batches are sent in the order of state indices until the first one
+// which is not in the database
+func (sm *stateManager) EventGetBatchesMsg(msg *committee.GetBatchesMsg) {
+	sm.log.Debugw("EventGetBatchesMsg",
+		"sender index", msg.SenderIndex,
+		"from", msg.StateIndex,
+		"to", msg.ToIndex,
+	)
+	to := msg.ToIndex
+	if to >= msg.StateIndex+maxBatchesPerRequest {
+		to = msg.StateIndex + maxBatchesPerRequest - 1
+	}
+	for stateIndex := msg.StateIndex; stateIndex <= to; stateIndex++ {
+		if !sm.sendBatch(msg.SenderIndex, stateIndex) {
+			return
+		}
+	}
+}
+
+// sendBatch sends the header and state updates of the batch to the peer.
+// Returns false if the batch can't be loaded or sent
+func (sm *stateManager) sendBatch(peerIndex uint16, stateIndex uint32) bool {
 	addr := sm.committee.Address()
-	batch, err := state.LoadBatch(addr, msg.StateIndex)
+	batch, err := state.LoadBatchFromPartition(sm.env.Partition(addr), stateIndex)
 	if err != nil || batch == nil {
-		//sm.log.Debugw("EventGetBatchMsg: can't find batch",
-		//	"sender index", msg.SenderIndex,
-		//	"state index", msg.StateIndex,
-		//	"addr", addr.String(),
-		//)
 		// can't load batch, can't respond
-		return
+		return false
 	}
 
-	sm.log.Debugf("EventGetBatchMsg: sending to %d batch %s", msg.SenderIndex, batch.String())
+	sm.log.Debugf("sendBatch: sending to %d batch %s", peerIndex, batch.String())
 
-	err = sm.committee.SendMsg(msg.SenderIndex, committee.MsgBatchHeader, util.MustBytes(&committee.BatchHeaderMsg{
+	err = sm.committee.SendMsg(peerIndex, committee.MsgBatchHeader, util.MustBytes(&committee.BatchHeaderMsg{
 		PeerMsgHeader: committee.PeerMsgHeader{
-			StateIndex: msg.StateIndex,
+			StateIndex: stateIndex,
 		},
 		Size:               batch.Size(),
 		StateTransactionId: batch.StateTransactionId(),
 	}))
 	if err != nil {
-		return
+		return false
 	}
 	batch.ForEach(func(batchIndex uint16, stateUpdate state.StateUpdate) bool {
-		err = sm.committee.SendMsg(msg.SenderIndex, committee.MsgStateUpdate, util.MustBytes(&committee.StateUpdateMsg{
+		err = sm.committee.SendMsg(peerIndex, committee.MsgStateUpdate, util.MustBytes(&committee.StateUpdateMsg{
 			PeerMsgHeader: committee.PeerMsgHeader{
-				StateIndex: msg.StateIndex,
+				StateIndex: stateIndex,
 			},
 			StateUpdate: stateUpdate,
 			BatchIndex:  batchIndex,
 		}))
-		sh := util.GetHashValue(stateUpdate)
-		sm.log.Debugw("EventGetBatchMsg: sending stateUpdate", "hash", sh.String())
-		return true
+		return err == nil
 	})
+	return err == nil
 }
 
 func (sm *stateManager) EventBatchHeaderMsg(msg *committee.BatchHeaderMsg) {
@@ -57,13 +79,17 @@ func (sm *stateManager) EventBatchHeaderMsg(msg *committee.BatchHeaderMsg) {
 		"size", msg.Size,
 		"state tx", msg.StateTransactionId.String(),
 	)
-	if sm.syncedBatch != nil &&
-		sm.syncedBatch.stateIndex == msg.StateIndex &&
-		sm.syncedBatch.stateTxId == msg.StateTransactionId &&
-		len(sm.syncedBatch.stateUpdates) == int(msg.Size) {
-		return // no need to start from scratch
+	if !sm.isSyncedIndex(msg.StateIndex) {
+		return
 	}
-	sm.syncedBatch = &syncedBatch{
+	sb, ok := sm.syncedBatches[msg.StateIndex]
+	if ok && sb.stateTxId == msg.StateTransactionId && len(sb.stateUpdates) == int(msg.Size) {
+		// no need to start from scratch, the rest of state updates is expected from the new sender
+		sb.senderIndex = msg.SenderIndex
+		return
+	}
+	sm.syncedBatches[msg.StateIndex] = &syncedBatch{
+		senderIndex:  msg.SenderIndex,
 		stateIndex:   msg.StateIndex,
 		stateUpdates: make([]state.StateUpdate, msg.Size),
 		stateTxId:    msg.StateTransactionId,
@@ -78,13 +104,15 @@ func (sm *stateManager) EventStateUpdateMsg(msg *committee.StateUpdateMsg) {
 		"state index", msg.StateIndex,
 		"batch index", msg.BatchIndex,
 	)
-	if sm.syncedBatch == nil {
+	sb, ok := sm.syncedBatches[msg.StateIndex]
+	if !ok {
 		return
 	}
-	if sm.syncedBatch.stateIndex != msg.StateIndex {
+	if sb.senderIndex != msg.SenderIndex {
+		// state updates are collected from the peer which sent the header
 		return
 	}
-	if int(msg.BatchIndex) >= len(sm.syncedBatch.stateUpdates) {
+	if int(msg.BatchIndex) >= len(sb.stateUpdates) {
 		sm.log.Errorf("bad batch index in the state update message")
 		return
 	}
@@ -92,37 +120,39 @@ func (sm *stateManager) EventStateUpdateMsg(msg *committee.StateUpdateMsg) {
 	sm.log.Debugf("EventStateUpdateMsg: receiving stateUpdate batch index: %d hash: %s",
 		msg.BatchIndex, sh.String())
 
-	sm.syncedBatch.stateUpdates[msg.BatchIndex] = msg.StateUpdate
-	sm.syncedBatch.msgCounter++
+	if sb.stateUpdates[msg.BatchIndex] == nil {
+		sb.msgCounter++
+	}
+	sb.stateUpdates[msg.BatchIndex] = msg.StateUpdate
 
-	if int(sm.syncedBatch.msgCounter) < len(sm.syncedBatch.stateUpdates) {
+	if int(sb.msgCounter) < len(sb.stateUpdates) {
 		// some are missing
 		return
 	}
-	// check if whole batch already received
-	for _, su := range sm.syncedBatch.stateUpdates {
-		if su == nil {
-			// some state updates are missing
-			return
-		}
-	}
 	// the whole batch received
-	batch, err := state.NewBatch(sm.syncedBatch.stateUpdates)
+	delete(sm.syncedBatches, msg.StateIndex)
+
+	batch, err := state.NewBatch(sb.stateUpdates)
 	if err != nil {
 		sm.log.Errorf("failed to create batch: %v", err)
-		sm.syncedBatch = nil
 		return
 	}
-	batch.WithStateIndex(sm.syncedBatch.stateIndex).WithStateTransaction(sm.syncedBatch.stateTxId)
+	batch.WithStateIndex(sb.stateIndex).WithStateTransaction(sb.stateTxId)
 
 	sm.log.Debugf("EventStateUpdateMsg: reconstructed batch %s", batch.String())
 
-	sm.syncedBatch = nil
-	go func() {
-		sm.committee.ReceiveMessage(committee.PendingBatchMsg{
-			Batch: batch,
-		})
-	}()
+	if sm.solidState != nil && sb.stateIndex > sm.solidState.StateIndex()+1 {
+		// the state is not there yet. The batch will be verified by the state transaction
+		// after the previous state transition. The state transaction is requested in advance
+		sm.syncedAhead[sb.stateIndex] = batch
+		txid := batch.StateTransactionId()
+		sm.log.Debugf("query transaction of the synced batch #%d from the node. txid = %s", sb.stateIndex, txid.String())
+		_ = sm.env.RequestTransaction(&txid)
+		return
+	}
+	sm.committee.ReceiveMessage(committee.PendingBatchMsg{
+		Batch: batch,
+	})
 	sm.takeAction()
 }
 
@@ -143,6 +173,16 @@ func (sm *stateManager) EventStateTransactionMsg(msg committee.StateTransactionM
 	sm.EvidenceStateIndex(stateBlock.StateIndex())
 
 	if sm.solidStateValid {
+		if batch, ok := sm.syncedAhead[stateBlock.StateIndex()]; ok && batch.StateTransactionId() == msg.ID() {
+			// the state transaction of the batch synced ahead
+			sm.syncedAheadTransactions[stateBlock.StateIndex()] = msg.Transaction
+			return
+		}
+		if stateBlock.StateIndex() == sm.solidState.StateIndex()+2 {
+			// the state transaction of the pipelined batch confirmed before the previous one was processed
+			sm.pipelinedTransaction = msg.Transaction
+			return
+		}
 		if stateBlock.StateIndex() != sm.solidState.StateIndex()+1 {
 			sm.log.Debugf("only interested for the state transaction to verify latest state update")
 			return
@@ -16,6 +16,7 @@ import (
 
 type stateManager struct {
 	committee committee.Committee
+	env       committee.Environment
 
 	// becomes true after initially loaded state is validated.
 	// after that it is always true
@@ -31,6 +32,12 @@ type stateManager struct {
 	// it may be nil if does not exist or not fetched yet
 	nextStateTransaction *sctransaction.Transaction
 
+	// batches and the state transaction with +2 state index from the state index of the solid state.
+	// They come from the pipelined consensus before the previous state transition and
+	// are used after it
+	pipelinedBatches     []state.Batch
+	pipelinedTransaction *sctransaction.Transaction
+
 	// last variable state stored in the database
 	// it may be nil at bootstrap when origin variable state is calculated
 	solidState state.VirtualState
@@ -43,8 +50,16 @@ type stateManager struct {
 	// the timeout deadline for sync inquiries
 	syncMessageDeadline time.Time
 
-	// current batch being synced
-	syncedBatch *syncedBatch
+	// current period between queries of the origin transaction in the pre-origin state
+	originRequestPeriod time.Duration
+
+	// batches being synced, by state index. Several ranges are synced from different peers in parallel
+	syncedBatches map[uint32]*syncedBatch
+
+	// completely synced batches with state indices more than +1 from the solid state and
+	// their state transactions. They are used when the state reaches them
+	syncedAhead             map[uint32]state.Batch
+	syncedAheadTransactions map[uint32]*sctransaction.Transaction
 
 	// for the pseudo-random sequence of peers
 	permutation *util.Permutation16
@@ -54,6 +69,8 @@ type stateManager struct {
 }
 
 type syncedBatch struct {
+	// the peer the batch is received from
+	senderIndex  uint16
 	msgCounter   uint16
 	stateIndex   uint32
 	stateUpdates []state.StateUpdate
@@ -71,10 +88,14 @@ type pendingBatch struct {
 
 func New(committee committee.Committee, log *logger.Logger) committee.StateManager {
 	ret := &stateManager{
-		committee:      committee,
-		pendingBatches: make(map[hashing.HashValue]*pendingBatch),
-		permutation:    util.NewPermutation16(committee.NumPeers(), nil),
-		log:            log.Named("s"),
+		committee:               committee,
+		env:                     committee.Environment(),
+		pendingBatches:          make(map[hashing.HashValue]*pendingBatch),
+		syncedBatches:           make(map[uint32]*syncedBatch),
+		syncedAhead:             make(map[uint32]state.Batch),
+		syncedAheadTransactions: make(map[uint32]*sctransaction.Transaction),
+		permutation:             util.NewPermutation16(committee.NumPeers(), nil),
+		log:                     log.Named("s"),
 	}
 	go ret.initLoadState()
 
@@ -87,7 +108,7 @@ func (sm *stateManager) initLoadState() {
 	var batch state.Batch
 	var stateExists bool
 
-	sm.solidState, batch, stateExists, err = state.LoadSolidState(sm.committee.Address())
+	sm.solidState, batch, stateExists, err = state.LoadSolidStateFromPartition(sm.committee.Address(), sm.env.Partition)
 	if err != nil {
 		sm.log.Error(err)
 		sm.committee.Dismiss()
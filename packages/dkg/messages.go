@@ -0,0 +1,340 @@
+package dkg
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/iotaledger/wasp/packages/util"
+	"github.com/iotaledger/wasp/plugins/peering"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/pairing/bn256"
+	"go.dedis.ch/kyber/v3/share"
+	dkg "go.dedis.ch/kyber/v3/share/dkg/pedersen"
+	vss "go.dedis.ch/kyber/v3/share/vss/pedersen"
+)
+
+const (
+	MsgPubKey        = 0 + peering.FirstDKGMsgCode
+	MsgDeal          = 1 + peering.FirstDKGMsgCode
+	MsgResponse      = 2 + peering.FirstDKGMsgCode
+	MsgJustification = 3 + peering.FirstDKGMsgCode
+	MsgReshareKey    = 4 + peering.FirstDKGMsgCode
+	MsgReshareDeal   = 5 + peering.FirstDKGMsgCode
+)
+
+// public part of the session key of the node. Session keys are used to encrypt deals and sign messages
+type PubKeyMsg struct {
+	PubKey kyber.Point
+}
+
+// deal of the dealer for one node, encrypted with the session key of the node
+type DealMsg struct {
+	Deal *dkg.Deal
+}
+
+// approval or complaint of the node about the deal of the dealer. Broadcast to all nodes
+type ResponseMsg struct {
+	Response *dkg.Response
+}
+
+// dealer's answer to the complaint: the deal is disclosed to all nodes
+type JustificationMsg struct {
+	Justification *dkg.Justification
+}
+
+// session public key of the new node in the resharing. Deals for the node are encrypted with it
+type ReshareKeyMsg struct {
+	PubKey kyber.Point
+}
+
+// share of the old node's private key for the new node, encrypted with the session key of the new node.
+// The old node sends public shares of the old key set and commitments of the resharing polynomial,
+// the new node verifies the share against them
+type ReshareDealMsg struct {
+	OldIndex       uint16
+	OldT           uint16
+	OldPubKeys     []kyber.Point
+	Commits        []kyber.Point
+	EncryptedShare []byte
+}
+
+// keys of the distributed key set are in G2, same as in tcrypto.DKShare
+var suite = bn256.NewSuiteG2()
+
+func encodeMsg(msg interface{ Write(io.Writer) error }) []byte {
+	var buf bytes.Buffer
+	_ = msg.Write(&buf)
+	return buf.Bytes()
+}
+
+func (msg *PubKeyMsg) Write(w io.Writer) error {
+	return writePoint(w, msg.PubKey)
+}
+
+func (msg *PubKeyMsg) Read(r io.Reader) error {
+	var err error
+	msg.PubKey, err = readPoint(r)
+	return err
+}
+
+func (msg *DealMsg) Write(w io.Writer) error {
+	if err := util.WriteUint32(w, msg.Deal.Index); err != nil {
+		return err
+	}
+	if err := util.WriteBytes16(w, msg.Deal.Deal.DHKey); err != nil {
+		return err
+	}
+	if err := util.WriteBytes16(w, msg.Deal.Deal.Signature); err != nil {
+		return err
+	}
+	if err := util.WriteBytes16(w, msg.Deal.Deal.Nonce); err != nil {
+		return err
+	}
+	if err := util.WriteBytes16(w, msg.Deal.Deal.Cipher); err != nil {
+		return err
+	}
+	return util.WriteBytes16(w, msg.Deal.Signature)
+}
+
+func (msg *DealMsg) Read(r io.Reader) error {
+	msg.Deal = &dkg.Deal{Deal: &vss.EncryptedDeal{}}
+	var err error
+	if err = util.ReadUint32(r, &msg.Deal.Index); err != nil {
+		return err
+	}
+	if msg.Deal.Deal.DHKey, err = util.ReadBytes16(r); err != nil {
+		return err
+	}
+	if msg.Deal.Deal.Signature, err = util.ReadBytes16(r); err != nil {
+		return err
+	}
+	if msg.Deal.Deal.Nonce, err = util.ReadBytes16(r); err != nil {
+		return err
+	}
+	if msg.Deal.Deal.Cipher, err = util.ReadBytes16(r); err != nil {
+		return err
+	}
+	msg.Deal.Signature, err = util.ReadBytes16(r)
+	return err
+}
+
+func (msg *ResponseMsg) Write(w io.Writer) error {
+	if err := util.WriteUint32(w, msg.Response.Index); err != nil {
+		return err
+	}
+	if err := util.WriteBytes16(w, msg.Response.Response.SessionID); err != nil {
+		return err
+	}
+	if err := util.WriteUint32(w, msg.Response.Response.Index); err != nil {
+		return err
+	}
+	if err := util.WriteBoolByte(w, msg.Response.Response.Status); err != nil {
+		return err
+	}
+	return util.WriteBytes16(w, msg.Response.Response.Signature)
+}
+
+func (msg *ResponseMsg) Read(r io.Reader) error {
+	msg.Response = &dkg.Response{Response: &vss.Response{}}
+	var err error
+	if err = util.ReadUint32(r, &msg.Response.Index); err != nil {
+		return err
+	}
+	if msg.Response.Response.SessionID, err = util.ReadBytes16(r); err != nil {
+		return err
+	}
+	if err = util.ReadUint32(r, &msg.Response.Response.Index); err != nil {
+		return err
+	}
+	if err = util.ReadBoolByte(r, &msg.Response.Response.Status); err != nil {
+		return err
+	}
+	msg.Response.Response.Signature, err = util.ReadBytes16(r)
+	return err
+}
+
+func (msg *JustificationMsg) Write(w io.Writer) error {
+	j := msg.Justification.Justification
+	if err := util.WriteUint32(w, msg.Justification.Index); err != nil {
+		return err
+	}
+	if err := util.WriteBytes16(w, j.SessionID); err != nil {
+		return err
+	}
+	if err := util.WriteUint32(w, j.Index); err != nil {
+		return err
+	}
+	if err := util.WriteBytes16(w, j.Deal.SessionID); err != nil {
+		return err
+	}
+	if err := util.WriteUint32(w, uint32(j.Deal.SecShare.I)); err != nil {
+		return err
+	}
+	if err := writeScalar(w, j.Deal.SecShare.V); err != nil {
+		return err
+	}
+	if err := util.WriteUint32(w, j.Deal.T); err != nil {
+		return err
+	}
+	if err := util.WriteUint16(w, uint16(len(j.Deal.Commitments))); err != nil {
+		return err
+	}
+	for _, p := range j.Deal.Commitments {
+		if err := writePoint(w, p); err != nil {
+			return err
+		}
+	}
+	return util.WriteBytes16(w, j.Signature)
+}
+
+func (msg *JustificationMsg) Read(r io.Reader) error {
+	j := &vss.Justification{Deal: &vss.Deal{SecShare: &share.PriShare{}}}
+	msg.Justification = &dkg.Justification{Justification: j}
+	var err error
+	if err = util.ReadUint32(r, &msg.Justification.Index); err != nil {
+		return err
+	}
+	if j.SessionID, err = util.ReadBytes16(r); err != nil {
+		return err
+	}
+	if err = util.ReadUint32(r, &j.Index); err != nil {
+		return err
+	}
+	if j.Deal.SessionID, err = util.ReadBytes16(r); err != nil {
+		return err
+	}
+	var idx uint32
+	if err = util.ReadUint32(r, &idx); err != nil {
+		return err
+	}
+	j.Deal.SecShare.I = int(idx)
+	if j.Deal.SecShare.V, err = readScalar(r); err != nil {
+		return err
+	}
+	if err = util.ReadUint32(r, &j.Deal.T); err != nil {
+		return err
+	}
+	var size uint16
+	if err = util.ReadUint16(r, &size); err != nil {
+		return err
+	}
+	j.Deal.Commitments = make([]kyber.Point, size)
+	for i := range j.Deal.Commitments {
+		if j.Deal.Commitments[i], err = readPoint(r); err != nil {
+			return err
+		}
+	}
+	j.Signature, err = util.ReadBytes16(r)
+	return err
+}
+
+func (msg *ReshareKeyMsg) Write(w io.Writer) error {
+	return writePoint(w, msg.PubKey)
+}
+
+func (msg *ReshareKeyMsg) Read(r io.Reader) error {
+	var err error
+	msg.PubKey, err = readPoint(r)
+	return err
+}
+
+func (msg *ReshareDealMsg) Write(w io.Writer) error {
+	if err := util.WriteUint16(w, msg.OldIndex); err != nil {
+		return err
+	}
+	if err := util.WriteUint16(w, msg.OldT); err != nil {
+		return err
+	}
+	if err := writePoints(w, msg.OldPubKeys); err != nil {
+		return err
+	}
+	if err := writePoints(w, msg.Commits); err != nil {
+		return err
+	}
+	return util.WriteBytes16(w, msg.EncryptedShare)
+}
+
+func (msg *ReshareDealMsg) Read(r io.Reader) error {
+	var err error
+	if err = util.ReadUint16(r, &msg.OldIndex); err != nil {
+		return err
+	}
+	if err = util.ReadUint16(r, &msg.OldT); err != nil {
+		return err
+	}
+	if msg.OldPubKeys, err = readPoints(r); err != nil {
+		return err
+	}
+	if msg.Commits, err = readPoints(r); err != nil {
+		return err
+	}
+	msg.EncryptedShare, err = util.ReadBytes16(r)
+	return err
+}
+
+func writePoints(w io.Writer, points []kyber.Point) error {
+	if err := util.WriteUint16(w, uint16(len(points))); err != nil {
+		return err
+	}
+	for _, p := range points {
+		if err := writePoint(w, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readPoints(r io.Reader) ([]kyber.Point, error) {
+	var size uint16
+	if err := util.ReadUint16(r, &size); err != nil {
+		return nil, err
+	}
+	ret := make([]kyber.Point, size)
+	for i := range ret {
+		var err error
+		if ret[i], err = readPoint(r); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+func writePoint(w io.Writer, p kyber.Point) error {
+	data, err := p.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return util.WriteBytes16(w, data)
+}
+
+func readPoint(r io.Reader) (kyber.Point, error) {
+	data, err := util.ReadBytes16(r)
+	if err != nil {
+		return nil, err
+	}
+	ret := suite.Point()
+	if err = ret.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func writeScalar(w io.Writer, s kyber.Scalar) error {
+	data, err := s.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return util.WriteBytes16(w, data)
+}
+
+func readScalar(r io.Reader) (kyber.Scalar, error) {
+	data, err := util.ReadBytes16(r)
+	if err != nil {
+		return nil, err
+	}
+	ret := suite.Scalar()
+	if err = ret.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
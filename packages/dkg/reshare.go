@@ -0,0 +1,326 @@
+package dkg
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	"github.com/iotaledger/hive.go/logger"
+	"github.com/iotaledger/wasp/packages/tcrypto"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/encrypt/ecies"
+)
+
+// ReshareSession reshares the existing key set from old nodes (dealers) to the new committee
+// without changing the address (see tcrypto.ReshareDKS).
+//   - each new node generates a session key pair and sends the public key to all dealers
+//   - each dealer shares own private key with the random polynomial and sends to each new node its value,
+//     encrypted with the session key of the node, along with commitments of the polynomial and public shares
+//     of the old key set
+//   - each new node verifies deals and interpolates them into the private key. Public shares of the new
+//     key set are calculated from commitments
+//
+// Private share material is exchanged only between old and new nodes.
+// Participants are indexed in one list: dealers first, then new nodes in the order of the new key set.
+// A node can be both the dealer and the new node, then it has two indices
+type ReshareSession struct {
+	mutex      sync.Mutex
+	numDealers uint16
+	t          uint16
+	n          uint16
+	// own index among dealers or -1
+	dealer int
+	// own index in the new committee or -1
+	receiver int
+	oldKS    *tcrypto.DKShare
+	addr     address.Address
+	send     SendFunc
+	log      *logger.Logger
+
+	// dealer
+	receiverKeys    []kyber.Point
+	numReceiverKeys uint16
+	dealt           bool
+
+	// new node
+	longterm kyber.Scalar
+	deals    []*ReshareDealMsg
+	numDeals uint16
+
+	result *tcrypto.DKShare
+	err    error
+	done   chan struct{}
+}
+
+// NewReshareSession creates the resharing session of numDealers old nodes and the new committee of n nodes
+// with quorum t. oldKS is the key share of the node in the old key set, nil if the node is not a dealer.
+// receiver is the index of the node in the new committee or -1
+func NewReshareSession(numDealers, t, n uint16, oldKS *tcrypto.DKShare, dealer, receiver int, addr *address.Address, send SendFunc, log *logger.Logger) (*ReshareSession, error) {
+	if err := tcrypto.ValidateDKSParams(t, n, 0); err != nil {
+		return nil, err
+	}
+	if numDealers == 0 {
+		return nil, errors.New("no old nodes")
+	}
+	if (dealer >= 0) != (oldKS != nil) || dealer >= int(numDealers) || receiver >= int(n) || (dealer < 0 && receiver < 0) {
+		return nil, errors.New("wrong role of the node in the resharing")
+	}
+	if oldKS != nil && *oldKS.Address != *addr {
+		return nil, errors.New("key share of the node is of another key set")
+	}
+	return &ReshareSession{
+		numDealers:   numDealers,
+		t:            t,
+		n:            n,
+		dealer:       dealer,
+		receiver:     receiver,
+		oldKS:        oldKS,
+		addr:         *addr,
+		send:         send,
+		log:          log,
+		receiverKeys: make([]kyber.Point, n),
+		deals:        make([]*ReshareDealMsg, numDealers),
+		done:         make(chan struct{}),
+	}, nil
+}
+
+// Start generates the session key of the new node and sends the public key to dealers
+func (s *ReshareSession) Start() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.receiver < 0 || s.longterm != nil {
+		return
+	}
+	s.longterm = suite.Scalar().Pick(suite.RandomStream())
+	data := encodeMsg(&ReshareKeyMsg{PubKey: suite.Point().Mul(s.longterm, nil)})
+	for i := uint16(0); i < s.numDealers; i++ {
+		s.sendTo(i, MsgReshareKey, data)
+	}
+}
+
+// Done is closed when the session is finished
+func (s *ReshareSession) Done() <-chan struct{} {
+	return s.done
+}
+
+// Result returns the new key share of the node or the error when the session is finished.
+// The key share is nil if the node is only the dealer
+func (s *ReshareSession) Result() (*tcrypto.DKShare, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.isDone() {
+		return nil, errors.New("resharing is in progress")
+	}
+	return s.result, s.err
+}
+
+// Timeout finishes the session. Deals of all dealers are needed, so the session fails if it is not done yet
+func (s *ReshareSession) Timeout() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.finish(nil, fmt.Errorf("timeout: received %d session keys out of %d, %d deals out of %d",
+		s.numReceiverKeys, s.n, s.numDeals, s.numDealers))
+}
+
+// ReceiveMessage processes the message of the participant
+func (s *ReshareSession) ReceiveMessage(senderIndex uint16, msgType byte, data []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.receiveMessage(senderIndex, msgType, data)
+}
+
+func (s *ReshareSession) receiveMessage(senderIndex uint16, msgType byte, data []byte) {
+	if s.isDone() {
+		return
+	}
+	rdr := bytes.NewReader(data)
+	switch msgType {
+	case MsgReshareKey:
+		if s.dealer < 0 || senderIndex < s.numDealers || senderIndex >= s.numDealers+s.n {
+			s.log.Warnf("unexpected session key from %d", senderIndex)
+			return
+		}
+		msg := &ReshareKeyMsg{}
+		if err := msg.Read(rdr); err != nil {
+			s.log.Errorf("wrong session key message from %d: %v", senderIndex, err)
+			return
+		}
+		s.receiveKey(senderIndex-s.numDealers, msg.PubKey)
+
+	case MsgReshareDeal:
+		if s.receiver < 0 || senderIndex >= s.numDealers {
+			s.log.Warnf("unexpected deal from %d", senderIndex)
+			return
+		}
+		msg := &ReshareDealMsg{}
+		if err := msg.Read(rdr); err != nil {
+			s.log.Errorf("wrong deal message from %d: %v", senderIndex, err)
+			return
+		}
+		s.receiveDeal(senderIndex, msg)
+
+	default:
+		s.log.Warnf("unexpected message type %d from %d", msgType, senderIndex)
+	}
+}
+
+func (s *ReshareSession) receiveKey(newIndex uint16, pubKey kyber.Point) {
+	if s.receiverKeys[newIndex] != nil {
+		if !s.receiverKeys[newIndex].Equal(pubKey) {
+			s.log.Warnf("new node %d changed session key", newIndex)
+		}
+		return
+	}
+	s.receiverKeys[newIndex] = pubKey
+	s.numReceiverKeys++
+	if s.numReceiverKeys == s.n {
+		s.deal()
+	}
+}
+
+// deal sends shares of the own private key to new nodes
+func (s *ReshareSession) deal() {
+	priShares, commits, err := s.oldKS.ReshareDKS(s.t, s.n, nil)
+	if err != nil {
+		s.finish(nil, err)
+		return
+	}
+	for j, pubKey := range s.receiverKeys {
+		shareBin, err := priShares[j].MarshalBinary()
+		if err != nil {
+			s.finish(nil, err)
+			return
+		}
+		encrypted, err := ecies.Encrypt(suite, pubKey, shareBin, sha256.New)
+		if err != nil {
+			s.finish(nil, err)
+			return
+		}
+		s.sendTo(s.numDealers+uint16(j), MsgReshareDeal, encodeMsg(&ReshareDealMsg{
+			OldIndex:       s.oldKS.Index,
+			OldT:           s.oldKS.T,
+			OldPubKeys:     s.oldKS.PubKeys,
+			Commits:        commits,
+			EncryptedShare: encrypted,
+		}))
+	}
+	s.log.Debugf("deals sent to %d new nodes", s.n)
+	s.dealt = true
+	s.checkDone()
+}
+
+func (s *ReshareSession) receiveDeal(dealer uint16, msg *ReshareDealMsg) {
+	if s.deals[dealer] != nil {
+		return
+	}
+	if s.longterm == nil {
+		s.log.Warnf("deal from %d before the session key was sent", dealer)
+		return
+	}
+	s.deals[dealer] = msg
+	s.numDeals++
+	if s.numDeals == s.numDealers {
+		s.aggregate()
+	}
+}
+
+// aggregate calculates the new key share from deals of all dealers. All dealers must be of the same
+// old key set. The new key share is verified against the old key set
+func (s *ReshareSession) aggregate() {
+	ks, err := tcrypto.NewReshareDKShare(s.t, s.n, uint16(s.receiver), &s.addr)
+	if err != nil {
+		s.finish(nil, err)
+		return
+	}
+	first := s.deals[0]
+	oldIndices := make([]uint16, s.numDealers)
+	priShares := make([]kyber.Scalar, s.numDealers)
+	commits := make([][]kyber.Point, s.numDealers)
+	for i, deal := range s.deals {
+		if deal.OldT != first.OldT || !equalPoints(deal.OldPubKeys, first.OldPubKeys) {
+			s.finish(nil, fmt.Errorf("dealer %d sent another old key set", i))
+			return
+		}
+		shareBin, err := ecies.Decrypt(suite, s.longterm, deal.EncryptedShare, sha256.New)
+		if err != nil {
+			s.finish(nil, fmt.Errorf("can't decrypt the deal of dealer %d: %v", i, err))
+			return
+		}
+		priShares[i] = suite.Scalar()
+		if err := priShares[i].UnmarshalBinary(shareBin); err != nil {
+			s.finish(nil, fmt.Errorf("wrong deal of dealer %d: %v", i, err))
+			return
+		}
+		oldIndices[i] = deal.OldIndex
+		commits[i] = deal.Commits
+	}
+	if err := ks.AggregateReshare(first.OldPubKeys, first.OldT, oldIndices, priShares, commits); err != nil {
+		s.finish(nil, err)
+		return
+	}
+	s.result = ks
+	s.checkDone()
+}
+
+func (s *ReshareSession) checkDone() {
+	if (s.dealer < 0 || s.dealt) && (s.receiver < 0 || s.result != nil) {
+		s.finish(s.result, nil)
+	}
+}
+
+func (s *ReshareSession) finish(ks *tcrypto.DKShare, err error) {
+	if s.isDone() {
+		return
+	}
+	s.result = ks
+	s.err = err
+	close(s.done)
+}
+
+func (s *ReshareSession) isDone() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// sendTo sends the message to the participant. Messages to own roles are processed in place
+func (s *ReshareSession) sendTo(index uint16, msgType byte, data []byte) {
+	if int(index) == s.dealer {
+		s.receiveMessage(s.receiverIndex(), msgType, data)
+		return
+	}
+	if s.receiver >= 0 && index == s.receiverIndex() {
+		s.receiveMessage(uint16(s.dealer), msgType, data)
+		return
+	}
+	if err := s.send(index, msgType, data); err != nil {
+		s.log.Warnf("failed to send message to %d: %v", index, err)
+	}
+}
+
+// receiverIndex is the participant index of the node as the new node
+func (s *ReshareSession) receiverIndex() uint16 {
+	return s.numDealers + uint16(s.receiver)
+}
+
+func equalPoints(p1, p2 []kyber.Point) bool {
+	if len(p1) != len(p2) {
+		return false
+	}
+	for i := range p1 {
+		if !p1[i].Equal(p2[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,152 @@
+package dkg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/iotaledger/hive.go/logger"
+	"github.com/iotaledger/wasp/packages/tcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// node of the resharing: dealer is the index among old nodes, receiver is the index in the new committee
+type reshareNode struct {
+	oldKS    *tcrypto.DKShare
+	dealer   int
+	receiver int
+}
+
+// runs resharing sessions of nodes connected with in-memory queues
+func runReshareSessions(t *testing.T, numDealers, threshold, n uint16, nodes []reshareNode) []*ReshareSession {
+	log := logger.NewExampleLogger("reshare")
+	addr := nodes[0].oldKS.Address
+	// participant index -> node index
+	byParticipant := make(map[uint16]int)
+	for i, node := range nodes {
+		if node.dealer >= 0 {
+			byParticipant[uint16(node.dealer)] = i
+		}
+		if node.receiver >= 0 {
+			byParticipant[numDealers+uint16(node.receiver)] = i
+		}
+	}
+	inboxes := make([]chan testMsg, len(nodes))
+	for i := range inboxes {
+		inboxes[i] = make(chan testMsg, 1000)
+	}
+	sessions := make([]*ReshareSession, len(nodes))
+	for i, node := range nodes {
+		node := node
+		send := func(peerIndex uint16, msgType byte, data []byte) error {
+			sender := uint16(node.dealer)
+			if msgType == MsgReshareKey {
+				sender = numDealers + uint16(node.receiver)
+			}
+			inboxes[byParticipant[peerIndex]] <- testMsg{sender: sender, msgType: msgType, data: data}
+			return nil
+		}
+		var err error
+		sessions[i], err = NewReshareSession(numDealers, threshold, n, node.oldKS, node.dealer, node.receiver, addr, send, log)
+		require.NoError(t, err)
+	}
+	for i, s := range sessions {
+		go func(s *ReshareSession, inbox chan testMsg) {
+			for {
+				select {
+				case msg := <-inbox:
+					s.ReceiveMessage(msg.sender, msg.msgType, msg.data)
+				case <-s.Done():
+					return
+				}
+			}
+		}(s, inboxes[i])
+	}
+	for _, s := range sessions {
+		go s.Start()
+	}
+	for _, s := range sessions {
+		select {
+		case <-s.Done():
+		case <-time.After(10 * time.Second):
+			s.Timeout()
+		}
+	}
+	return sessions
+}
+
+func TestReshareSession(t *testing.T) {
+	old, err := tcrypto.NewDKSharesInProcess(3, 4, nil)
+	require.NoError(t, err)
+
+	// old nodes 0, 2, 3 reshare to the committee of 5. Old node 2 is the new node 0
+	nodes := []reshareNode{
+		{oldKS: old[0], dealer: 0, receiver: -1},
+		{oldKS: old[2], dealer: 1, receiver: 0},
+		{oldKS: old[3], dealer: 2, receiver: -1},
+		{dealer: -1, receiver: 1},
+		{dealer: -1, receiver: 2},
+		{dealer: -1, receiver: 3},
+		{dealer: -1, receiver: 4},
+	}
+	sessions := runReshareSessions(t, 3, 4, 5, nodes)
+
+	reshared := make([]*tcrypto.DKShare, 0, 5)
+	for i, s := range sessions {
+		ks, err := s.Result()
+		require.NoError(t, err)
+		if nodes[i].receiver < 0 {
+			assert.Nil(t, ks)
+			continue
+		}
+		require.NotNil(t, ks)
+		assert.EqualValues(t, nodes[i].receiver, ks.Index)
+		assert.Equal(t, *old[0].Address, *ks.Address)
+		reshared = append(reshared, ks)
+	}
+	require.Len(t, reshared, 5)
+
+	// the new quorum signs for the same address
+	data := []byte("data to sign")
+	sigShares := make([][]byte, 0, 4)
+	for _, ks := range reshared[:4] {
+		sigShare, err := ks.SignShare(data)
+		require.NoError(t, err)
+		require.NoError(t, reshared[4].VerifySigShare(data, sigShare))
+		sigShares = append(sigShares, sigShare)
+	}
+	sig, err := reshared[4].RecoverFullSignature(sigShares, data)
+	require.NoError(t, err)
+	assert.True(t, sig.IsValid(data))
+	assert.Equal(t, *old[0].Address, sig.Address())
+}
+
+func TestReshareSessionWrongDealer(t *testing.T) {
+	old, err := tcrypto.NewDKSharesInProcess(3, 4, nil)
+	require.NoError(t, err)
+	other, err := tcrypto.NewDKSharesInProcess(3, 4, nil)
+	require.NoError(t, err)
+	// the dealer deals the private key of another key set with the address of the old one
+	wrong := *other[1]
+	wrong.Address = old[0].Address
+	wrong.Index = 1
+	wrong.PubKeys = old[0].PubKeys
+
+	nodes := []reshareNode{
+		{oldKS: old[0], dealer: 0, receiver: -1},
+		{oldKS: &wrong, dealer: 1, receiver: -1},
+		{oldKS: old[2], dealer: 2, receiver: -1},
+		{dealer: -1, receiver: 0},
+		{dealer: -1, receiver: 1},
+	}
+	sessions := runReshareSessions(t, 3, 2, 2, nodes)
+	for i, s := range sessions {
+		ks, err := s.Result()
+		if nodes[i].receiver < 0 {
+			assert.NoError(t, err)
+			continue
+		}
+		assert.Error(t, err)
+		assert.Nil(t, ks)
+	}
+}
@@ -0,0 +1,317 @@
+// package dkg implements peer-to-peer distributed key generation for committees
+package dkg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/iotaledger/hive.go/logger"
+	"github.com/iotaledger/wasp/packages/tcrypto"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/share"
+	dkg "go.dedis.ch/kyber/v3/share/dkg/pedersen"
+	vss "go.dedis.ch/kyber/v3/share/vss/pedersen"
+)
+
+// Session runs Pedersen distributed key generation (Joint-Feldman DKG with complaints) between n nodes.
+//   - each node generates a session key pair and broadcasts the public key
+//   - each node deals shares of own random secret to all other nodes. Each share is encrypted with the session key
+//     of the receiver and is verifiable against public commitments of the dealer
+//   - each node broadcasts approval or complaint about each deal it received
+//   - the dealer answers complaints by disclosing the deal (justification). Dealers with unjustified complaints
+//     are excluded
+//
+// The master secret is the sum of secrets of qualified dealers and is not known to anyone.
+// Private share material never leaves the node unencrypted. The session doesn't depend on the transport:
+// messages are sent with the SendFunc and passed to ReceiveMessage by the caller.
+// Session public keys are not signed: the caller must pass to ReceiveMessage only messages authenticated
+// as sent by the peer with the sender index. The DKG node of the dkg plugin signs messages with node identity keys
+type Session struct {
+	mutex sync.Mutex
+	t     uint16
+	n     uint16
+	index uint16
+	send  SendFunc
+	log   *logger.Logger
+
+	longterm   kyber.Scalar
+	pubKeys    []kyber.Point
+	numPubKeys uint16
+	gen        *dkg.DistKeyGenerator
+	// messages which can't be processed yet
+	pendingDeals          []*dkg.Deal
+	pendingResponses      []*dkg.Response
+	pendingJustifications []*dkg.Justification
+
+	result *tcrypto.DKShare
+	err    error
+	done   chan struct{}
+}
+
+// the error of the justification which comes before the complaint. Such justification is processed later
+const errNoComplaintYet = "vss: no complaints received for this justification"
+
+// SendFunc sends the message to the peer with the index
+type SendFunc func(peerIndex uint16, msgType byte, data []byte) error
+
+func NewSession(t, n, index uint16, send SendFunc, log *logger.Logger) (*Session, error) {
+	if err := tcrypto.ValidateDKSParams(t, n, index); err != nil {
+		return nil, err
+	}
+	if n < 2 {
+		return nil, errors.New("at least 2 nodes are needed for the distributed key generation")
+	}
+	return &Session{
+		t:       t,
+		n:       n,
+		index:   index,
+		send:    send,
+		log:     log,
+		pubKeys: make([]kyber.Point, n),
+		done:    make(chan struct{}),
+	}, nil
+}
+
+// Start generates the session key and sends the public key to peers
+func (s *Session) Start() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.longterm != nil {
+		return
+	}
+	s.longterm = suite.Scalar().Pick(suite.RandomStream())
+	pubKey := suite.Point().Mul(s.longterm, nil)
+	s.broadcast(MsgPubKey, encodeMsg(&PubKeyMsg{PubKey: pubKey}))
+	s.receivePubKey(s.index, pubKey)
+}
+
+// Done is closed when the session is finished
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+// Result returns committed key share of the node or the error when the session is finished
+func (s *Session) Result() (*tcrypto.DKShare, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.isDone() {
+		return nil, errors.New("distributed key generation is in progress")
+	}
+	return s.result, s.err
+}
+
+// Timeout ends waiting for missing responses. Deals without enough approvals are excluded,
+// the key set is generated if at least t deals are qualified
+func (s *Session) Timeout() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.isDone() {
+		return
+	}
+	if s.gen == nil {
+		s.finish(nil, fmt.Errorf("timeout: received %d public keys out of %d", s.numPubKeys, s.n))
+		return
+	}
+	s.gen.SetTimeout()
+	if !s.gen.ThresholdCertified() {
+		s.finish(nil, fmt.Errorf("timeout: only %d deals qualified, %d needed", len(s.gen.QUAL()), s.t))
+		return
+	}
+	s.finalize()
+}
+
+// ReceiveMessage processes the message of the peer
+func (s *Session) ReceiveMessage(senderIndex uint16, msgType byte, data []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.isDone() {
+		return
+	}
+	if senderIndex >= s.n || senderIndex == s.index {
+		s.log.Warnf("wrong sender index %d", senderIndex)
+		return
+	}
+	rdr := bytes.NewReader(data)
+	switch msgType {
+	case MsgPubKey:
+		msg := &PubKeyMsg{}
+		if err := msg.Read(rdr); err != nil {
+			s.log.Errorf("wrong public key message from %d: %v", senderIndex, err)
+			return
+		}
+		s.receivePubKey(senderIndex, msg.PubKey)
+
+	case MsgDeal:
+		msg := &DealMsg{}
+		if err := msg.Read(rdr); err != nil || msg.Deal.Index != uint32(senderIndex) {
+			s.log.Errorf("wrong deal message from %d: %v", senderIndex, err)
+			return
+		}
+		s.pendingDeals = append(s.pendingDeals, msg.Deal)
+
+	case MsgResponse:
+		msg := &ResponseMsg{}
+		if err := msg.Read(rdr); err != nil || msg.Response.Response.Index != uint32(senderIndex) {
+			s.log.Errorf("wrong response message from %d: %v", senderIndex, err)
+			return
+		}
+		s.pendingResponses = append(s.pendingResponses, msg.Response)
+
+	case MsgJustification:
+		msg := &JustificationMsg{}
+		if err := msg.Read(rdr); err != nil || msg.Justification.Index != uint32(senderIndex) {
+			s.log.Errorf("wrong justification message from %d: %v", senderIndex, err)
+			return
+		}
+		s.pendingJustifications = append(s.pendingJustifications, msg.Justification)
+
+	default:
+		s.log.Warnf("unexpected message type %d from %d", msgType, senderIndex)
+		return
+	}
+	s.processPending()
+}
+
+func (s *Session) receivePubKey(senderIndex uint16, pubKey kyber.Point) {
+	if s.pubKeys[senderIndex] != nil {
+		if !s.pubKeys[senderIndex].Equal(pubKey) {
+			s.log.Warnf("peer %d changed public key", senderIndex)
+		}
+		return
+	}
+	s.pubKeys[senderIndex] = pubKey
+	s.numPubKeys++
+	s.startDealing()
+	s.processPending()
+}
+
+// when all public keys are known, deals are sent to peers
+func (s *Session) startDealing() {
+	if s.gen != nil || s.longterm == nil || s.numPubKeys < s.n {
+		return
+	}
+	var err error
+	s.gen, err = dkg.NewDistKeyGenerator(suite, s.longterm, s.pubKeys, int(s.t))
+	if err != nil {
+		s.finish(nil, err)
+		return
+	}
+	deals, err := s.gen.Deals()
+	if err != nil {
+		s.finish(nil, err)
+		return
+	}
+	for i, deal := range deals {
+		if err := s.send(uint16(i), MsgDeal, encodeMsg(&DealMsg{Deal: deal})); err != nil {
+			s.log.Warnf("failed to send deal to %d: %v", i, err)
+		}
+	}
+	s.log.Debugf("deals sent to %d peers", len(deals))
+}
+
+// processPending processes deals, responses and justifications in the order of dependencies.
+// Responses which come before the deal and justifications which come before the complaint are kept
+func (s *Session) processPending() {
+	if s.gen == nil || s.isDone() {
+		return
+	}
+	for _, deal := range s.pendingDeals {
+		resp, err := s.gen.ProcessDeal(deal)
+		if err != nil {
+			s.log.Warnf("deal of %d rejected: %v", deal.Index, err)
+			continue
+		}
+		if resp.Response.Status != vss.StatusApproval {
+			s.log.Warnf("complaint about the deal of %d", deal.Index)
+		}
+		s.broadcast(MsgResponse, encodeMsg(&ResponseMsg{Response: resp}))
+	}
+	s.pendingDeals = nil
+
+	pendingResponses := s.pendingResponses
+	s.pendingResponses = nil
+	for _, resp := range pendingResponses {
+		j, err := s.gen.ProcessResponse(resp)
+		if err == vss.ErrNoDealBeforeResponse {
+			s.pendingResponses = append(s.pendingResponses, resp)
+			continue
+		}
+		if err != nil {
+			s.log.Warnf("response of %d about the deal of %d rejected: %v", resp.Response.Index, resp.Index, err)
+			continue
+		}
+		if j != nil {
+			s.log.Infof("complaint of %d about own deal: justification sent", resp.Response.Index)
+			s.broadcast(MsgJustification, encodeMsg(&JustificationMsg{Justification: j}))
+		}
+	}
+
+	pendingJustifications := s.pendingJustifications
+	s.pendingJustifications = nil
+	for _, j := range pendingJustifications {
+		err := s.gen.ProcessJustification(j)
+		if err == nil {
+			continue
+		}
+		if err.Error() == errNoComplaintYet {
+			s.pendingJustifications = append(s.pendingJustifications, j)
+			continue
+		}
+		s.log.Warnf("justification of %d rejected: %v", j.Index, err)
+	}
+
+	if s.gen.Certified() {
+		s.finalize()
+	}
+}
+
+func (s *Session) finalize() {
+	dks, err := s.gen.DistKeyShare()
+	if err != nil {
+		s.finish(nil, err)
+		return
+	}
+	pubPoly := share.NewPubPoly(suite, suite.Point().Base(), dks.Commits)
+	ks, err := tcrypto.NewDKShareFromPriShare(s.t, s.n, dks.Share, pubPoly)
+	if err != nil {
+		s.finish(nil, err)
+		return
+	}
+	s.log.Debugf("distributed key generated. Qualified dealers: %+v", s.gen.QUAL())
+	s.finish(ks, nil)
+}
+
+func (s *Session) finish(ks *tcrypto.DKShare, err error) {
+	if s.isDone() {
+		return
+	}
+	s.result = ks
+	s.err = err
+	close(s.done)
+}
+
+func (s *Session) isDone() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *Session) broadcast(msgType byte, data []byte) {
+	for i := uint16(0); i < s.n; i++ {
+		if i == s.index {
+			continue
+		}
+		if err := s.send(i, msgType, data); err != nil {
+			s.log.Warnf("failed to send message to %d: %v", i, err)
+		}
+	}
+}
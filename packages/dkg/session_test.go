@@ -0,0 +1,156 @@
+package dkg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/iotaledger/hive.go/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/kyber/v3"
+	dkg "go.dedis.ch/kyber/v3/share/dkg/pedersen"
+	vss "go.dedis.ch/kyber/v3/share/vss/pedersen"
+)
+
+type testMsg struct {
+	sender  uint16
+	msgType byte
+	data    []byte
+}
+
+// runs sessions connected with in-memory queues. Messages of muted nodes, except public keys, are dropped
+func runSessions(t *testing.T, threshold, n uint16, muted map[uint16]bool) []*Session {
+	log := logger.NewExampleLogger("dkg")
+	inboxes := make([]chan testMsg, n)
+	for i := range inboxes {
+		inboxes[i] = make(chan testMsg, 1000)
+	}
+	sessions := make([]*Session, n)
+	for i := range sessions {
+		sender := uint16(i)
+		send := func(peerIndex uint16, msgType byte, data []byte) error {
+			if muted[sender] && msgType != MsgPubKey {
+				return nil
+			}
+			inboxes[peerIndex] <- testMsg{sender: sender, msgType: msgType, data: data}
+			return nil
+		}
+		var err error
+		sessions[i], err = NewSession(threshold, n, sender, send, log)
+		require.NoError(t, err)
+	}
+	for i, s := range sessions {
+		go func(s *Session, inbox chan testMsg) {
+			for {
+				select {
+				case msg := <-inbox:
+					s.ReceiveMessage(msg.sender, msg.msgType, msg.data)
+				case <-s.Done():
+					return
+				}
+			}
+		}(s, inboxes[i])
+	}
+	for _, s := range sessions {
+		go s.Start()
+	}
+	return sessions
+}
+
+func waitDone(t *testing.T, s *Session, timeout time.Duration) bool {
+	select {
+	case <-s.Done():
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func TestSession(t *testing.T) {
+	sessions := runSessions(t, 3, 4, nil)
+	for _, s := range sessions {
+		require.True(t, waitDone(t, s, 10*time.Second))
+	}
+	ks0, err := sessions[0].Result()
+	require.NoError(t, err)
+	for i, s := range sessions {
+		ks, err := s.Result()
+		require.NoError(t, err)
+		assert.EqualValues(t, i, ks.Index)
+		assert.True(t, ks.Committed)
+		assert.Equal(t, *ks0.Address, *ks.Address)
+	}
+
+	// any quorum produces valid signature
+	data := []byte("data to sign")
+	sigShares := make([][]byte, 0, 3)
+	for _, s := range sessions[1:] {
+		ks, _ := s.Result()
+		sigShare, err := ks.SignShare(data)
+		require.NoError(t, err)
+		require.NoError(t, ks0.VerifySigShare(data, sigShare))
+		sigShares = append(sigShares, sigShare)
+	}
+	sig, err := ks0.RecoverFullSignature(sigShares, data)
+	require.NoError(t, err)
+	assert.True(t, sig.IsValid(data))
+}
+
+func TestSessionTimeout(t *testing.T) {
+	// node 3 sends public key but doesn't deal and doesn't respond
+	sessions := runSessions(t, 3, 4, map[uint16]bool{3: true})
+	for _, s := range sessions[:3] {
+		assert.False(t, waitDone(t, s, 300*time.Millisecond))
+	}
+	for _, s := range sessions {
+		s.Timeout()
+	}
+	ks0, err := sessions[0].Result()
+	require.NoError(t, err)
+	// deal of the muted node is excluded, but it still receives deals of others and holds the share
+	for _, s := range sessions[1:] {
+		ks, err := s.Result()
+		require.NoError(t, err)
+		assert.Equal(t, *ks0.Address, *ks.Address)
+	}
+}
+
+func TestSessionNoPeers(t *testing.T) {
+	s, err := NewSession(3, 4, 0, func(uint16, byte, []byte) error { return nil }, logger.NewExampleLogger("dkg"))
+	require.NoError(t, err)
+	s.Start()
+	s.Timeout()
+	_, err = s.Result()
+	assert.Error(t, err)
+
+	_, err = NewSession(3, 4, 4, nil, nil)
+	assert.Error(t, err)
+}
+
+// the justification which comes before the complaint is kept, others with errors are dropped.
+// The error is recognized by the text of the kyber error
+func TestJustificationBeforeComplaint(t *testing.T) {
+	n := 3
+	longterms := make([]kyber.Scalar, n)
+	pubKeys := make([]kyber.Point, n)
+	for i := range longterms {
+		longterms[i] = suite.Scalar().Pick(suite.RandomStream())
+		pubKeys[i] = suite.Point().Mul(longterms[i], nil)
+	}
+	gen0, err := dkg.NewDistKeyGenerator(suite, longterms[0], pubKeys, 2)
+	require.NoError(t, err)
+	gen1, err := dkg.NewDistKeyGenerator(suite, longterms[1], pubKeys, 2)
+	require.NoError(t, err)
+	deals, err := gen0.Deals()
+	require.NoError(t, err)
+	_, err = gen1.ProcessDeal(deals[1])
+	require.NoError(t, err)
+
+	// justification of the complaint of node 2 about the deal of node 0, which node 1 hasn't received
+	err = gen1.ProcessJustification(&dkg.Justification{
+		Index:         0,
+		Justification: &vss.Justification{Index: 2},
+	})
+	require.Error(t, err)
+	assert.Equal(t, errNoComplaintYet, err.Error())
+}
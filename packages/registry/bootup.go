@@ -21,6 +21,12 @@ type BootupData struct {
 	Color          balance.Color   // origin tx hash
 	CommitteeNodes []string        // "host_addr:port"
 	AccessNodes    []string        // "host_addr:port"
+	// address of the partition with the state of the smart contract. Nil if the state is stored
+	// under Address, i.e. the smart contract wasn't rotated to the committee from another one
+	OriginAddress address.Address
+	// address of the committee the smart contract was rotated to. The committee of the record
+	// isn't activated anymore. Nil if the smart contract wasn't rotated away
+	RotatedTo address.Address
 }
 
 func dbkeyBootupData(addr *address.Address) []byte {
@@ -70,6 +76,18 @@ func GetBootupData(addr *address.Address) (*BootupData, bool, error) {
 	return ret, true, nil
 }
 
+// GetStateAddress returns the address of the partition with the state of the smart contract
+func GetStateAddress(addr *address.Address) (*address.Address, error) {
+	bd, exists, err := GetBootupData(addr)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return addr, nil
+	}
+	return bd.StateAddress(), nil
+}
+
 func GetBootupRecords() ([]*BootupData, error) {
 	db := database.GetRegistryPartition()
 	ret := make([]*BootupData, 0)
@@ -86,6 +104,34 @@ func GetBootupRecords() ([]*BootupData, error) {
 	return ret, err
 }
 
+// StateAddress is the address of the partition with the state of the smart contract
+func (bd *BootupData) StateAddress() *address.Address {
+	var niladdr address.Address
+	if bd.OriginAddress == niladdr {
+		return &bd.Address
+	}
+	return &bd.OriginAddress
+}
+
+// IsRotated returns true if the smart contract was rotated to another committee
+func (bd *BootupData) IsRotated() bool {
+	var niladdr address.Address
+	return bd.RotatedTo != niladdr
+}
+
+// MarkRotated records in the bootup record that the smart contract was rotated to the committee with the new address
+func MarkRotated(addr, newAddr *address.Address) error {
+	bd, exists, err := GetBootupData(addr)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("bootup record for %s does not exist", addr.String())
+	}
+	bd.RotatedTo = *newAddr
+	return SaveBootupData(bd, false)
+}
+
 func (bd *BootupData) Write(w io.Writer) error {
 	if _, err := w.Write(bd.Address[:]); err != nil {
 		return err
@@ -102,6 +148,12 @@ func (bd *BootupData) Write(w io.Writer) error {
 	if err := util.WriteStrings16(w, bd.AccessNodes); err != nil {
 		return err
 	}
+	if _, err := w.Write(bd.OriginAddress[:]); err != nil {
+		return err
+	}
+	if _, err := w.Write(bd.RotatedTo[:]); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -122,5 +174,11 @@ func (bd *BootupData) Read(r io.Reader) error {
 	if bd.AccessNodes, err = util.ReadStrings16(r); err != nil {
 		return err
 	}
+	if err = util.ReadAddress(r, &bd.OriginAddress); err != nil {
+		return err
+	}
+	if err = util.ReadAddress(r, &bd.RotatedTo); err != nil {
+		return err
+	}
 	return nil
 }
@@ -0,0 +1,39 @@
+package registry
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/balance"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBootupDataSerialization(t *testing.T) {
+	bd := &BootupData{
+		Address:        address.Random(),
+		OwnerAddress:   address.Random(),
+		Color:          balance.Color{1, 2, 3},
+		CommitteeNodes: []string{"127.0.0.1:4000", "127.0.0.1:4001"},
+		AccessNodes:    []string{},
+	}
+	assert.Equal(t, bd.Address, *bd.StateAddress())
+	assert.False(t, bd.IsRotated())
+
+	var buf bytes.Buffer
+	assert.NoError(t, bd.Write(&buf))
+	back := new(BootupData)
+	assert.NoError(t, back.Read(bytes.NewReader(buf.Bytes())))
+	assert.Equal(t, bd, back)
+
+	bd.OriginAddress = address.Random()
+	bd.RotatedTo = address.Random()
+	assert.Equal(t, bd.OriginAddress, *bd.StateAddress())
+	assert.True(t, bd.IsRotated())
+
+	buf.Reset()
+	assert.NoError(t, bd.Write(&buf))
+	back = new(BootupData)
+	assert.NoError(t, back.Read(bytes.NewReader(buf.Bytes())))
+	assert.Equal(t, bd, back)
+}
@@ -6,16 +6,8 @@ import (
 	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
 	"github.com/iotaledger/wasp/packages/tcrypto"
 	"github.com/iotaledger/wasp/plugins/database"
-	"go.dedis.ch/kyber/v3"
 )
 
-func CommitDKShare(ks *tcrypto.DKShare, pubKeys []kyber.Point) error {
-	if err := ks.FinalizeDKS(pubKeys); err != nil {
-		return err
-	}
-	return SaveDKShareToRegistry(ks)
-}
-
 func dbkey(addr *address.Address) []byte {
 	return database.MakeKey(database.ObjectTypeDistributedKeyData, addr.Bytes())
 }
@@ -32,14 +24,24 @@ func SaveDKShareToRegistry(ks *tcrypto.DKShare) error {
 	if exists {
 		return fmt.Errorf("attempt to overwrite existing DK key share")
 	}
-
-	var buf bytes.Buffer
-
-	err = ks.Write(&buf)
+	data, err := marshalDKShare(ks)
 	if err != nil {
 		return err
 	}
-	return dbase.Set(dbkey(ks.Address), buf.Bytes())
+	return dbase.Set(dbkey(ks.Address), data)
+}
+
+// marshalDKShare serializes the key share, encrypted with the master key if it is set
+func marshalDKShare(ks *tcrypto.DKShare) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := ks.Write(&buf); err != nil {
+		return nil, err
+	}
+	data := buf.Bytes()
+	if key := getMasterKey(); key != nil {
+		return sealDKShare(ks.Address, data, key)
+	}
+	return data, nil
 }
 
 func GetDKShare(addr *address.Address) (*tcrypto.DKShare, bool, error) {
@@ -60,6 +62,9 @@ func LoadDKShare(addr *address.Address, maskPrivate bool) (*tcrypto.DKShare, err
 	if err != nil {
 		return nil, err
 	}
+	if data, err = openDKShare(addr, data, getMasterKey()); err != nil {
+		return nil, err
+	}
 	ret, err := tcrypto.UnmarshalDKShare(data, maskPrivate)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,381 @@
+package registry
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	"github.com/iotaledger/hive.go/kvstore"
+	"github.com/iotaledger/wasp/packages/tcrypto"
+	"github.com/iotaledger/wasp/packages/util"
+	"github.com/iotaledger/wasp/plugins/config"
+	"github.com/iotaledger/wasp/plugins/database"
+	flag "github.com/spf13/pflag"
+)
+
+// Private keys of DKShares are encrypted with the envelope encryption:
+// each record is encrypted with its own random data key, the data key is encrypted with the master key of the node.
+// The master key is derived from the passphrase or read from the key file. It is never stored.
+// The keystore record in the registry keeps the salt of the passphrase and the check value of the master key.
+// Rotation of the master key only re-encrypts the data keys.
+
+const (
+	// CfgKeystoreKeyFile is the file with the master key.
+	CfgKeystoreKeyFile = "keystore.keyFile"
+	// CfgKeystorePassphrase is the passphrase the master key is derived from. Alternatively, use env variable.
+	CfgKeystorePassphrase = "keystore.passphrase"
+	// CfgKeystoreNewKeyFile is the file with the new master key, to rotate the master key at startup.
+	CfgKeystoreNewKeyFile = "keystore.newKeyFile"
+	// CfgKeystoreNewPassphrase is the new passphrase, to rotate the master key at startup.
+	CfgKeystoreNewPassphrase = "keystore.newPassphrase"
+
+	EnvKeystorePassphrase    = "WASP_KEYSTORE_PASSPHRASE"
+	EnvKeystoreNewPassphrase = "WASP_KEYSTORE_NEW_PASSPHRASE"
+)
+
+func init() {
+	flag.String(CfgKeystoreKeyFile, "", "file with the master key which encrypts private keys of DKShares")
+	flag.String(CfgKeystorePassphrase, "", "passphrase of the master key which encrypts private keys of DKShares. Env variable "+EnvKeystorePassphrase+" can be used instead")
+	flag.String(CfgKeystoreNewKeyFile, "", "file with the new master key. If set, the master key is rotated at startup")
+	flag.String(CfgKeystoreNewPassphrase, "", "new passphrase of the master key. If set, the master key is rotated at startup")
+}
+
+const (
+	keystoreRecordVersion = byte(1)
+	// plain DKShare record starts with the version of BLS address (2)
+	dkshareEnvelopeMarker = byte(0xE1)
+	minKeyFileSize        = 32
+)
+
+var (
+	ErrKeystoreLocked   = errors.New("keystore is locked: master key is not available")
+	ErrWrongMasterKey   = errors.New("wrong master key")
+	keystoreCheckPhrase = []byte("wasp keystore check")
+
+	masterKey      []byte
+	masterKeyMutex sync.RWMutex
+)
+
+func dbkeyKeystore() []byte {
+	return database.MakeKey(database.ObjectTypeKeystore)
+}
+
+// keystore record: version || salt || check value of the master key
+type keystoreRecord struct {
+	salt  []byte
+	check []byte
+}
+
+func newKeystoreRecord(key, salt []byte) *keystoreRecord {
+	return &keystoreRecord{salt: salt, check: keyCheck(key)}
+}
+
+func keyCheck(key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(keystoreCheckPhrase)
+	return mac.Sum(nil)
+}
+
+func (rec *keystoreRecord) verify(key []byte) bool {
+	return hmac.Equal(rec.check, keyCheck(key))
+}
+
+func (rec *keystoreRecord) Bytes() []byte {
+	var buf bytes.Buffer
+	buf.WriteByte(keystoreRecordVersion)
+	_ = util.WriteBytes16(&buf, rec.salt)
+	_ = util.WriteBytes16(&buf, rec.check)
+	return buf.Bytes()
+}
+
+func keystoreRecordFromBytes(data []byte) (*keystoreRecord, error) {
+	r := bytes.NewReader(data)
+	ver, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if ver != keystoreRecordVersion {
+		return nil, fmt.Errorf("unsupported version of the keystore record: %d", ver)
+	}
+	ret := &keystoreRecord{}
+	if ret.salt, err = util.ReadBytes16(r); err != nil {
+		return nil, err
+	}
+	if ret.check, err = util.ReadBytes16(r); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func loadKeystoreRecord() (*keystoreRecord, bool, error) {
+	data, err := database.GetRegistryPartition().Get(dbkeyKeystore())
+	if err == kvstore.ErrKeyNotFound {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	ret, err := keystoreRecordFromBytes(data)
+	if err != nil {
+		return nil, false, err
+	}
+	return ret, true, nil
+}
+
+// MasterKeyFromFile reads the key file. The master key is the hash of its content
+func MasterKeyFromFile(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) < minKeyFileSize {
+		return nil, fmt.Errorf("key file %s must contain at least %d bytes", path, minKeyFileSize)
+	}
+	ret := sha256.Sum256(data)
+	return ret[:], nil
+}
+
+// configuredMasterKey returns the master key from the key file or the passphrase in the config.
+// Returns false if neither is configured
+func configuredMasterKey(cfgKeyFile, cfgPassphrase, envPassphrase string, salt []byte) ([]byte, bool, error) {
+	if keyFile := config.Node.GetString(cfgKeyFile); keyFile != "" {
+		key, err := MasterKeyFromFile(keyFile)
+		return key, err == nil, err
+	}
+	passphrase := config.Node.GetString(cfgPassphrase)
+	if passphrase == "" {
+		passphrase = os.Getenv(envPassphrase)
+	}
+	if passphrase == "" {
+		return nil, false, nil
+	}
+	key, err := tcrypto.DeriveKey(passphrase, salt)
+	return key, err == nil, err
+}
+
+// UnlockKeystore is called at startup. It makes the configured master key available for DKShare records.
+// Existing records, stored in plain form, are encrypted with the master key.
+// If the new key is configured, rotates the master key.
+// Without the configured master key DKShares are stored unencrypted, unless the registry is already encrypted
+func UnlockKeystore() error {
+	rec, exists, err := loadKeystoreRecord()
+	if err != nil {
+		return err
+	}
+	salt, err := tcrypto.NewRandomBytes(tcrypto.SaltSize)
+	if err != nil {
+		return err
+	}
+	if exists {
+		salt = rec.salt
+	}
+	key, configured, err := configuredMasterKey(CfgKeystoreKeyFile, CfgKeystorePassphrase, EnvKeystorePassphrase, salt)
+	if err != nil {
+		return err
+	}
+	if !configured {
+		if exists {
+			return fmt.Errorf("%w: DKShares are encrypted. Configure '%s' or '%s'",
+				ErrKeystoreLocked, CfgKeystoreKeyFile, CfgKeystorePassphrase)
+		}
+		log.Warnf("master key is not configured: private keys of DKShares are stored unencrypted")
+		return nil
+	}
+	if exists && !rec.verify(key) {
+		// the node could have been restarted after the rotation with the same configuration
+		newKey, ok, err := configuredMasterKey(CfgKeystoreNewKeyFile, CfgKeystoreNewPassphrase, EnvKeystoreNewPassphrase, salt)
+		if err != nil || !ok || !rec.verify(newKey) {
+			return ErrWrongMasterKey
+		}
+		setMasterKey(newKey)
+		log.Warnf("keystore unlocked with the new master key. Rotation was already done")
+		return nil
+	}
+	// encrypts records stored in plain form and creates the keystore record if needed
+	num, err := reencryptDKShares(key, key, salt)
+	if err != nil {
+		return err
+	}
+	if num > 0 {
+		log.Infof("encrypted %d DKShare records stored in plain form", num)
+	}
+	setMasterKey(key)
+	log.Infof("keystore unlocked")
+
+	newSalt, err := tcrypto.NewRandomBytes(tcrypto.SaltSize)
+	if err != nil {
+		return err
+	}
+	newKey, rotate, err := configuredMasterKey(CfgKeystoreNewKeyFile, CfgKeystoreNewPassphrase, EnvKeystoreNewPassphrase, newSalt)
+	if err != nil || !rotate {
+		return err
+	}
+	if err = RotateMasterKey(newKey, newSalt); err != nil {
+		return err
+	}
+	log.Infof("master key was rotated. Replace the master key in the configuration with the new one")
+	return nil
+}
+
+// RotateMasterKey re-encrypts data keys of all DKShare records with the new master key.
+// The salt is needed only if the key was derived from the passphrase
+func RotateMasterKey(newKey, salt []byte) error {
+	oldKey := getMasterKey()
+	if oldKey == nil {
+		return ErrKeystoreLocked
+	}
+	num, err := reencryptDKShares(oldKey, newKey, salt)
+	if err != nil {
+		return err
+	}
+	setMasterKey(newKey)
+	log.Infof("re-encrypted %d DKShare records with the new master key", num)
+	return nil
+}
+
+func setMasterKey(key []byte) {
+	masterKeyMutex.Lock()
+	defer masterKeyMutex.Unlock()
+	masterKey = key
+}
+
+func getMasterKey() []byte {
+	masterKeyMutex.RLock()
+	defer masterKeyMutex.RUnlock()
+	return masterKey
+}
+
+// reencryptDKShares saves all DKShare records encrypted with the new key and the new keystore record in one batch.
+// Returns number of re-encrypted records
+func reencryptDKShares(oldKey, newKey, salt []byte) (int, error) {
+	dbase := database.GetRegistryPartition()
+	keys := make([][]byte, 0)
+	values := make([][]byte, 0)
+	var errIter error
+	err := dbase.Iterate([]byte{database.ObjectTypeDistributedKeyData}, func(key kvstore.Key, value kvstore.Value) bool {
+		addr, _, err := address.FromBytes(key[1:])
+		if err != nil {
+			errIter = err
+			return false
+		}
+		if oldKey != nil && bytes.Equal(oldKey, newKey) && isDKShareEnvelope(value) {
+			// already encrypted with the key
+			return true
+		}
+		value, errIter = rewrapDKShareRecord(&addr, value, oldKey, newKey)
+		if errIter != nil {
+			return false
+		}
+		keys = append(keys, key)
+		values = append(values, value)
+		return true
+	})
+	if err != nil {
+		return 0, err
+	}
+	if errIter != nil {
+		return 0, errIter
+	}
+	num := len(keys)
+	keys = append(keys, dbkeyKeystore())
+	values = append(values, newKeystoreRecord(newKey, salt).Bytes())
+	if err = util.DbSetMulti(dbase, keys, values); err != nil {
+		return 0, err
+	}
+	return num, nil
+}
+
+func isDKShareEnvelope(data []byte) bool {
+	return len(data) > 0 && data[0] == dkshareEnvelopeMarker
+}
+
+// sealDKShare encrypts serialized DKShare with the new random data key and the data key with the master key.
+// Both are bound to the address
+func sealDKShare(addr *address.Address, plain []byte, key []byte) ([]byte, error) {
+	dataKey, err := tcrypto.NewRandomBytes(tcrypto.SymKeySize)
+	if err != nil {
+		return nil, err
+	}
+	sealedKey, err := tcrypto.Seal(key, dataKey, addr[:])
+	if err != nil {
+		return nil, err
+	}
+	sealedData, err := tcrypto.Seal(dataKey, plain, addr[:])
+	if err != nil {
+		return nil, err
+	}
+	return encodeDKShareEnvelope(sealedKey, sealedData), nil
+}
+
+func encodeDKShareEnvelope(sealedKey, sealedData []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte(dkshareEnvelopeMarker)
+	_ = util.WriteBytes16(&buf, sealedKey)
+	_ = util.WriteBytes32(&buf, sealedData)
+	return buf.Bytes()
+}
+
+func decodeDKShareEnvelope(data []byte) ([]byte, []byte, error) {
+	if !isDKShareEnvelope(data) {
+		return nil, nil, errors.New("not an encrypted DKShare record")
+	}
+	r := bytes.NewReader(data[1:])
+	sealedKey, err := util.ReadBytes16(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	sealedData, err := util.ReadBytes32(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sealedKey, sealedData, nil
+}
+
+// openDKShare returns serialized DKShare from the record, decrypting it if needed
+func openDKShare(addr *address.Address, data []byte, key []byte) ([]byte, error) {
+	if !isDKShareEnvelope(data) {
+		return data, nil
+	}
+	if key == nil {
+		return nil, ErrKeystoreLocked
+	}
+	sealedKey, sealedData, err := decodeDKShareEnvelope(data)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := tcrypto.Open(key, sealedKey, addr[:])
+	if err != nil {
+		return nil, fmt.Errorf("DKShare %s: %w", addr.String(), ErrWrongMasterKey)
+	}
+	return tcrypto.Open(dataKey, sealedData, addr[:])
+}
+
+// rewrapDKShareRecord re-encrypts the data key of the record with the new master key.
+// Records in plain form are encrypted
+func rewrapDKShareRecord(addr *address.Address, data []byte, oldKey, newKey []byte) ([]byte, error) {
+	if !isDKShareEnvelope(data) {
+		return sealDKShare(addr, data, newKey)
+	}
+	if oldKey == nil {
+		return nil, ErrKeystoreLocked
+	}
+	sealedKey, sealedData, err := decodeDKShareEnvelope(data)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := tcrypto.Open(oldKey, sealedKey, addr[:])
+	if err != nil {
+		return nil, fmt.Errorf("DKShare %s: %w", addr.String(), ErrWrongMasterKey)
+	}
+	if sealedKey, err = tcrypto.Seal(newKey, dataKey, addr[:]); err != nil {
+		return nil, err
+	}
+	return encodeDKShareEnvelope(sealedKey, sealedData), nil
+}
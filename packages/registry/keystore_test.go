@@ -0,0 +1,80 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	"github.com/iotaledger/wasp/packages/tcrypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDKShareEnvelope(t *testing.T) {
+	addr := address.Random()
+	plain := []byte{address.VersionBLS, 1, 2, 3}
+	key, err := tcrypto.NewRandomBytes(tcrypto.SymKeySize)
+	assert.NoError(t, err)
+
+	// plain records are returned as is
+	data, err := openDKShare(&addr, plain, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, plain, data)
+
+	sealed, err := sealDKShare(&addr, plain, key)
+	assert.NoError(t, err)
+	assert.True(t, isDKShareEnvelope(sealed))
+
+	_, err = openDKShare(&addr, sealed, nil)
+	assert.Equal(t, ErrKeystoreLocked, err)
+
+	data, err = openDKShare(&addr, sealed, key)
+	assert.NoError(t, err)
+	assert.Equal(t, plain, data)
+
+	// bound to the address
+	otherAddr := address.Random()
+	_, err = openDKShare(&otherAddr, sealed, key)
+	assert.Error(t, err)
+}
+
+func TestDKShareRewrap(t *testing.T) {
+	addr := address.Random()
+	plain := []byte{address.VersionBLS, 1, 2, 3}
+	oldKey, err := tcrypto.NewRandomBytes(tcrypto.SymKeySize)
+	assert.NoError(t, err)
+	newKey, err := tcrypto.NewRandomBytes(tcrypto.SymKeySize)
+	assert.NoError(t, err)
+
+	// plain record is encrypted
+	sealed, err := rewrapDKShareRecord(&addr, plain, nil, oldKey)
+	assert.NoError(t, err)
+	data, err := openDKShare(&addr, sealed, oldKey)
+	assert.NoError(t, err)
+	assert.Equal(t, plain, data)
+
+	rewrapped, err := rewrapDKShareRecord(&addr, sealed, oldKey, newKey)
+	assert.NoError(t, err)
+	_, err = openDKShare(&addr, rewrapped, oldKey)
+	assert.Error(t, err)
+	data, err = openDKShare(&addr, rewrapped, newKey)
+	assert.NoError(t, err)
+	assert.Equal(t, plain, data)
+
+	_, err = rewrapDKShareRecord(&addr, rewrapped, oldKey, newKey)
+	assert.Error(t, err)
+}
+
+func TestKeystoreRecord(t *testing.T) {
+	key, err := tcrypto.NewRandomBytes(tcrypto.SymKeySize)
+	assert.NoError(t, err)
+	salt, err := tcrypto.NewRandomBytes(tcrypto.SaltSize)
+	assert.NoError(t, err)
+
+	rec, err := keystoreRecordFromBytes(newKeystoreRecord(key, salt).Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, salt, rec.salt)
+	assert.True(t, rec.verify(key))
+
+	otherKey, err := tcrypto.NewRandomBytes(tcrypto.SymKeySize)
+	assert.NoError(t, err)
+	assert.False(t, rec.verify(otherKey))
+}
@@ -0,0 +1,123 @@
+package registry
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/iotaledger/hive.go/kvstore"
+	"github.com/iotaledger/wasp/packages/util"
+	"github.com/iotaledger/wasp/plugins/database"
+	"github.com/mr-tron/base58"
+	"go.uber.org/atomic"
+)
+
+// TrustedPeer is the allow-list record of the peering network: the node only connects to peers
+// which prove possession of the private key of the public key trusted for their network location.
+// All committee and access nodes of the BootupData record must be trusted to activate the committee
+type TrustedPeer struct {
+	NetId  string // "host_addr:port"
+	PubKey ed25519.PublicKey
+}
+
+// trustedPeersVersion changes with each change of the allow-list, so keys taken from it can be invalidated
+var trustedPeersVersion atomic.Uint64
+
+// TrustedPeersVersion returns the version of the allow-list
+func TrustedPeersVersion() uint64 {
+	return trustedPeersVersion.Load()
+}
+
+var msgEpochMutex sync.Mutex
+
+// NextMsgEpoch increments the counter of message epochs of the node and returns its new value.
+// Each committee object signs messages with the new epoch, so peers tell messages of the new run of the node
+// from replayed messages of previous runs. The counter is kept in the registry, so it grows across restarts
+// independently of the clock. It starts from the current time, which continues epochs of older versions
+// of the node, which used the start time as the epoch
+func NextMsgEpoch() (uint64, error) {
+	msgEpochMutex.Lock()
+	defer msgEpochMutex.Unlock()
+
+	db := database.GetRegistryPartition()
+	key := database.MakeKey(database.ObjectTypeMsgEpoch)
+	var epoch uint64
+	data, err := db.Get(key)
+	switch {
+	case err == kvstore.ErrKeyNotFound:
+		epoch = uint64(time.Now().UnixNano())
+	case err != nil:
+		return 0, err
+	case len(data) != 8:
+		return 0, fmt.Errorf("corrupted message epoch record")
+	default:
+		epoch = util.Uint64From8Bytes(data)
+	}
+	epoch++
+	if err = db.Set(key, util.Uint64To8Bytes(epoch)); err != nil {
+		return 0, err
+	}
+	return epoch, nil
+}
+
+func dbkeyTrustedPeer(netId string) []byte {
+	return database.MakeKey(database.ObjectTypeTrustedPeer, []byte(netId))
+}
+
+// SaveTrustedPeer adds the peer to the allow-list or replaces its public key
+func SaveTrustedPeer(peer *TrustedPeer) error {
+	if peer.NetId == "" {
+		return fmt.Errorf("empty network location of the peer")
+	}
+	if len(peer.PubKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("wrong public key of the peer %s", peer.NetId)
+	}
+	if err := database.GetRegistryPartition().Set(dbkeyTrustedPeer(peer.NetId), peer.PubKey); err != nil {
+		return err
+	}
+	trustedPeersVersion.Inc()
+	return nil
+}
+
+// RemoveTrustedPeer removes the peer from the allow-list
+func RemoveTrustedPeer(netId string) error {
+	if err := database.GetRegistryPartition().Delete(dbkeyTrustedPeer(netId)); err != nil {
+		return err
+	}
+	trustedPeersVersion.Inc()
+	return nil
+}
+
+// GetTrustedPeerKey returns the public key trusted for the network location
+func GetTrustedPeerKey(netId string) (ed25519.PublicKey, bool, error) {
+	data, err := database.GetRegistryPartition().Get(dbkeyTrustedPeer(netId))
+	if err == kvstore.ErrKeyNotFound {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if len(data) != ed25519.PublicKeySize {
+		return nil, false, fmt.Errorf("corrupted trusted peer record %s", netId)
+	}
+	return data, true, nil
+}
+
+func GetTrustedPeers() ([]*TrustedPeer, error) {
+	db := database.GetRegistryPartition()
+	ret := make([]*TrustedPeer, 0)
+
+	err := db.Iterate([]byte{database.ObjectTypeTrustedPeer}, func(key kvstore.Key, value kvstore.Value) bool {
+		if len(value) == ed25519.PublicKeySize {
+			ret = append(ret, &TrustedPeer{
+				NetId:  string(key[1:]),
+				PubKey: value,
+			})
+		} else {
+			log.Warnf("corrupted trusted peer record with key %s", base58.Encode(key))
+		}
+		return true
+	})
+	return ret, err
+}
@@ -0,0 +1,92 @@
+package registry
+
+import (
+	"fmt"
+
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	"github.com/iotaledger/hive.go/kvstore"
+	"github.com/iotaledger/wasp/packages/tcrypto"
+	"github.com/iotaledger/wasp/packages/util"
+	"github.com/iotaledger/wasp/plugins/database"
+)
+
+// Key shares of the reshared key set go through the registry in three steps:
+//   - the new node saves the reshared key share next to the key share in use (SaveResharedDKShare)
+//   - the new node switches to it when the committee is activated with the new nodes (SwitchToResharedDKShare)
+//   - the old node which is not in the new committee erases its key share (EraseRetiredDKShare).
+//     Until then, any T old holders can still sign for the address
+
+// SaveResharedDKShare finalizes the key share reshared to the new committee and saves it next to the key share
+// in use. The reshared key share has the same address. It replaces the key share in use only when
+// the committee is switched to the new nodes (see SwitchToResharedDKShare)
+func SaveResharedDKShare(ks *tcrypto.DKShare) error {
+	if !ks.Committed {
+		return fmt.Errorf("uncommited DK share: can't be saved to the registry")
+	}
+	data, err := marshalDKShare(ks)
+	if err != nil {
+		return err
+	}
+	return database.GetRegistryPartition().Set(dbkeyReshared(ks.Address), data)
+}
+
+// SwitchToResharedDKShare makes the reshared key share of the address the key share in use if the committee
+// nodes are of the new committee, i.e. the node is at the index of the reshared key share.
+// Returns true if switched
+func SwitchToResharedDKShare(addr *address.Address, committeeNodes []string, ownLocation string) (bool, error) {
+	return switchToResharedDKShare(database.GetRegistryPartition(), addr, committeeNodes, ownLocation)
+}
+
+func switchToResharedDKShare(dbase kvstore.KVStore, addr *address.Address, committeeNodes []string, ownLocation string) (bool, error) {
+	data, err := dbase.Get(dbkeyReshared(addr))
+	if err == kvstore.ErrKeyNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	opened, err := openDKShare(addr, data, getMasterKey())
+	if err != nil {
+		return false, err
+	}
+	ks, err := tcrypto.UnmarshalDKShare(opened, true)
+	if err != nil {
+		return false, err
+	}
+	if len(committeeNodes) != int(ks.N) || committeeNodes[ks.Index] != ownLocation {
+		return false, nil
+	}
+	if err := util.DbSetMulti(dbase, [][]byte{dbkey(addr), dbkeyReshared(addr)}, [][]byte{data, nil}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// EraseRetiredDKShare erases the key share of the address of the old node after the key set was reshared
+// to the new committee. newCommitteeNodes are peering locations of the new committee. The node in the new
+// committee is refused: its key share is replaced by the reshared one when it switches.
+// Returns false if the node has no key share of the address
+func EraseRetiredDKShare(addr *address.Address, newCommitteeNodes []string, ownLocation string) (bool, error) {
+	return eraseRetiredDKShare(database.GetRegistryPartition(), addr, newCommitteeNodes, ownLocation)
+}
+
+func eraseRetiredDKShare(dbase kvstore.KVStore, addr *address.Address, newCommitteeNodes []string, ownLocation string) (bool, error) {
+	for _, loc := range newCommitteeNodes {
+		if loc == ownLocation {
+			return false, fmt.Errorf("%s is in the new committee of %s: its key share is not retired", ownLocation, addr.String())
+		}
+	}
+	exists, err := dbase.Has(dbkey(addr))
+	if err != nil || !exists {
+		return false, err
+	}
+	if err := util.DbSetMulti(dbase, [][]byte{dbkey(addr), dbkeyReshared(addr)}, [][]byte{nil, nil}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// dbkeyReshared is the key of the reshared key share which is not in use yet
+func dbkeyReshared(addr *address.Address) []byte {
+	return database.MakeKey(database.ObjectTypeDistributedKeyData, addr.Bytes(), []byte("reshared"))
+}
@@ -0,0 +1,115 @@
+package registry
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/iotaledger/hive.go/kvstore"
+	"github.com/iotaledger/hive.go/kvstore/mapdb"
+	"github.com/iotaledger/wasp/packages/tcrypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/kyber/v3"
+)
+
+// reshares the key set from old nodes to the new committee in one process
+func reshareInProcess(t *testing.T, old []*tcrypto.DKShare, newT, newN uint16) []*tcrypto.DKShare {
+	oldIndices := make([]uint16, len(old))
+	priShares := make([][]kyber.Scalar, len(old))
+	commits := make([][]kyber.Point, len(old))
+	for i, ks := range old {
+		var err error
+		priShares[i], commits[i], err = ks.ReshareDKS(newT, newN, nil)
+		require.NoError(t, err)
+		oldIndices[i] = ks.Index
+	}
+	ret := make([]*tcrypto.DKShare, newN)
+	for j := range ret {
+		var err error
+		ret[j], err = tcrypto.NewReshareDKShare(newT, newN, uint16(j), old[0].Address)
+		require.NoError(t, err)
+		col := make([]kyber.Scalar, len(old))
+		for i := range old {
+			col[i] = priShares[i][j]
+		}
+		require.NoError(t, ret[j].AggregateReshare(old[0].PubKeys, old[0].T, oldIndices, col, commits))
+	}
+	return ret
+}
+
+func loadTestDKShare(t *testing.T, dbase kvstore.KVStore, key []byte) *tcrypto.DKShare {
+	data, err := dbase.Get(key)
+	if err == kvstore.ErrKeyNotFound {
+		return nil
+	}
+	require.NoError(t, err)
+	ks, err := tcrypto.UnmarshalDKShare(data, false)
+	require.NoError(t, err)
+	return ks
+}
+
+func TestReshareLifecycle(t *testing.T) {
+	// old committee of nodes 0..3 reshares the key set to the new committee of nodes 2, 4, 5, 6
+	locations := make([]string, 7)
+	dbs := make([]kvstore.KVStore, len(locations))
+	for i := range locations {
+		locations[i] = fmt.Sprintf("node%d", i)
+		dbs[i] = mapdb.NewMapDB()
+	}
+	old, err := tcrypto.NewDKSharesInProcess(3, 4, nil)
+	require.NoError(t, err)
+	addr := old[0].Address
+	for i, ks := range old {
+		data, err := marshalDKShare(ks)
+		require.NoError(t, err)
+		require.NoError(t, dbs[i].Set(dbkey(addr), data))
+	}
+	newCommittee := []string{locations[2], locations[4], locations[5], locations[6]}
+	reshared := reshareInProcess(t, old[:3], 3, 4)
+	for j, i := range []int{2, 4, 5, 6} {
+		data, err := marshalDKShare(reshared[j])
+		require.NoError(t, err)
+		require.NoError(t, dbs[i].Set(dbkeyReshared(addr), data))
+	}
+
+	// the node stays in the committee: its key share is replaced, not erased
+	_, err = eraseRetiredDKShare(dbs[2], addr, newCommittee, locations[2])
+	assert.Error(t, err)
+
+	for _, i := range []int{2, 4, 5, 6} {
+		switched, err := switchToResharedDKShare(dbs[i], addr, newCommittee, locations[i])
+		require.NoError(t, err)
+		assert.True(t, switched)
+	}
+	for _, i := range []int{0, 1, 3} {
+		erased, err := eraseRetiredDKShare(dbs[i], addr, newCommittee, locations[i])
+		require.NoError(t, err)
+		assert.True(t, erased)
+		assert.Nil(t, loadTestDKShare(t, dbs[i], dbkey(addr)))
+
+		// erasing is idempotent
+		erased, err = eraseRetiredDKShare(dbs[i], addr, newCommittee, locations[i])
+		require.NoError(t, err)
+		assert.False(t, erased)
+	}
+
+	// only the new committee can sign for the address
+	data := []byte("data to sign")
+	sigShares := make([][]byte, 0, 3)
+	for _, i := range []int{4, 5, 6} {
+		ks := loadTestDKShare(t, dbs[i], dbkey(addr))
+		require.NotNil(t, ks)
+		assert.EqualValues(t, 4, ks.N)
+		assert.Nil(t, loadTestDKShare(t, dbs[i], dbkeyReshared(addr)))
+		sigShare, err := ks.SignShare(data)
+		require.NoError(t, err)
+		sigShares = append(sigShares, sigShare)
+	}
+	ks := loadTestDKShare(t, dbs[2], dbkey(addr))
+	require.NotNil(t, ks)
+	assert.EqualValues(t, 0, ks.Index)
+	sig, err := ks.RecoverFullSignature(sigShares, data)
+	require.NoError(t, err)
+	assert.True(t, sig.IsValid(data))
+	assert.Equal(t, *addr, sig.Address())
+}
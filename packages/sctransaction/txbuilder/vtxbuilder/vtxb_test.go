@@ -1,8 +1,11 @@
 package vtxbuilder
 
 import (
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
 	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/balance"
+	valuetransaction "github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/transaction"
 	"github.com/iotaledger/goshimmer/packages/waspconn/utxodb"
+	"github.com/iotaledger/wasp/packages/util"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
@@ -60,3 +63,42 @@ func TestColor(t *testing.T) {
 
 	assert.Equal(t, txb2.GetInputBalance(color), int64(5))
 }
+
+func TestMoveAllToAddress(t *testing.T) {
+	addr := address.Random()
+	target := address.Random()
+	other := address.Random()
+	scColor := balance.Color{1}
+	reqColor := balance.Color{2}
+	txb, err := NewFromAddressBalances(&addr, map[valuetransaction.ID][]*balance.Balance{
+		valuetransaction.ID{1}: {balance.New(scColor, 1)},
+		valuetransaction.ID{2}: {balance.New(reqColor, 1), balance.New(balance.ColorIOTA, 10)},
+		valuetransaction.ID{3}: {balance.New(balance.ColorIOTA, 5)},
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, txb.MoveToAddress(addr, scColor, 1))
+	assert.NoError(t, txb.EraseColor(addr, reqColor, 1))
+	assert.NoError(t, txb.MoveToAddress(other, balance.ColorIOTA, 2))
+	assert.NoError(t, txb.MintColor(addr, balance.ColorIOTA, 1))
+
+	txb.MoveAllToAddress(addr, target)
+	tx := txb.Build(false)
+
+	numInputs := 0
+	tx.Inputs().ForEach(func(_ valuetransaction.OutputID) bool {
+		numInputs++
+		return true
+	})
+	assert.Equal(t, 3, numInputs)
+	bals, ok := tx.Outputs().Get(addr)
+	assert.True(t, ok)
+	assert.Equal(t, []*balance.Balance{balance.New(balance.ColorNew, 1)}, bals)
+	bals, ok = tx.Outputs().Get(other)
+	assert.True(t, ok)
+	assert.EqualValues(t, 2, util.BalanceOfColor(bals.([]*balance.Balance), balance.ColorIOTA))
+	bals, ok = tx.Outputs().Get(target)
+	assert.True(t, ok)
+	assert.EqualValues(t, 1, util.BalanceOfColor(bals.([]*balance.Balance), scColor))
+	assert.EqualValues(t, 13, util.BalanceOfColor(bals.([]*balance.Balance), balance.ColorIOTA))
+}
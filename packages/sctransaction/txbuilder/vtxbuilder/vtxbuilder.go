@@ -73,6 +73,7 @@ func NewFromAddressBalances(addr *address.Address, addressBalances map[valuetran
 		}
 		ret.inputBalancesByOutput = append(ret.inputBalancesByOutput, inb)
 	}
+	ret.sortInputBalances()
 	return ret, nil
 }
 
@@ -94,9 +95,17 @@ func NewFromOutputBalances(outputBalances map[valuetransaction.OutputID][]*balan
 		}
 		ret.inputBalancesByOutput = append(ret.inputBalancesByOutput, inb)
 	}
+	ret.sortInputBalances()
 	return ret, nil
 }
 
+// sortInputBalances sorts inputs by output id: the same balances must always result in the same transaction
+func (vtxb *Builder) sortInputBalances() {
+	sort.Slice(vtxb.inputBalancesByOutput, func(i, j int) bool {
+		return bytes.Compare(vtxb.inputBalancesByOutput[i].outputId[:], vtxb.inputBalancesByOutput[j].outputId[:]) < 0
+	})
+}
+
 // makes each color unique, sums up balances of repeating colors. Sorts colors.
 // Returns underlying array!!
 func compressAndSortBalances(bals []*balance.Balance) ([]*balance.Balance, error) {
@@ -342,6 +351,45 @@ func (vtxb *Builder) MintColorFromTransaction(targetAddr address.Address, col ba
 	return nil
 }
 
+// MoveAllToAddress moves all not consumed inputs and outputs of the address to the target address.
+// Newly minted tokens stay where they are, they belong to request blocks
+func (vtxb *Builder) MoveAllToAddress(addr, targetAddr address.Address) {
+	if vtxb.finalized {
+		panic("using finalized transaction builder")
+	}
+	if addr == targetAddr {
+		return
+	}
+	for i := range vtxb.inputBalancesByOutput {
+		inp := &vtxb.inputBalancesByOutput[i]
+		if inp.outputId.Address() != addr {
+			continue
+		}
+		for _, bal := range inp.reminder {
+			if bal.Value == 0 {
+				continue
+			}
+			inp.consumed = addAmount(inp.consumed, bal.Color, bal.Value)
+			vtxb.addToOutputs(targetAddr, bal.Color, bal.Value)
+			bal.Value = 0
+		}
+	}
+	cmap, ok := vtxb.outputBalances[addr]
+	if !ok {
+		return
+	}
+	for col, b := range cmap {
+		if col == balance.ColorNew {
+			continue
+		}
+		vtxb.addToOutputs(targetAddr, col, b)
+		delete(cmap, col)
+	}
+	if len(cmap) == 0 {
+		delete(vtxb.outputBalances, addr)
+	}
+}
+
 // Build build the final value transaction: not signed and without data payload
 
 func (vtxb *Builder) Build(useAllInputs bool) *valuetransaction.Transaction {
@@ -174,7 +174,12 @@ func dbkeyBatch(stateIndex uint32) []byte {
 }
 
 func LoadBatch(addr *address.Address, stateIndex uint32) (Batch, error) {
-	data, err := database.GetPartition(addr).Get(dbkeyBatch(stateIndex))
+	return LoadBatchFromPartition(database.GetPartition(addr), stateIndex)
+}
+
+// LoadBatchFromPartition same as LoadBatch for the provided partition of the smart contract
+func LoadBatchFromPartition(partition kvstore.KVStore, stateIndex uint32) (Batch, error) {
+	data, err := partition.Get(dbkeyBatch(stateIndex))
 	if err == kvstore.ErrKeyNotFound {
 		return nil, nil
 	}
@@ -0,0 +1,203 @@
+package state
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"testing"
+
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/transaction"
+	"github.com/iotaledger/hive.go/kvstore"
+	"github.com/iotaledger/wasp/packages/hashing"
+	"github.com/iotaledger/wasp/packages/sctransaction"
+	"github.com/iotaledger/wasp/packages/table"
+	"github.com/iotaledger/wasp/plugins/database"
+	"github.com/stretchr/testify/assert"
+)
+
+// crash recovery test runs the commit of two states in a child process, which exits before the given write
+// of the second commit to the store, without closing the database.
+// The parent process opens the database again and checks that the second state is committed all-or-nothing.
+// Batched mutations are one write: engines commit them atomically, which is checked by
+// the crash test of the database plugin
+
+const (
+	envCrashEngine = "WASP_CRASH_TEST_ENGINE"
+	envCrashDir    = "WASP_CRASH_TEST_DIR"
+	envCrashAfter  = "WASP_CRASH_TEST_AFTER"
+	crashExitCode  = 3
+	// number of writes of the second commit: one batch of 3 records, 1 request and 2 variables.
+	// With crashAfter = crashPoints the process exits after the commit
+	crashPoints = 1
+)
+
+var crashTestAddress = address.Address{1, 2, 3}
+
+func crashTestBatches(t *testing.T) (Batch, Batch) {
+	txid := (transaction.ID)(*hashing.HashStrings("crash test"))
+
+	reqid0 := sctransaction.NewRequestId(txid, 0)
+	su0 := NewStateUpdate(&reqid0)
+	su0.Mutations().Add(table.NewMutationSet("counter", []byte{0}))
+	su0.Mutations().Add(table.NewMutationSet("tmp", []byte{1}))
+	batch0, err := NewBatch([]StateUpdate{su0})
+	assert.NoError(t, err)
+
+	reqid1 := sctransaction.NewRequestId(txid, 1)
+	su1 := NewStateUpdate(&reqid1)
+	su1.Mutations().Add(table.NewMutationSet("counter", []byte{1}))
+	su1.Mutations().Add(table.NewMutationDel("tmp"))
+	batch1, err := NewBatch([]StateUpdate{su1})
+	assert.NoError(t, err)
+	batch1.WithStateIndex(1)
+
+	return batch0, batch1
+}
+
+// crashingStore exits the process before the write to the store when crashAfter writes were done
+type crashingStore struct {
+	kvstore.KVStore
+	armed      bool
+	writes     int
+	crashAfter int
+}
+
+type crashingBatch struct {
+	kvstore.BatchedMutations
+	store *crashingStore
+}
+
+func (s *crashingStore) write() {
+	if !s.armed {
+		return
+	}
+	if s.writes == s.crashAfter {
+		os.Exit(crashExitCode)
+	}
+	s.writes++
+}
+
+func (s *crashingStore) Set(key kvstore.Key, value kvstore.Value) error {
+	s.write()
+	return s.KVStore.Set(key, value)
+}
+
+func (s *crashingStore) Delete(key kvstore.Key) error {
+	s.write()
+	return s.KVStore.Delete(key)
+}
+
+func (s *crashingStore) DeletePrefix(prefix kvstore.KeyPrefix) error {
+	s.write()
+	return s.KVStore.DeletePrefix(prefix)
+}
+
+func (s *crashingStore) Batched() kvstore.BatchedMutations {
+	return &crashingBatch{BatchedMutations: s.KVStore.Batched(), store: s}
+}
+
+func (b *crashingBatch) Commit() error {
+	b.store.write()
+	return b.BatchedMutations.Commit()
+}
+
+func runCrashingCommit(t *testing.T, engine, dir string, crashAfter int) {
+	db, err := database.NewDB(engine, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &crashingStore{
+		KVStore:    db.NewStore().WithRealm(crashTestAddress[:]),
+		crashAfter: crashAfter,
+	}
+	getPartition := func(*address.Address) kvstore.KVStore { return store }
+
+	batch0, batch1 := crashTestBatches(t)
+	vs := newVirtualState(&crashTestAddress, getPartition)
+	if err = vs.ApplyBatch(batch0); err != nil {
+		t.Fatal(err)
+	}
+	if err = vs.CommitToDb(batch0); err != nil {
+		t.Fatal(err)
+	}
+	if err = vs.ApplyBatch(batch1); err != nil {
+		t.Fatal(err)
+	}
+	store.armed = true
+	if err = vs.CommitToDb(batch1); err != nil {
+		t.Fatal(err)
+	}
+	os.Exit(crashExitCode)
+}
+
+func checkRecoveredState(t *testing.T, engine, dir string, crashAfter int) {
+	db, err := database.NewDB(engine, dir)
+	assert.NoError(t, err)
+	defer db.Close()
+	store := db.NewStore().WithRealm(crashTestAddress[:])
+	getPartition := func(*address.Address) kvstore.KVStore { return store }
+
+	vs, batch, ok, err := loadSolidState(&crashTestAddress, getPartition)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	idx := vs.StateIndex()
+	assert.EqualValues(t, idx, batch.StateIndex())
+	if crashAfter < crashPoints {
+		assert.EqualValues(t, 0, idx)
+	} else {
+		assert.EqualValues(t, 1, idx)
+	}
+
+	counter, err := vs.Variables().Get("counter")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{byte(idx)}, counter)
+
+	tmp, err := vs.Variables().Get("tmp")
+	assert.NoError(t, err)
+	if idx == 0 {
+		assert.Equal(t, []byte{1}, tmp)
+	} else {
+		assert.Nil(t, tmp)
+	}
+
+	_, batch1 := crashTestBatches(t)
+	processed, err := store.Has(dbkeyRequest(batch1.RequestIds()[0]))
+	assert.NoError(t, err)
+	assert.Equal(t, idx == 1, processed)
+}
+
+func TestCommitCrashRecovery(t *testing.T) {
+	if engine := os.Getenv(envCrashEngine); engine != "" {
+		crashAfter, err := strconv.Atoi(os.Getenv(envCrashAfter))
+		if err != nil {
+			t.Fatal(err)
+		}
+		runCrashingCommit(t, engine, os.Getenv(envCrashDir), crashAfter)
+		return
+	}
+	for _, engine := range []string{database.EngineBadger, database.EngineBolt, database.EnginePebble} {
+		for crashAfter := 0; crashAfter <= crashPoints; crashAfter++ {
+			t.Run(fmt.Sprintf("%s/%d", engine, crashAfter), func(t *testing.T) {
+				dir, err := ioutil.TempDir("", "waspdb-crash")
+				assert.NoError(t, err)
+				defer os.RemoveAll(dir)
+
+				cmd := exec.Command(os.Args[0], "-test.run=^TestCommitCrashRecovery$")
+				cmd.Env = append(os.Environ(),
+					envCrashEngine+"="+engine,
+					envCrashDir+"="+dir,
+					envCrashAfter+"="+strconv.Itoa(crashAfter),
+				)
+				out, err := cmd.CombinedOutput()
+				exitErr, ok := err.(*exec.ExitError)
+				if !ok || exitErr.ExitCode() != crashExitCode {
+					t.Fatalf("child process didn't crash as expected: %v\n%s", err, out)
+				}
+				checkRecoveredState(t, engine, dir, crashAfter)
+			})
+		}
+	}
+}
@@ -44,6 +44,11 @@ func NewEmptyVirtualState(addr *address.Address) VirtualState {
 	return newVirtualState(addr, getSCPartition)
 }
 
+// NewEmptyVirtualStateInPartition same as NewEmptyVirtualState, the state is stored in the provided partition
+func NewEmptyVirtualStateInPartition(addr *address.Address, getPartition func(*address.Address) kvstore.KVStore) VirtualState {
+	return newVirtualState(addr, getPartition)
+}
+
 func (vs *virtualState) Clone() VirtualState {
 	return &virtualState{
 		scAddress:    vs.scAddress,
@@ -151,6 +156,8 @@ func (vs *virtualState) Read(r io.Reader) error {
 	if _, err := r.Read(vs.stateHash[:]); err != nil {
 		return err
 	}
+	// only states with the batch applied are stored
+	vs.empty = false
 	return nil
 }
 
@@ -202,6 +209,11 @@ func LoadSolidState(addr *address.Address) (VirtualState, Batch, bool, error) {
 	return loadSolidState(addr, getSCPartition)
 }
 
+// LoadSolidStateFromPartition same as LoadSolidState, the state is loaded from the provided partition
+func LoadSolidStateFromPartition(addr *address.Address, getPartition func(*address.Address) kvstore.KVStore) (VirtualState, Batch, bool, error) {
+	return loadSolidState(addr, getPartition)
+}
+
 func loadSolidState(scAddress *address.Address, getPartition func(*address.Address) kvstore.KVStore) (VirtualState, Batch, bool, error) {
 	db := getPartition(scAddress)
 
@@ -266,15 +278,20 @@ const maxRetriesForRequest = byte(5)
 
 // IsRequestCompleted returns true if it was completed successfully or number of retries reached maximum
 func IsRequestCompleted(addr *address.Address, reqid *sctransaction.RequestId) (bool, error) {
+	return IsRequestCompletedInPartition(getSCPartition(addr), reqid)
+}
+
+// IsRequestCompletedInPartition same as IsRequestCompleted for the provided partition of the smart contract
+func IsRequestCompletedInPartition(partition kvstore.KVStore, reqid *sctransaction.RequestId) (bool, error) {
 	dbkey := dbkeyRequest(reqid)
-	has, err := getSCPartition(addr).Has(dbkey)
+	has, err := partition.Has(dbkey)
 	if err != nil {
 		return false, err
 	}
 	if !has {
 		return false, nil
 	}
-	val, err := getSCPartition(addr).Get(dbkey)
+	val, err := partition.Get(dbkey)
 	if err != nil {
 		return false, err
 	}
@@ -200,4 +200,13 @@ func TestCommit(t *testing.T) {
 
 	v, _ = vs2.Variables().Get(table.Key([]byte("x")))
 	assert.Equal(t, []byte{1}, v)
+
+	// the loaded state takes the next batch
+	su2 := NewStateUpdate(nil)
+	su2.Mutations().Add(table.NewMutationSet("x", []byte{2}))
+	batch3, err := NewBatch([]StateUpdate{su2})
+	assert.NoError(t, err)
+	batch3.WithStateIndex(1)
+	assert.NoError(t, vs2.ApplyBatch(batch3))
+	assert.EqualValues(t, 1, vs2.StateIndex())
 }
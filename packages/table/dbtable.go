@@ -144,9 +144,9 @@ func (s *subrealm) Get(key Key) ([]byte, error) {
 	return v, err
 }
 
+// Iterate passes keys without the prefix. The store already strips its realm from keys
 func (s *subrealm) Iterate(f func(Key, []byte) bool) error {
-	db := s.db()
-	return db.Iterate(s.prefix, func(key kvstore.Key, value kvstore.Value) bool {
-		return f(Key(key[len(db.Realm())+len(s.prefix):]), value)
+	return s.db().Iterate(s.prefix, func(key kvstore.Key, value kvstore.Value) bool {
+		return f(Key(key[len(s.prefix):]), value)
 	})
 }
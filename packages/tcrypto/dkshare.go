@@ -2,6 +2,7 @@
 package tcrypto
 
 import (
+	"crypto/cipher"
 	"fmt"
 	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
 	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address/signaturescheme"
@@ -68,14 +69,23 @@ func ValidateDKSParams(t, n, index uint16) error {
 }
 
 func NewRndDKShare(t, n, index uint16) (*DKShare, error) {
+	return NewRndDKShareFromStream(t, n, index, nil)
+}
+
+// NewRndDKShareFromStream same as NewRndDKShare, only randomness is taken from the provided stream.
+// Nil means the cryptographically secure random stream of the suite. Used for reproducible tests
+func NewRndDKShareFromStream(t, n, index uint16, rnd cipher.Stream) (*DKShare, error) {
 	if err := ValidateDKSParams(t, n, index); err != nil {
 		return nil, err
 	}
 	suite := bn256.NewSuite()
+	if rnd == nil {
+		rnd = suite.RandomStream()
+	}
 	// create seed secret
-	secret := suite.G1().Scalar().Pick(suite.RandomStream())
+	secret := suite.G1().Scalar().Pick(rnd)
 	// create random polynomial of degree t
-	priPoly := share.NewPriPoly(suite.G2(), int(t), secret, suite.RandomStream())
+	priPoly := share.NewPriPoly(suite.G2(), int(t), secret, rnd)
 	// create private shares of the random polynomial
 	// with index n corresponds to p(n+1)
 	shares := priPoly.Shares(int(n))
@@ -89,6 +99,71 @@ func NewRndDKShare(t, n, index uint16) (*DKShare, error) {
 	return ret, nil
 }
 
+// NewDKSharesInProcess runs the whole distributed key generation for n nodes in one process
+// and returns committed key shares of all nodes. Used for testing
+func NewDKSharesInProcess(t, n uint16, rnd cipher.Stream) ([]*DKShare, error) {
+	ret := make([]*DKShare, n)
+	var err error
+	for i := range ret {
+		if ret[i], err = NewRndDKShareFromStream(t, n, uint16(i), rnd); err != nil {
+			return nil, err
+		}
+	}
+	for j := range ret {
+		priShares := make([]kyber.Scalar, n)
+		for i := range ret {
+			if i != j {
+				priShares[i] = ret[i].PriShares[j].V
+			}
+		}
+		if err = ret[j].AggregateDKS(priShares); err != nil {
+			return nil, err
+		}
+	}
+	pubKeys := make([]kyber.Point, n)
+	for i := range ret {
+		pubKeys[i] = ret[i].PubKeyOwn
+	}
+	for i := range ret {
+		if err = ret[i].FinalizeDKS(pubKeys); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+// NewDKShareFromPriShare creates committed DKShare from the private share of the master secret and
+// the public polynomial of it, both produced by the peer-to-peer distributed key generation
+func NewDKShareFromPriShare(t, n uint16, priShare *share.PriShare, pubPoly *share.PubPoly) (*DKShare, error) {
+	if err := ValidateDKSParams(t, n, uint16(priShare.I)); err != nil {
+		return nil, err
+	}
+	if pubPoly.Threshold() != int(t) {
+		return nil, fmt.Errorf("public polynomial has threshold %d, expected %d", pubPoly.Threshold(), t)
+	}
+	suite := bn256.NewSuite()
+	ret := &DKShare{
+		Suite:      suite,
+		N:          n,
+		T:          t,
+		Index:      uint16(priShare.I),
+		priKey:     priShare.V,
+		PubKeyOwn:  suite.G2().Point().Mul(priShare.V, nil),
+		Aggregated: true,
+	}
+	pubKeys := make([]kyber.Point, n)
+	for i, s := range pubPoly.Shares(int(n)) {
+		pubKeys[i] = s.V
+	}
+	if !pubKeys[ret.Index].Equal(ret.PubKeyOwn) {
+		return nil, errors.New("private share doesn't match the public polynomial")
+	}
+	if err := ret.FinalizeDKS(pubKeys); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
 func (ks *DKShare) AggregateDKS(priShares []kyber.Scalar) error {
 	if ks.Aggregated {
 		return errors.New("already Aggregated")
@@ -118,8 +193,8 @@ func (ks *DKShare) FinalizeDKS(pubKeys []kyber.Point) error {
 	if err != nil {
 		return err
 	}
-	pubKeyMaster := ks.PubPoly.Commit()
-	pubKeyBin, err := pubKeyMaster.MarshalBinary()
+	ks.PubKeyMaster = ks.PubPoly.Commit()
+	pubKeyBin, err := ks.PubKeyMaster.MarshalBinary()
 	if err != nil {
 		return err
 	}
@@ -157,12 +232,28 @@ func (ks *DKShare) VerifySigShare(data []byte, sigshare tbdn.SigShare) error {
 		return errors.New("key set is not Committed")
 	}
 	idx, err := sigshare.Index()
-	if err != nil || idx >= int(ks.N) || idx < 0 {
+	if err != nil {
 		return err
 	}
+	if idx >= int(ks.N) || idx < 0 {
+		return fmt.Errorf("wrong signature share index %d", idx)
+	}
 	return bdn.Verify(ks.Suite, ks.PubKeys[idx], data, sigshare.Value())
 }
 
+// VerifySigShareOfPeer checks if the signature share was produced by the key share of the peer.
+// A valid share of the other peer doesn't pass
+func (ks *DKShare) VerifySigShareOfPeer(peerIndex uint16, data []byte, sigshare tbdn.SigShare) error {
+	idx, err := sigshare.Index()
+	if err != nil {
+		return err
+	}
+	if idx != int(peerIndex) {
+		return fmt.Errorf("signature share index %d doesn't match peer index %d", idx, peerIndex)
+	}
+	return ks.VerifySigShare(data, sigshare)
+}
+
 func (ks *DKShare) VerifyMasterSignature(data []byte, signature []byte) error {
 	if !ks.Committed {
 		return errors.New("key set is not Committed")
@@ -26,3 +26,20 @@ func TestNewRandomDKShare(t *testing.T) {
 	_, err = NewRndDKShare(4, 5, 6)
 	assert.Equal(t, err != nil, true)
 }
+
+func TestVerifySigShareOfPeer(t *testing.T) {
+	dkshares, err := NewDKSharesInProcess(3, 4, nil)
+	assert.Equal(t, err, nil)
+
+	data := []byte("data to sign")
+	sigShare, err := dkshares[1].SignShare(data)
+	assert.Equal(t, err, nil)
+
+	assert.Equal(t, dkshares[0].VerifySigShareOfPeer(1, data, sigShare), nil)
+	assert.Equal(t, dkshares[0].VerifySigShareOfPeer(2, data, sigShare) != nil, true)
+	assert.Equal(t, dkshares[0].VerifySigShareOfPeer(1, []byte("other data"), sigShare) != nil, true)
+
+	// index out of range
+	sigShare[0] = 0xFF
+	assert.Equal(t, dkshares[0].VerifySigShare(data, sigShare) != nil, true)
+}
@@ -0,0 +1,176 @@
+package tcrypto
+
+import (
+	"crypto/cipher"
+	"errors"
+	"fmt"
+
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/pairing/bn256"
+	"go.dedis.ch/kyber/v3/share"
+)
+
+// Proactive resharing of the master secret to the new (T', N') committee.
+// Each of at least T old holders shares own private key with the random polynomial of degree T'-1
+// and sends value of it to each of N' new holders. The new holder interpolates received values
+// at 0 with Lagrange coefficients of the old indices. The result is the share of the same master secret,
+// so the master public key and the address remain the same.
+// Old shares remain valid shares of the master secret: any T old holders can still sign for the address
+// unless they delete their shares. The resharing only protects against holders who do it
+// (see registry.EraseRetiredDKShare)
+
+// ReshareDKS creates shares of the own private key for the new committee of n nodes with threshold t.
+// Returns private shares, i-th share is for the new node with index i,
+// and commitments of the polynomial used by new nodes to verify shares.
+// Nil rnd means the cryptographically secure random stream of the suite
+func (ks *DKShare) ReshareDKS(t, n uint16, rnd cipher.Stream) ([]kyber.Scalar, []kyber.Point, error) {
+	if !ks.Committed {
+		return nil, nil, errors.New("key set is not Committed")
+	}
+	if err := ValidateDKSParams(t, n, 0); err != nil {
+		return nil, nil, err
+	}
+	if rnd == nil {
+		rnd = ks.Suite.RandomStream()
+	}
+	priPoly := share.NewPriPoly(ks.Suite.G2(), int(t), ks.priKey, rnd)
+	priShares := make([]kyber.Scalar, n)
+	for i, s := range priPoly.Shares(int(n)) {
+		priShares[i] = s.V
+	}
+	_, commits := priPoly.Commit(ks.Suite.G2().Point().Base()).Info()
+	return priShares, commits, nil
+}
+
+// NewReshareDKShare creates uncommitted DKShare of the new node for the key set with the existing address
+func NewReshareDKShare(t, n, index uint16, addr *address.Address) (*DKShare, error) {
+	if err := ValidateDKSParams(t, n, index); err != nil {
+		return nil, err
+	}
+	if addr.Version() != address.VersionBLS {
+		return nil, errors.New("not a BLS address")
+	}
+	a := *addr
+	return &DKShare{
+		Suite:   bn256.NewSuite(),
+		N:       n,
+		T:       t,
+		Index:   index,
+		Address: &a,
+	}, nil
+}
+
+// AggregateReshare calculates the private key of the new node from shares received from old nodes
+// and finalizes the key set. oldPubKeys are public shares of the old committee with threshold oldT.
+// priShares[i] and commits[i] are received from the old node with index oldIndices[i].
+// Each share is verified against commitments, and commitments of each old node are verified against its
+// public share in the old key set, so the old node can't deal anything but its own private key.
+// Public shares of the new committee are calculated from commitments. All new nodes must receive
+// the same commitments, otherwise they end up with different key sets
+func (ks *DKShare) AggregateReshare(oldPubKeys []kyber.Point, oldT uint16, oldIndices []uint16, priShares []kyber.Scalar, commits [][]kyber.Point) error {
+	if ks.Aggregated {
+		return errors.New("already Aggregated")
+	}
+	if len(oldIndices) < int(oldT) {
+		return fmt.Errorf("at least %d shares of old nodes are needed, got %d", oldT, len(oldIndices))
+	}
+	if len(priShares) != len(oldIndices) || len(commits) != len(oldIndices) {
+		return errors.New("wrong number of private shares or commitments")
+	}
+	oldPubPoly, err := ks.verifiedPubPoly(oldPubKeys, oldT)
+	if err != nil {
+		return fmt.Errorf("old key set: %v", err)
+	}
+	base := ks.Suite.G2().Point().Base()
+	shares := make([]*share.PriShare, len(oldIndices))
+	maxIndex := 0
+	for i, oldIndex := range oldIndices {
+		if int(oldIndex) >= len(oldPubKeys) {
+			return fmt.Errorf("wrong old index %d", oldIndex)
+		}
+		if len(commits[i]) != int(ks.T) {
+			return fmt.Errorf("wrong number of commitments from old node %d", oldIndex)
+		}
+		if !commits[i][0].Equal(oldPubPoly.Eval(int(oldIndex)).V) {
+			return fmt.Errorf("commitments of old node %d don't match its public share", oldIndex)
+		}
+		s := &share.PriShare{I: int(ks.Index), V: priShares[i]}
+		if !share.NewPubPoly(ks.Suite.G2(), base, commits[i]).Check(s) {
+			return fmt.Errorf("private share from old node %d doesn't match commitments", oldIndex)
+		}
+		for j := 0; j < i; j++ {
+			if oldIndices[j] == oldIndex {
+				return fmt.Errorf("duplicate old index %d", oldIndex)
+			}
+		}
+		shares[i] = &share.PriShare{I: int(oldIndex), V: priShares[i]}
+		if int(oldIndex) > maxIndex {
+			maxIndex = int(oldIndex)
+		}
+	}
+	priKey, err := share.RecoverSecret(ks.Suite.G2(), shares, int(oldT), maxIndex+1)
+	if err != nil {
+		return err
+	}
+	// public share of the new node k is interpolated from values of commitment polynomials at k
+	// the same way as the private key
+	pubKeys := make([]kyber.Point, ks.N)
+	for k := range pubKeys {
+		pubShares := make([]*share.PubShare, len(oldIndices))
+		for i, oldIndex := range oldIndices {
+			pubShares[i] = &share.PubShare{
+				I: int(oldIndex),
+				V: share.NewPubPoly(ks.Suite.G2(), base, commits[i]).Eval(k).V,
+			}
+		}
+		if pubKeys[k], err = share.RecoverCommit(ks.Suite.G2(), pubShares, int(oldT), maxIndex+1); err != nil {
+			return err
+		}
+	}
+	ks.priKey = priKey
+	ks.PubKeyOwn = ks.Suite.G2().Point().Mul(ks.priKey, nil)
+	ks.Aggregated = true
+	if !ks.PubKeyOwn.Equal(pubKeys[ks.Index]) {
+		return errors.New("private key doesn't match public shares")
+	}
+	return ks.finalizeReshare(pubKeys)
+}
+
+// verifiedPubPoly recovers the public polynomial of the key set from public shares. All public shares must be
+// on the polynomial and the master public key must correspond to the address of the key set
+func (ks *DKShare) verifiedPubPoly(pubKeys []kyber.Point, t uint16) (*share.PubPoly, error) {
+	if err := ValidateDKSParams(t, uint16(len(pubKeys)), 0); err != nil {
+		return nil, err
+	}
+	pubPoly, err := RecoverPubPoly(ks.Suite, pubKeys, t, uint16(len(pubKeys)))
+	if err != nil {
+		return nil, err
+	}
+	for i, pk := range pubKeys {
+		if !pubPoly.Eval(i).V.Equal(pk) {
+			return nil, fmt.Errorf("public share %d is not consistent with others", i)
+		}
+	}
+	pubKeyBin, err := pubPoly.Commit().MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	if address.FromBLSPubKey(pubKeyBin) != *ks.Address {
+		return nil, errors.New("master public key doesn't correspond to the address")
+	}
+	return pubPoly, nil
+}
+
+// finalizeReshare finalizes the reshared key set and checks if the master public key corresponds
+// to the address of the key set
+func (ks *DKShare) finalizeReshare(pubKeys []kyber.Point) error {
+	expected := *ks.Address
+	if err := ks.FinalizeDKS(pubKeys); err != nil {
+		return err
+	}
+	if *ks.Address != expected {
+		return fmt.Errorf("reshared key set has address %s, expected %s", ks.Address.String(), expected.String())
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package tcrypto
+
+import (
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+	"go.dedis.ch/kyber/v3"
+)
+
+// reshares key set from given old nodes to the new committee in one process
+func reshareInProcess(t *testing.T, old []*DKShare, newT, newN uint16) []*DKShare {
+	oldIndices := make([]uint16, len(old))
+	priShares := make([][]kyber.Scalar, len(old))
+	commits := make([][]kyber.Point, len(old))
+	for i, ks := range old {
+		var err error
+		priShares[i], commits[i], err = ks.ReshareDKS(newT, newN, nil)
+		assert.Equal(t, err, nil)
+		oldIndices[i] = ks.Index
+	}
+	ret := make([]*DKShare, newN)
+	for j := range ret {
+		var err error
+		ret[j], err = NewReshareDKShare(newT, newN, uint16(j), old[0].Address)
+		assert.Equal(t, err, nil)
+		col := make([]kyber.Scalar, len(old))
+		for i := range old {
+			col[i] = priShares[i][j]
+		}
+		err = ret[j].AggregateReshare(old[0].PubKeys, old[0].T, oldIndices, col, commits)
+		assert.Equal(t, err, nil)
+	}
+	// all new nodes calculate the same public shares
+	for j := range ret {
+		for k := range ret {
+			assert.Equal(t, ret[j].PubKeys[k].Equal(ret[k].PubKeyOwn), true)
+		}
+	}
+	return ret
+}
+
+func TestReshareDKS(t *testing.T) {
+	old, err := NewDKSharesInProcess(3, 4, nil)
+	assert.Equal(t, err, nil)
+
+	// any quorum of old nodes reshares to the bigger committee
+	reshared := reshareInProcess(t, []*DKShare{old[3], old[0], old[2]}, 4, 5)
+	for _, ks := range reshared {
+		assert.Equal(t, *ks.Address, *old[0].Address)
+		assert.Equal(t, ks.PubKeyMaster.Equal(old[0].PubKeyMaster), true)
+	}
+
+	// signature of the new quorum is valid for the same master key
+	data := []byte("data to sign")
+	sigShares := make([][]byte, 0, 4)
+	for _, ks := range reshared[1:] {
+		sigShare, err := ks.SignShare(data)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, ks.VerifySigShare(data, sigShare), nil)
+		sigShares = append(sigShares, sigShare)
+	}
+	sig, err := reshared[0].RecoverFullSignature(sigShares, data)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, sig.IsValid(data), true)
+	assert.Equal(t, sig.Address(), *old[1].Address)
+
+	// resharing back to the smaller committee
+	back := reshareInProcess(t, reshared[:4], 3, 4)
+	assert.Equal(t, *back[0].Address, *old[0].Address)
+}
+
+func TestReshareDKSWrongShares(t *testing.T) {
+	old, err := NewDKSharesInProcess(3, 4, nil)
+	assert.Equal(t, err, nil)
+
+	priShares0, commits0, err := old[0].ReshareDKS(3, 4, nil)
+	assert.Equal(t, err, nil)
+	priShares1, commits1, err := old[1].ReshareDKS(3, 4, nil)
+	assert.Equal(t, err, nil)
+
+	ks, err := NewReshareDKShare(3, 4, 0, old[0].Address)
+	assert.Equal(t, err, nil)
+
+	// not enough old nodes
+	err = ks.AggregateReshare(old[0].PubKeys, 3, []uint16{0, 1}, []kyber.Scalar{priShares0[0], priShares1[0]}, [][]kyber.Point{commits0, commits1})
+	assert.Equal(t, err != nil, true)
+
+	// share doesn't match commitments
+	priShares2, commits2, err := old[2].ReshareDKS(3, 4, nil)
+	assert.Equal(t, err, nil)
+	err = ks.AggregateReshare(old[0].PubKeys, 3, []uint16{0, 1, 2},
+		[]kyber.Scalar{priShares0[0], priShares1[1], priShares2[0]},
+		[][]kyber.Point{commits0, commits1, commits2})
+	assert.Equal(t, err != nil, true)
+	assert.Equal(t, ks.Aggregated, false)
+}
+
+func TestReshareDKSWrongDealer(t *testing.T) {
+	old, err := NewDKSharesInProcess(3, 4, nil)
+	assert.Equal(t, err, nil)
+	other, err := NewDKSharesInProcess(3, 4, nil)
+	assert.Equal(t, err, nil)
+
+	// the node of another key set pretends to be the old node 2. Shares match its own commitments
+	dealers := []*DKShare{old[0], old[1], other[2]}
+	priShares := make([]kyber.Scalar, len(dealers))
+	commits := make([][]kyber.Point, len(dealers))
+	for i, dealer := range dealers {
+		shares, c, err := dealer.ReshareDKS(3, 4, nil)
+		assert.Equal(t, err, nil)
+		priShares[i] = shares[0]
+		commits[i] = c
+	}
+	ks, err := NewReshareDKShare(3, 4, 0, old[0].Address)
+	assert.Equal(t, err, nil)
+	err = ks.AggregateReshare(old[0].PubKeys, 3, []uint16{0, 1, 2}, priShares, commits)
+	assert.Equal(t, err != nil, true)
+	assert.Equal(t, ks.Aggregated, false)
+
+	// public shares of the old key set must correspond to the address
+	ks, err = NewReshareDKShare(3, 4, 0, old[0].Address)
+	assert.Equal(t, err, nil)
+	err = ks.AggregateReshare(other[0].PubKeys, 3, []uint16{0, 1, 2}, priShares, commits)
+	assert.Equal(t, err != nil, true)
+}
@@ -0,0 +1,111 @@
+package tcrypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// symmetric encryption of secrets at rest: AES-256-GCM with the random nonce prepended to the ciphertext
+
+const (
+	SymKeySize = 32
+	SaltSize   = 32
+
+	// scrypt parameters recommended for interactive logins (2017)
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+var ErrDecryptionFailed = errors.New("decryption failed: wrong key or corrupted data")
+
+// NewRandomBytes returns cryptographically secure random bytes, used for keys and salts
+func NewRandomBytes(size int) ([]byte, error) {
+	ret := make([]byte, size)
+	if _, err := rand.Read(ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// DeriveKey derives symmetric key from the passphrase with scrypt
+func DeriveKey(passphrase string, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, SymKeySize)
+}
+
+// Seal encrypts and authenticates plaintext and authenticates additional data.
+// The same additional data must be provided to Open
+func Seal(key, plaintext, additionalData []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := NewRandomBytes(aead.NonceSize())
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// Open decrypts data sealed with Seal
+func Open(key, sealed, additionalData []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize()+aead.Overhead() {
+		return nil, ErrDecryptionFailed
+	}
+	nonce := sealed[:aead.NonceSize()]
+	ret, err := aead.Open(nil, nonce, sealed[aead.NonceSize():], additionalData)
+	if err != nil {
+		return nil, ErrDecryptionFailed
+	}
+	return ret, nil
+}
+
+// SealWithPassphrase encrypts data with the key derived from the passphrase.
+// The random salt is prepended to the result
+func SealWithPassphrase(passphrase string, plaintext []byte) ([]byte, error) {
+	salt, err := NewRandomBytes(SaltSize)
+	if err != nil {
+		return nil, err
+	}
+	key, err := DeriveKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := Seal(key, plaintext, salt)
+	if err != nil {
+		return nil, err
+	}
+	return append(salt, sealed...), nil
+}
+
+// OpenWithPassphrase decrypts data encrypted with SealWithPassphrase
+func OpenWithPassphrase(passphrase string, data []byte) ([]byte, error) {
+	if len(data) < SaltSize {
+		return nil, ErrDecryptionFailed
+	}
+	salt := data[:SaltSize]
+	key, err := DeriveKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	return Open(key, data[SaltSize:], salt)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != SymKeySize {
+		return nil, errors.New("wrong size of the symmetric key")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
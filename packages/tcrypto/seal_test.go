@@ -0,0 +1,46 @@
+package tcrypto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSealOpen(t *testing.T) {
+	key, err := NewRandomBytes(SymKeySize)
+	assert.NoError(t, err)
+	plain := []byte("private key share")
+
+	sealed, err := Seal(key, plain, []byte("ad"))
+	assert.NoError(t, err)
+	assert.NotContains(t, string(sealed), string(plain))
+
+	opened, err := Open(key, sealed, []byte("ad"))
+	assert.NoError(t, err)
+	assert.Equal(t, plain, opened)
+
+	_, err = Open(key, sealed, []byte("other ad"))
+	assert.Equal(t, ErrDecryptionFailed, err)
+
+	otherKey, err := NewRandomBytes(SymKeySize)
+	assert.NoError(t, err)
+	_, err = Open(otherKey, sealed, []byte("ad"))
+	assert.Equal(t, ErrDecryptionFailed, err)
+
+	_, err = Open(key, sealed[:10], []byte("ad"))
+	assert.Equal(t, ErrDecryptionFailed, err)
+}
+
+func TestSealWithPassphrase(t *testing.T) {
+	plain := []byte("private key share")
+
+	data, err := SealWithPassphrase("secret", plain)
+	assert.NoError(t, err)
+
+	opened, err := OpenWithPassphrase("secret", data)
+	assert.NoError(t, err)
+	assert.Equal(t, plain, opened)
+
+	_, err = OpenWithPassphrase("wrong", data)
+	assert.Equal(t, ErrDecryptionFailed, err)
+}
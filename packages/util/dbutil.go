@@ -5,13 +5,22 @@ import (
 	"github.com/iotaledger/hive.go/kvstore"
 )
 
+// DbSetMulti writes all keys in one batch: either all values are written or none.
+// The key with nil value is deleted
 func DbSetMulti(store kvstore.KVStore, keys [][]byte, values [][]byte) error {
 	if len(keys) != len(values) {
 		return fmt.Errorf("number of keys muts be equal to number of values")
 	}
 	atomic := store.Batched()
 	for i := range keys {
-		if err := atomic.Set(keys[i], values[i]); err != nil {
+		var err error
+		if values[i] == nil {
+			err = atomic.Delete(keys[i])
+		} else {
+			err = atomic.Set(keys[i], values[i])
+		}
+		if err != nil {
+			atomic.Cancel()
 			return err
 		}
 	}
@@ -1,7 +1,9 @@
 package builtin
 
 import (
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
 	"github.com/iotaledger/wasp/packages/sctransaction"
+	"github.com/iotaledger/wasp/packages/table"
 	"github.com/iotaledger/wasp/packages/vm/vmconst"
 	"github.com/iotaledger/wasp/packages/vm/vmtypes"
 )
@@ -11,10 +13,12 @@ type builtinProcessor map[sctransaction.RequestCode]builtinEntryPoint
 type builtinEntryPoint func(ctx vmtypes.Sandbox)
 
 var Processor = builtinProcessor{
-	vmconst.RequestCodeInit:             initRequest,
-	vmconst.RequestCodeNOP:              nopRequest,
-	vmconst.RequestCodeSetMinimumReward: setMinimumReward,
-	vmconst.RequestCodeSetDescription:   setDescription,
+	vmconst.RequestCodeInit:               initRequest,
+	vmconst.RequestCodeNOP:                nopRequest,
+	vmconst.RequestCodeSetMinimumReward:   setMinimumReward,
+	vmconst.RequestCodeSetDescription:     setDescription,
+	vmconst.RequestCodeSetConsensusParams: setConsensusParams,
+	vmconst.RequestCodeRotateCommittee:    rotateCommittee,
 }
 
 func (v *builtinProcessor) GetEntryPoint(code sctransaction.RequestCode) (vmtypes.EntryPoint, bool) {
@@ -94,3 +98,38 @@ func setDescription(ctx vmtypes.Sandbox) {
 		ctx.AccessState().Variables().SetString("description", v)
 	}
 }
+
+func setConsensusParams(ctx vmtypes.Sandbox) {
+	stub(ctx, "setConsensusParams")
+	for _, name := range vmconst.ConsensusParamNames {
+		v, ok, _ := ctx.AccessRequest().Args().GetInt64(table.Key(name))
+		switch {
+		case !ok || v < 0:
+			continue
+		case v == 0:
+			ctx.AccessState().Variables().Del(table.Key(name))
+		default:
+			ctx.AccessState().Variables().SetInt64(table.Key(name), v)
+		}
+	}
+}
+
+// rotateCommittee sets the address of the new committee. The VM moves the smart contract token
+// and balances to the address when the batch is finished
+func rotateCommittee(ctx vmtypes.Sandbox) {
+	stub(ctx, "rotateCommittee")
+	v, ok, _ := ctx.AccessRequest().Args().GetString(vmconst.VarNameCommitteeAddress)
+	if !ok {
+		ctx.GetLog().Debugf("rotateCommittee: address of the new committee not set")
+		return
+	}
+	addr, err := address.FromBase58(v)
+	if err != nil {
+		ctx.GetLog().Errorf("rotateCommittee: wrong address of the new committee: %v", err)
+		return
+	}
+	if addr == *ctx.GetOwnAddress() {
+		return
+	}
+	ctx.AccessState().Variables().SetAddress(vmconst.VarNameCommitteeAddress, &addr)
+}
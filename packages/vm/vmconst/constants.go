@@ -9,10 +9,32 @@ const (
 	RequestCodeInit             = sctransaction.RequestCode(uint16(1) | sctransaction.RequestCodeProtectedReserved)
 	RequestCodeSetMinimumReward = sctransaction.RequestCode(uint16(2) | sctransaction.RequestCodeProtectedReserved)
 	RequestCodeSetDescription   = sctransaction.RequestCode(uint16(3) | sctransaction.RequestCodeProtectedReserved)
+	// sets consensus parameters from the request arguments with the same names as state variables.
+	// Value 0 resets the parameter to the protocol default
+	RequestCodeSetConsensusParams = sctransaction.RequestCode(uint16(4) | sctransaction.RequestCodeProtectedReserved)
+	// rotates the smart contract to the committee with the address (base58) in the argument VarNameCommitteeAddress.
+	// The state transaction moves the smart contract token and all balances to the new address
+	RequestCodeRotateCommittee = sctransaction.RequestCode(uint16(5) | sctransaction.RequestCodeProtectedReserved)
 )
 
 const (
 	VarNameOwnerAddress  = "$owneraddr$"
 	VarNameProgramHash   = "$proghash$"
 	VarNameMinimumReward = "$minreward$"
+	// address of the committee the smart contract was rotated to
+	VarNameCommitteeAddress = "$committeeaddr$"
+
+	// consensus parameters. Durations are in milliseconds
+	VarNameMaxBatchSize   = "$maxbatchsize$"
+	VarNameMaxBatchVMTime = "$maxbatchvmtime$"
+	VarNameLeaderTimeout  = "$leadertimeout$"
+	VarNameMaxBacklog     = "$maxbacklog$"
 )
+
+// ConsensusParamNames are names of all consensus parameters
+var ConsensusParamNames = []string{
+	VarNameMaxBatchSize,
+	VarNameMaxBatchVMTime,
+	VarNameLeaderTimeout,
+	VarNameMaxBacklog,
+}
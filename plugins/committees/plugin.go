@@ -2,6 +2,7 @@ package committees
 
 import (
 	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/balance"
 	"github.com/iotaledger/hive.go/daemon"
 	"github.com/iotaledger/hive.go/logger"
 	"github.com/iotaledger/hive.go/node"
@@ -76,6 +77,11 @@ func ActivateCommittee(bootupData *registry.BootupData, subscribe bool) committe
 	committeesMutex.Lock()
 	defer committeesMutex.Unlock()
 
+	if bootupData.IsRotated() {
+		log.Infof("smart contract %s was rotated to the committee %s. Committee is not activated",
+			bootupData.Address.String(), bootupData.RotatedTo.String())
+		return nil
+	}
 	_, ok := committeesByAddress[bootupData.Address]
 	if ok {
 		log.Warnf("committee already active: %s", bootupData.Address)
@@ -104,3 +110,32 @@ func CommitteeByAddress(addr address.Address) committee.Committee {
 	}
 	return ret
 }
+
+// ActiveAddresses returns addresses of all active committees
+func ActiveAddresses() []address.Address {
+	committeesMutex.RLock()
+	defer committeesMutex.RUnlock()
+
+	ret := make([]address.Address, 0, len(committeesByAddress))
+	for addr, c := range committeesByAddress {
+		if !c.IsDismissed() {
+			ret = append(ret, addr)
+		}
+	}
+	return ret
+}
+
+// CommitteesByColor returns committees of the smart contract. There may be more than one
+// if the smart contract was rotated from one committee to another
+func CommitteesByColor(color balance.Color) []committee.Committee {
+	committeesMutex.RLock()
+	defer committeesMutex.RUnlock()
+
+	ret := make([]committee.Committee, 0, 1)
+	for _, c := range committeesByAddress {
+		if *c.Color() == color && !c.IsDismissed() {
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
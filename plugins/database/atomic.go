@@ -0,0 +1,223 @@
+package database
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/iotaledger/goshimmer/packages/database"
+	"github.com/iotaledger/hive.go/kvstore"
+	"go.etcd.io/bbolt"
+)
+
+// atomicDB wraps the database so that the batched mutations of its stores are written in one transaction.
+// Batches of the badger and bolt stores from hive.go are split into several transactions,
+// so a crash in the middle of the commit could leave the partition in an inconsistent state
+type atomicDB struct {
+	database.DB
+	commit   commitFunc
+	snapshot func() (snapshotSource, error)
+}
+
+// commitFunc writes all mutations of the realms in one transaction, in the order they were added
+type commitFunc func(batches []realmBatch) error
+
+// realmBatch is the list of mutations of one realm
+type realmBatch struct {
+	realm     kvstore.Realm
+	mutations []mutation
+}
+
+type mutation struct {
+	key    kvstore.Key
+	value  kvstore.Value
+	delete bool
+}
+
+func (db *atomicDB) NewStore() kvstore.KVStore {
+	return &atomicStore{
+		KVStore:     db.DB.NewStore(),
+		commit:      db.commit,
+		newSnapshot: db.snapshot,
+	}
+}
+
+type atomicStore struct {
+	kvstore.KVStore
+	commit      commitFunc
+	newSnapshot func() (snapshotSource, error)
+}
+
+func (s *atomicStore) WithRealm(realm kvstore.Realm) kvstore.KVStore {
+	return &atomicStore{
+		KVStore:     s.KVStore.WithRealm(realm),
+		commit:      s.commit,
+		newSnapshot: s.newSnapshot,
+	}
+}
+
+func (s *atomicStore) snapshot() (snapshotSource, error) {
+	return s.newSnapshot()
+}
+
+func (s *atomicStore) commitRealms(batches []realmBatch) error {
+	return s.commit(batches)
+}
+
+func (s *atomicStore) Batched() kvstore.BatchedMutations {
+	return &atomicBatch{
+		realm:  s.Realm(),
+		commit: s.commit,
+	}
+}
+
+type atomicBatch struct {
+	sync.Mutex
+	realm     kvstore.Realm
+	mutations []mutation
+	commit    commitFunc
+}
+
+func (b *atomicBatch) Set(key kvstore.Key, value kvstore.Value) error {
+	b.Lock()
+	defer b.Unlock()
+	b.mutations = append(b.mutations, mutation{key: concatBytes(key), value: concatBytes(value)})
+	return nil
+}
+
+func (b *atomicBatch) Delete(key kvstore.Key) error {
+	b.Lock()
+	defer b.Unlock()
+	b.mutations = append(b.mutations, mutation{key: concatBytes(key), delete: true})
+	return nil
+}
+
+func (b *atomicBatch) Cancel() {
+	b.Lock()
+	defer b.Unlock()
+	b.mutations = nil
+}
+
+func (b *atomicBatch) Commit() error {
+	b.Lock()
+	defer b.Unlock()
+	if len(b.mutations) == 0 {
+		return nil
+	}
+	err := b.commit([]realmBatch{{realm: b.realm, mutations: b.mutations}})
+	b.mutations = nil
+	return err
+}
+
+// commitHook, if set, is called inside the transaction of the atomic commit before each mutation is written,
+// with the number of mutations of the commit written so far. Only set by tests
+var commitHook func(written int)
+
+func beforeWrite(written int) {
+	if commitHook != nil {
+		commitHook(written)
+	}
+}
+
+// badger database of goshimmer embeds *badger.DB
+type badgerUpdater interface {
+	Update(fn func(txn *badger.Txn) error) error
+	NewTransaction(update bool) *badger.Txn
+}
+
+func newAtomicBadgerDB(dirname string) (database.DB, error) {
+	db, err := database.NewDB(dirname)
+	if err != nil {
+		return nil, err
+	}
+	bdb, ok := db.(badgerUpdater)
+	if !ok {
+		_ = db.Close()
+		return nil, fmt.Errorf("unexpected type of the badger database")
+	}
+	return &atomicDB{
+		DB: db,
+		commit: func(batches []realmBatch) error {
+			// fails with badger.ErrTxnTooBig rather than writing partially
+			return bdb.Update(func(txn *badger.Txn) error {
+				written := 0
+				for _, b := range batches {
+					for _, mut := range b.mutations {
+						beforeWrite(written)
+						written++
+						var err error
+						if mut.delete {
+							err = txn.Delete(concatBytes(b.realm, mut.key))
+						} else {
+							err = txn.Set(concatBytes(b.realm, mut.key), mut.value)
+						}
+						if err != nil {
+							return err
+						}
+					}
+				}
+				return nil
+			})
+		},
+		snapshot: func() (snapshotSource, error) {
+			return &badgerSnapshot{txn: bdb.NewTransaction(false)}, nil
+		},
+	}, nil
+}
+
+func newAtomicBoltDB(dirname string) (database.DB, error) {
+	db, err := newBoltDB(dirname)
+	if err != nil {
+		return nil, err
+	}
+	bdb := db.(*boltDB).DB
+	return &atomicDB{
+		DB: db,
+		commit: func(batches []realmBatch) error {
+			return bdb.Update(func(tx *bbolt.Tx) error {
+				written := 0
+				for _, b := range batches {
+					bucket, err := tx.CreateBucketIfNotExists(b.realm)
+					if err != nil {
+						return err
+					}
+					for _, mut := range b.mutations {
+						beforeWrite(written)
+						written++
+						if mut.delete {
+							err = bucket.Delete(mut.key)
+						} else {
+							err = bucket.Put(mut.key, mut.value)
+						}
+						if err != nil {
+							return err
+						}
+					}
+				}
+				return nil
+			})
+		},
+		snapshot: func() (snapshotSource, error) {
+			tx, err := bdb.Begin(false)
+			if err != nil {
+				return nil, err
+			}
+			return &boltSnapshot{tx: tx}, nil
+		},
+	}, nil
+}
+
+// multiRealmStore writes mutations of several realms in one transaction
+type multiRealmStore interface {
+	commitRealms(batches []realmBatch) error
+}
+
+// commitAtomically writes mutations of several realms of the store in one transaction.
+// All engines returned by NewDB support it
+func commitAtomically(store kvstore.KVStore, batches []realmBatch) error {
+	s, ok := store.(multiRealmStore)
+	if !ok {
+		return fmt.Errorf("store does not support atomic commit of several realms")
+	}
+	return s.commitRealms(batches)
+}
@@ -0,0 +1,461 @@
+package database
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"time"
+
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	"github.com/iotaledger/hive.go/kvstore"
+	"github.com/iotaledger/wasp/packages/util"
+)
+
+// Backup archive consists of:
+// - header: magic string, format version, DB schema version, timestamp, number of partitions
+// - partitions: realm (address), number of records, records (key, value)
+// - sha256 checksum of all preceding bytes
+// All partitions are read from one snapshot of the database, so the archive is consistent
+// even if committees of the node are running.
+// The archive is restored only to the database of the stopped node (see RestoreBackupToDir).
+
+const (
+	backupMagic         = "WASPBACKUP"
+	backupFormatVersion = byte(0)
+)
+
+var (
+	ErrBackupCorrupted       = errors.New("backup archive is corrupted")
+	ErrBackupVersionMismatch = errors.New("backup archive has different database schema version")
+	ErrBackupLiveCommittee   = errors.New("backup archive would overwrite partition of the running committee")
+	ErrBackupMasterKey       = errors.New("backup archive was made with a different master key")
+)
+
+// BackupPartition is a partition in the backup archive. Nil address means registry
+type BackupPartition struct {
+	Address    address.Address
+	NumRecords int
+	Records    map[string][]byte
+}
+
+// Backup is the decoded and verified backup archive
+type Backup struct {
+	DBVersion  byte
+	Timestamp  time.Time
+	Partitions []*BackupPartition
+}
+
+// checksumWriter computes hash of everything written
+type checksumWriter struct {
+	w io.Writer
+	h hash.Hash
+}
+
+func (cw *checksumWriter) Write(p []byte) (int, error) {
+	cw.h.Write(p)
+	return cw.w.Write(p)
+}
+
+// scAddresses returns addresses of all smart contracts with the bootup record in the registry
+func scAddresses(store kvstore.KVStore) ([]address.Address, error) {
+	ret := make([]address.Address, 0)
+	err := registryRealm(store).IterateKeys([]byte{ObjectTypeBootupData}, func(key kvstore.Key) bool {
+		var addr address.Address
+		if len(key) == 1+len(addr) {
+			copy(addr[:], key[1:])
+			ret = append(ret, addr)
+		}
+		return true
+	})
+	return ret, err
+}
+
+// WriteBackup writes the archive of the registry (if withRegistry) and partitions of the smart contracts.
+// Empty list of addresses means all smart contracts in the registry.
+// The archive is read from one snapshot. With the bolt engine writes may wait until the backup is written
+func WriteBackup(store kvstore.KVStore, w io.Writer, addrs []address.Address, withRegistry bool) (*Backup, error) {
+	store, release, err := snapshotOf(store)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	ver, exists, err := readDatabaseVersion(store)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("database schema version is not set")
+	}
+	if len(addrs) == 0 {
+		if addrs, err = scAddresses(store); err != nil {
+			return nil, err
+		}
+	}
+	var niladdr address.Address
+	if withRegistry {
+		addrs = append([]address.Address{niladdr}, addrs...)
+	}
+	info := &Backup{
+		DBVersion:  ver,
+		Timestamp:  time.Now(),
+		Partitions: make([]*BackupPartition, 0, len(addrs)),
+	}
+
+	bw := bufio.NewWriter(w)
+	cw := &checksumWriter{w: bw, h: sha256.New()}
+	if _, err = cw.Write([]byte(backupMagic)); err != nil {
+		return nil, err
+	}
+	if _, err = cw.Write([]byte{backupFormatVersion, ver}); err != nil {
+		return nil, err
+	}
+	if err = util.WriteUint64(cw, uint64(info.Timestamp.UnixNano())); err != nil {
+		return nil, err
+	}
+	if err = util.WriteUint16(cw, uint16(len(addrs))); err != nil {
+		return nil, err
+	}
+	for i := range addrs {
+		part, err := writeBackupPartition(cw, store, &addrs[i])
+		if err != nil {
+			return nil, err
+		}
+		info.Partitions = append(info.Partitions, part)
+	}
+	if _, err = bw.Write(cw.h.Sum(nil)); err != nil {
+		return nil, err
+	}
+	return info, bw.Flush()
+}
+
+// the partition is collected and then written.
+// Records are not kept in the returned partition info
+func writeBackupPartition(w io.Writer, store kvstore.KVStore, addr *address.Address) (*BackupPartition, error) {
+	var buf bytes.Buffer
+	num := uint32(0)
+	var errWrite error
+	err := store.WithRealm(addr[:]).Iterate(kvstore.EmptyPrefix, func(key kvstore.Key, value kvstore.Value) bool {
+		if errWrite = util.WriteBytes32(&buf, key); errWrite != nil {
+			return false
+		}
+		if errWrite = util.WriteBytes32(&buf, value); errWrite != nil {
+			return false
+		}
+		num++
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if errWrite != nil {
+		return nil, errWrite
+	}
+	if _, err = w.Write(addr[:]); err != nil {
+		return nil, err
+	}
+	if err = util.WriteUint32(w, num); err != nil {
+		return nil, err
+	}
+	if _, err = w.Write(buf.Bytes()); err != nil {
+		return nil, err
+	}
+	return &BackupPartition{Address: *addr, NumRecords: int(num)}, nil
+}
+
+// ReadBackup reads the archive and verifies its checksum
+func ReadBackup(r io.Reader) (*Backup, error) {
+	data, err := readAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < len(backupMagic)+sha256.Size {
+		return nil, ErrBackupCorrupted
+	}
+	content := data[:len(data)-sha256.Size]
+	checksum := sha256.Sum256(content)
+	if !bytes.Equal(checksum[:], data[len(content):]) {
+		return nil, fmt.Errorf("%w: wrong checksum", ErrBackupCorrupted)
+	}
+	rdr := bytes.NewReader(content)
+	magic := make([]byte, len(backupMagic))
+	if _, err = io.ReadFull(rdr, magic); err != nil || string(magic) != backupMagic {
+		return nil, fmt.Errorf("%w: not a backup archive", ErrBackupCorrupted)
+	}
+	var header [2]byte
+	if _, err = io.ReadFull(rdr, header[:]); err != nil {
+		return nil, ErrBackupCorrupted
+	}
+	if header[0] != backupFormatVersion {
+		return nil, fmt.Errorf("%w: unsupported format version %d", ErrBackupCorrupted, header[0])
+	}
+	ret := &Backup{DBVersion: header[1]}
+	var ts uint64
+	if err = util.ReadUint64(rdr, &ts); err != nil {
+		return nil, ErrBackupCorrupted
+	}
+	ret.Timestamp = time.Unix(0, int64(ts))
+	var numPartitions uint16
+	if err = util.ReadUint16(rdr, &numPartitions); err != nil {
+		return nil, ErrBackupCorrupted
+	}
+	ret.Partitions = make([]*BackupPartition, numPartitions)
+	for i := range ret.Partitions {
+		if ret.Partitions[i], err = readBackupPartition(rdr); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrBackupCorrupted, err)
+		}
+	}
+	if rdr.Len() != 0 {
+		return nil, fmt.Errorf("%w: unexpected data at the end", ErrBackupCorrupted)
+	}
+	return ret, nil
+}
+
+func readBackupPartition(r io.Reader) (*BackupPartition, error) {
+	ret := &BackupPartition{}
+	if _, err := io.ReadFull(r, ret.Address[:]); err != nil {
+		return nil, err
+	}
+	var num uint32
+	if err := util.ReadUint32(r, &num); err != nil {
+		return nil, err
+	}
+	ret.Records = make(map[string][]byte)
+	for i := uint32(0); i < num; i++ {
+		key, err := util.ReadBytes32(r)
+		if err != nil {
+			return nil, err
+		}
+		value, err := util.ReadBytes32(r)
+		if err != nil {
+			return nil, err
+		}
+		ret.Records[string(key)] = value
+	}
+	ret.NumRecords = len(ret.Records)
+	return ret, nil
+}
+
+func readAll(r io.Reader) ([]byte, error) {
+	var buf bytes.Buffer
+	_, err := buf.ReadFrom(r)
+	return buf.Bytes(), err
+}
+
+func (b *Backup) IsRegistry(part *BackupPartition) bool {
+	var niladdr address.Address
+	return part.Address == niladdr
+}
+
+// RestoreBackup writes partitions from the archive to the database.
+// The schema version of the archive must be the same as of the database.
+// Partitions of smart contracts are replaced. Records of the registry are added or overwritten,
+// except the schema version. Restoring partitions or bootup records of existing committees
+// (isLive returns true) requires force.
+// Must not be used with the database of the running node: it doesn't stop committees using the partitions
+func RestoreBackup(store kvstore.KVStore, backup *Backup, force bool, isLive func(addr *address.Address) bool) error {
+	ver, exists, err := readDatabaseVersion(store)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		ver = DBVersion
+	}
+	if backup.DBVersion != ver {
+		return fmt.Errorf("%w: archive: %d, database: %d", ErrBackupVersionMismatch, backup.DBVersion, ver)
+	}
+	if !force {
+		for _, part := range backup.Partitions {
+			for _, addr := range part.affectedAddresses(backup) {
+				if isLive(&addr) {
+					return fmt.Errorf("%w: %s. Use force to overwrite", ErrBackupLiveCommittee, addr.String())
+				}
+			}
+		}
+	}
+	for _, part := range backup.Partitions {
+		if backup.IsRegistry(part) {
+			err = restoreRegistry(store, part)
+		} else {
+			err = restorePartition(store, part)
+		}
+		if err != nil {
+			return fmt.Errorf("restoring partition %s: %w", part.Address.String(), err)
+		}
+	}
+	if !exists {
+		return writeDatabaseVersion(store, ver)
+	}
+	return nil
+}
+
+// affectedAddresses returns addresses of smart contracts which are changed by restoring the partition
+func (part *BackupPartition) affectedAddresses(backup *Backup) []address.Address {
+	if !backup.IsRegistry(part) {
+		return []address.Address{part.Address}
+	}
+	ret := make([]address.Address, 0)
+	for k := range part.Records {
+		var addr address.Address
+		if len(k) == 1+len(addr) && k[0] == ObjectTypeBootupData {
+			copy(addr[:], k[1:])
+			ret = append(ret, addr)
+		}
+	}
+	return ret
+}
+
+// records of the partition are restored in transactions of at most restoreChunkRecords records
+// and restoreChunkBytes bytes, so large partitions don't exceed the transaction limits of the engine
+var (
+	restoreChunkRecords = 1000
+	restoreChunkBytes   = 1 << 20
+)
+
+// restorePartition deletes all records of the partition and writes records of the archive, both in chunks.
+// The partition is inconsistent until all chunks are written, so a failed restore must be repeated
+func restorePartition(store kvstore.KVStore, part *BackupPartition) error {
+	realm := store.WithRealm(part.Address[:])
+	existing := make([][]byte, 0)
+	err := realm.IterateKeys(kvstore.EmptyPrefix, func(key kvstore.Key) bool {
+		existing = append(existing, key)
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	w := &chunkWriter{store: realm}
+	for _, key := range existing {
+		if err = w.write(key, nil); err != nil {
+			return err
+		}
+	}
+	// deletes are written before the records of the archive with the same keys
+	if err = w.flush(); err != nil {
+		return err
+	}
+	for k, v := range part.Records {
+		if err = w.write([]byte(k), v); err != nil {
+			return err
+		}
+	}
+	return w.flush()
+}
+
+// chunkWriter writes records in transactions of bounded size. Nil value deletes the key
+type chunkWriter struct {
+	store  kvstore.KVStore
+	keys   [][]byte
+	values [][]byte
+	size   int
+}
+
+func (w *chunkWriter) write(key, value []byte) error {
+	w.keys = append(w.keys, key)
+	w.values = append(w.values, value)
+	w.size += len(key) + len(value)
+	if len(w.keys) < restoreChunkRecords && w.size < restoreChunkBytes {
+		return nil
+	}
+	return w.flush()
+}
+
+func (w *chunkWriter) flush() error {
+	if len(w.keys) == 0 {
+		return nil
+	}
+	err := util.DbSetMulti(w.store, w.keys, w.values)
+	w.keys, w.values, w.size = nil, nil, 0
+	return err
+}
+
+func restoreRegistry(store kvstore.KVStore, part *BackupPartition) error {
+	reg := registryRealm(store)
+	keys := make([][]byte, 0, len(part.Records))
+	values := make([][]byte, 0, len(part.Records))
+	for k, v := range part.Records {
+		key := []byte(k)
+		switch {
+		case bytes.Equal(key, MakeKey(ObjectTypeDBSchemaVersion)):
+			continue
+		case bytes.Equal(key, MakeKey(ObjectTypeKeystore)):
+			// encrypted DKShares can only be restored with the same master key
+			existing, err := reg.Get(key)
+			if err == nil && !bytes.Equal(existing, v) {
+				return ErrBackupMasterKey
+			}
+			if err != nil && err != kvstore.ErrKeyNotFound {
+				return err
+			}
+		}
+		keys = append(keys, key)
+		values = append(values, v)
+	}
+	return util.DbSetMulti(reg, keys, values)
+}
+
+// RestoreBackupToDir restores the archive to the database of the stopped node in the directory.
+// The archive is restored to a copy of the database, which replaces the directory only if the restore succeeded,
+// so a failed or interrupted restore leaves the database unchanged.
+// Committees with the bootup record in the registry are live: they are activated when the node starts
+func RestoreBackupToDir(engine, dir string, backup *Backup, force bool) error {
+	tmpDir := dir + ".restore"
+	if err := os.RemoveAll(tmpDir); err != nil {
+		return err
+	}
+	_, err := os.Stat(dir)
+	exists := err == nil
+	if exists {
+		if err = copyDir(dir, tmpDir); err != nil {
+			_ = os.RemoveAll(tmpDir)
+			return err
+		}
+	}
+	if err = restoreToNewDir(engine, tmpDir, backup, force); err != nil {
+		_ = os.RemoveAll(tmpDir)
+		return err
+	}
+	if !exists {
+		return os.Rename(tmpDir, dir)
+	}
+	// the previous database is kept until the restored one is in place
+	oldDir := dir + ".old"
+	if err = os.RemoveAll(oldDir); err != nil {
+		return err
+	}
+	if err = os.Rename(dir, oldDir); err != nil {
+		return err
+	}
+	if err = os.Rename(tmpDir, dir); err != nil {
+		_ = os.Rename(oldDir, dir)
+		return err
+	}
+	return os.RemoveAll(oldDir)
+}
+
+func restoreToNewDir(engine, dir string, backup *Backup, force bool) error {
+	db, err := NewDB(engine, dir)
+	if err != nil {
+		return err
+	}
+	store := db.NewStore()
+	registry := registryRealm(store)
+	err = RestoreBackup(store, backup, force, func(addr *address.Address) bool {
+		ok, err := registry.Has(MakeKey(ObjectTypeBootupData, addr[:]))
+		return ok || err != nil
+	})
+	if errClose := db.Close(); err == nil {
+		err = errClose
+	}
+	return err
+}
+
+// WriteNodeBackup writes the archive of the node's database
+func WriteNodeBackup(w io.Writer, addrs []address.Address, withRegistry bool) (*Backup, error) {
+	return WriteBackup(storeInstance(), w, addrs, withRegistry)
+}
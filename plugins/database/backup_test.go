@@ -0,0 +1,218 @@
+package database
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	"github.com/iotaledger/hive.go/kvstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestBackupStore(t *testing.T) (kvstore.KVStore, address.Address) {
+	db, err := NewDB(EngineMemory, "")
+	assert.NoError(t, err)
+	s := db.NewStore()
+
+	addr := address.Random()
+	assert.NoError(t, writeDatabaseVersion(s, DBVersion))
+	assert.NoError(t, registryRealm(s).Set(MakeKey(ObjectTypeBootupData, addr[:]), []byte("bootup")))
+	assert.NoError(t, registryRealm(s).Set(MakeKey(ObjectTypeKeystore), []byte("keystore")))
+	assert.NoError(t, s.WithRealm(addr[:]).Set([]byte("k1"), []byte("v1")))
+	assert.NoError(t, s.WithRealm(addr[:]).Set([]byte("k2"), []byte("v2")))
+	return s, addr
+}
+
+func notLive(*address.Address) bool {
+	return false
+}
+
+func writeTestBackup(t *testing.T, s kvstore.KVStore) []byte {
+	var buf bytes.Buffer
+	backup, err := WriteBackup(s, &buf, nil, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(backup.Partitions))
+	return buf.Bytes()
+}
+
+func TestBackupRoundTrip(t *testing.T) {
+	src, addr := newTestBackupStore(t)
+	data := writeTestBackup(t, src)
+
+	backup, err := ReadBackup(bytes.NewReader(data))
+	assert.NoError(t, err)
+	assert.EqualValues(t, DBVersion, backup.DBVersion)
+	assert.Equal(t, 2, len(backup.Partitions))
+	assert.True(t, backup.IsRegistry(backup.Partitions[0]))
+	assert.Equal(t, addr, backup.Partitions[1].Address)
+	assert.Equal(t, 2, backup.Partitions[1].NumRecords)
+
+	// restoring to the new database
+	db, err := NewDB(EngineMemory, "")
+	assert.NoError(t, err)
+	dst := db.NewStore()
+	assert.NoError(t, RestoreBackup(dst, backup, false, notLive))
+
+	v, err := dst.WithRealm(addr[:]).Get([]byte("k2"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("v2"), v)
+	v, err = registryRealm(dst).Get(MakeKey(ObjectTypeBootupData, addr[:]))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("bootup"), v)
+	ver, exists, err := readDatabaseVersion(dst)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	assert.EqualValues(t, DBVersion, ver)
+
+	// partition is replaced
+	part := src.WithRealm(addr[:])
+	assert.NoError(t, part.Set([]byte("k3"), []byte("v3")))
+	assert.NoError(t, part.Set([]byte("k1"), []byte("changed")))
+	assert.NoError(t, RestoreBackup(src, backup, false, notLive))
+	assert.Equal(t, 2, count(t, part))
+	v, err = part.Get([]byte("k1"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("v1"), v)
+}
+
+func TestBackupCorrupted(t *testing.T) {
+	src, _ := newTestBackupStore(t)
+	data := writeTestBackup(t, src)
+
+	data[len(backupMagic)+5] ^= 0xff
+	_, err := ReadBackup(bytes.NewReader(data))
+	assert.True(t, errors.Is(err, ErrBackupCorrupted))
+
+	_, err = ReadBackup(bytes.NewReader(data[:10]))
+	assert.True(t, errors.Is(err, ErrBackupCorrupted))
+}
+
+func TestBackupVersionMismatch(t *testing.T) {
+	src, _ := newTestBackupStore(t)
+	backup, err := ReadBackup(bytes.NewReader(writeTestBackup(t, src)))
+	assert.NoError(t, err)
+
+	assert.NoError(t, writeDatabaseVersion(src, DBVersion+1))
+	err = RestoreBackup(src, backup, true, notLive)
+	assert.True(t, errors.Is(err, ErrBackupVersionMismatch))
+}
+
+func TestBackupLiveCommittee(t *testing.T) {
+	src, addr := newTestBackupStore(t)
+	backup, err := ReadBackup(bytes.NewReader(writeTestBackup(t, src)))
+	assert.NoError(t, err)
+
+	part := src.WithRealm(addr[:])
+	assert.NoError(t, part.Set([]byte("k1"), []byte("changed")))
+	isLive := func(a *address.Address) bool {
+		return *a == addr
+	}
+	err = RestoreBackup(src, backup, false, isLive)
+	assert.True(t, errors.Is(err, ErrBackupLiveCommittee))
+	v, err := part.Get([]byte("k1"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("changed"), v)
+
+	assert.NoError(t, RestoreBackup(src, backup, true, isLive))
+	v, err = part.Get([]byte("k1"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("v1"), v)
+}
+
+func TestBackupMasterKey(t *testing.T) {
+	src, _ := newTestBackupStore(t)
+	backup, err := ReadBackup(bytes.NewReader(writeTestBackup(t, src)))
+	assert.NoError(t, err)
+
+	assert.NoError(t, registryRealm(src).Set(MakeKey(ObjectTypeKeystore), []byte("other keystore")))
+	err = RestoreBackup(src, backup, true, notLive)
+	assert.True(t, errors.Is(err, ErrBackupMasterKey))
+}
+
+// large partitions are restored in several transactions and replace all existing records
+func TestBackupLargePartition(t *testing.T) {
+	dir, err := ioutil.TempDir("", "waspdb-backup")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	db, err := NewDB(EngineBadger, dir)
+	assert.NoError(t, err)
+	defer db.Close()
+	s := db.NewStore()
+	assert.NoError(t, writeDatabaseVersion(s, DBVersion))
+
+	// more records than badger accepts in one transaction
+	addr := address.Random()
+	const numRecords = 150000
+	value := []byte{1}
+	part := &BackupPartition{Address: addr, Records: make(map[string][]byte)}
+	for i := 0; i < numRecords; i++ {
+		part.Records[fmt.Sprintf("k%d", i)] = value
+	}
+	backup := &Backup{DBVersion: DBVersion, Partitions: []*BackupPartition{part}}
+	realm := s.WithRealm(addr[:])
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, realm.Set([]byte(fmt.Sprintf("extra%d", i)), value))
+	}
+
+	assert.NoError(t, RestoreBackup(s, backup, false, notLive))
+	assert.Equal(t, numRecords, count(t, realm))
+	has, err := realm.Has([]byte("extra0"))
+	assert.NoError(t, err)
+	assert.False(t, has)
+
+	// restored again over the restored partition
+	assert.NoError(t, RestoreBackup(s, backup, false, notLive))
+	assert.Equal(t, numRecords, count(t, realm))
+}
+
+// the archive is restored to the copy of the database, which replaces the database only if the restore succeeded
+func TestRestoreBackupToDir(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "waspdb-restore")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmp)
+	dir := filepath.Join(tmp, "waspdb")
+
+	src, addr := newTestBackupStore(t)
+	backup, err := ReadBackup(bytes.NewReader(writeTestBackup(t, src)))
+	assert.NoError(t, err)
+
+	// new database
+	assert.NoError(t, RestoreBackupToDir(EngineBolt, dir, backup, false))
+
+	// existing committee without force
+	err = RestoreBackupToDir(EngineBolt, dir, backup, false)
+	assert.True(t, errors.Is(err, ErrBackupLiveCommittee))
+
+	db, err := NewDB(EngineBolt, dir)
+	assert.NoError(t, err)
+	part := db.NewStore().WithRealm(addr[:])
+	assert.NoError(t, part.Set([]byte("k1"), []byte("changed")))
+	assert.NoError(t, db.Close())
+
+	// failed restore leaves the database unchanged
+	backup.DBVersion++
+	err = RestoreBackupToDir(EngineBolt, dir, backup, true)
+	assert.True(t, errors.Is(err, ErrBackupVersionMismatch))
+	checkValue := func(expected string) {
+		db, err := NewDB(EngineBolt, dir)
+		assert.NoError(t, err)
+		defer db.Close()
+		v, err := db.NewStore().WithRealm(addr[:]).Get([]byte("k1"))
+		assert.NoError(t, err)
+		assert.Equal(t, []byte(expected), v)
+	}
+	checkValue("changed")
+
+	backup.DBVersion--
+	assert.NoError(t, RestoreBackupToDir(EngineBolt, dir, backup, true))
+	checkValue("v1")
+
+	entries, err := ioutil.ReadDir(tmp)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
@@ -0,0 +1,55 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/iotaledger/goshimmer/packages/database"
+	"github.com/iotaledger/hive.go/kvstore"
+	boltstore "github.com/iotaledger/hive.go/kvstore/bolt"
+	"go.etcd.io/bbolt"
+)
+
+const (
+	boltDBFileName = "wasp.db"
+	// how long to wait for the file lock held by another process
+	boltOpenTimeout = 1 * time.Second
+)
+
+type boltDB struct {
+	*bbolt.DB
+}
+
+// newBoltDB opens or creates bbolt database file in the directory.
+// Each realm is stored in a separate bucket
+func newBoltDB(dirname string) (database.DB, error) {
+	if err := os.MkdirAll(dirname, 0700); err != nil {
+		return nil, fmt.Errorf("could not create DB directory: %w", err)
+	}
+	opts := *bbolt.DefaultOptions
+	opts.Timeout = boltOpenTimeout
+
+	db, err := boltstore.CreateDB(dirname, boltDBFileName, &opts)
+	if err != nil {
+		return nil, err
+	}
+	return &boltDB{DB: db}, nil
+}
+
+func (db *boltDB) NewStore() kvstore.KVStore {
+	return boltstore.New(db.DB)
+}
+
+func (db *boltDB) Close() error {
+	return db.DB.Close()
+}
+
+// bbolt reuses freed pages itself
+func (db *boltDB) RequiresGC() bool {
+	return false
+}
+
+func (db *boltDB) GC() error {
+	return nil
+}
@@ -0,0 +1,39 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/iotaledger/goshimmer/packages/database"
+)
+
+// storage engines which can be selected with the 'database.engine' option
+const (
+	// EngineBadger is the default engine. Fast writes, needs periodic value log GC
+	EngineBadger = "badger"
+	// EngineBolt is a single file B+tree. Small memory footprint, slower writes
+	EngineBolt = "bolt"
+	// EnginePebble is an LSM tree compacting in the background. No GC needed
+	EnginePebble = "pebble"
+	// EngineMemory keeps everything in memory (pebble on the in-memory file system). Nothing is persisted
+	EngineMemory = "memory"
+)
+
+// Engines lists all supported storage engines
+var Engines = []string{EngineBadger, EngineBolt, EnginePebble, EngineMemory}
+
+// NewDB opens the database with the given storage engine in the directory.
+// The directory is ignored by the in-memory engine.
+// Batched mutations of the stores are committed atomically by all engines
+func NewDB(engine string, dir string) (database.DB, error) {
+	switch engine {
+	case EngineBadger:
+		return newAtomicBadgerDB(dir)
+	case EngineBolt:
+		return newAtomicBoltDB(dir)
+	case EnginePebble:
+		return newPebbleDB(dir)
+	case EngineMemory:
+		return newPebbleMemDB()
+	}
+	return nil, fmt.Errorf("unknown database engine '%s'. Supported engines: %v", engine, Engines)
+}
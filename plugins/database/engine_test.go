@@ -0,0 +1,355 @@
+package database
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/iotaledger/goshimmer/packages/database"
+	"github.com/iotaledger/hive.go/kvstore"
+	"github.com/stretchr/testify/assert"
+)
+
+// conformance tests, run against every engine through the kvstore.KVStore interface
+
+func forEachEngine(t *testing.T, f func(t *testing.T, engine string, db database.DB, dir string)) {
+	for _, engine := range Engines {
+		t.Run(engine, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "waspdb-"+engine)
+			assert.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			db, err := NewDB(engine, dir)
+			assert.NoError(t, err)
+			f(t, engine, db, dir)
+		})
+	}
+}
+
+func TestEngineUnknown(t *testing.T) {
+	_, err := NewDB("leveldb", "tmp")
+	assert.Error(t, err)
+}
+
+func TestEngineSetGet(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, _ string, db database.DB, _ string) {
+		defer db.Close()
+		s := db.NewStore().WithRealm([]byte("realm"))
+
+		_, err := s.Get([]byte("k"))
+		assert.Equal(t, kvstore.ErrKeyNotFound, err)
+		has, err := s.Has([]byte("k"))
+		assert.NoError(t, err)
+		assert.False(t, has)
+
+		assert.NoError(t, s.Set([]byte("k"), []byte("v1")))
+		v, err := s.Get([]byte("k"))
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("v1"), v)
+		has, err = s.Has([]byte("k"))
+		assert.NoError(t, err)
+		assert.True(t, has)
+
+		assert.NoError(t, s.Set([]byte("k"), []byte("v2")))
+		v, err = s.Get([]byte("k"))
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("v2"), v)
+
+		assert.NoError(t, s.Delete([]byte("k")))
+		_, err = s.Get([]byte("k"))
+		assert.Equal(t, kvstore.ErrKeyNotFound, err)
+	})
+}
+
+func TestEngineRealms(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, _ string, db database.DB, _ string) {
+		defer db.Close()
+		r1 := db.NewStore().WithRealm([]byte("realm1"))
+		r2 := db.NewStore().WithRealm([]byte("realm2"))
+		assert.Equal(t, []byte("realm1"), r1.Realm())
+
+		assert.NoError(t, r1.Set([]byte("k"), []byte("v1")))
+		assert.NoError(t, r2.Set([]byte("k"), []byte("v2")))
+
+		v, err := r1.Get([]byte("k"))
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("v1"), v)
+
+		// same realm on a different store object sees the same data
+		v, err = db.NewStore().WithRealm([]byte("realm2")).Get([]byte("k"))
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("v2"), v)
+
+		assert.NoError(t, r1.Clear())
+		assert.EqualValues(t, 0, count(t, r1))
+		assert.EqualValues(t, 1, count(t, r2))
+	})
+}
+
+func TestEngineCommitAtomically(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, _ string, db database.DB, _ string) {
+		defer db.Close()
+		s := db.NewStore()
+		r1 := s.WithRealm([]byte("realm1"))
+		assert.NoError(t, r1.Set([]byte("old"), []byte("v")))
+
+		assert.NoError(t, commitAtomically(s, []realmBatch{
+			{realm: []byte("realm1"), mutations: []mutation{{key: []byte("old"), delete: true}}},
+			{realm: []byte("realm2"), mutations: []mutation{{key: []byte("k"), value: []byte("v2")}}},
+		}))
+		has, err := r1.Has([]byte("old"))
+		assert.NoError(t, err)
+		assert.False(t, has)
+		v, err := s.WithRealm([]byte("realm2")).Get([]byte("k"))
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("v2"), v)
+	})
+}
+
+const (
+	envCrashEngine = "WASP_ENGINE_CRASH_ENGINE"
+	envCrashDir    = "WASP_ENGINE_CRASH_DIR"
+	envCrashAfter  = "WASP_ENGINE_CRASH_AFTER"
+	crashExitCode  = 3
+)
+
+// crashBatches are 2 mutations in each of 2 realms
+func crashBatches() []realmBatch {
+	return []realmBatch{
+		{realm: []byte("realm1"), mutations: []mutation{
+			{key: []byte("k1"), value: []byte("new")},
+			{key: []byte("old"), delete: true},
+		}},
+		{realm: []byte("realm2"), mutations: []mutation{
+			{key: []byte("k1"), value: []byte("new")},
+			{key: []byte("k2"), value: []byte("new")},
+		}},
+	}
+}
+
+// the child process exits inside the engine transaction after the number of written mutations
+func runCrashingCommitAtomically(t *testing.T, engine, dir string, crashAfter int) {
+	db, err := NewDB(engine, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := db.NewStore()
+	if err = s.WithRealm([]byte("realm1")).Set([]byte("old"), []byte("v")); err != nil {
+		t.Fatal(err)
+	}
+	SetCommitHook(func(written int) {
+		if written == crashAfter {
+			os.Exit(crashExitCode)
+		}
+	})
+	if err = commitAtomically(s, crashBatches()); err != nil {
+		t.Fatal(err)
+	}
+	os.Exit(crashExitCode)
+}
+
+// mutations of all realms are written all-or-nothing when the process crashes in the middle of the commit
+func TestEngineCommitCrash(t *testing.T) {
+	if engine := os.Getenv(envCrashEngine); engine != "" {
+		crashAfter, err := strconv.Atoi(os.Getenv(envCrashAfter))
+		if err != nil {
+			t.Fatal(err)
+		}
+		runCrashingCommitAtomically(t, engine, os.Getenv(envCrashDir), crashAfter)
+		return
+	}
+	for _, engine := range []string{EngineBadger, EngineBolt, EnginePebble} {
+		for crashAfter := 0; crashAfter <= 4; crashAfter++ {
+			t.Run(fmt.Sprintf("%s/%d", engine, crashAfter), func(t *testing.T) {
+				dir, err := ioutil.TempDir("", "waspdb-crash")
+				assert.NoError(t, err)
+				defer os.RemoveAll(dir)
+
+				cmd := exec.Command(os.Args[0], "-test.run=^TestEngineCommitCrash$")
+				cmd.Env = append(os.Environ(),
+					envCrashEngine+"="+engine,
+					envCrashDir+"="+dir,
+					envCrashAfter+"="+strconv.Itoa(crashAfter),
+				)
+				out, err := cmd.CombinedOutput()
+				exitErr, ok := err.(*exec.ExitError)
+				if !ok || exitErr.ExitCode() != crashExitCode {
+					t.Fatalf("child process didn't crash as expected: %v\n%s", err, out)
+				}
+
+				db, err := NewDB(engine, dir)
+				assert.NoError(t, err)
+				defer db.Close()
+				s := db.NewStore()
+				committed := crashAfter == 4
+				for _, b := range crashBatches() {
+					for _, mut := range b.mutations {
+						has, err := s.WithRealm(b.realm).Has(mut.key)
+						assert.NoError(t, err)
+						assert.Equal(t, committed != mut.delete, has, "%s/%s", b.realm, mut.key)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestEngineSnapshot(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, engine string, db database.DB, _ string) {
+		defer db.Close()
+		s := db.NewStore()
+		r1 := s.WithRealm([]byte("realm1"))
+		r2 := s.WithRealm([]byte("realm2"))
+		assert.NoError(t, r1.Set([]byte("a1"), []byte("v1")))
+		assert.NoError(t, r2.Set([]byte("a1"), []byte("v2")))
+
+		snap, release, err := snapshotOf(s)
+		assert.NoError(t, err)
+
+		// changes after the snapshot are not seen.
+		// Bolt writers may wait until read transactions are finished, so they finish after the release
+		written := make(chan struct{})
+		go func() {
+			assert.NoError(t, r1.Set([]byte("a2"), []byte("v1")))
+			assert.NoError(t, r2.Delete([]byte("a1")))
+			close(written)
+		}()
+		if engine != EngineBolt {
+			<-written
+		}
+
+		assert.EqualValues(t, 1, count(t, snap.WithRealm([]byte("realm1"))))
+		v, err := snap.WithRealm([]byte("realm2")).Get([]byte("a1"))
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("v2"), v)
+		has, err := snap.WithRealm([]byte("realm1")).Has([]byte("a2"))
+		assert.NoError(t, err)
+		assert.False(t, has)
+		_, err = snap.WithRealm([]byte("realm3")).Get([]byte("a1"))
+		assert.Equal(t, kvstore.ErrKeyNotFound, err)
+
+		assert.Error(t, snap.Set([]byte("a3"), []byte("v")))
+		release()
+		<-written
+	})
+}
+
+func TestEngineIterate(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, _ string, db database.DB, _ string) {
+		defer db.Close()
+		s := db.NewStore().WithRealm([]byte("realm"))
+		for _, k := range []string{"a1", "a2", "a3", "b1", "b2"} {
+			assert.NoError(t, s.Set([]byte(k), []byte("v"+k)))
+		}
+
+		keys := make([]string, 0)
+		err := s.Iterate([]byte("a"), func(k kvstore.Key, v kvstore.Value) bool {
+			// keys are returned without the realm
+			assert.Equal(t, "v"+string(k), string(v))
+			keys = append(keys, string(k))
+			return true
+		})
+		assert.NoError(t, err)
+		sort.Strings(keys)
+		assert.Equal(t, []string{"a1", "a2", "a3"}, keys)
+
+		keys = keys[:0]
+		err = s.IterateKeys(kvstore.EmptyPrefix, func(k kvstore.Key) bool {
+			keys = append(keys, string(k))
+			return true
+		})
+		assert.NoError(t, err)
+		sort.Strings(keys)
+		assert.Equal(t, []string{"a1", "a2", "a3", "b1", "b2"}, keys)
+
+		// returning false stops the iteration
+		n := 0
+		err = s.IterateKeys(kvstore.EmptyPrefix, func(k kvstore.Key) bool {
+			n++
+			return false
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, n)
+
+		assert.NoError(t, s.DeletePrefix([]byte("a")))
+		assert.EqualValues(t, 2, count(t, s))
+	})
+}
+
+func TestEngineBatched(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, _ string, db database.DB, _ string) {
+		defer db.Close()
+		s := db.NewStore().WithRealm([]byte("realm"))
+		assert.NoError(t, s.Set([]byte("old"), []byte("v")))
+
+		b := s.Batched()
+		assert.NoError(t, b.Set([]byte("k1"), []byte("v1")))
+		assert.NoError(t, b.Set([]byte("k2"), []byte("v2")))
+		assert.NoError(t, b.Delete([]byte("old")))
+		assert.NoError(t, b.Commit())
+
+		v, err := s.Get([]byte("k2"))
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("v2"), v)
+		has, err := s.Has([]byte("old"))
+		assert.NoError(t, err)
+		assert.False(t, has)
+		assert.EqualValues(t, 2, count(t, s))
+	})
+}
+
+func TestEnginePersistence(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, engine string, db database.DB, dir string) {
+		if engine == EngineMemory {
+			assert.NoError(t, db.Close())
+			return
+		}
+		assert.NoError(t, db.NewStore().WithRealm([]byte("realm")).Set([]byte("k"), []byte("v")))
+		assert.NoError(t, db.Close())
+
+		db, err := NewDB(engine, dir)
+		assert.NoError(t, err)
+		defer db.Close()
+
+		v, err := db.NewStore().WithRealm([]byte("realm")).Get([]byte("k"))
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("v"), v)
+	})
+}
+
+func TestEngineBatchedOrder(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, _ string, db database.DB, _ string) {
+		defer db.Close()
+		s := db.NewStore().WithRealm([]byte("realm"))
+
+		// mutations are applied in the order they were added
+		b := s.Batched()
+		assert.NoError(t, b.Set([]byte("k1"), []byte("v1")))
+		assert.NoError(t, b.Delete([]byte("k1")))
+		assert.NoError(t, b.Delete([]byte("k2")))
+		assert.NoError(t, b.Set([]byte("k2"), []byte("v2")))
+		assert.NoError(t, b.Commit())
+
+		has, err := s.Has([]byte("k1"))
+		assert.NoError(t, err)
+		assert.False(t, has)
+		v, err := s.Get([]byte("k2"))
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("v2"), v)
+
+		// nothing is written before the commit and after the cancel
+		b = s.Batched()
+		assert.NoError(t, b.Set([]byte("k3"), []byte("v3")))
+		has, err = s.Has([]byte("k3"))
+		assert.NoError(t, err)
+		assert.False(t, has)
+		b.Cancel()
+		has, err = s.Has([]byte("k3"))
+		assert.NoError(t, err)
+		assert.False(t, has)
+	})
+}
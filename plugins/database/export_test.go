@@ -0,0 +1,7 @@
+package database
+
+// SetCommitHook sets the function called inside the atomic commit of every engine before each mutation is written.
+// Crash recovery tests exit the process there to check that the commit is all-or-nothing
+func SetCommitHook(hook func(written int)) {
+	commitHook = hook
+}
@@ -0,0 +1,375 @@
+package database
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	"github.com/iotaledger/hive.go/kvstore"
+	"github.com/iotaledger/hive.go/logger"
+	"github.com/iotaledger/wasp/plugins/config"
+)
+
+// Migration is a step which upgrades the database schema from version Version-1 to Version
+type Migration struct {
+	Version     byte
+	Description string
+	// Apply rewrites records through the context. Mutations of all partitions are written
+	// together with the new version when the step finishes. Big steps are written in several
+	// transactions; if they are interrupted, the database is rolled back from the backup.
+	// Big steps are refused without the backup
+	Apply func(ctx *MigrationContext) error
+}
+
+// maxMigrationChunk is the maximum number of mutations written by the migration step in one transaction,
+// so that the transaction size limit of the engine (badger.ErrTxnTooBig) is not hit on big databases
+var maxMigrationChunk = 10000
+
+// migrations are all upgrade steps of the schema, starting from version 1.
+// The last one must be to DBVersion
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "add origin and rotation addresses to bootup records",
+		Apply:       migrateBootupRotation,
+	},
+}
+
+// MigrationContext gives access to the database for the migration step.
+// Reads go directly to the database, mutations are collected and written when the step finishes.
+// Reads do not see mutations of the same step
+type MigrationContext struct {
+	store     kvstore.KVStore
+	dryRun    bool
+	backupDir string
+	batches   []realmBatch
+	numSet    int
+	numDel    int
+}
+
+func newMigrationContext(store kvstore.KVStore, dryRun bool, backupDir string) *MigrationContext {
+	return &MigrationContext{
+		store:     store,
+		dryRun:    dryRun,
+		backupDir: backupDir,
+		batches:   make([]realmBatch, 0),
+	}
+}
+
+// Registry returns the registry partition
+func (ctx *MigrationContext) Registry() kvstore.KVStore {
+	return registryRealm(ctx.store)
+}
+
+// Partition returns the partition of the smart contract
+func (ctx *MigrationContext) Partition(addr *address.Address) kvstore.KVStore {
+	return ctx.store.WithRealm(addr[:])
+}
+
+// Addresses returns addresses of all smart contracts with the bootup record in the registry
+func (ctx *MigrationContext) Addresses() ([]address.Address, error) {
+	return scAddresses(ctx.store)
+}
+
+// Set schedules the key to be set in the partition
+func (ctx *MigrationContext) Set(partition kvstore.KVStore, key kvstore.Key, value kvstore.Value) error {
+	ctx.numSet++
+	ctx.add(partition.Realm(), mutation{key: concatBytes(key), value: concatBytes(value)})
+	return nil
+}
+
+// Delete schedules the key to be deleted from the partition
+func (ctx *MigrationContext) Delete(partition kvstore.KVStore, key kvstore.Key) error {
+	ctx.numDel++
+	ctx.add(partition.Realm(), mutation{key: concatBytes(key), delete: true})
+	return nil
+}
+
+func (ctx *MigrationContext) add(realm kvstore.Realm, mut mutation) {
+	for i := range ctx.batches {
+		if bytes.Equal(ctx.batches[i].realm, realm) {
+			ctx.batches[i].mutations = append(ctx.batches[i].mutations, mut)
+			return
+		}
+	}
+	ctx.batches = append(ctx.batches, realmBatch{
+		realm:     concatBytes(realm),
+		mutations: []mutation{mut},
+	})
+}
+
+func (ctx *MigrationContext) cancel() {
+	ctx.batches = nil
+}
+
+// commit writes the mutations of the step and the new version.
+// If they do not fit into one transaction, the migration marker is written first and deleted
+// together with the new version in the last transaction. Without the backup an interrupted
+// migration can't be rolled back, so such steps are refused before anything is written
+func (ctx *MigrationContext) commit(ver byte) error {
+	if ctx.dryRun {
+		ctx.cancel()
+		return nil
+	}
+	chunks := splitBatches(ctx.batches, maxMigrationChunk)
+	if len(chunks) > 1 && ctx.backupDir == "" {
+		ctx.cancel()
+		return fmt.Errorf("%d mutations don't fit into one transaction of %d and there is no backup to roll back from "+
+			"if the migration is interrupted. Enable %s", ctx.numSet+ctx.numDel, maxMigrationChunk, CfgDatabaseMigrationBackup)
+	}
+	if len(chunks) > 1 {
+		if err := writeMigrationMarker(ctx.store, ctx.backupDir); err != nil {
+			return err
+		}
+	}
+	last := len(chunks) - 1
+	chunks[last] = append(chunks[last], realmBatch{
+		realm: ctx.Registry().Realm(),
+		mutations: []mutation{
+			{key: MakeKey(ObjectTypeDBSchemaVersion), value: versionData(ver)},
+			{key: MakeKey(ObjectTypeDBMigration), delete: true},
+		},
+	})
+	for _, chunk := range chunks {
+		if err := commitAtomically(ctx.store, chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitBatches splits mutations into chunks of at most max mutations, keeping their order.
+// Always returns at least one chunk
+func splitBatches(batches []realmBatch, max int) [][]realmBatch {
+	ret := [][]realmBatch{nil}
+	size := 0
+	for _, b := range batches {
+		muts := b.mutations
+		for len(muts) > 0 {
+			if size == max {
+				ret = append(ret, nil)
+				size = 0
+			}
+			n := max - size
+			if n > len(muts) {
+				n = len(muts)
+			}
+			ret[len(ret)-1] = append(ret[len(ret)-1], realmBatch{realm: b.realm, mutations: muts[:n]})
+			size += n
+			muts = muts[n:]
+		}
+	}
+	return ret
+}
+
+// writeMigrationMarker marks the database as being migrated. The value is the backup folder
+// to roll back from if the migration is interrupted, empty if there is no backup
+func writeMigrationMarker(store kvstore.KVStore, backupDir string) error {
+	return registryRealm(store).Set(MakeKey(ObjectTypeDBMigration), []byte(backupDir))
+}
+
+// readMigrationMarker returns true if the migration was interrupted, with the backup folder written in the marker
+func readMigrationMarker(store kvstore.KVStore) (string, bool, error) {
+	v, err := registryRealm(store).Get(MakeKey(ObjectTypeDBMigration))
+	if err == kvstore.ErrKeyNotFound {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return string(v), true, nil
+}
+
+// runMigrations upgrades the database from version 'from' to version 'to'.
+// In dry run mode nothing is written, only the number of mutations is reported.
+// Note that in dry run each step sees the data as it was before the previous steps.
+// backupDir is recorded in the migration marker of steps written in several transactions
+func runMigrations(store kvstore.KVStore, steps []Migration, from, to byte, dryRun bool, backupDir string, log *logger.Logger) error {
+	if from > to {
+		return fmt.Errorf("%w: supported version: %d, version of database: %d", ErrDBVersionIncompatible, to, from)
+	}
+	for v := int(from) + 1; v <= int(to); v++ {
+		ver := byte(v)
+		step, ok := findMigration(steps, ver)
+		if !ok {
+			return fmt.Errorf("%w: no migration from version %d to version %d", ErrDBVersionIncompatible, ver-1, ver)
+		}
+		log.Infof("migrating database to version %d: %s", ver, step.Description)
+
+		ctx := newMigrationContext(store, dryRun, backupDir)
+		if err := step.Apply(ctx); err != nil {
+			ctx.cancel()
+			return fmt.Errorf("migration to version %d failed: %v", ver, err)
+		}
+		if err := ctx.commit(ver); err != nil {
+			return fmt.Errorf("migration to version %d failed to commit: %v", ver, err)
+		}
+		if dryRun {
+			log.Infof("dry run: migration to version %d would set %d and delete %d records", ver, ctx.numSet, ctx.numDel)
+		} else {
+			log.Infof("migrated database to version %d: set %d and deleted %d records", ver, ctx.numSet, ctx.numDel)
+		}
+	}
+	return nil
+}
+
+func findMigration(steps []Migration, ver byte) (*Migration, bool) {
+	for i := range steps {
+		if steps[i].Version == ver {
+			return &steps[i], true
+		}
+	}
+	return nil, false
+}
+
+// migrateDatabase checks the schema version of the database and upgrades it if needed.
+// Before upgrading, the database folder is copied to the backup folder. If the migration fails
+// or was interrupted in the previous run, the database is restored from the backup.
+// Also automatically sets the version if the database is new.
+// In dry run mode nothing is written: neither the rollback nor the version of the new database
+func migrateDatabase() error {
+	dryRun := config.Node.GetBool(CfgDatabaseMigrationDryRun)
+	interruptedBackup, interrupted, err := readMigrationMarker(store)
+	if err != nil {
+		return err
+	}
+	if interrupted {
+		if interruptedBackup == "" {
+			return fmt.Errorf("the previous migration of the database was interrupted and there is no backup to roll back from")
+		}
+		if dryRun {
+			log.Warnf("dry run: the previous migration of the database was interrupted. It would be rolled back from %s", interruptedBackup)
+			return nil
+		}
+		if err := rollbackMigration(interruptedBackup); err != nil {
+			return fmt.Errorf("failed to roll back the interrupted migration from %s: %v", interruptedBackup, err)
+		}
+		log.Warnf("the previous migration of the database was interrupted. Rolled back from %s", interruptedBackup)
+	}
+	ver, exists, err := readDatabaseVersion(store)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		if dryRun {
+			log.Infof("dry run: the database is new. Version %d would be written", DBVersion)
+			return nil
+		}
+		return writeDatabaseVersion(store, DBVersion)
+	}
+	if ver == DBVersion {
+		return nil
+	}
+	backupDir := ""
+	if !dryRun && ver < DBVersion && config.Node.GetBool(CfgDatabaseMigrationBackup) {
+		backupDir, err = backupDatabase(ver)
+		if err != nil {
+			return fmt.Errorf("failed to backup database before migration: %v", err)
+		}
+		if backupDir != "" {
+			log.Infof("database version %d was backed up to %s", ver, backupDir)
+		}
+	}
+	err = runMigrations(store, migrations, ver, DBVersion, dryRun, backupDir, log)
+	if err == nil || backupDir == "" {
+		return err
+	}
+	if errRollback := rollbackMigration(backupDir); errRollback != nil {
+		return fmt.Errorf("%v. Failed to roll back from %s: %v", err, backupDir, errRollback)
+	}
+	return fmt.Errorf("%v. Rolled back from %s", err, backupDir)
+}
+
+// backupDatabase closes the database, copies its folder and opens it again.
+// Returns the backup folder or empty string if the database is not persisted
+func backupDatabase(ver byte) (string, error) {
+	if dbEngine == EngineMemory {
+		return "", nil
+	}
+	backupDir := fmt.Sprintf("%s.backup-v%d", dbDir, ver)
+	if _, err := os.Stat(backupDir); err == nil {
+		backupDir = fmt.Sprintf("%s-%d", backupDir, time.Now().Unix())
+	}
+	if err := db.Close(); err != nil {
+		return "", err
+	}
+	errCopy := copyDir(dbDir, backupDir)
+	if err := reopenDatabase(); err != nil {
+		return "", err
+	}
+	return backupDir, errCopy
+}
+
+// rollbackMigration closes the database, replaces its folder with the copy of the backup and opens it again.
+// The backup is kept
+func rollbackMigration(backupDir string) error {
+	if err := db.Close(); err != nil {
+		return err
+	}
+	tmpDir := dbDir + ".rollback"
+	err := os.RemoveAll(tmpDir)
+	if err == nil {
+		err = copyDir(backupDir, tmpDir)
+	}
+	if err == nil {
+		err = os.RemoveAll(dbDir)
+	}
+	if err == nil {
+		err = os.Rename(tmpDir, dbDir)
+	}
+	if errOpen := reopenDatabase(); err == nil {
+		err = errOpen
+	}
+	return err
+}
+
+func reopenDatabase() error {
+	var err error
+	if db, err = NewDB(dbEngine, dbDir); err != nil {
+		return err
+	}
+	store = db.NewStore()
+
+	partitionsMutex.Lock()
+	partitions = make(map[address.Address]*Partition)
+	partitionsMutex.Unlock()
+	return nil
+}
+
+func copyDir(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, 0700)
+		}
+		return copyFile(path, target)
+	})
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,330 @@
+package database
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	"github.com/iotaledger/hive.go/kvstore"
+	"github.com/iotaledger/hive.go/logger"
+	"github.com/iotaledger/wasp/plugins/config"
+	"github.com/stretchr/testify/assert"
+)
+
+var testMigrations = []Migration{
+	{
+		Version:     1,
+		Description: "append field to bootup records, rename key in partitions",
+		Apply: func(ctx *MigrationContext) error {
+			reg := ctx.Registry()
+			err := reg.Iterate([]byte{ObjectTypeBootupData}, func(key kvstore.Key, value kvstore.Value) bool {
+				_ = ctx.Set(reg, key, append(value, 0xff))
+				return true
+			})
+			if err != nil {
+				return err
+			}
+			addrs, err := ctx.Addresses()
+			if err != nil {
+				return err
+			}
+			for i := range addrs {
+				part := ctx.Partition(&addrs[i])
+				v, err := part.Get([]byte("old"))
+				if err == kvstore.ErrKeyNotFound {
+					continue
+				}
+				if err != nil {
+					return err
+				}
+				_ = ctx.Set(part, []byte("new"), v)
+				_ = ctx.Delete(part, []byte("old"))
+			}
+			return nil
+		},
+	},
+	{
+		Version:     2,
+		Description: "delete key in partitions",
+		Apply: func(ctx *MigrationContext) error {
+			addrs, err := ctx.Addresses()
+			if err != nil {
+				return err
+			}
+			for i := range addrs {
+				_ = ctx.Delete(ctx.Partition(&addrs[i]), []byte("new"))
+			}
+			return nil
+		},
+	},
+}
+
+func newTestMigrationStore(t *testing.T) (kvstore.KVStore, address.Address) {
+	db, err := NewDB(EngineMemory, "")
+	assert.NoError(t, err)
+	s := db.NewStore()
+
+	addr := address.Random()
+	assert.NoError(t, writeDatabaseVersion(s, 0))
+	assert.NoError(t, registryRealm(s).Set(MakeKey(ObjectTypeBootupData, addr[:]), []byte("bootup")))
+	assert.NoError(t, s.WithRealm(addr[:]).Set([]byte("old"), []byte("value")))
+	return s, addr
+}
+
+func TestMigrationsNumbering(t *testing.T) {
+	assert.Equal(t, DBVersion, len(migrations))
+	for i, m := range migrations {
+		assert.EqualValues(t, i+1, m.Version)
+	}
+}
+
+func TestMigrationNewDatabase(t *testing.T) {
+	db, err := NewDB(EngineMemory, "")
+	assert.NoError(t, err)
+	s := db.NewStore()
+
+	_, exists, err := readDatabaseVersion(s)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	assert.NoError(t, writeDatabaseVersion(s, 3))
+	ver, exists, err := readDatabaseVersion(s)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	assert.EqualValues(t, 3, ver)
+}
+
+func TestMigrationRun(t *testing.T) {
+	s, addr := newTestMigrationStore(t)
+	log := logger.NewExampleLogger("migration")
+
+	assert.NoError(t, runMigrations(s, testMigrations, 0, 1, false, "", log))
+
+	ver, _, err := readDatabaseVersion(s)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, ver)
+
+	v, err := registryRealm(s).Get(MakeKey(ObjectTypeBootupData, addr[:]))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("bootup\xff"), v)
+
+	part := s.WithRealm(addr[:])
+	v, err = part.Get([]byte("new"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value"), v)
+	_, err = part.Get([]byte("old"))
+	assert.Equal(t, kvstore.ErrKeyNotFound, err)
+
+	// continues from the persisted version
+	assert.NoError(t, runMigrations(s, testMigrations, ver, 2, false, "", log))
+	ver, _, err = readDatabaseVersion(s)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, ver)
+	has, err := part.Has([]byte("new"))
+	assert.NoError(t, err)
+	assert.False(t, has)
+}
+
+func TestMigrationDryRun(t *testing.T) {
+	s, addr := newTestMigrationStore(t)
+
+	assert.NoError(t, runMigrations(s, testMigrations, 0, 2, true, "", logger.NewExampleLogger("migration")))
+
+	ver, _, err := readDatabaseVersion(s)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, ver)
+
+	v, err := registryRealm(s).Get(MakeKey(ObjectTypeBootupData, addr[:]))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("bootup"), v)
+	v, err = s.WithRealm(addr[:]).Get([]byte("old"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value"), v)
+}
+
+func TestMigrationIncompatible(t *testing.T) {
+	s, _ := newTestMigrationStore(t)
+	log := logger.NewExampleLogger("migration")
+
+	// downgrade
+	err := runMigrations(s, testMigrations, 2, 1, false, "", log)
+	assert.True(t, errors.Is(err, ErrDBVersionIncompatible))
+
+	// missing step
+	err = runMigrations(s, testMigrations[1:], 0, 2, false, "", log)
+	assert.True(t, errors.Is(err, ErrDBVersionIncompatible))
+	ver, _, err := readDatabaseVersion(s)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, ver)
+}
+
+// openTestDatabase opens the bolt database in the temporary folder as the database of the plugin.
+// The previous database of the plugin is restored when the test finishes
+func openTestDatabase(t *testing.T) {
+	dir, err := ioutil.TempDir("", "waspdb-migration")
+	assert.NoError(t, err)
+
+	prevDB, prevStore, prevEngine, prevDir, prevLog := db, store, dbEngine, dbDir, log
+	t.Cleanup(func() {
+		if db != nil {
+			_ = db.Close()
+		}
+		db, store, dbEngine, dbDir, log = prevDB, prevStore, prevEngine, prevDir, prevLog
+		partitionsMutex.Lock()
+		partitions = make(map[address.Address]*Partition)
+		partitionsMutex.Unlock()
+		_ = os.RemoveAll(dir)
+	})
+
+	log = logger.NewExampleLogger("migration")
+	dbEngine = EngineBolt
+	dbDir = filepath.Join(dir, "waspdb")
+	db, err = NewDB(dbEngine, dbDir)
+	assert.NoError(t, err)
+	store = db.NewStore()
+}
+
+func TestMigrationBackup(t *testing.T) {
+	openTestDatabase(t)
+	assert.NoError(t, writeDatabaseVersion(store, 0))
+
+	backupDir, err := backupDatabase(0)
+	assert.NoError(t, err)
+	assert.Equal(t, dbDir+".backup-v0", backupDir)
+
+	// the database is open again
+	_, exists, err := readDatabaseVersion(store)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	backup, err := NewDB(dbEngine, backupDir)
+	assert.NoError(t, err)
+	defer backup.Close()
+	ver, exists, err := readDatabaseVersion(backup.NewStore())
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	assert.EqualValues(t, 0, ver)
+}
+
+func TestMigrationChunked(t *testing.T) {
+	prev := maxMigrationChunk
+	maxMigrationChunk = 1
+	t.Cleanup(func() { maxMigrationChunk = prev })
+
+	s, addr := newTestMigrationStore(t)
+	assert.NoError(t, runMigrations(s, testMigrations, 0, 1, false, "backup", logger.NewExampleLogger("migration")))
+
+	ver, _, err := readDatabaseVersion(s)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, ver)
+	v, err := s.WithRealm(addr[:]).Get([]byte("new"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value"), v)
+	_, interrupted, err := readMigrationMarker(s)
+	assert.NoError(t, err)
+	assert.False(t, interrupted)
+}
+
+func TestMigrationChunkedWithoutBackup(t *testing.T) {
+	prev := maxMigrationChunk
+	maxMigrationChunk = 1
+	t.Cleanup(func() { maxMigrationChunk = prev })
+
+	s, addr := newTestMigrationStore(t)
+	assert.Error(t, runMigrations(s, testMigrations, 0, 1, false, "", logger.NewExampleLogger("migration")))
+
+	// nothing is written
+	ver, _, err := readDatabaseVersion(s)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, ver)
+	v, err := s.WithRealm(addr[:]).Get([]byte("old"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value"), v)
+	_, interrupted, err := readMigrationMarker(s)
+	assert.NoError(t, err)
+	assert.False(t, interrupted)
+}
+
+func TestSplitBatches(t *testing.T) {
+	batches := []realmBatch{
+		{realm: []byte("a"), mutations: make([]mutation, 3)},
+		{realm: []byte("b"), mutations: make([]mutation, 2)},
+	}
+	chunks := splitBatches(batches, 2)
+	assert.Len(t, chunks, 3)
+	for i, n := range []int{2, 2, 1} {
+		size := 0
+		for _, b := range chunks[i] {
+			size += len(b.mutations)
+		}
+		assert.Equal(t, n, size)
+	}
+	assert.Len(t, splitBatches(nil, 2), 1)
+}
+
+func TestMigrationRollback(t *testing.T) {
+	openTestDatabase(t)
+	addr := address.Random()
+	assert.NoError(t, writeDatabaseVersion(store, 0))
+	assert.NoError(t, store.WithRealm(addr[:]).Set([]byte("old"), []byte("value")))
+
+	backupDir, err := backupDatabase(0)
+	assert.NoError(t, err)
+
+	// the migration is interrupted after the first chunk
+	assert.NoError(t, writeMigrationMarker(store, backupDir))
+	assert.NoError(t, store.WithRealm(addr[:]).Delete([]byte("old")))
+
+	assert.NoError(t, migrateDatabase())
+
+	_, interrupted, err := readMigrationMarker(store)
+	assert.NoError(t, err)
+	assert.False(t, interrupted)
+	v, err := store.WithRealm(addr[:]).Get([]byte("old"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value"), v)
+	_, err = os.Stat(backupDir)
+	assert.NoError(t, err)
+}
+
+func TestMigrationDryRunNoWrites(t *testing.T) {
+	config.Node.Set(CfgDatabaseMigrationDryRun, true)
+	t.Cleanup(func() { config.Node.Set(CfgDatabaseMigrationDryRun, false) })
+
+	// the version of the new database is not written
+	openTestDatabase(t)
+	assert.NoError(t, migrateDatabase())
+	_, exists, err := readDatabaseVersion(store)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	// the interrupted migration is not rolled back
+	addr := address.Random()
+	assert.NoError(t, writeDatabaseVersion(store, 0))
+	assert.NoError(t, store.WithRealm(addr[:]).Set([]byte("old"), []byte("value")))
+	backupDir, err := backupDatabase(0)
+	assert.NoError(t, err)
+	assert.NoError(t, writeMigrationMarker(store, backupDir))
+	assert.NoError(t, store.WithRealm(addr[:]).Delete([]byte("old")))
+
+	assert.NoError(t, migrateDatabase())
+	_, interrupted, err := readMigrationMarker(store)
+	assert.NoError(t, err)
+	assert.True(t, interrupted)
+	_, err = store.WithRealm(addr[:]).Get([]byte("old"))
+	assert.Equal(t, kvstore.ErrKeyNotFound, err)
+}
+
+func TestMigrationBootupRotation(t *testing.T) {
+	s, addr := newTestMigrationStore(t)
+
+	assert.NoError(t, runMigrations(s, migrations, 0, 1, false, "", logger.NewExampleLogger("migration")))
+
+	v, err := registryRealm(s).Get(MakeKey(ObjectTypeBootupData, addr[:]))
+	assert.NoError(t, err)
+	assert.Len(t, v, len("bootup")+2*address.Length)
+	assert.Equal(t, []byte("bootup"), v[:len("bootup")])
+}
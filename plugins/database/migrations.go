@@ -0,0 +1,25 @@
+package database
+
+import (
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	"github.com/iotaledger/hive.go/kvstore"
+)
+
+// migrateBootupRotation appends nil OriginAddress and RotatedTo to all bootup records
+func migrateBootupRotation(ctx *MigrationContext) error {
+	var niladdr address.Address
+	reg := ctx.Registry()
+	var errSet error
+	err := reg.Iterate([]byte{ObjectTypeBootupData}, func(key kvstore.Key, value kvstore.Value) bool {
+		data := make([]byte, 0, len(value)+2*len(niladdr))
+		data = append(data, value...)
+		data = append(data, niladdr[:]...)
+		data = append(data, niladdr[:]...)
+		errSet = ctx.Set(reg, key, data)
+		return errSet == nil
+	})
+	if err != nil {
+		return err
+	}
+	return errSet
+}
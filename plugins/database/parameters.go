@@ -9,9 +9,18 @@ const (
 	CfgDatabaseDir = "database.directory"
 	// CfgDatabaseInMemory defines whether to use an in-memory database.
 	CfgDatabaseInMemory = "database.inMemory"
+	// CfgDatabaseEngine defines the storage engine of the database.
+	CfgDatabaseEngine = "database.engine"
+	// CfgDatabaseMigrationBackup defines whether to copy the database folder before the schema migration.
+	CfgDatabaseMigrationBackup = "database.migration.backup"
+	// CfgDatabaseMigrationDryRun defines whether to only report the schema migration and exit.
+	CfgDatabaseMigrationDryRun = "database.migration.dryRun"
 )
 
 func init() {
 	flag.String(CfgDatabaseDir, "waspdb", "path to the database folder")
 	flag.Bool(CfgDatabaseInMemory, false, "whether the database is only kept in memory and not persisted")
+	flag.Bool(CfgDatabaseMigrationBackup, true, "whether to copy the database folder before the schema migration")
+	flag.Bool(CfgDatabaseMigrationDryRun, false, "only report what the schema migration would change and exit, without modifying the database")
+	flag.String(CfgDatabaseEngine, EngineBadger, "storage engine of the database: 'badger', 'bolt', 'pebble' or 'memory'")
 }
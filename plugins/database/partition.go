@@ -3,7 +3,6 @@ package database
 import (
 	"bytes"
 	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
-	"github.com/iotaledger/goshimmer/packages/database"
 	"github.com/iotaledger/hive.go/kvstore"
 	"github.com/iotaledger/hive.go/logger"
 	"github.com/iotaledger/wasp/plugins/config"
@@ -23,6 +22,10 @@ const (
 	ObjectTypeStateVariable
 	ObjectTypeProgramMetadata
 	ObjectTypeProgramCode
+	ObjectTypeKeystore
+	ObjectTypeTrustedPeer
+	ObjectTypeDBMigration
+	ObjectTypeMsgEpoch
 )
 
 type Partition struct {
@@ -104,14 +107,15 @@ func MakeKey(objType byte, keyBytes ...[]byte) []byte {
 func createStore() {
 	log = logger.NewLogger(PluginName)
 
-	var err error
+	dbEngine = config.Node.GetString(CfgDatabaseEngine)
 	if config.Node.GetBool(CfgDatabaseInMemory) {
-		log.Infof("IN MEMORY DATABASE")
-		db, err = database.NewMemDB()
-	} else {
-		dbDir := config.Node.GetString(CfgDatabaseDir)
-		db, err = database.NewDB(dbDir)
+		dbEngine = EngineMemory
 	}
+	dbDir = config.Node.GetString(CfgDatabaseDir)
+	log.Infof("database engine: %s", dbEngine)
+
+	var err error
+	db, err = NewDB(dbEngine, dbDir)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -0,0 +1,217 @@
+package database
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/iotaledger/goshimmer/packages/database"
+	"github.com/iotaledger/hive.go/kvstore"
+)
+
+type pebbleDB struct {
+	*pebble.DB
+}
+
+// newPebbleDB opens or creates pebble database in the directory
+func newPebbleDB(dirname string) (database.DB, error) {
+	if err := os.MkdirAll(dirname, 0700); err != nil {
+		return nil, fmt.Errorf("could not create DB directory: %w", err)
+	}
+	db, err := pebble.Open(dirname, &pebble.Options{})
+	if err != nil {
+		return nil, fmt.Errorf("could not open DB: %w", err)
+	}
+	return &pebbleDB{DB: db}, nil
+}
+
+// newPebbleMemDB creates pebble database on the in-memory file system. Nothing is persisted
+func newPebbleMemDB() (database.DB, error) {
+	db, err := pebble.Open("", &pebble.Options{FS: vfs.NewMem()})
+	if err != nil {
+		return nil, fmt.Errorf("could not open DB: %w", err)
+	}
+	return &pebbleDB{DB: db}, nil
+}
+
+func (db *pebbleDB) NewStore() kvstore.KVStore {
+	return &pebbleStore{instance: db.DB}
+}
+
+func (db *pebbleDB) Close() error {
+	return db.DB.Close()
+}
+
+// pebble compacts in the background
+func (db *pebbleDB) RequiresGC() bool {
+	return false
+}
+
+func (db *pebbleDB) GC() error {
+	return nil
+}
+
+// pebbleStore implements kvstore.KVStore around a pebble instance.
+// The realm is a prefix of every key, the same way as in the badger store
+type pebbleStore struct {
+	instance *pebble.DB
+	realm    []byte
+}
+
+func (s *pebbleStore) WithRealm(realm kvstore.Realm) kvstore.KVStore {
+	return &pebbleStore{
+		instance: s.instance,
+		realm:    concatBytes(realm),
+	}
+}
+
+func (s *pebbleStore) Realm() kvstore.Realm {
+	return concatBytes(s.realm)
+}
+
+func (s *pebbleStore) Iterate(prefix kvstore.KeyPrefix, consumerFunc kvstore.IteratorKeyValueConsumerFunc) error {
+	return s.iterate(prefix, func(it *pebble.Iterator) bool {
+		return consumerFunc(concatBytes(it.Key()[len(s.realm):]), concatBytes(it.Value()))
+	})
+}
+
+func (s *pebbleStore) IterateKeys(prefix kvstore.KeyPrefix, consumerFunc kvstore.IteratorKeyConsumerFunc) error {
+	return s.iterate(prefix, func(it *pebble.Iterator) bool {
+		return consumerFunc(concatBytes(it.Key()[len(s.realm):]))
+	})
+}
+
+func (s *pebbleStore) iterate(prefix kvstore.KeyPrefix, f func(it *pebble.Iterator) bool) error {
+	lower := concatBytes(s.realm, prefix)
+	it := s.instance.NewIter(&pebble.IterOptions{
+		LowerBound: lower,
+		UpperBound: prefixUpperBound(lower),
+	})
+	for valid := it.First(); valid; valid = it.Next() {
+		if !f(it) {
+			break
+		}
+	}
+	return it.Close()
+}
+
+func (s *pebbleStore) Clear() error {
+	return s.DeletePrefix(kvstore.EmptyPrefix)
+}
+
+func (s *pebbleStore) Get(key kvstore.Key) (kvstore.Value, error) {
+	value, closer, err := s.instance.Get(concatBytes(s.realm, key))
+	if err == pebble.ErrNotFound {
+		return nil, kvstore.ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+	return concatBytes(value), nil
+}
+
+func (s *pebbleStore) Set(key kvstore.Key, value kvstore.Value) error {
+	return s.instance.Set(concatBytes(s.realm, key), value, pebble.Sync)
+}
+
+func (s *pebbleStore) Has(key kvstore.Key) (bool, error) {
+	_, closer, err := s.instance.Get(concatBytes(s.realm, key))
+	if err == pebble.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, closer.Close()
+}
+
+func (s *pebbleStore) Delete(key kvstore.Key) error {
+	return s.instance.Delete(concatBytes(s.realm, key), pebble.Sync)
+}
+
+func (s *pebbleStore) DeletePrefix(prefix kvstore.KeyPrefix) error {
+	start := concatBytes(s.realm, prefix)
+	end := prefixUpperBound(start)
+	if end != nil {
+		return s.instance.DeleteRange(start, end, pebble.Sync)
+	}
+	// no upper bound for the range: delete keys one by one
+	b := s.instance.NewBatch()
+	defer b.Close()
+	var errDelete error
+	err := s.iterate(prefix, func(it *pebble.Iterator) bool {
+		errDelete = b.Delete(concatBytes(it.Key()), nil)
+		return errDelete == nil
+	})
+	if err != nil {
+		return err
+	}
+	if errDelete != nil {
+		// the partial delete is not committed
+		return errDelete
+	}
+	return b.Commit(pebble.Sync)
+}
+
+// Batched mutations are written with commitRealms, as mutations of several realms
+func (s *pebbleStore) Batched() kvstore.BatchedMutations {
+	return &atomicBatch{
+		realm:  s.realm,
+		commit: s.commitRealms,
+	}
+}
+
+func (s *pebbleStore) snapshot() (snapshotSource, error) {
+	return &pebbleSnapshot{snap: s.instance.NewSnapshot()}, nil
+}
+
+// commitRealms writes mutations of the realms in one pebble batch
+func (s *pebbleStore) commitRealms(batches []realmBatch) error {
+	b := s.instance.NewBatch()
+	defer b.Close()
+	written := 0
+	for _, rb := range batches {
+		for _, mut := range rb.mutations {
+			beforeWrite(written)
+			written++
+			var err error
+			if mut.delete {
+				err = b.Delete(concatBytes(rb.realm, mut.key), nil)
+			} else {
+				err = b.Set(concatBytes(rb.realm, mut.key), mut.value, nil)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return b.Commit(pebble.Sync)
+}
+
+// concatBytes returns a new slice, never sharing memory with the arguments
+func concatBytes(data ...[]byte) []byte {
+	size := 0
+	for _, d := range data {
+		size += len(d)
+	}
+	ret := make([]byte, 0, size)
+	for _, d := range data {
+		ret = append(ret, d...)
+	}
+	return ret
+}
+
+// prefixUpperBound returns the smallest key greater than all keys with the prefix
+// or nil if there is no such key
+func prefixUpperBound(prefix []byte) []byte {
+	end := concatBytes(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		end[i]++
+		if end[i] != 0 {
+			return end[:i+1]
+		}
+	}
+	return nil
+}
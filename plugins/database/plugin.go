@@ -1,8 +1,10 @@
-// Package database is a plugin that manages the badger database (e.g. garbage collection).
+// Package database is a plugin that manages the database (e.g. garbage collection).
+// The storage engine is selected with the 'database.engine' option.
 package database
 
 import (
 	"errors"
+	"os"
 	"sync"
 	"time"
 
@@ -13,6 +15,7 @@ import (
 	"github.com/iotaledger/hive.go/node"
 	"github.com/iotaledger/hive.go/timeutil"
 	"github.com/iotaledger/wasp/packages/shutdown"
+	"github.com/iotaledger/wasp/plugins/config"
 )
 
 // Database is the name of the database plugin.
@@ -24,6 +27,8 @@ var (
 	log    *logger.Logger
 
 	db        database.DB
+	dbEngine  string
+	dbDir     string
 	store     kvstore.KVStore
 	storeOnce sync.Once
 )
@@ -32,12 +37,17 @@ func configure(_ *node.Plugin) {
 	// assure that the store is initialized
 	_ = storeInstance()
 
-	err := checkDatabaseVersion()
+	err := migrateDatabase()
 	if errors.Is(err, ErrDBVersionIncompatible) {
-		log.Panicf("The database scheme was updated. Please delete the database folder.\n%s", err)
+		log.Panicf("The database scheme can't be migrated to the version %d.\n%s", DBVersion, err)
 	}
 	if err != nil {
-		log.Panicf("Failed to check database version: %s", err)
+		log.Panicf("Failed to migrate database: %s", err)
+	}
+	if config.Node.GetBool(CfgDatabaseMigrationDryRun) {
+		_ = db.Close()
+		log.Infof("Dry run of the database migration finished. The database was not modified. Exiting")
+		os.Exit(0)
 	}
 
 	// we open the database in the configure, so we must also make sure it's closed here
@@ -0,0 +1,213 @@
+package database
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/dgraph-io/badger/v2"
+	"github.com/iotaledger/hive.go/kvstore"
+	"go.etcd.io/bbolt"
+)
+
+// snapshotSource reads the whole database as it was at the moment the snapshot was taken.
+// Keys passed to the consumer are without the realm
+type snapshotSource interface {
+	get(realm kvstore.Realm, key kvstore.Key) (kvstore.Value, error)
+	iterate(realm kvstore.Realm, prefix kvstore.KeyPrefix, f func(key kvstore.Key, value kvstore.Value) bool) error
+	release()
+}
+
+// snapshotter is implemented by stores of all engines returned by NewDB
+type snapshotter interface {
+	snapshot() (snapshotSource, error)
+}
+
+var errReadOnly = errors.New("snapshot is read only")
+
+// snapshotOf returns the read-only store with the data of the whole database at the moment of the call.
+// The snapshot must be released after use
+func snapshotOf(store kvstore.KVStore) (kvstore.KVStore, func(), error) {
+	s, ok := store.(snapshotter)
+	if !ok {
+		return nil, nil, fmt.Errorf("store does not support snapshots")
+	}
+	src, err := s.snapshot()
+	if err != nil {
+		return nil, nil, err
+	}
+	return &snapshotStore{src: src, realm: store.Realm()}, src.release, nil
+}
+
+// snapshotStore implements the read-only kvstore.KVStore over the snapshot
+type snapshotStore struct {
+	src   snapshotSource
+	realm kvstore.Realm
+}
+
+func (s *snapshotStore) WithRealm(realm kvstore.Realm) kvstore.KVStore {
+	return &snapshotStore{src: s.src, realm: concatBytes(realm)}
+}
+
+func (s *snapshotStore) Realm() kvstore.Realm {
+	return concatBytes(s.realm)
+}
+
+func (s *snapshotStore) Iterate(prefix kvstore.KeyPrefix, consumerFunc kvstore.IteratorKeyValueConsumerFunc) error {
+	return s.src.iterate(s.realm, prefix, consumerFunc)
+}
+
+func (s *snapshotStore) IterateKeys(prefix kvstore.KeyPrefix, consumerFunc kvstore.IteratorKeyConsumerFunc) error {
+	return s.src.iterate(s.realm, prefix, func(key kvstore.Key, _ kvstore.Value) bool {
+		return consumerFunc(key)
+	})
+}
+
+func (s *snapshotStore) Get(key kvstore.Key) (kvstore.Value, error) {
+	return s.src.get(s.realm, key)
+}
+
+func (s *snapshotStore) Has(key kvstore.Key) (bool, error) {
+	_, err := s.src.get(s.realm, key)
+	if err == kvstore.ErrKeyNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *snapshotStore) Clear() error {
+	return errReadOnly
+}
+
+func (s *snapshotStore) Set(kvstore.Key, kvstore.Value) error {
+	return errReadOnly
+}
+
+func (s *snapshotStore) Delete(kvstore.Key) error {
+	return errReadOnly
+}
+
+func (s *snapshotStore) DeletePrefix(kvstore.KeyPrefix) error {
+	return errReadOnly
+}
+
+func (s *snapshotStore) Batched() kvstore.BatchedMutations {
+	return readOnlyBatch{}
+}
+
+type readOnlyBatch struct{}
+
+func (readOnlyBatch) Set(kvstore.Key, kvstore.Value) error { return errReadOnly }
+func (readOnlyBatch) Delete(kvstore.Key) error             { return errReadOnly }
+func (readOnlyBatch) Cancel()                              {}
+func (readOnlyBatch) Commit() error                        { return errReadOnly }
+
+// badger: read-only transaction
+type badgerSnapshot struct {
+	txn *badger.Txn
+}
+
+func (s *badgerSnapshot) get(realm kvstore.Realm, key kvstore.Key) (kvstore.Value, error) {
+	item, err := s.txn.Get(concatBytes(realm, key))
+	if err == badger.ErrKeyNotFound {
+		return nil, kvstore.ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return item.ValueCopy(nil)
+}
+
+func (s *badgerSnapshot) iterate(realm kvstore.Realm, prefix kvstore.KeyPrefix, f func(kvstore.Key, kvstore.Value) bool) error {
+	p := concatBytes(realm, prefix)
+	opts := badger.DefaultIteratorOptions
+	opts.Prefix = p
+	it := s.txn.NewIterator(opts)
+	defer it.Close()
+	for it.Seek(p); it.ValidForPrefix(p); it.Next() {
+		item := it.Item()
+		value, err := item.ValueCopy(nil)
+		if err != nil {
+			return err
+		}
+		if !f(item.KeyCopy(nil)[len(realm):], value) {
+			break
+		}
+	}
+	return nil
+}
+
+func (s *badgerSnapshot) release() {
+	s.txn.Discard()
+}
+
+// bolt: read-only transaction
+type boltSnapshot struct {
+	tx *bbolt.Tx
+}
+
+func (s *boltSnapshot) get(realm kvstore.Realm, key kvstore.Key) (kvstore.Value, error) {
+	bucket := s.tx.Bucket(realm)
+	if bucket == nil {
+		return nil, kvstore.ErrKeyNotFound
+	}
+	value := bucket.Get(key)
+	if value == nil {
+		return nil, kvstore.ErrKeyNotFound
+	}
+	return concatBytes(value), nil
+}
+
+func (s *boltSnapshot) iterate(realm kvstore.Realm, prefix kvstore.KeyPrefix, f func(kvstore.Key, kvstore.Value) bool) error {
+	bucket := s.tx.Bucket(realm)
+	if bucket == nil {
+		return nil
+	}
+	c := bucket.Cursor()
+	for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		if !f(concatBytes(k), concatBytes(v)) {
+			break
+		}
+	}
+	return nil
+}
+
+func (s *boltSnapshot) release() {
+	_ = s.tx.Rollback()
+}
+
+// pebble: snapshot of the LSM tree
+type pebbleSnapshot struct {
+	snap *pebble.Snapshot
+}
+
+func (s *pebbleSnapshot) get(realm kvstore.Realm, key kvstore.Key) (kvstore.Value, error) {
+	value, closer, err := s.snap.Get(concatBytes(realm, key))
+	if err == pebble.ErrNotFound {
+		return nil, kvstore.ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+	return concatBytes(value), nil
+}
+
+func (s *pebbleSnapshot) iterate(realm kvstore.Realm, prefix kvstore.KeyPrefix, f func(kvstore.Key, kvstore.Value) bool) error {
+	lower := concatBytes(realm, prefix)
+	it := s.snap.NewIter(&pebble.IterOptions{
+		LowerBound: lower,
+		UpperBound: prefixUpperBound(lower),
+	})
+	for valid := it.First(); valid; valid = it.Next() {
+		if !f(concatBytes(it.Key()[len(realm):]), concatBytes(it.Value())) {
+			break
+		}
+	}
+	return it.Close()
+}
+
+func (s *pebbleSnapshot) release() {
+	_ = s.snap.Close()
+}
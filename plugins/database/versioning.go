@@ -12,40 +12,49 @@ import (
 
 const (
 	// DBVersion defines the version of the database schema this version of Wasp supports.
-	// Every time there's a breaking change regarding the stored data, this version flag should be adjusted.
-	DBVersion = 0
+	// Every time there's a breaking change regarding the stored data, this version flag should be adjusted
+	// and the migration step to the new version must be added to 'migrations'
+	DBVersion = 1
 )
 
 var (
 	// ErrDBVersionIncompatible is returned when the database has an unexpected version.
-	ErrDBVersionIncompatible = errors.New("database version is not compatible. please delete your database folder and restart")
+	ErrDBVersionIncompatible = errors.New("database version is not compatible")
 )
 
-// checks whether the database is compatible with the current schema version.
-// also automatically sets the version if the database if new.
 // version is stored in niladdr partition.
 // it consists of one byte of version and the hash (checksum) of that one byte
-func checkDatabaseVersion() error {
-	var niladdr address.Address
-	db := GetPartition(&niladdr)
-	ver, err := db.Get(MakeKey(ObjectTypeDBSchemaVersion))
-
+func versionData(ver byte) []byte {
 	var versiondata [1 + hashing.HashSize]byte
-	versiondata[0] = DBVersion
-	copy(versiondata[1:], hashing.HashStrings(fmt.Sprintf("dbversion = %d", DBVersion)).Bytes())
+	versiondata[0] = ver
+	copy(versiondata[1:], hashing.HashStrings(fmt.Sprintf("dbversion = %d", ver)).Bytes())
+	return versiondata[:]
+}
+
+func registryRealm(store kvstore.KVStore) kvstore.KVStore {
+	var niladdr address.Address
+	return store.WithRealm(niladdr[:])
+}
 
+// readDatabaseVersion returns the schema version of the database.
+// Returns false if the version was never set, i.e. the database is new
+func readDatabaseVersion(store kvstore.KVStore) (byte, bool, error) {
+	ver, err := registryRealm(store).Get(MakeKey(ObjectTypeDBSchemaVersion))
 	if err == kvstore.ErrKeyNotFound {
-		// set the version in an empty DB
-		return db.Set(MakeKey(ObjectTypeDBSchemaVersion), versiondata[:])
+		return 0, false, nil
 	}
 	if err != nil {
-		return err
+		return 0, false, err
 	}
 	if len(ver) == 0 {
-		return fmt.Errorf("%w: no database version was persisted", ErrDBVersionIncompatible)
+		return 0, false, fmt.Errorf("%w: no database version was persisted", ErrDBVersionIncompatible)
 	}
-	if !bytes.Equal(ver, versiondata[:]) {
-		return fmt.Errorf("%w: supported version: %d, version of database: %d", ErrDBVersionIncompatible, DBVersion, ver[0])
+	if !bytes.Equal(ver, versionData(ver[0])) {
+		return 0, false, fmt.Errorf("%w: corrupted database version record", ErrDBVersionIncompatible)
 	}
-	return nil
+	return ver[0], true, nil
+}
+
+func writeDatabaseVersion(store kvstore.KVStore, ver byte) error {
+	return registryRealm(store).Set(MakeKey(ObjectTypeDBSchemaVersion), versionData(ver))
 }
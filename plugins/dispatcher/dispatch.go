@@ -8,33 +8,66 @@ import (
 	"github.com/iotaledger/wasp/packages/committee"
 	"github.com/iotaledger/wasp/packages/sctransaction"
 	"github.com/iotaledger/wasp/plugins/committees"
+	"github.com/iotaledger/wasp/plugins/nodeconn"
 )
 
 func dispatchState(tx *sctransaction.Transaction) {
-	cmt := getCommitteeByState(tx)
-	if cmt == nil {
+	stateAddr, ok, err := tx.StateAddress()
+	if err != nil {
+		log.Errorf("dispatchState: StateAddress returned for txid = %s: %v", tx.ID().String(), err)
+	}
+	if !ok || err != nil {
 		return
 	}
-	log.Debugw("dispatchState",
-		"txid", tx.ID().String(),
-		"addr", cmt.Address().String(),
-	)
-	_, err := tx.ValidateBlocks(cmt.Address())
+	_, err = tx.ValidateBlocks(&stateAddr)
 	if err != nil {
 		log.Errorf("invalid transaction %s ignored: %v", tx.ID().String(), err)
 		return
 	}
-
-	cmt.ReceiveMessage(committee.StateTransactionMsg{
-		Transaction: tx,
-	})
+	// the state is followed by all committees of the smart contract:
+	// the old one must see its state rotated away, the new one syncs the state before the rotation
+	for _, cmt := range getCommitteesByColor(tx) {
+		log.Debugw("dispatchState",
+			"txid", tx.ID().String(),
+			"addr", cmt.Address().String(),
+		)
+		cmt.ReceiveMessage(committee.StateTransactionMsg{
+			Transaction: tx,
+		})
+	}
 }
 
 func dispatchBalances(addr address.Address, bals map[valuetransaction.ID][]*balance.Balance) {
 	// pass to the committee by address
-	if cmt := committees.CommitteeByAddress(addr); cmt != nil {
-		cmt.ReceiveMessage(committee.BalancesMsg{Balances: bals})
+	cmt := committees.CommitteeByAddress(addr)
+	if cmt == nil {
+		reconc.forget(addr)
+		return
 	}
+	cmt.ReceiveMessage(committee.BalancesMsg{Balances: bals})
+	balancesDispatched(addr, bals)
+}
+
+// balancesDispatched remembers balances passed to the committee and requests transactions the committee missed
+func balancesDispatched(addr address.Address, bals map[valuetransaction.ID][]*balance.Balance) {
+	for _, txid := range reconc.balancesDispatched(addr, bals) {
+		txid := txid
+		if err := nodeconn.RequestTransactionFromNode(&txid); err != nil {
+			log.Warnf("reconciliation: requesting transaction %s: %v", txid.String(), err)
+		}
+	}
+}
+
+// dispatchMissedTransaction dispatches the transaction missed by the committee as the address update.
+// Returns false if the transaction was not missed
+func dispatchMissedTransaction(tx *sctransaction.Transaction) bool {
+	addr, bals, ok := reconc.missedTransaction(tx.ID())
+	if !ok {
+		return false
+	}
+	log.Infof("reconciliation: dispatching missed transaction %s to %s", tx.ID().String(), addr.String())
+	dispatchAddressUpdate(addr, bals, tx)
+	return true
 }
 
 func dispatchAddressUpdate(addr address.Address, balances map[valuetransaction.ID][]*balance.Balance, tx *sctransaction.Transaction) {
@@ -78,10 +111,18 @@ func dispatchAddressUpdate(addr address.Address, balances map[valuetransaction.I
 	// balances must be refreshed before requests to ensure corresponding outputs with request tokens
 	if stateTxMsg.Transaction != nil || len(requestMsgs) > 0 {
 		cmt.ReceiveMessage(committee.BalancesMsg{Balances: balances})
+		balancesDispatched(addr, balances)
 	}
 
 	if stateTxMsg.Transaction != nil {
 		cmt.ReceiveMessage(stateTxMsg)
+		// the rotation moves all outputs to the new address, so the ledger doesn't update the address
+		// of the old committee. It must see the state rotated away to dismiss itself
+		for _, c := range getCommitteesByColor(tx) {
+			if c != cmt {
+				c.ReceiveMessage(stateTxMsg)
+			}
+		}
 
 		sh := stateTxMsg.Transaction.MustState().StateHash()
 		log.Debugw("state tx dispatched",
@@ -118,3 +159,17 @@ func getCommitteeByState(tx *sctransaction.Transaction) committee.Committee {
 
 	return committees.CommitteeByAddress(stateAddr)
 }
+
+// getCommitteesByColor returns committees of the smart contract of the state transaction
+func getCommitteesByColor(tx *sctransaction.Transaction) []committee.Committee {
+	stateBlock, ok := tx.State()
+	if !ok {
+		return nil
+	}
+	color := stateBlock.Color()
+	if color == balance.ColorNew {
+		// origin transaction
+		color = (balance.Color)(tx.ID())
+	}
+	return committees.CommitteesByColor(color)
+}
@@ -36,6 +36,10 @@ func run(_ *node.Plugin) {
 			chNodeMsg <- msg
 		})
 
+		connectedClosure := events.NewClosure(func() {
+			reconc.connected()
+		})
+
 		processPeerMsgClosure := events.NewClosure(func(msg *peering.PeerMessage) {
 			if committee := committees.CommitteeByAddress(msg.Address); committee != nil {
 				committee.ReceiveMessage(msg)
@@ -54,8 +58,9 @@ func run(_ *node.Plugin) {
 
 			log.Infof("Stopping %s..", PluginName)
 			go func() {
-				nodeconn.EventMessageReceived.Detach(processNodeMsgClosure)
-				peering.EventPeerMessageReceived.Detach(processPeerMsgClosure)
+				nodeconn.Events().MessageReceived.Detach(processNodeMsgClosure)
+				nodeconn.Events().Connected.Detach(connectedClosure)
+				peering.Events().MessageReceived.Detach(processPeerMsgClosure)
 
 				close(chNodeMsg)
 				log.Infof("Stopping %s.. Done", PluginName)
@@ -68,9 +73,11 @@ func run(_ *node.Plugin) {
 
 		// event attachments
 		// receiving events from NodeConn --> producing dispatcher events
-		nodeconn.EventMessageReceived.Attach(processNodeMsgClosure)
+		nodeconn.Events().MessageReceived.Attach(processNodeMsgClosure)
+		// after reconnection to the ledger --> reconcile balances of committees
+		nodeconn.Events().Connected.Attach(connectedClosure)
 		// receiving messages from peering --> send to respective committees
-		peering.EventPeerMessageReceived.Attach(processPeerMsgClosure)
+		peering.Events().MessageReceived.Attach(processPeerMsgClosure)
 
 		log.Infof("dispatcher started")
 
@@ -89,9 +96,13 @@ func processNodeMsg(msg interface{}) {
 		if err != nil {
 			log.Debugw("!!!! after parsing", "txid", msgt.Tx.ID().String(), "err", err)
 			// not a SC transaction. Ignore
+			reconc.missedTransaction(msgt.Tx.ID())
 			return
 		}
-		dispatchState(tx)
+		// the missed transaction is dispatched as the address update, together with its state
+		if !dispatchMissedTransaction(tx) {
+			dispatchState(tx)
+		}
 
 	case *waspconn.WaspFromNodeAddressOutputsMsg:
 		dispatchBalances(msgt.Address, msgt.Balances)
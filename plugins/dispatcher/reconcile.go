@@ -0,0 +1,121 @@
+package dispatcher
+
+import (
+	"sync"
+
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/balance"
+	valuetransaction "github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/transaction"
+	"github.com/iotaledger/wasp/plugins/committees"
+	"github.com/iotaledger/wasp/plugins/nodeconn"
+)
+
+// reconciler recovers committees from missed address updates while the node was disconnected from the ledger.
+// It keeps balances of each committee address as they were last passed to the committee, i.e. balances cached
+// by the operator. After reconnection fresh outputs of all committee addresses are requested from the node.
+// Transactions in the outputs which are not among the cached balances were missed: they are requested
+// from the node and dispatched as address updates when they arrive
+type reconciler struct {
+	mutex       sync.Mutex
+	balances    map[address.Address]map[valuetransaction.ID][]*balance.Balance
+	reconciling map[address.Address]struct{}
+	// missed transactions by the committee address
+	missing map[valuetransaction.ID]address.Address
+}
+
+var reconc = newReconciler()
+
+func newReconciler() *reconciler {
+	return &reconciler{
+		balances:    make(map[address.Address]map[valuetransaction.ID][]*balance.Balance),
+		reconciling: make(map[address.Address]struct{}),
+		missing:     make(map[valuetransaction.ID]address.Address),
+	}
+}
+
+// connected requests outputs of all active committees from the node
+func (r *reconciler) connected() {
+	addrs := committees.ActiveAddresses()
+	r.startReconciling(addrs)
+	for i := range addrs {
+		if err := nodeconn.RequestOutputsFromNode(&addrs[i]); err != nil {
+			log.Warnf("reconciliation: requesting outputs of %s: %v", addrs[i].String(), err)
+		}
+	}
+	if len(addrs) > 0 {
+		log.Infof("reconciliation: requested outputs of %d committee address(es) after reconnection", len(addrs))
+	}
+}
+
+func (r *reconciler) startReconciling(addrs []address.Address) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, addr := range addrs {
+		r.reconciling[addr] = struct{}{}
+		for txid, a := range r.missing {
+			if a == addr {
+				delete(r.missing, txid)
+			}
+		}
+	}
+}
+
+// balancesDispatched remembers balances passed to the committee. If the address is being reconciled,
+// it returns transactions the committee has not seen yet. Nothing is missed if no balances were passed
+// to the committee before: the committee takes all outputs from the first balances
+func (r *reconciler) balancesDispatched(addr address.Address, bals map[valuetransaction.ID][]*balance.Balance) []valuetransaction.ID {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	prev := r.balances[addr]
+	r.balances[addr] = bals
+
+	if _, ok := r.reconciling[addr]; !ok {
+		return nil
+	}
+	delete(r.reconciling, addr)
+	if prev == nil {
+		return nil
+	}
+
+	var missed []valuetransaction.ID
+	for txid := range bals {
+		if _, ok := prev[txid]; !ok {
+			missed = append(missed, txid)
+			r.missing[txid] = addr
+		}
+	}
+	numSpent := 0
+	for txid := range prev {
+		if _, ok := bals[txid]; !ok {
+			numSpent++
+		}
+	}
+	if len(missed) > 0 || numSpent > 0 {
+		log.Infof("reconciliation of %s: %d new output transaction(s), %d output transaction(s) spent",
+			addr.String(), len(missed), numSpent)
+	}
+	return missed
+}
+
+// missedTransaction returns the committee address and its current balances if the transaction was missed
+func (r *reconciler) missedTransaction(txid valuetransaction.ID) (address.Address, map[valuetransaction.ID][]*balance.Balance, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	addr, ok := r.missing[txid]
+	if !ok {
+		return address.Address{}, nil, false
+	}
+	delete(r.missing, txid)
+	return addr, r.balances[addr], true
+}
+
+func (r *reconciler) forget(addr address.Address) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.balances, addr)
+	delete(r.reconciling, addr)
+}
@@ -0,0 +1,66 @@
+package dispatcher
+
+import (
+	"testing"
+
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/balance"
+	valuetransaction "github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/transaction"
+	"github.com/iotaledger/hive.go/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	log = logger.NewExampleLogger(PluginName)
+}
+
+func balancesOf(txids ...valuetransaction.ID) map[valuetransaction.ID][]*balance.Balance {
+	ret := make(map[valuetransaction.ID][]*balance.Balance)
+	for _, txid := range txids {
+		ret[txid] = []*balance.Balance{balance.New(balance.ColorIOTA, 1)}
+	}
+	return ret
+}
+
+func TestReconciler(t *testing.T) {
+	r := newReconciler()
+	addr := address.Random()
+	tx1 := valuetransaction.RandomID()
+	tx2 := valuetransaction.RandomID()
+	tx3 := valuetransaction.RandomID()
+
+	// not reconciling: balances are only remembered
+	assert.Empty(t, r.balancesDispatched(addr, balancesOf(tx1, tx2)))
+	_, _, ok := r.missedTransaction(tx1)
+	assert.False(t, ok)
+
+	// after reconnection tx2 is spent and tx3 is new
+	r.startReconciling([]address.Address{addr})
+	bals := balancesOf(tx1, tx3)
+	assert.Equal(t, []valuetransaction.ID{tx3}, r.balancesDispatched(addr, bals))
+
+	// reconciliation is done with the first outputs
+	assert.Empty(t, r.balancesDispatched(addr, bals))
+
+	a, b, ok := r.missedTransaction(tx3)
+	assert.True(t, ok)
+	assert.Equal(t, addr, a)
+	assert.Equal(t, bals, b)
+	_, _, ok = r.missedTransaction(tx3)
+	assert.False(t, ok)
+
+	// missed transactions are dropped when reconciliation starts again
+	r.startReconciling([]address.Address{addr})
+	tx4 := valuetransaction.RandomID()
+	assert.Equal(t, []valuetransaction.ID{tx4}, r.balancesDispatched(addr, balancesOf(tx1, tx3, tx4)))
+	r.startReconciling([]address.Address{addr})
+	_, _, ok = r.missedTransaction(tx4)
+	assert.False(t, ok)
+
+	// nothing is missed by the committee without previous balances
+	r.forget(addr)
+	r.startReconciling([]address.Address{addr})
+	assert.Empty(t, r.balancesDispatched(addr, balancesOf(tx1, tx3)))
+	_, _, ok = r.missedTransaction(tx1)
+	assert.False(t, ok)
+}
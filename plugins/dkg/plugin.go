@@ -0,0 +1,52 @@
+// dkg plugin runs peer-to-peer distributed key generation sessions over peering connections
+package dkg
+
+import (
+	"time"
+
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	"github.com/iotaledger/hive.go/daemon"
+	"github.com/iotaledger/hive.go/logger"
+	"github.com/iotaledger/hive.go/node"
+	"github.com/iotaledger/wasp/packages/shutdown"
+	"github.com/iotaledger/wasp/packages/tcrypto"
+	"github.com/iotaledger/wasp/plugins/peering"
+)
+
+// PluginName is the name of the DKG plugin.
+const PluginName = "DKG"
+
+var (
+	// Plugin is the plugin instance of the DKG plugin.
+	Plugin = node.NewPlugin(PluginName, node.Enabled, configure, run)
+	log    *logger.Logger
+	// dkgNode runs sessions over the transport of the Wasp node
+	dkgNode *Node
+)
+
+func configure(_ *node.Plugin) {
+	log = logger.NewLogger(PluginName)
+	dkgNode = NewNode(peering.GetTransport(), peering.GetIdentity(), log)
+}
+
+func run(_ *node.Plugin) {
+	err := daemon.BackgroundWorker(PluginName, func(shutdownSignal <-chan struct{}) {
+		<-shutdownSignal
+
+		dkgNode.Close()
+		log.Infof("shutdown DKG... Done")
+	}, shutdown.PriorityDispatcher)
+	if err != nil {
+		log.Errorf("failed to start DKG worker: %v", err)
+	}
+}
+
+// RunDKG runs the distributed key generation on the Wasp node (see Node.RunDKG)
+func RunDKG(sessionId uint64, peerLocations []string, t uint16, timeout time.Duration) (*tcrypto.DKShare, error) {
+	return dkgNode.RunDKG(sessionId, peerLocations, t, timeout)
+}
+
+// RunReshare reshares the key set on the Wasp node (see Node.RunReshare)
+func RunReshare(sessionId uint64, addr *address.Address, oldKS *tcrypto.DKShare, oldLocations, newLocations []string, t uint16, timeout time.Duration) (*tcrypto.DKShare, error) {
+	return dkgNode.RunReshare(sessionId, addr, oldKS, oldLocations, newLocations, t, timeout)
+}
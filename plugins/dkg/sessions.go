@@ -0,0 +1,446 @@
+package dkg
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	"github.com/iotaledger/hive.go/events"
+	"github.com/iotaledger/hive.go/logger"
+	"github.com/iotaledger/wasp/packages/dkg"
+	"github.com/iotaledger/wasp/packages/tcrypto"
+	"github.com/iotaledger/wasp/packages/util"
+	"github.com/iotaledger/wasp/plugins/peering"
+)
+
+const (
+	// messages of the session which is not started yet on this node are kept this long
+	keepEarlyMessages = 1 * time.Minute
+	// maximum number of early messages kept per session
+	maxEarlyMessages = 1000
+	// maximum number of sessions, including sessions known only from early messages
+	maxSessions = 16
+	// how often peers are checked while waiting for connections
+	checkPeersEvery = 100 * time.Millisecond
+)
+
+// DKG messages are signed with the identity key of the sender:
+// SessionId  8 bytes
+// Target     string16, network location of the receiver
+// Data       bytes32, message of the session
+// Signature  bytes16
+// The signature covers the sender index, the message type and the message without signature.
+// It is checked with the key trusted for the network location of the participant with the sender index,
+// so messages of sessions are authenticated over any transport. The target prevents forwarding of
+// the message to another participant
+
+// session is the DKG or the resharing session
+type session interface {
+	ReceiveMessage(senderIndex uint16, msgType byte, data []byte)
+	Timeout()
+}
+
+type sessionEntry struct {
+	session session
+	// network locations of participants by index. Messages are accepted only from the participant
+	// with the sender index
+	locations []string
+	// messages received before the session is started
+	early   []*peering.PeerMessage
+	created time.Time
+}
+
+// Node runs DKG and resharing sessions of the Wasp node over its peering transport.
+// The plugin runs the node over the transport of the process. Several nodes with transports
+// of peering.MemNetwork can run in one process
+type Node struct {
+	transport peering.Transport
+	// signs messages of the node and provides keys of peers at network locations
+	identity      peering.Identity
+	log           *logger.Logger
+	sessions      map[uint64]*sessionEntry
+	sessionsMutex sync.Mutex
+	closure       *events.Closure
+}
+
+// NewNode creates the DKG node, which receives DKG messages from the transport until closed
+func NewNode(transport peering.Transport, identity peering.Identity, log *logger.Logger) *Node {
+	ret := &Node{
+		transport: transport,
+		identity:  identity,
+		log:       log,
+		sessions:  make(map[uint64]*sessionEntry),
+	}
+	ret.closure = events.NewClosure(func(msg *peering.PeerMessage) {
+		if msg.MsgType >= peering.FirstDKGMsgCode {
+			ret.receiveMessage(msg)
+		}
+	})
+	transport.Events().MessageReceived.Attach(ret.closure)
+	return ret
+}
+
+// Close stops receiving messages and finishes all sessions
+func (n *Node) Close() {
+	n.transport.Events().MessageReceived.Detach(n.closure)
+	n.closeAllSessions()
+}
+
+// RunDKG runs the distributed key generation with peers at peerLocations. The node must be one of them,
+// its index in the key set is its position in the list. All peers must run it with the same session id.
+// Returns the committed key share of the node. It is not saved to the registry
+func (n *Node) RunDKG(sessionId uint64, peerLocations []string, t uint16, timeout time.Duration) (*tcrypto.DKShare, error) {
+	size := uint16(len(peerLocations))
+	myNetworkId := n.transport.MyNetworkId()
+	index := -1
+	for i, loc := range peerLocations {
+		if loc == myNetworkId {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, fmt.Errorf("own network location %s is not among peers", myNetworkId)
+	}
+	if err := peering.CheckTrusted(n.identity, myNetworkId, peerLocations...); err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(timeout)
+
+	peers := make([]peering.Peer, size)
+	for i, loc := range peerLocations {
+		if i == index {
+			continue
+		}
+		peers[i] = n.transport.UsePeer(loc)
+		if peers[i] == nil {
+			return nil, fmt.Errorf("peering is not available")
+		}
+		defer n.transport.StopUsingPeer(loc)
+	}
+	if err := waitPeersAlive(peers, deadline); err != nil {
+		return nil, err
+	}
+
+	send := func(peerIndex uint16, msgType byte, data []byte) error {
+		return peers[peerIndex].SendMsg(n.signMessage(sessionId, uint16(index), peerLocations[peerIndex], msgType, data))
+	}
+	session, err := dkg.NewSession(t, size, uint16(index), send, n.log.Named(fmt.Sprintf("%d", sessionId)))
+	if err != nil {
+		return nil, err
+	}
+	early, err := n.startSession(sessionId, session, peerLocations)
+	if err != nil {
+		return nil, err
+	}
+	defer n.removeSession(sessionId)
+
+	session.Start()
+	for _, msg := range early {
+		session.ReceiveMessage(msg.SenderIndex, msg.MsgType, msg.MsgData)
+	}
+	select {
+	case <-session.Done():
+	case <-time.After(time.Until(deadline)):
+		session.Timeout()
+	}
+	return session.Result()
+}
+
+// RunReshare reshares the key set with the address from old nodes to the new committee with quorum t
+// (see dkg.ReshareSession). oldLocations are peering locations of old nodes which deal shares of own keys,
+// newLocations are locations of new nodes in the order of the new key set. The node must be among them.
+// All nodes must run it with the same session id.
+// Returns the new key share of the node, nil if the node is only the old node. It is not saved to the registry
+func (n *Node) RunReshare(sessionId uint64, addr *address.Address, oldKS *tcrypto.DKShare, oldLocations, newLocations []string, t uint16, timeout time.Duration) (*tcrypto.DKShare, error) {
+	locations := append(append([]string{}, oldLocations...), newLocations...)
+	myNetworkId := n.transport.MyNetworkId()
+	dealer, receiver := -1, -1
+	for i, loc := range oldLocations {
+		if loc == myNetworkId {
+			dealer = i
+		}
+	}
+	for i, loc := range newLocations {
+		if loc == myNetworkId {
+			receiver = i
+		}
+	}
+	if dealer < 0 && receiver < 0 {
+		return nil, fmt.Errorf("own network location %s is not among peers", myNetworkId)
+	}
+	if dealer < 0 {
+		oldKS = nil
+	} else if oldKS == nil {
+		return nil, fmt.Errorf("the node is among old nodes but doesn't have the key share of %s", addr.String())
+	}
+	if util.ContainsDuplicates(oldLocations) || util.ContainsDuplicates(newLocations) {
+		return nil, fmt.Errorf("duplicate peer locations")
+	}
+	if err := peering.CheckTrusted(n.identity, myNetworkId, locations...); err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(timeout)
+
+	// the node doesn't send messages to itself. Peers are used once per location
+	peers := make([]peering.Peer, len(locations))
+	usedPeers := make(map[string]peering.Peer)
+	for i, loc := range locations {
+		if loc == myNetworkId {
+			continue
+		}
+		if peer, ok := usedPeers[loc]; ok {
+			peers[i] = peer
+			continue
+		}
+		peers[i] = n.transport.UsePeer(loc)
+		if peers[i] == nil {
+			return nil, fmt.Errorf("peering is not available")
+		}
+		usedPeers[loc] = peers[i]
+		defer n.transport.StopUsingPeer(loc)
+	}
+	// nil stands for the own node
+	alivePeers := []peering.Peer{nil}
+	for _, peer := range usedPeers {
+		alivePeers = append(alivePeers, peer)
+	}
+	if err := waitPeersAlive(alivePeers, deadline); err != nil {
+		return nil, err
+	}
+	send := func(peerIndex uint16, msgType byte, data []byte) error {
+		// session keys are sent by the node as the new node, deals as the old node
+		senderIndex := uint16(dealer)
+		if msgType == dkg.MsgReshareKey {
+			senderIndex = uint16(len(oldLocations) + receiver)
+		}
+		return peers[peerIndex].SendMsg(n.signMessage(sessionId, senderIndex, locations[peerIndex], msgType, data))
+	}
+	session, err := dkg.NewReshareSession(uint16(len(oldLocations)), t, uint16(len(newLocations)), oldKS,
+		dealer, receiver, addr, send, n.log.Named(fmt.Sprintf("%d", sessionId)))
+	if err != nil {
+		return nil, err
+	}
+	early, err := n.startSession(sessionId, session, locations)
+	if err != nil {
+		return nil, err
+	}
+	defer n.removeSession(sessionId)
+
+	session.Start()
+	for _, msg := range early {
+		session.ReceiveMessage(msg.SenderIndex, msg.MsgType, msg.MsgData)
+	}
+	select {
+	case <-session.Done():
+	case <-time.After(time.Until(deadline)):
+		session.Timeout()
+	}
+	return session.Result()
+}
+
+func waitPeersAlive(peers []peering.Peer, deadline time.Time) error {
+	for {
+		numAlive := 0
+		for _, peer := range peers {
+			if peer == nil {
+				continue
+			}
+			if alive, _ := peer.IsAlive(); alive {
+				numAlive++
+			}
+		}
+		if numAlive == len(peers)-1 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timeout: connected to %d peers out of %d", numAlive, len(peers)-1)
+		}
+		time.Sleep(checkPeersEvery)
+	}
+}
+
+// startSession registers the session and returns messages of participants received before it was started
+func (n *Node) startSession(sessionId uint64, session session, locations []string) ([]*peering.PeerMessage, error) {
+	n.sessionsMutex.Lock()
+	defer n.sessionsMutex.Unlock()
+
+	n.cleanupEarlyMessages()
+	entry, ok := n.sessions[sessionId]
+	if ok && entry.session != nil {
+		return nil, fmt.Errorf("duplicate DKG session id %d", sessionId)
+	}
+	if !ok {
+		if len(n.sessions) >= maxSessions {
+			return nil, fmt.Errorf("too many DKG sessions")
+		}
+		entry = &sessionEntry{created: time.Now()}
+		n.sessions[sessionId] = entry
+	}
+	entry.session = session
+	entry.locations = locations
+	early := make([]*peering.PeerMessage, 0, len(entry.early))
+	for _, msg := range entry.early {
+		if n.openMessage(entry, msg) {
+			early = append(early, msg)
+		}
+	}
+	entry.early = nil
+	return early, nil
+}
+
+// signMessage signs the message of the session to the peer at the target location
+func (n *Node) signMessage(sessionId uint64, senderIndex uint16, target string, msgType byte, data []byte) *peering.PeerMessage {
+	var buf bytes.Buffer
+	writeEnvelope(&buf, sessionId, target, data)
+	sig := n.identity.Sign(essence(senderIndex, msgType, buf.Bytes()))
+	_ = util.WriteBytes16(&buf, sig)
+	return &peering.PeerMessage{
+		SenderIndex: senderIndex,
+		MsgType:     msgType,
+		MsgData:     buf.Bytes(),
+	}
+}
+
+func writeEnvelope(w *bytes.Buffer, sessionId uint64, target string, data []byte) {
+	_ = util.WriteUint64(w, sessionId)
+	_ = util.WriteString16(w, target)
+	_ = util.WriteBytes32(w, data)
+}
+
+func essence(senderIndex uint16, msgType byte, envelope []byte) []byte {
+	var buf bytes.Buffer
+	_ = util.WriteUint16(&buf, senderIndex)
+	_ = util.WriteByte(&buf, msgType)
+	buf.Write(envelope)
+	return buf.Bytes()
+}
+
+// openMessage checks if the message is received from the participant with the sender index and is signed by it.
+// It replaces data of the message with the message of the session
+func (n *Node) openMessage(entry *sessionEntry, msg *peering.PeerMessage) bool {
+	if int(msg.SenderIndex) >= len(entry.locations) || entry.locations[msg.SenderIndex] != msg.SenderNetworkId {
+		n.log.Warnf("DKG message from %s with the sender index %d is not from the participant",
+			msg.SenderNetworkId, msg.SenderIndex)
+		return false
+	}
+	data, err := n.verifyMessage(msg)
+	if err != nil {
+		n.log.Warnf("DKG message from %s dropped: %v", msg.SenderNetworkId, err)
+		return false
+	}
+	msg.MsgData = data
+	return true
+}
+
+// verifyMessage checks the signature of the message with the key trusted for the location of the sender
+func (n *Node) verifyMessage(msg *peering.PeerMessage) ([]byte, error) {
+	rdr := bytes.NewReader(msg.MsgData)
+	var sessionId uint64
+	if err := util.ReadUint64(rdr, &sessionId); err != nil {
+		return nil, err
+	}
+	target, err := util.ReadString16(rdr)
+	if err != nil {
+		return nil, err
+	}
+	data, err := util.ReadBytes32(rdr)
+	if err != nil {
+		return nil, err
+	}
+	envelope := msg.MsgData[:len(msg.MsgData)-rdr.Len()]
+	sig, err := util.ReadBytes16(rdr)
+	if err != nil {
+		return nil, err
+	}
+	if rdr.Len() != 0 {
+		return nil, errors.New("unexpected bytes after the signature")
+	}
+	if target != n.transport.MyNetworkId() {
+		return nil, fmt.Errorf("message is addressed to %s", target)
+	}
+	key, ok, err := n.identity.TrustedPeerKey(msg.SenderNetworkId)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("peer %s is not trusted", msg.SenderNetworkId)
+	}
+	if !ed25519.Verify(key, essence(msg.SenderIndex, msg.MsgType, envelope), sig) {
+		return nil, errors.New("invalid signature")
+	}
+	return data, nil
+}
+
+func (n *Node) removeSession(sessionId uint64) {
+	n.sessionsMutex.Lock()
+	defer n.sessionsMutex.Unlock()
+
+	delete(n.sessions, sessionId)
+}
+
+func (n *Node) receiveMessage(msg *peering.PeerMessage) {
+	rdr := bytes.NewReader(msg.MsgData)
+	var sessionId uint64
+	if err := util.ReadUint64(rdr, &sessionId); err != nil {
+		n.log.Warnf("wrong DKG message: %v", err)
+		return
+	}
+	n.sessionsMutex.Lock()
+	n.cleanupEarlyMessages()
+	entry, ok := n.sessions[sessionId]
+	if !ok {
+		// the session is not started on this node yet. Only trusted peers can announce sessions
+		if err := peering.CheckTrusted(n.identity, n.transport.MyNetworkId(), msg.SenderNetworkId); err != nil {
+			n.sessionsMutex.Unlock()
+			n.log.Warnf("DKG message dropped: %v", err)
+			return
+		}
+		if len(n.sessions) >= maxSessions {
+			n.sessionsMutex.Unlock()
+			n.log.Warnf("DKG message from %s dropped: too many DKG sessions", msg.SenderNetworkId)
+			return
+		}
+		entry = &sessionEntry{created: time.Now()}
+		n.sessions[sessionId] = entry
+	}
+	if entry.session == nil {
+		if len(entry.early) < maxEarlyMessages {
+			entry.early = append(entry.early, msg)
+		}
+		n.sessionsMutex.Unlock()
+		return
+	}
+	if !n.openMessage(entry, msg) {
+		n.sessionsMutex.Unlock()
+		return
+	}
+	session := entry.session
+	n.sessionsMutex.Unlock()
+
+	session.ReceiveMessage(msg.SenderIndex, msg.MsgType, msg.MsgData)
+}
+
+// removes messages of sessions which were never started on this node
+func (n *Node) cleanupEarlyMessages() {
+	for id, entry := range n.sessions {
+		if entry.session == nil && time.Since(entry.created) > keepEarlyMessages {
+			delete(n.sessions, id)
+		}
+	}
+}
+
+func (n *Node) closeAllSessions() {
+	n.sessionsMutex.Lock()
+	defer n.sessionsMutex.Unlock()
+
+	for _, entry := range n.sessions {
+		if entry.session != nil {
+			entry.session.Timeout()
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package dkg
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/iotaledger/hive.go/logger"
+	"github.com/iotaledger/wasp/packages/tcrypto"
+	"github.com/iotaledger/wasp/plugins/peering"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newMemNodes runs DKG nodes at network locations, each with own transport of the in-memory network
+func newMemNodes(t *testing.T, network *peering.MemNetwork, locations []string, identities []peering.Identity) []*Node {
+	ret := make([]*Node, len(locations))
+	for i, loc := range locations {
+		transport, err := network.NewTransport(loc)
+		require.NoError(t, err)
+		ret[i] = NewNode(transport, identities[i], logger.NewExampleLogger(loc))
+
+		shutdown := make(chan struct{})
+		go transport.Run(shutdown)
+		node := ret[i]
+		t.Cleanup(func() {
+			node.Close()
+			close(shutdown)
+		})
+	}
+	return ret
+}
+
+func memLocations(prefix string, n int) []string {
+	ret := make([]string, n)
+	for i := range ret {
+		ret[i] = fmt.Sprintf("%s%d:4000", prefix, i)
+	}
+	return ret
+}
+
+func TestDKGInProcess(t *testing.T) {
+	network := peering.NewMemNetwork()
+	locations := memLocations("node", 4)
+	newLocations := []string{locations[2], "new0:4000", "new1:4000"}
+	identities, err := peering.NewStaticIdentities(append(locations, newLocations[1:]...)...)
+	require.NoError(t, err)
+	nodes := newMemNodes(t, network, locations, identities[:4])
+
+	shares := make([]*tcrypto.DKShare, len(nodes))
+	errs := make([]error, len(nodes))
+	var wg sync.WaitGroup
+	for i, node := range nodes {
+		wg.Add(1)
+		go func(i int, node *Node) {
+			defer wg.Done()
+			shares[i], errs[i] = node.RunDKG(1, locations, 3, 10*time.Second)
+		}(i, node)
+	}
+	wg.Wait()
+
+	for i := range nodes {
+		require.NoError(t, errs[i])
+		require.NotNil(t, shares[i])
+		assert.EqualValues(t, i, shares[i].Index)
+		assert.Equal(t, *shares[0].Address, *shares[i].Address)
+	}
+
+	// the old committee 0, 1, 2 reshares the key set to the new committee of 3 nodes, node 2 stays
+	nodes = append(nodes, newMemNodes(t, network, newLocations[1:], identities[4:])...)
+	oldKS := []*tcrypto.DKShare{shares[0], shares[1], shares[2], nil, nil, nil}
+	reshared := make([]*tcrypto.DKShare, len(nodes))
+	errs = make([]error, len(nodes))
+	for i, node := range nodes {
+		if i == 3 {
+			// old node 3 doesn't take part
+			continue
+		}
+		wg.Add(1)
+		go func(i int, node *Node) {
+			defer wg.Done()
+			reshared[i], errs[i] = node.RunReshare(2, shares[0].Address, oldKS[i], locations[:3], newLocations, 3, 10*time.Second)
+		}(i, node)
+	}
+	wg.Wait()
+
+	for i := range nodes {
+		require.NoError(t, errs[i])
+	}
+	assert.Nil(t, reshared[0])
+	assert.Nil(t, reshared[1])
+	newShares := []*tcrypto.DKShare{reshared[2], reshared[4], reshared[5]}
+	for i, ks := range newShares {
+		require.NotNil(t, ks)
+		assert.EqualValues(t, i, ks.Index)
+		assert.Equal(t, *shares[0].Address, *ks.Address)
+	}
+
+	// the new committee signs for the address
+	data := []byte("data to sign")
+	sigShares := make([][]byte, 0, 3)
+	for _, ks := range newShares {
+		sigShare, err := ks.SignShare(data)
+		require.NoError(t, err)
+		sigShares = append(sigShares, sigShare)
+	}
+	sig, err := newShares[0].RecoverFullSignature(sigShares, data)
+	require.NoError(t, err)
+	assert.True(t, sig.IsValid(data))
+	assert.Equal(t, *shares[0].Address, sig.Address())
+}
+
+func TestDKGUntrustedPeers(t *testing.T) {
+	network := peering.NewMemNetwork()
+	locations := memLocations("node", 2)
+	transport, err := network.NewTransport(locations[0])
+	require.NoError(t, err)
+	// the identity trusts only itself
+	identities, err := peering.NewStaticIdentities(locations[0])
+	require.NoError(t, err)
+	node := NewNode(transport, identities[0], logger.NewExampleLogger("node"))
+
+	_, err = node.RunDKG(1, locations, 2, time.Second)
+	assert.Error(t, err)
+	_, err = node.RunDKG(1, memLocations("other", 2), 2, time.Second)
+	assert.Error(t, err)
+}
+
+type receivedMsg struct {
+	senderIndex uint16
+	msgType     byte
+	data        []byte
+}
+
+// testSession records messages passed to the session
+type testSession struct {
+	received []receivedMsg
+}
+
+func (s *testSession) ReceiveMessage(senderIndex uint16, msgType byte, data []byte) {
+	s.received = append(s.received, receivedMsg{senderIndex, msgType, data})
+}
+
+func (s *testSession) Timeout() {}
+
+// messages are accepted only if signed by the participant with the sender index for the receiver
+func TestDKGMessageSignature(t *testing.T) {
+	network := peering.NewMemNetwork()
+	locations := memLocations("node", 3)
+	identities, err := peering.NewStaticIdentities(locations...)
+	require.NoError(t, err)
+	nodes := make([]*Node, len(locations))
+	for i, loc := range locations {
+		transport, err := network.NewTransport(loc)
+		require.NoError(t, err)
+		nodes[i] = NewNode(transport, identities[i], logger.NewExampleLogger(loc))
+	}
+	session := &testSession{}
+	_, err = nodes[0].startSession(1, session, locations)
+	require.NoError(t, err)
+
+	receive := func(msg *peering.PeerMessage, from int) {
+		msg.SenderNetworkId = locations[from]
+		nodes[0].receiveMessage(msg)
+	}
+	data := []byte("session message")
+	receive(nodes[1].signMessage(1, 1, locations[0], 5, data), 1)
+	require.Len(t, session.received, 1)
+	assert.Equal(t, receivedMsg{1, 5, data}, session.received[0])
+
+	// signed by another participant
+	receive(nodes[2].signMessage(1, 1, locations[0], 5, data), 1)
+	// addressed to another node
+	receive(nodes[1].signMessage(1, 1, locations[2], 5, data), 1)
+	// signed for another message type
+	msg := nodes[1].signMessage(1, 1, locations[0], 5, data)
+	msg.MsgType = 6
+	receive(msg, 1)
+	// tampered
+	msg = nodes[1].signMessage(1, 1, locations[0], 5, data)
+	msg.MsgData[len(msg.MsgData)-70] ^= 0xff
+	receive(msg, 1)
+	assert.Len(t, session.received, 1)
+}
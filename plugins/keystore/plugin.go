@@ -0,0 +1,26 @@
+// Package keystore is a plugin which unlocks the master key of the node at startup.
+// The master key encrypts private keys of DKShares in the registry
+package keystore
+
+import (
+	"github.com/iotaledger/hive.go/logger"
+	"github.com/iotaledger/hive.go/node"
+	"github.com/iotaledger/wasp/packages/registry"
+)
+
+// PluginName is the name of the Keystore plugin.
+const PluginName = "Keystore"
+
+var (
+	Plugin = node.NewPlugin(PluginName, node.Enabled, configure)
+	log    *logger.Logger
+)
+
+func configure(_ *node.Plugin) {
+	log = logger.NewLogger(PluginName)
+	registry.InitLogger()
+
+	if err := registry.UnlockKeystore(); err != nil {
+		log.Panicf("failed to unlock keystore: %v", err)
+	}
+}
@@ -1,24 +1,32 @@
 package nodeconn
 
 import (
-	"github.com/iotaledger/goshimmer/dapps/waspconn/packages/chopper"
 	"github.com/iotaledger/goshimmer/dapps/waspconn/packages/waspconn"
 	"github.com/iotaledger/hive.go/events"
-	"github.com/iotaledger/hive.go/netutil/buffconn"
 	"time"
 )
 
-var EventMessageReceived *events.Event
+// LedgerEvents are events of the ledger connection
+type LedgerEvents struct {
+	// MessageReceived is triggered with the message from the ledger
+	MessageReceived *events.Event
+	// Connected is triggered when the connection with the ledger is (re)established
+	Connected *events.Event
+}
 
-func init() {
-	EventMessageReceived = events.NewEvent(param1Caller)
+func newLedgerEvents() *LedgerEvents {
+	return &LedgerEvents{
+		MessageReceived: events.NewEvent(param1Caller),
+		Connected:       events.NewEvent(events.CallbackCaller),
+	}
 }
 
 func param1Caller(handler interface{}, params ...interface{}) {
 	handler.(func(interface{}))(params[0])
 }
 
-func msgDataToEvent(data []byte) {
+// msgDataToEvent triggers the event for the message from the node. Only messages from the primary node are passed on
+func (ep *nodeEndpoint) msgDataToEvent(data []byte) {
 	msg, err := waspconn.DecodeMsg(data, true)
 	if err != nil {
 		log.Errorf("wrong message from node: %v", err)
@@ -29,20 +37,31 @@ func msgDataToEvent(data []byte) {
 
 	switch msgt := msg.(type) {
 	case *waspconn.WaspMsgChunk:
-		finalData, err := chopper.IncomingChunk(msgt.Data, buffconn.MaxMessageSize-waspconn.ChunkMessageHeaderSize)
+		assembler := ep.chunkAssembler()
+		if assembler == nil {
+			return
+		}
+		finalData, err := assembler.Incoming(msgt.Data, time.Now())
 		if err != nil {
 			log.Errorf("receiving message chunk: %v", err)
 			return
 		}
 		if finalData != nil {
-			msgDataToEvent(finalData)
+			ep.msgDataToEvent(finalData)
 		}
 
 	case *waspconn.WaspPingMsg:
-		roundtrip := time.Since(time.Unix(0, msgt.Timestamp))
-		log.Infof("PING %d response from node. Roundtrip %v", msgt.Id, roundtrip)
+		ep.pongReceived(time.Now())
+		if msgt.Id != healthCheckPingId {
+			roundtrip := time.Since(time.Unix(0, msgt.Timestamp))
+			log.Infof("PING %d response from node %s. Roundtrip %v", msgt.Id, ep.address, roundtrip)
+		}
 
 	default:
-		EventMessageReceived.Trigger(msgt)
+		if getPrimary() != ep {
+			log.Debugf("message %T from node %s which is not primary ignored", msgt, ep.address)
+			return
+		}
+		goshimmerConn.events.MessageReceived.Trigger(msgt)
 	}
 }
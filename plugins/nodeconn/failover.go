@@ -0,0 +1,173 @@
+package nodeconn
+
+import (
+	"sync"
+	"time"
+
+	"github.com/iotaledger/goshimmer/dapps/waspconn/packages/waspconn"
+	"github.com/iotaledger/hive.go/netutil/buffconn"
+	"github.com/iotaledger/wasp/packages/chunks"
+)
+
+// Health of Goshimmer nodes is checked with pings every healthCheckPeriod. The node is healthy
+// if it is connected and answered a ping during the last unhealthyAfter.
+// The primary node is changed only when it becomes unhealthy, to the first healthy node in the configured order.
+// All subscriptions are sent to the new primary node
+const (
+	healthCheckPeriod = 2 * time.Second
+	unhealthyAfter    = 3 * healthCheckPeriod
+	// id of pings sent by health checks
+	healthCheckPingId = ^uint32(0)
+)
+
+// nodeEndpoint is the connection with one Goshimmer node
+type nodeEndpoint struct {
+	address string
+
+	mutex       sync.RWMutex
+	bconn       *buffconn.BufferedConnection
+	chunks      *chunks.Assembler
+	connectedAt time.Time
+	lastPong    time.Time
+}
+
+func newNodeEndpoints(addrs []string) []*nodeEndpoint {
+	ret := make([]*nodeEndpoint, len(addrs))
+	for i, addr := range addrs {
+		ret[i] = &nodeEndpoint{address: addr}
+	}
+	return ret
+}
+
+func (ep *nodeEndpoint) setConnection(bconn *buffconn.BufferedConnection) {
+	ep.mutex.Lock()
+	defer ep.mutex.Unlock()
+
+	ep.bconn = bconn
+	ep.chunks = newChunkAssembler()
+	ep.connectedAt = time.Now()
+	ep.lastPong = time.Time{}
+}
+
+// clearConnection forgets the closed connection unless it was already replaced
+func (ep *nodeEndpoint) clearConnection(bconn *buffconn.BufferedConnection) {
+	ep.mutex.Lock()
+	defer ep.mutex.Unlock()
+
+	if ep.bconn == bconn {
+		ep.bconn = nil
+	}
+}
+
+func (ep *nodeEndpoint) close() {
+	ep.mutex.RLock()
+	defer ep.mutex.RUnlock()
+
+	if ep.bconn != nil {
+		log.Infof("Closing connection with node %s..", ep.address)
+		_ = ep.bconn.Close()
+		log.Infof("Closing connection with node %s.. Done", ep.address)
+	}
+}
+
+// chunkAssembler returns the assembler of chunks received from the current connection
+func (ep *nodeEndpoint) chunkAssembler() *chunks.Assembler {
+	ep.mutex.RLock()
+	defer ep.mutex.RUnlock()
+	return ep.chunks
+}
+
+func (ep *nodeEndpoint) pongReceived(now time.Time) {
+	ep.mutex.Lock()
+	defer ep.mutex.Unlock()
+	ep.lastPong = now
+}
+
+func (ep *nodeEndpoint) isHealthy(now time.Time) bool {
+	ep.mutex.RLock()
+	defer ep.mutex.RUnlock()
+
+	if ep.bconn == nil {
+		return false
+	}
+	lastSeen := ep.lastPong
+	if lastSeen.Before(ep.connectedAt) {
+		// not answered yet
+		lastSeen = ep.connectedAt
+	}
+	return now.Sub(lastSeen) < unhealthyAfter
+}
+
+func (ep *nodeEndpoint) sendPing(now time.Time) error {
+	data, err := waspconn.EncodeMsg(&waspconn.WaspPingMsg{
+		Id:        healthCheckPingId,
+		Timestamp: now.UnixNano(),
+	})
+	if err != nil {
+		return err
+	}
+	return ep.sendData(data)
+}
+
+func getPrimary() *nodeEndpoint {
+	primaryMutex.RLock()
+	defer primaryMutex.RUnlock()
+	return primary
+}
+
+// selectPrimary keeps the primary node while it is healthy, otherwise switches to the first healthy node.
+// When the primary node changes, subscriptions are sent to the new one and the Connected event is triggered
+func selectPrimary(now time.Time) {
+	primaryMutex.Lock()
+	prev := primary
+	if primary == nil || !primary.isHealthy(now) {
+		primary = nil
+		for _, ep := range endpoints {
+			if ep.isHealthy(now) {
+				primary = ep
+				break
+			}
+		}
+	}
+	changed := primary != prev
+	newPrimary := primary
+	if changed {
+		subscriptionsSent = false
+	}
+	primaryMutex.Unlock()
+
+	if !changed {
+		return
+	}
+	switch {
+	case newPrimary == nil:
+		log.Errorf("no healthy Goshimmer node to connect to")
+	case prev == nil:
+		log.Infof("connected to Goshimmer node %s", newPrimary.address)
+	default:
+		log.Warnf("Goshimmer node %s is not healthy. Switched to %s", prev.address, newPrimary.address)
+	}
+	if newPrimary != nil {
+		// subscriptions go first, so the node doesn't miss updates of addresses it requests after reconnection
+		sendSubscriptionsIfNeeded()
+		goshimmerConn.events.Connected.Trigger()
+	}
+}
+
+// keepCheckingHealth pings all connected nodes and switches the primary node if needed
+func keepCheckingHealth(shutdownSignal <-chan struct{}) {
+	for {
+		select {
+		case <-shutdownSignal:
+			return
+		case <-time.After(healthCheckPeriod):
+		}
+		now := time.Now()
+		for _, ep := range endpoints {
+			if err := ep.sendPing(now); err != nil {
+				log.Debugf("health check of node %s: %v", ep.address, err)
+			}
+		}
+		selectPrimary(now)
+	}
+}
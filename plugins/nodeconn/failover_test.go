@@ -0,0 +1,153 @@
+package nodeconn
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	"github.com/iotaledger/goshimmer/dapps/waspconn/packages/waspconn"
+	"github.com/iotaledger/hive.go/events"
+	"github.com/iotaledger/hive.go/netutil/buffconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeNode is Goshimmer node which answers pings and records all other messages from Wasp
+type fakeNode struct {
+	listener net.Listener
+	mutex    sync.Mutex
+	conns    []net.Conn
+	received []interface{}
+}
+
+func startFakeNode(t *testing.T) *fakeNode {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ret := &fakeNode{listener: listener}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			ret.mutex.Lock()
+			ret.conns = append(ret.conns, conn)
+			ret.mutex.Unlock()
+
+			bconn := buffconn.NewBufferedConnection(conn)
+			bconn.Events.ReceiveMessage.Attach(events.NewClosure(func(data []byte) {
+				msg, err := waspconn.DecodeMsg(data, false)
+				if err != nil {
+					return
+				}
+				if ping, ok := msg.(*waspconn.WaspPingMsg); ok {
+					if data, err := waspconn.EncodeMsg(ping); err == nil {
+						_, _ = bconn.Write(data)
+					}
+					return
+				}
+				ret.mutex.Lock()
+				ret.received = append(ret.received, msg)
+				ret.mutex.Unlock()
+			}))
+			go func() { _ = bconn.Read() }()
+		}
+	}()
+	return ret
+}
+
+func (n *fakeNode) address() string {
+	return n.listener.Addr().String()
+}
+
+func (n *fakeNode) stop() {
+	_ = n.listener.Close()
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	for _, conn := range n.conns {
+		_ = conn.Close()
+	}
+}
+
+func (n *fakeNode) hasReceived(match func(msg interface{}) bool) bool {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	for _, msg := range n.received {
+		if match(msg) {
+			return true
+		}
+	}
+	return false
+}
+
+func isSubscribe(msg interface{}) bool {
+	_, ok := msg.(*waspconn.WaspToNodeSubscribeMsg)
+	return ok
+}
+
+func isTransaction(msg interface{}) bool {
+	_, ok := msg.(*waspconn.WaspToNodeTransactionMsg)
+	return ok
+}
+
+func TestFailover(t *testing.T) {
+	node1 := startFakeNode(t)
+	defer node1.stop()
+	node2 := startFakeNode(t)
+	defer node2.stop()
+
+	endpoints = newNodeEndpoints([]string{node1.address(), node2.address()})
+	postToAll = true
+	numConnected := 0
+	var connectedMutex sync.Mutex
+	goshimmerConn.Events().Connected.Attach(events.NewClosure(func() {
+		connectedMutex.Lock()
+		defer connectedMutex.Unlock()
+		numConnected++
+	}))
+	getNumConnected := func() int {
+		connectedMutex.Lock()
+		defer connectedMutex.Unlock()
+		return numConnected
+	}
+
+	shutdown := make(chan struct{})
+	defer close(shutdown)
+	go goshimmerConn.Run(shutdown)
+
+	require.Eventually(t, func() bool {
+		return getPrimary() != nil && endpoints[1].isHealthy(time.Now())
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1, getNumConnected())
+	// the node connected first is used
+	used, other := node1, node2
+	if getPrimary().address == node2.address() {
+		used, other = node2, node1
+	}
+
+	goshimmerConn.Subscribe([]address.Address{address.Random()})
+	require.Eventually(t, func() bool { return used.hasReceived(isSubscribe) }, 5*time.Second, 10*time.Millisecond)
+	assert.False(t, other.hasReceived(isSubscribe))
+
+	require.NoError(t, goshimmerConn.PostTransaction(newTestTx(1)))
+	require.Eventually(t, func() bool {
+		return used.hasReceived(isTransaction) && other.hasReceived(isTransaction)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// the primary node goes down. The dropped connection is closed and forgotten
+	usedEndpoint := getPrimary()
+	used.stop()
+	require.Eventually(t, func() bool {
+		p := getPrimary()
+		return p != nil && p.address == other.address()
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		usedEndpoint.mutex.RLock()
+		defer usedEndpoint.mutex.RUnlock()
+		return usedEndpoint.bconn == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 2, getNumConnected())
+	require.Eventually(t, func() bool { return other.hasReceived(isSubscribe) }, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,80 @@
+package nodeconn
+
+import (
+	"time"
+
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	valuetransaction "github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/transaction"
+)
+
+// LedgerConnection is the connection of the Wasp node with the ledger of value transactions.
+// Messages from the ledger are waspconn messages WaspFromNodeTransactionMsg, WaspFromNodeAddressOutputsMsg
+// and WaspFromNodeAddressUpdateMsg, delivered with the MessageReceived event.
+// The node connects to the WaspConn plugin of Goshimmer. The embedded ledger (see UtxoDBLedger)
+// keeps the ledger in memory of the Wasp process, so the node can run without Goshimmer
+type LedgerConnection interface {
+	// PostTransaction sends the value transaction to the ledger
+	PostTransaction(tx *valuetransaction.Transaction) error
+	// RequestOutputs requests outputs of the address. They are delivered with WaspFromNodeAddressOutputsMsg
+	RequestOutputs(addr *address.Address) error
+	// RequestTransaction requests the transaction by id. It is delivered with WaspFromNodeTransactionMsg
+	RequestTransaction(txid *valuetransaction.ID) error
+	// Subscribe subscribes to confirmed transactions with outputs to the addresses.
+	// They are delivered with WaspFromNodeAddressUpdateMsg
+	Subscribe(addrs []address.Address)
+	// Unsubscribe cancels the subscription to the address
+	Unsubscribe(addr address.Address)
+	// IsConnected returns true if the ledger is reachable
+	IsConnected() bool
+	// Events returns events of the connection
+	Events() *LedgerEvents
+	// Run runs the connection until the shutdown signal
+	Run(shutdownSignal <-chan struct{})
+}
+
+var ledger LedgerConnection = goshimmerConn
+
+// SetLedgerConnection replaces the ledger connection of the node. Must be called before the plugin is configured
+func SetLedgerConnection(l LedgerConnection) {
+	ledger = l
+}
+
+// GetLedgerConnection returns the ledger connection of the node
+func GetLedgerConnection() LedgerConnection {
+	return ledger
+}
+
+// Events returns events of the ledger connection of the node
+func Events() *LedgerEvents {
+	return ledger.Events()
+}
+
+func RequestOutputsFromNode(addr *address.Address) error {
+	return ledger.RequestOutputs(addr)
+}
+
+func RequestTransactionFromNode(txid *valuetransaction.ID) error {
+	return ledger.RequestTransaction(txid)
+}
+
+// PostTransactionToNode posts the transaction to the ledger and tracks it until it is confirmed
+func PostTransactionToNode(tx *valuetransaction.Transaction) error {
+	return tracker.post(tx, time.Now())
+}
+
+// GetTxMetrics returns counters and confirmation latencies of transactions posted by the node
+func GetTxMetrics() TxMetrics {
+	return tracker.getMetrics()
+}
+
+func Subscribe(addrs []address.Address) {
+	ledger.Subscribe(addrs)
+}
+
+func Unsubscribe(addr address.Address) {
+	ledger.Unsubscribe(addr)
+}
+
+func IsConnected() bool {
+	return ledger.IsConnected()
+}
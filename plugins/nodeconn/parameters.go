@@ -5,9 +5,27 @@ import flag "github.com/spf13/pflag"
 const (
 	CfgNodeAddress = "nodeconn.address"
 	CfgNodeAPIBind = "nodeconn.webapi"
+
+	CfgNodeAddresses = "nodeconn.addresses"
+	CfgNodePostToAll = "nodeconn.postToAll"
+
+	CfgLedger                  = "nodeconn.ledger"
+	CfgUtxoDBConfirmationDelay = "nodeconn.utxodb.confirmationDelay"
+
+	LedgerGoshimmer = "goshimmer"
+	LedgerUtxoDB    = "utxodb"
 )
 
+// Ledgers lists all supported ledgers
+var Ledgers = []string{LedgerGoshimmer, LedgerUtxoDB}
+
 func init() {
 	flag.String(CfgNodeAddress, "127.0.0.1:5000", "node host address")
 	flag.String(CfgNodeAPIBind, "127.0.0.1:8080", "webapi bind address")
+	flag.StringSlice(CfgNodeAddresses, nil,
+		"addresses of Goshimmer nodes in the order of preference. The node fails over to the next one. Overrides "+CfgNodeAddress)
+	flag.Bool(CfgNodePostToAll, false, "post transactions to all healthy Goshimmer nodes, not only to the one in use")
+	flag.String(CfgLedger, LedgerGoshimmer,
+		"ledger of value transactions: 'goshimmer' connects to the Goshimmer node, 'utxodb' runs the ledger in memory of the process, not shared with other processes")
+	flag.Int(CfgUtxoDBConfirmationDelay, 0, "milliseconds until the transaction posted to the embedded ledger is confirmed")
 }
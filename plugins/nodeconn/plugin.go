@@ -4,9 +4,10 @@ import (
 	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
 	"github.com/iotaledger/hive.go/daemon"
 	"github.com/iotaledger/hive.go/logger"
-	"github.com/iotaledger/hive.go/netutil/buffconn"
 	"github.com/iotaledger/hive.go/node"
 	"github.com/iotaledger/wasp/packages/shutdown"
+	"github.com/iotaledger/wasp/plugins/config"
+	"github.com/iotaledger/wasp/plugins/publisher"
 	"sync"
 	"time"
 )
@@ -19,39 +20,55 @@ var (
 	Plugin = node.NewPlugin(PluginName, node.Enabled, configure, run)
 	log    *logger.Logger
 
-	bconn             *buffconn.BufferedConnection
-	bconnMutex        = &sync.RWMutex{}
+	// Goshimmer nodes in the order of preference and the one in use. The mutex guards the primary node and subscriptions
+	endpoints         []*nodeEndpoint
+	primary           *nodeEndpoint
+	primaryMutex      = &sync.RWMutex{}
 	subscriptions     = make(map[address.Address]struct{})
 	subscriptionsSent bool
+	postToAll         bool
+
+	// not nil if the node runs with the embedded ledger
+	utxodbLedger *UtxoDBLedger
+	tracker      *txTracker
 )
 
 func configure(_ *node.Plugin) {
 	log = logger.NewLogger(PluginName)
-}
-
-func run(_ *node.Plugin) {
-	err := daemon.BackgroundWorker(PluginName, func(shutdownSignal <-chan struct{}) {
-		go nodeConnect()
-		go keepSendingSubscriptionIfNeeded(shutdownSignal)
 
-		<-shutdownSignal
-
-		log.Info("Stopping node connection..")
-		go func() {
-			bconnMutex.Lock()
-			defer bconnMutex.Unlock()
+	addrs := config.Node.GetStringSlice(CfgNodeAddresses)
+	if len(addrs) == 0 {
+		addrs = []string{config.Node.GetString(CfgNodeAddress)}
+	}
+	endpoints = newNodeEndpoints(addrs)
+	postToAll = config.Node.GetBool(CfgNodePostToAll)
 
-			if bconn != nil {
-				log.Infof("Closing connection with node..")
-				_ = bconn.Close()
-				log.Infof("Closing connection with node.. Done")
-			}
-		}()
+	switch l := config.Node.GetString(CfgLedger); l {
+	case LedgerGoshimmer:
+	case LedgerUtxoDB:
+		utxodbLedger = NewUtxoDBLedger(time.Duration(config.Node.GetInt(CfgUtxoDBConfirmationDelay))*time.Millisecond, log)
+		SetLedgerConnection(utxodbLedger.NewConnection())
+		log.Infof("running with the embedded ledger. Confirmation delay %v", utxodbLedger.confirmationDelay)
+	default:
+		log.Panicf("unknown ledger '%s'. Supported ledgers: %v", l, Ledgers)
+	}
+	tracker = newTxTracker(ledger, publisher.Publish)
+}
 
-	}, shutdown.PriorityNodeConnection)
+func run(_ *node.Plugin) {
+	err := daemon.BackgroundWorker(PluginName, ledger.Run, shutdown.PriorityNodeConnection)
 	if err != nil {
 		log.Errorf("failed to start NodeConn worker")
 	}
+	err = daemon.BackgroundWorker("NodeConn tx tracker", tracker.run, shutdown.PriorityNodeConnection)
+	if err != nil {
+		log.Errorf("failed to start tx tracker worker")
+	}
+}
+
+// GetUtxoDBLedger returns the embedded ledger or nil if the node is connected to Goshimmer
+func GetUtxoDBLedger() *UtxoDBLedger {
+	return utxodbLedger
 }
 
 func keepSendingSubscriptionIfNeeded(shutdownSignal <-chan struct{}) {
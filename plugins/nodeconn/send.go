@@ -5,22 +5,20 @@ import (
 	valuetransaction "github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/transaction"
 	"github.com/iotaledger/goshimmer/dapps/waspconn/packages/waspconn"
 	"github.com/iotaledger/wasp/plugins/peering"
+	"time"
 )
 
-func SendWaspIdToNode() error {
+func (ep *nodeEndpoint) sendWaspId() error {
 	data, err := waspconn.EncodeMsg(&waspconn.WaspToNodeSetIdMsg{
 		Waspid: peering.MyNetworkId(),
 	})
 	if err != nil {
 		return err
 	}
-	if err := SendDataToNode(data); err != nil {
-		return err
-	}
-	return nil
+	return ep.sendData(data)
 }
 
-func RequestOutputsFromNode(addr *address.Address) error {
+func (*goshimmerConnection) RequestOutputs(addr *address.Address) error {
 	data, err := waspconn.EncodeMsg(&waspconn.WaspToNodeGetOutputsMsg{
 		Address: *addr,
 	})
@@ -33,7 +31,7 @@ func RequestOutputsFromNode(addr *address.Address) error {
 	return nil
 }
 
-func RequestTransactionFromNode(txid *valuetransaction.ID) error {
+func (*goshimmerConnection) RequestTransaction(txid *valuetransaction.ID) error {
 	data, err := waspconn.EncodeMsg(&waspconn.WaspToNodeGetTransactionMsg{
 		TxId: txid,
 	})
@@ -46,7 +44,7 @@ func RequestTransactionFromNode(txid *valuetransaction.ID) error {
 	return nil
 }
 
-func PostTransactionToNode(tx *valuetransaction.Transaction) error {
+func (*goshimmerConnection) PostTransaction(tx *valuetransaction.Transaction) error {
 	data, err := waspconn.EncodeMsg(&waspconn.WaspToNodeTransactionMsg{
 		Tx: tx,
 	})
@@ -56,5 +54,17 @@ func PostTransactionToNode(tx *valuetransaction.Transaction) error {
 	if err = SendDataToNode(data); err != nil {
 		return err
 	}
+	if postToAll {
+		// other healthy nodes receive the transaction too, for faster propagation
+		primary := getPrimary()
+		for _, ep := range endpoints {
+			if ep == primary || !ep.isHealthy(time.Now()) {
+				continue
+			}
+			if err := ep.sendData(data); err != nil {
+				log.Debugf("posting transaction to node %s: %v", ep.address, err)
+			}
+		}
+	}
 	return nil
 }
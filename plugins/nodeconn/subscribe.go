@@ -5,57 +5,55 @@ import (
 	"github.com/iotaledger/goshimmer/dapps/waspconn/packages/waspconn"
 )
 
-func Subscribe(addrs []address.Address) {
-	bconnMutex.Lock()
-	defer bconnMutex.Unlock()
-
+// Subscribe sends new subscriptions to the node right away: the transaction to the new address may be confirmed
+// within a second, e.g. the rotation to the new committee, and the node doesn't send updates it has missed
+func (*goshimmerConnection) Subscribe(addrs []address.Address) {
+	primaryMutex.Lock()
 	for _, a := range addrs {
 		if _, ok := subscriptions[a]; !ok {
 			subscriptionsSent = false
 		}
 		subscriptions[a] = struct{}{}
 	}
+	primaryMutex.Unlock()
+
+	sendSubscriptionsIfNeeded()
 }
 
-func Unsubscribe(addr address.Address) {
-	bconnMutex.Lock()
-	defer bconnMutex.Unlock()
+func (*goshimmerConnection) Unsubscribe(addr address.Address) {
+	primaryMutex.Lock()
+	defer primaryMutex.Unlock()
 
 	delete(subscriptions, addr)
 }
 
+// sendSubscriptionsIfNeeded sends all subscriptions to the primary node, if they were not sent yet to it
 func sendSubscriptionsIfNeeded() {
-	bconnMutex.RLock()
-	if subscriptionsSent || bconn == nil {
-		bconnMutex.RUnlock()
+	primaryMutex.Lock()
+	if subscriptionsSent || primary == nil {
+		primaryMutex.Unlock()
 		return
 	}
-	// switch to write lock
-	bconnMutex.RUnlock()
-	bconnMutex.Lock()
-	defer bconnMutex.Unlock()
-
 	addrs := make([]address.Address, 0, len(subscriptions))
 	for a := range subscriptions {
 		addrs = append(addrs, a)
 	}
 	subscriptionsSent = true
+	primaryMutex.Unlock()
 
-	go func() {
-		data, err := waspconn.EncodeMsg(&waspconn.WaspToNodeSubscribeMsg{
-			Addresses: addrs,
-		})
-		if err != nil {
-			log.Errorf("sending subscriptions: %v", err)
-			return
-		}
-		if err := SendDataToNode(data); err != nil {
-			log.Errorf("sending subscriptions: %v", err)
-			bconnMutex.Lock()
-			defer bconnMutex.Unlock()
-			subscriptionsSent = false
-		} else {
-			log.Infof("sent subscriptions to node for %d addresses", len(addrs))
-		}
-	}()
+	data, err := waspconn.EncodeMsg(&waspconn.WaspToNodeSubscribeMsg{
+		Addresses: addrs,
+	})
+	if err != nil {
+		log.Errorf("sending subscriptions: %v", err)
+		return
+	}
+	if err := SendDataToNode(data); err != nil {
+		log.Errorf("sending subscriptions: %v", err)
+		primaryMutex.Lock()
+		defer primaryMutex.Unlock()
+		subscriptionsSent = false
+	} else {
+		log.Infof("sent subscriptions to node for %d addresses", len(addrs))
+	}
 }
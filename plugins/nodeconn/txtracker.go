@@ -0,0 +1,260 @@
+package nodeconn
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/balance"
+	valuetransaction "github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/transaction"
+	"github.com/iotaledger/goshimmer/dapps/waspconn/packages/waspconn"
+	"github.com/iotaledger/hive.go/events"
+)
+
+// txTracker follows transactions posted by the node until they are confirmed. The transaction is confirmed
+// when the ledger reports its outputs: in the update of the subscribed address or in the outputs of the address.
+// The transaction sent as the response to the request is not a confirmation: the ledger may return
+// the transaction it has seen but not confirmed yet. The ledger doesn't report outputs which are already spent,
+// e.g. by the next state transaction pipelined on the previous one, so the transaction is also confirmed
+// when the confirmed transaction spends its outputs.
+// The tracker requests outputs of addresses of not confirmed transactions every trackerPollPeriod,
+// re-posts transactions after repostAfter and when the connection with the ledger is re-established.
+// The transaction is rejected when the ledger refuses it or it is not confirmed after maxPosts posts
+type txTracker struct {
+	ledger  LedgerConnection
+	publish func(msgType string, parts ...string)
+
+	mutex      sync.Mutex
+	txs        map[valuetransaction.ID]*trackedTx
+	metrics    TxMetrics
+	sumLatency time.Duration
+}
+
+type trackedTx struct {
+	tx       *valuetransaction.Transaction
+	posted   time.Time
+	lastPost time.Time
+	numPosts int
+}
+
+const (
+	trackerPollPeriod = 5 * time.Second
+	repostAfter       = 20 * time.Second
+	maxPosts          = 5
+)
+
+// TxMetrics are counters of transactions posted by the node and latencies of their confirmation,
+// i.e. the time from the first post until the confirmation
+type TxMetrics struct {
+	NumPosted     int   `json:"num_posted"`
+	NumReposted   int   `json:"num_reposted"`
+	NumConfirmed  int   `json:"num_confirmed"`
+	NumRejected   int   `json:"num_rejected"`
+	NumPending    int   `json:"num_pending"`
+	AvgLatencyMs  int64 `json:"avg_latency_ms"`
+	MaxLatencyMs  int64 `json:"max_latency_ms"`
+	LastLatencyMs int64 `json:"last_latency_ms"`
+}
+
+func newTxTracker(ledger LedgerConnection, publish func(msgType string, parts ...string)) *txTracker {
+	return &txTracker{
+		ledger:  ledger,
+		publish: publish,
+		txs:     make(map[valuetransaction.ID]*trackedTx),
+	}
+}
+
+// run follows messages from the ledger and re-posts transactions until the shutdown signal
+func (t *txTracker) run(shutdownSignal <-chan struct{}) {
+	msgClosure := events.NewClosure(func(msg interface{}) {
+		t.messageReceived(msg, time.Now())
+	})
+	connectedClosure := events.NewClosure(func() {
+		t.repostAll(time.Now())
+	})
+	t.ledger.Events().MessageReceived.Attach(msgClosure)
+	t.ledger.Events().Connected.Attach(connectedClosure)
+	defer func() {
+		t.ledger.Events().MessageReceived.Detach(msgClosure)
+		t.ledger.Events().Connected.Detach(connectedClosure)
+	}()
+
+	for {
+		select {
+		case <-shutdownSignal:
+			return
+		case <-time.After(1 * time.Second):
+			t.check(time.Now())
+		}
+	}
+}
+
+// post starts tracking the transaction and posts it to the ledger.
+// Transactions which can't be sent because the ledger is not connected are re-posted later
+func (t *txTracker) post(tx *valuetransaction.Transaction, now time.Time) error {
+	t.mutex.Lock()
+	tt, tracked := t.txs[tx.ID()]
+	if !tracked {
+		tt = &trackedTx{
+			tx:     tx,
+			posted: now,
+		}
+		t.txs[tx.ID()] = tt
+		t.metrics.NumPosted++
+	} else {
+		t.metrics.NumReposted++
+	}
+	tt.lastPost = now
+	tt.numPosts++
+	t.mutex.Unlock()
+
+	if !tracked {
+		t.publish("tx_posted", tx.ID().String())
+	}
+	err := t.ledger.PostTransaction(tx)
+	if err != nil && t.ledger.IsConnected() {
+		t.reject(tx.ID())
+	}
+	return err
+}
+
+// messageReceived confirms transactions with outputs reported by the ledger
+func (t *txTracker) messageReceived(msg interface{}, now time.Time) {
+	switch msgt := msg.(type) {
+	case *waspconn.WaspFromNodeAddressUpdateMsg:
+		t.confirmed(msgt.Tx.ID(), now)
+		t.confirmedSpent(msgt.Tx, now)
+	case *waspconn.WaspFromNodeAddressOutputsMsg:
+		for txid := range msgt.Balances {
+			t.confirmed(txid, now)
+		}
+	}
+}
+
+func (t *txTracker) confirmed(txid valuetransaction.ID, now time.Time) {
+	t.mutex.Lock()
+	tt, ok := t.txs[txid]
+	if !ok {
+		t.mutex.Unlock()
+		return
+	}
+	delete(t.txs, txid)
+	latency := now.Sub(tt.posted)
+	t.sumLatency += latency
+	t.metrics.NumConfirmed++
+	t.metrics.LastLatencyMs = latency.Milliseconds()
+	if t.metrics.LastLatencyMs > t.metrics.MaxLatencyMs {
+		t.metrics.MaxLatencyMs = t.metrics.LastLatencyMs
+	}
+	t.mutex.Unlock()
+
+	log.Debugf("transaction %s confirmed in %v after %d post(s)", txid.String(), latency, tt.numPosts)
+	t.publish("tx_confirmed", txid.String(), fmt.Sprintf("%d", latency.Milliseconds()))
+	t.confirmedSpent(tt.tx, now)
+}
+
+// confirmedSpent confirms transactions with outputs spent by the confirmed transaction
+func (t *txTracker) confirmedSpent(tx *valuetransaction.Transaction, now time.Time) {
+	tx.Inputs().ForEachTransaction(func(txid valuetransaction.ID) bool {
+		t.confirmed(txid, now)
+		return true
+	})
+}
+
+func (t *txTracker) reject(txid valuetransaction.ID) {
+	t.mutex.Lock()
+	tt, ok := t.txs[txid]
+	if !ok {
+		t.mutex.Unlock()
+		return
+	}
+	delete(t.txs, txid)
+	t.metrics.NumRejected++
+	t.mutex.Unlock()
+
+	log.Warnf("transaction %s rejected after %d post(s)", txid.String(), tt.numPosts)
+	t.publish("tx_rejected", txid.String(), fmt.Sprintf("%d", tt.numPosts))
+}
+
+// check requests, re-posts or rejects not confirmed transactions
+func (t *txTracker) check(now time.Time) {
+	var toRequest, toRepost []*valuetransaction.Transaction
+	var toReject []valuetransaction.ID
+
+	t.mutex.Lock()
+	for txid, tt := range t.txs {
+		sinceLastPost := now.Sub(tt.lastPost)
+		switch {
+		case sinceLastPost >= repostAfter && tt.numPosts >= maxPosts:
+			toReject = append(toReject, txid)
+		case sinceLastPost >= repostAfter:
+			toRepost = append(toRepost, tt.tx)
+		case sinceLastPost >= trackerPollPeriod:
+			toRequest = append(toRequest, tt.tx)
+		}
+	}
+	t.mutex.Unlock()
+
+	for _, txid := range toReject {
+		t.reject(txid)
+	}
+	for _, tx := range toRepost {
+		log.Debugf("transaction %s is not confirmed. Re-posting", tx.ID().String())
+		_ = t.post(tx, now)
+	}
+	for _, addr := range outputAddresses(toRequest) {
+		addr := addr
+		_ = t.ledger.RequestOutputs(&addr)
+	}
+}
+
+// outputAddresses returns addresses of outputs of transactions, each address once
+func outputAddresses(txs []*valuetransaction.Transaction) []address.Address {
+	ret := make([]address.Address, 0)
+	seen := make(map[address.Address]bool)
+	for _, tx := range txs {
+		tx.Outputs().ForEach(func(addr address.Address, _ []*balance.Balance) bool {
+			if !seen[addr] {
+				seen[addr] = true
+				ret = append(ret, addr)
+			}
+			return true
+		})
+	}
+	return ret
+}
+
+// repostAll re-posts all not confirmed transactions when the connection is re-established.
+// These posts don't count to maxPosts
+func (t *txTracker) repostAll(now time.Time) {
+	t.mutex.Lock()
+	txs := make([]*valuetransaction.Transaction, 0, len(t.txs))
+	for _, tt := range t.txs {
+		tt.lastPost = now
+		txs = append(txs, tt.tx)
+	}
+	t.metrics.NumReposted += len(txs)
+	t.mutex.Unlock()
+
+	if len(txs) > 0 {
+		log.Infof("re-posting %d not confirmed transaction(s) after reconnection", len(txs))
+	}
+	for _, tx := range txs {
+		if err := t.ledger.PostTransaction(tx); err != nil {
+			log.Warnf("re-posting transaction %s: %v", tx.ID().String(), err)
+		}
+	}
+}
+
+func (t *txTracker) getMetrics() TxMetrics {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	ret := t.metrics
+	ret.NumPending = len(t.txs)
+	if ret.NumConfirmed > 0 {
+		ret.AvgLatencyMs = (t.sumLatency / time.Duration(ret.NumConfirmed)).Milliseconds()
+	}
+	return ret
+}
@@ -0,0 +1,180 @@
+package nodeconn
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/balance"
+	valuetransaction "github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/transaction"
+	"github.com/iotaledger/goshimmer/dapps/waspconn/packages/waspconn"
+	"github.com/stretchr/testify/assert"
+)
+
+// testLedger records posts and requests
+type testLedger struct {
+	events    *LedgerEvents
+	connected bool
+	postErr   error
+	posted    []valuetransaction.ID
+	requested []address.Address
+}
+
+func (l *testLedger) PostTransaction(tx *valuetransaction.Transaction) error {
+	if !l.connected {
+		return errors.New("not connected")
+	}
+	l.posted = append(l.posted, tx.ID())
+	return l.postErr
+}
+
+func (l *testLedger) RequestOutputs(addr *address.Address) error {
+	l.requested = append(l.requested, *addr)
+	return nil
+}
+
+func (l *testLedger) RequestTransaction(*valuetransaction.ID) error { return nil }
+
+func (l *testLedger) Subscribe([]address.Address)  {}
+func (l *testLedger) Unsubscribe(address.Address)  {}
+func (l *testLedger) IsConnected() bool            { return l.connected }
+func (l *testLedger) Events() *LedgerEvents        { return l.events }
+func (l *testLedger) Run(shutdown <-chan struct{}) { <-shutdown }
+
+func newTestTx(value int64) *valuetransaction.Transaction {
+	return newTestTxTo(address.Random(), value)
+}
+
+func newTestTxTo(addr address.Address, value int64) *valuetransaction.Transaction {
+	return valuetransaction.New(
+		valuetransaction.NewInputs(valuetransaction.NewOutputID(address.Random(), valuetransaction.ID{1})),
+		valuetransaction.NewOutputs(map[address.Address][]*balance.Balance{
+			addr: {balance.New(balance.ColorIOTA, value)},
+		}),
+	)
+}
+
+func TestTxTracker(t *testing.T) {
+	ledger := &testLedger{events: newLedgerEvents(), connected: true}
+	var published []string
+	tracker := newTxTracker(ledger, func(msgType string, parts ...string) {
+		published = append(published, msgType+" "+strings.Join(parts, " "))
+	})
+	now := time.Now()
+
+	addr1 := address.Random()
+	tx1 := newTestTxTo(addr1, 1)
+	assert.NoError(t, tracker.post(tx1, now))
+	assert.Equal(t, []valuetransaction.ID{tx1.ID()}, ledger.posted)
+	assert.Equal(t, []string{"tx_posted " + tx1.ID().String()}, published)
+
+	// outputs of the address are polled. The transaction sent by the ledger is not a confirmation
+	tracker.check(now.Add(trackerPollPeriod))
+	assert.Equal(t, []address.Address{addr1}, ledger.requested)
+	tracker.messageReceived(&waspconn.WaspFromNodeTransactionMsg{Tx: tx1}, now.Add(trackerPollPeriod))
+	assert.Equal(t, 1, tracker.getMetrics().NumPending)
+
+	// confirmed by the address update
+	tracker.messageReceived(&waspconn.WaspFromNodeAddressUpdateMsg{Tx: tx1}, now.Add(trackerPollPeriod+time.Second))
+	assert.Equal(t, "tx_confirmed "+tx1.ID().String()+" 6000", published[1])
+	metrics := tracker.getMetrics()
+	assert.Equal(t, 1, metrics.NumConfirmed)
+	assert.Zero(t, metrics.NumPending)
+	assert.EqualValues(t, 6000, metrics.AvgLatencyMs)
+
+	// not sent while disconnected, re-posted on reconnection
+	ledger.connected = false
+	tx2 := newTestTx(2)
+	assert.Error(t, tracker.post(tx2, now))
+	assert.Equal(t, 1, tracker.getMetrics().NumPending)
+	ledger.connected = true
+	tracker.repostAll(now.Add(time.Second))
+	assert.Equal(t, tx2.ID(), ledger.posted[len(ledger.posted)-1])
+
+	// re-posted until maxPosts, then rejected
+	ledger.posted = nil
+	for i := 1; i <= maxPosts; i++ {
+		tracker.check(now.Add(time.Second + time.Duration(i)*repostAfter))
+	}
+	assert.Len(t, ledger.posted, maxPosts-1)
+	metrics = tracker.getMetrics()
+	assert.Equal(t, 1, metrics.NumRejected)
+	assert.Zero(t, metrics.NumPending)
+	assert.Equal(t, "tx_rejected "+tx2.ID().String()+" 5", published[len(published)-1])
+
+	// refused by the ledger
+	ledger.postErr = errors.New("conflict")
+	assert.Error(t, tracker.post(newTestTx(3), now))
+	metrics = tracker.getMetrics()
+	assert.Equal(t, 2, metrics.NumRejected)
+	assert.Equal(t, 3, metrics.NumPosted)
+	assert.Zero(t, metrics.NumPending)
+
+	// confirmed by outputs of the address
+	tx5 := newTestTx(5)
+	ledger.postErr = nil
+	assert.NoError(t, tracker.post(tx5, now))
+	tracker.messageReceived(&waspconn.WaspFromNodeAddressOutputsMsg{
+		Balances: map[valuetransaction.ID][]*balance.Balance{tx5.ID(): {balance.New(balance.ColorIOTA, 5)}},
+	}, now.Add(time.Second))
+	assert.Equal(t, 2, tracker.getMetrics().NumConfirmed)
+
+	// transactions not posted by the node are ignored
+	tracker.messageReceived(&waspconn.WaspFromNodeAddressUpdateMsg{Tx: newTestTx(4)}, now)
+	assert.Equal(t, 2, tracker.getMetrics().NumConfirmed)
+}
+
+// the ledger doesn't report outputs spent by the next transaction. The transaction is confirmed
+// by the confirmation of the transaction spending its outputs, it is not re-posted and rejected
+func TestTxTrackerSpentOutputs(t *testing.T) {
+	ledger := &testLedger{events: newLedgerEvents(), connected: true}
+	var published []string
+	tracker := newTxTracker(ledger, func(msgType string, parts ...string) {
+		published = append(published, msgType+" "+strings.Join(parts, " "))
+	})
+	now := time.Now()
+	spending := func(prev *valuetransaction.Transaction) *valuetransaction.Transaction {
+		var addr address.Address
+		prev.Outputs().ForEach(func(a address.Address, _ []*balance.Balance) bool {
+			addr = a
+			return false
+		})
+		return valuetransaction.New(
+			valuetransaction.NewInputs(valuetransaction.NewOutputID(addr, prev.ID())),
+			valuetransaction.NewOutputs(map[address.Address][]*balance.Balance{
+				addr: {balance.New(balance.ColorIOTA, 1)},
+			}),
+		)
+	}
+
+	// the state transaction and the one pipelined on it are confirmed by outputs of the last one
+	tx1 := newTestTx(1)
+	tx2 := spending(tx1)
+	tx3 := spending(tx2)
+	for _, tx := range []*valuetransaction.Transaction{tx1, tx2, tx3} {
+		assert.NoError(t, tracker.post(tx, now))
+	}
+	tracker.messageReceived(&waspconn.WaspFromNodeAddressOutputsMsg{
+		Balances: map[valuetransaction.ID][]*balance.Balance{tx3.ID(): {balance.New(balance.ColorIOTA, 1)}},
+	}, now.Add(time.Second))
+	metrics := tracker.getMetrics()
+	assert.Equal(t, 3, metrics.NumConfirmed)
+	assert.Zero(t, metrics.NumPending)
+	assert.Contains(t, published, "tx_confirmed "+tx1.ID().String()+" 1000")
+
+	// confirmed by the update with the transaction not posted by the node
+	tx4 := newTestTx(4)
+	assert.NoError(t, tracker.post(tx4, now))
+	tracker.messageReceived(&waspconn.WaspFromNodeAddressUpdateMsg{Tx: spending(tx4)}, now.Add(time.Second))
+	assert.Equal(t, 4, tracker.getMetrics().NumConfirmed)
+
+	// nothing is left to re-post or reject
+	ledger.posted = nil
+	for i := 1; i <= maxPosts; i++ {
+		tracker.check(now.Add(time.Duration(i) * repostAfter))
+	}
+	assert.Empty(t, ledger.posted)
+	assert.Zero(t, tracker.getMetrics().NumRejected)
+}
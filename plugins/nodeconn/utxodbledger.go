@@ -0,0 +1,318 @@
+package nodeconn
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/balance"
+	valuetransaction "github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/transaction"
+	"github.com/iotaledger/goshimmer/dapps/waspconn/packages/utxodb"
+	"github.com/iotaledger/goshimmer/dapps/waspconn/packages/waspconn"
+	"github.com/iotaledger/hive.go/logger"
+)
+
+// UtxoDBLedger is the ledger of value transactions in memory of the process, the same utxodb
+// the WaspConn plugin of Goshimmer uses. Posted transactions are checked at once and confirmed,
+// i.e. added to the ledger, after the confirmation delay in the order they were posted.
+// The utxodb is global to the process, so all Wasp nodes running in the process share the ledger,
+// each node with its own connection
+type UtxoDBLedger struct {
+	confirmationDelay time.Duration
+	log               *logger.Logger
+
+	mutex       sync.Mutex
+	connections map[*utxodbConnection]struct{}
+	// posted and not confirmed yet, in the order of posting
+	pending []pendingTx
+}
+
+type pendingTx struct {
+	tx          *valuetransaction.Transaction
+	confirmTime time.Time
+}
+
+// NewUtxoDBLedger creates the embedded ledger. Transactions are confirmed immediately with zero delay
+func NewUtxoDBLedger(confirmationDelay time.Duration, log *logger.Logger) *UtxoDBLedger {
+	return &UtxoDBLedger{
+		confirmationDelay: confirmationDelay,
+		log:               log,
+		connections:       make(map[*utxodbConnection]struct{}),
+	}
+}
+
+// NewConnection returns new connection with the ledger. It is closed when it stops running
+func (l *UtxoDBLedger) NewConnection() LedgerConnection {
+	return l.newConnection()
+}
+
+func (l *UtxoDBLedger) newConnection() *utxodbConnection {
+	ret := &utxodbConnection{
+		ledger:        l,
+		events:        newLedgerEvents(),
+		subscriptions: make(map[address.Address]struct{}),
+		chSignal:      make(chan struct{}, 1),
+		chClosed:      make(chan struct{}),
+	}
+	l.mutex.Lock()
+	l.connections[ret] = struct{}{}
+	l.mutex.Unlock()
+
+	go ret.deliverLoop()
+	return ret
+}
+
+// GetAddressOutputs returns confirmed outputs of the address
+func (l *UtxoDBLedger) GetAddressOutputs(addr address.Address) map[valuetransaction.OutputID][]*balance.Balance {
+	return utxodb.GetAddressOutputs(addr)
+}
+
+// PostTransaction checks the transaction and schedules its confirmation.
+// With zero confirmation delay the transaction is confirmed before return and conflicts are reported as errors,
+// otherwise conflicting transactions are rejected at the confirmation and only logged
+func (l *UtxoDBLedger) PostTransaction(tx *valuetransaction.Transaction) error {
+	if err := utxodb.CheckInputsOutputs(tx); err != nil {
+		return fmt.Errorf("%v: txid %s", err, tx.ID().String())
+	}
+	if !tx.SignaturesValid() {
+		return fmt.Errorf("invalid signature txid = %s", tx.ID().String())
+	}
+	if l.confirmationDelay <= 0 {
+		return l.confirm(tx)
+	}
+	l.mutex.Lock()
+	l.pending = append(l.pending, pendingTx{
+		tx:          tx,
+		confirmTime: time.Now().Add(l.confirmationDelay),
+	})
+	l.mutex.Unlock()
+
+	time.AfterFunc(l.confirmationDelay, func() {
+		l.confirmPending(time.Now())
+	})
+	return nil
+}
+
+// confirmPending confirms pending transactions which are due at the time
+func (l *UtxoDBLedger) confirmPending(now time.Time) {
+	for {
+		l.mutex.Lock()
+		if len(l.pending) == 0 || l.pending[0].confirmTime.After(now) {
+			l.mutex.Unlock()
+			return
+		}
+		tx := l.pending[0].tx
+		l.pending = l.pending[1:]
+		l.mutex.Unlock()
+
+		if err := l.confirm(tx); err != nil {
+			l.log.Warnf("embedded ledger: transaction rejected: %v", err)
+		}
+	}
+}
+
+// confirm adds the transaction to the ledger and notifies all connections subscribed to its outputs
+func (l *UtxoDBLedger) confirm(tx *valuetransaction.Transaction) error {
+	if err := utxodb.AddTransaction(tx); err != nil {
+		return err
+	}
+	l.log.Debugf("embedded ledger: confirmed transaction %s", tx.ID().String())
+
+	l.mutex.Lock()
+	conns := make([]*utxodbConnection, 0, len(l.connections))
+	for c := range l.connections {
+		conns = append(conns, c)
+	}
+	l.mutex.Unlock()
+
+	for _, c := range conns {
+		c.transactionConfirmed(tx)
+	}
+	return nil
+}
+
+func (l *UtxoDBLedger) removeConnection(c *utxodbConnection) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	delete(l.connections, c)
+}
+
+// utxodbConnection is the connection of the node with the embedded ledger.
+// Messages to the node are queued without limit, so the ledger never waits for the node
+type utxodbConnection struct {
+	ledger *UtxoDBLedger
+	events *LedgerEvents
+
+	mutex         sync.Mutex
+	subscriptions map[address.Address]struct{}
+	queue         []interface{}
+	chSignal      chan struct{}
+	closeOnce     sync.Once
+	chClosed      chan struct{}
+}
+
+func (c *utxodbConnection) Events() *LedgerEvents {
+	return c.events
+}
+
+func (c *utxodbConnection) Run(shutdownSignal <-chan struct{}) {
+	<-shutdownSignal
+	c.close()
+}
+
+func (c *utxodbConnection) close() {
+	c.closeOnce.Do(func() {
+		c.ledger.removeConnection(c)
+		close(c.chClosed)
+	})
+}
+
+func (c *utxodbConnection) IsConnected() bool {
+	select {
+	case <-c.chClosed:
+		return false
+	default:
+		return true
+	}
+}
+
+func (c *utxodbConnection) PostTransaction(tx *valuetransaction.Transaction) error {
+	if !c.IsConnected() {
+		return fmt.Errorf("PostTransaction: not connected to the ledger")
+	}
+	return c.ledger.PostTransaction(tx)
+}
+
+func (c *utxodbConnection) RequestOutputs(addr *address.Address) error {
+	if !c.IsConnected() {
+		return fmt.Errorf("RequestOutputs: not connected to the ledger")
+	}
+	outs := utxodb.GetAddressOutputs(*addr)
+	if len(outs) == 0 {
+		return nil
+	}
+	c.send(&waspconn.WaspFromNodeAddressOutputsMsg{
+		Address:  *addr,
+		Balances: waspconn.OutputsToBalances(outs),
+	})
+	return nil
+}
+
+func (c *utxodbConnection) RequestTransaction(txid *valuetransaction.ID) error {
+	if !c.IsConnected() {
+		return fmt.Errorf("RequestTransaction: not connected to the ledger")
+	}
+	tx, ok := utxodb.GetTransaction(*txid)
+	if !ok {
+		c.ledger.log.Debugf("embedded ledger: transaction %s not found", txid.String())
+		return nil
+	}
+	c.send(&waspconn.WaspFromNodeTransactionMsg{
+		Tx: tx,
+	})
+	return nil
+}
+
+// Subscribe sends transactions with outputs to new subscribed addresses as Goshimmer does:
+// for each color of tokens at the address, the transaction which minted it
+func (c *utxodbConnection) Subscribe(addrs []address.Address) {
+	newAddrs := make([]address.Address, 0, len(addrs))
+	c.mutex.Lock()
+	for _, addr := range addrs {
+		if _, ok := c.subscriptions[addr]; !ok {
+			c.subscriptions[addr] = struct{}{}
+			newAddrs = append(newAddrs, addr)
+		}
+	}
+	c.mutex.Unlock()
+
+	for _, addr := range newAddrs {
+		c.pushBacklog(addr)
+	}
+}
+
+func (c *utxodbConnection) Unsubscribe(addr address.Address) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.subscriptions, addr)
+}
+
+func (c *utxodbConnection) isSubscribed(addr address.Address) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	_, ok := c.subscriptions[addr]
+	return ok
+}
+
+func (c *utxodbConnection) pushBacklog(addr address.Address) {
+	outs := utxodb.GetAddressOutputs(addr)
+	if len(outs) == 0 {
+		return
+	}
+	balances := waspconn.OutputsToBalances(outs)
+	colors := make(map[valuetransaction.ID]struct{})
+	for _, bals := range balances {
+		for _, b := range bals {
+			if b.Color != balance.ColorIOTA && b.Color != balance.ColorNew {
+				colors[(valuetransaction.ID)(b.Color)] = struct{}{}
+			}
+		}
+	}
+	for txid := range colors {
+		tx, ok := utxodb.GetTransaction(txid)
+		if !ok {
+			c.ledger.log.Errorf("embedded ledger: inconsistency: can't find txid = %s", txid.String())
+			continue
+		}
+		c.send(&waspconn.WaspFromNodeAddressUpdateMsg{
+			Address:  addr,
+			Balances: balances,
+			Tx:       tx,
+		})
+	}
+}
+
+// transactionConfirmed sends the transaction with outputs of each subscribed address
+func (c *utxodbConnection) transactionConfirmed(tx *valuetransaction.Transaction) {
+	tx.Outputs().ForEach(func(addr address.Address, _ []*balance.Balance) bool {
+		if c.isSubscribed(addr) {
+			c.send(&waspconn.WaspFromNodeAddressUpdateMsg{
+				Address:  addr,
+				Balances: waspconn.OutputsToBalances(utxodb.GetAddressOutputs(addr)),
+				Tx:       tx,
+			})
+		}
+		return true
+	})
+}
+
+func (c *utxodbConnection) send(msg interface{}) {
+	c.mutex.Lock()
+	c.queue = append(c.queue, msg)
+	c.mutex.Unlock()
+
+	select {
+	case c.chSignal <- struct{}{}:
+	default:
+	}
+}
+
+// deliverLoop triggers events for messages in the order they were sent
+func (c *utxodbConnection) deliverLoop() {
+	for {
+		select {
+		case <-c.chSignal:
+		case <-c.chClosed:
+			return
+		}
+		c.mutex.Lock()
+		msgs := c.queue
+		c.queue = nil
+		c.mutex.Unlock()
+
+		for _, msg := range msgs {
+			c.events.MessageReceived.Trigger(msg)
+		}
+	}
+}
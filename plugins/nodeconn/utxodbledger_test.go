@@ -0,0 +1,191 @@
+package nodeconn
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	valuetransaction "github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/transaction"
+	"github.com/iotaledger/goshimmer/dapps/waspconn/packages/utxodb"
+	"github.com/iotaledger/goshimmer/dapps/waspconn/packages/waspconn"
+	"github.com/iotaledger/hive.go/events"
+	"github.com/iotaledger/hive.go/logger"
+	"github.com/iotaledger/hive.go/netutil/buffconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	log = logger.NewExampleLogger(PluginName)
+}
+
+func receiveMessages(c LedgerConnection) chan interface{} {
+	ret := make(chan interface{}, 10)
+	c.Events().MessageReceived.Attach(events.NewClosure(func(msg interface{}) {
+		ret <- msg
+	}))
+	return ret
+}
+
+func expectMessage(t *testing.T, ch chan interface{}) interface{} {
+	select {
+	case msg := <-ch:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("message not received")
+		return nil
+	}
+}
+
+// expectNoMessage checks that the connection has not delivered any message before the answer to the request
+// of outputs of the genesis address: messages are delivered in the order they are sent
+func expectNoMessage(t *testing.T, c LedgerConnection, ch chan interface{}) {
+	genesis := utxodb.GetGenesisAddress()
+	require.NoError(t, c.RequestOutputs(&genesis))
+	msg, ok := expectMessage(t, ch).(*waspconn.WaspFromNodeAddressOutputsMsg)
+	require.True(t, ok, "unexpected message %T", msg)
+	assert.Equal(t, genesis, msg.Address)
+}
+
+func TestUtxoDBLedger(t *testing.T) {
+	// the ledger is confirmed by the test, not by the timer
+	const delay = time.Hour
+	utxodb.Init()
+	ledger := NewUtxoDBLedger(delay, log)
+	conn1 := ledger.NewConnection()
+	conn2 := ledger.NewConnection()
+	received1 := receiveMessages(conn1)
+	received2 := receiveMessages(conn2)
+
+	target := address.Random()
+	conn1.Subscribe([]address.Address{target})
+	expectNoMessage(t, conn1, received1)
+
+	tx, err := utxodb.DistributeIotas(100, utxodb.GetAddress(1), []address.Address{target})
+	require.NoError(t, err)
+	conflicting, err := utxodb.DistributeIotas(50, utxodb.GetAddress(1), []address.Address{target})
+	require.NoError(t, err)
+	require.NoError(t, ledger.PostTransaction(tx))
+	require.NoError(t, ledger.PostTransaction(conflicting))
+
+	// not confirmed yet
+	ledger.confirmPending(time.Now())
+	expectNoMessage(t, conn1, received1)
+	assert.Empty(t, ledger.GetAddressOutputs(target))
+
+	ledger.confirmPending(time.Now().Add(delay))
+	msg := expectMessage(t, received1)
+	update, ok := msg.(*waspconn.WaspFromNodeAddressUpdateMsg)
+	require.True(t, ok)
+	assert.Equal(t, target, update.Address)
+	assert.Equal(t, tx.ID(), update.Tx.ID())
+	assert.EqualValues(t, 100, update.Balances[tx.ID()][0].Value)
+	// the conflicting transaction is rejected, the other connection is not subscribed
+	expectNoMessage(t, conn1, received1)
+	expectNoMessage(t, conn2, received2)
+	assert.Len(t, ledger.GetAddressOutputs(target), 1)
+
+	require.NoError(t, conn2.RequestOutputs(&target))
+	outputs, ok := expectMessage(t, received2).(*waspconn.WaspFromNodeAddressOutputsMsg)
+	require.True(t, ok)
+	assert.Equal(t, update.Balances, outputs.Balances)
+
+	txid := tx.ID()
+	require.NoError(t, conn2.RequestTransaction(&txid))
+	txMsg, ok := expectMessage(t, received2).(*waspconn.WaspFromNodeTransactionMsg)
+	require.True(t, ok)
+	assert.Equal(t, txid, txMsg.Tx.ID())
+
+	// not signed transaction is rejected at once
+	assert.Error(t, ledger.PostTransaction(valuetransaction.New(tx.Inputs(), tx.Outputs())))
+
+	// closed connection
+	shutdown := make(chan struct{})
+	close(shutdown)
+	conn2.Run(shutdown)
+	assert.False(t, conn2.IsConnected())
+	assert.Error(t, conn2.RequestOutputs(&target))
+	assert.True(t, conn1.IsConnected())
+}
+
+// the Wasp node in another process talks to the embedded ledger with the WaspConn protocol of Goshimmer
+func TestServeWaspConn(t *testing.T) {
+	utxodb.Init()
+	ledger := NewUtxoDBLedger(0, log)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		_ = ledger.ServeWaspConn(listener)
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	bconn := buffconn.NewBufferedConnection(conn)
+	defer bconn.Close()
+	received := make(chan interface{}, 10)
+	assembler := newChunkAssembler()
+	var receiveData func(data []byte)
+	receiveData = func(data []byte) {
+		// called by the reading goroutine
+		msg, err := waspconn.DecodeMsg(data, true)
+		if !assert.NoError(t, err) {
+			return
+		}
+		if chunk, ok := msg.(*waspconn.WaspMsgChunk); ok {
+			data, err := assembler.Incoming(chunk.Data, time.Now())
+			if assert.NoError(t, err) && data != nil {
+				receiveData(data)
+			}
+			return
+		}
+		received <- msg
+	}
+	bconn.Events.ReceiveMessage.Attach(events.NewClosure(func(data []byte) {
+		receiveData(data)
+	}))
+	go func() {
+		_ = bconn.Read()
+	}()
+	send := func(msg interface{ Write(io.Writer) error }) {
+		data, err := waspconn.EncodeMsg(msg)
+		require.NoError(t, err)
+		require.NoError(t, writeChopped(bconn, data))
+	}
+
+	send(&waspconn.WaspPingMsg{Id: 7})
+	pong, ok := expectMessage(t, received).(*waspconn.WaspPingMsg)
+	require.True(t, ok)
+	assert.EqualValues(t, 7, pong.Id)
+
+	target := address.Random()
+	send(&waspconn.WaspToNodeSubscribeMsg{Addresses: []address.Address{target}})
+	// the subscription is processed before the transaction, which is confirmed at once
+	targets := make([]address.Address, 100)
+	targets[0] = target
+	for i := 1; i < len(targets); i++ {
+		targets[i] = address.Random()
+	}
+	tx, err := utxodb.DistributeIotas(1, utxodb.GetAddress(1), targets)
+	require.NoError(t, err)
+	require.True(t, len(tx.Bytes()) > buffconn.MaxMessageSize, "the transaction is sent in chunks")
+	send(&waspconn.WaspToNodeTransactionMsg{Tx: tx})
+
+	update, ok := expectMessage(t, received).(*waspconn.WaspFromNodeAddressUpdateMsg)
+	require.True(t, ok)
+	assert.Equal(t, target, update.Address)
+	assert.Equal(t, tx.ID(), update.Tx.ID())
+
+	send(&waspconn.WaspToNodeGetOutputsMsg{Address: target})
+	outputs, ok := expectMessage(t, received).(*waspconn.WaspFromNodeAddressOutputsMsg)
+	require.True(t, ok)
+	assert.Equal(t, update.Balances, outputs.Balances)
+
+	txid := tx.ID()
+	send(&waspconn.WaspToNodeGetTransactionMsg{TxId: &txid})
+	txMsg, ok := expectMessage(t, received).(*waspconn.WaspFromNodeTransactionMsg)
+	require.True(t, ok)
+	assert.Equal(t, txid, txMsg.Tx.ID())
+}
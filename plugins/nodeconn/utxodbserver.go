@@ -0,0 +1,122 @@
+package nodeconn
+
+import (
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iotaledger/goshimmer/dapps/waspconn/packages/waspconn"
+	"github.com/iotaledger/hive.go/events"
+	"github.com/iotaledger/hive.go/netutil/buffconn"
+	"github.com/iotaledger/wasp/packages/chunks"
+)
+
+// ServeWaspConn serves the WaspConn protocol of Goshimmer from the embedded ledger, so Wasp nodes running
+// in other processes connect to the ledger as to the Goshimmer node. It returns when the listener is closed
+func (l *UtxoDBLedger) ServeWaspConn(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go l.serveWaspConnection(conn)
+	}
+}
+
+// waspConnection is the connection of the Wasp node with the embedded ledger over the network
+type waspConnection struct {
+	ledger *UtxoDBLedger
+	conn   *utxodbConnection
+	bconn  *buffconn.BufferedConnection
+	chunks *chunks.Assembler
+	id     string
+
+	writeMutex sync.Mutex
+}
+
+func (l *UtxoDBLedger) serveWaspConnection(conn net.Conn) {
+	wconn := &waspConnection{
+		ledger: l,
+		conn:   l.newConnection(),
+		bconn:  buffconn.NewBufferedConnection(conn),
+		chunks: newChunkAssembler(),
+		id:     conn.RemoteAddr().String(),
+	}
+	l.log.Infof("embedded ledger: wasp %s connected", wconn.id)
+
+	wconn.conn.Events().MessageReceived.Attach(events.NewClosure(func(msg interface{}) {
+		wconn.sendMsg(msg.(interface{ Write(io.Writer) error }))
+	}))
+	wconn.bconn.Events.ReceiveMessage.Attach(events.NewClosure(func(data []byte) {
+		wconn.receiveData(data)
+	}))
+
+	if err := wconn.bconn.Read(); err != nil {
+		if err != io.EOF && !strings.Contains(err.Error(), "use of closed network connection") {
+			l.log.Warnw("embedded ledger: permanent error", "wasp", wconn.id, "err", err)
+		}
+	}
+	wconn.conn.close()
+	_ = wconn.bconn.Close()
+	l.log.Infof("embedded ledger: wasp %s disconnected", wconn.id)
+}
+
+func (wconn *waspConnection) sendMsg(msg interface{ Write(io.Writer) error }) {
+	data, err := waspconn.EncodeMsg(msg)
+	if err != nil {
+		wconn.ledger.log.Errorf("embedded ledger: encoding %T: %v", msg, err)
+		return
+	}
+	wconn.writeMutex.Lock()
+	defer wconn.writeMutex.Unlock()
+
+	if err = writeChopped(wconn.bconn, data); err != nil {
+		wconn.ledger.log.Warnf("embedded ledger: sending %T to wasp %s: %v", msg, wconn.id, err)
+	}
+}
+
+// receiveData processes the message from the Wasp node as the WaspConn plugin of Goshimmer does
+func (wconn *waspConnection) receiveData(data []byte) {
+	msg, err := waspconn.DecodeMsg(data, false)
+	if err != nil {
+		wconn.ledger.log.Errorf("embedded ledger: wrong message from wasp %s: %v", wconn.id, err)
+		return
+	}
+	switch msgt := msg.(type) {
+	case *waspconn.WaspMsgChunk:
+		finalData, err := wconn.chunks.Incoming(msgt.Data, time.Now())
+		if err != nil {
+			wconn.ledger.log.Errorf("embedded ledger: receiving message chunk: %v", err)
+			return
+		}
+		if finalData != nil {
+			wconn.receiveData(finalData)
+		}
+
+	case *waspconn.WaspPingMsg:
+		wconn.sendMsg(msgt)
+
+	case *waspconn.WaspToNodeTransactionMsg:
+		// posted transactions are not answered, the Wasp node learns about the confirmation from subscriptions
+		if err := wconn.ledger.PostTransaction(msgt.Tx); err != nil {
+			wconn.ledger.log.Warnf("embedded ledger: transaction from wasp %s rejected: %v", wconn.id, err)
+		}
+
+	case *waspconn.WaspToNodeSubscribeMsg:
+		wconn.conn.Subscribe(msgt.Addresses)
+
+	case *waspconn.WaspToNodeGetTransactionMsg:
+		_ = wconn.conn.RequestTransaction(msgt.TxId)
+
+	case *waspconn.WaspToNodeGetOutputsMsg:
+		_ = wconn.conn.RequestOutputs(&msgt.Address)
+
+	case *waspconn.WaspToNodeSetIdMsg:
+		wconn.ledger.log.Infof("embedded ledger: wasp %s has id %s", wconn.id, msgt.Waspid)
+
+	default:
+		wconn.ledger.log.Errorf("embedded ledger: unexpected message %T from wasp %s", msgt, wconn.id)
+	}
+}
@@ -9,7 +9,7 @@ import (
 	"github.com/iotaledger/hive.go/backoff"
 	"github.com/iotaledger/hive.go/events"
 	"github.com/iotaledger/hive.go/netutil/buffconn"
-	"github.com/iotaledger/wasp/plugins/config"
+	"github.com/iotaledger/wasp/packages/chunks"
 	"github.com/iotaledger/wasp/plugins/peering"
 	"io"
 	"net"
@@ -22,109 +22,157 @@ const (
 	dialRetries  = 10
 	backoffDelay = 500 * time.Millisecond
 	retryAfter   = 8 * time.Second
+
+	// chunks of unfinished message are dropped after chunkTTL
+	chunkTTL = 5 * time.Minute
+	// unfinished chunked messages received from one connection take at most maxChunkedSize bytes
+	maxChunkedSize = 32 << 20
 )
 
 // retry net.Dial once, on fail after 0.5s
 var dialRetryPolicy = backoff.ConstantBackOff(backoffDelay).With(backoff.MaxRetries(dialRetries))
 
-// dials outbound address and established connection
-func nodeConnect() {
-	addr := config.Node.GetString(CfgNodeAddress)
-	log.Infof("connecting with node at %s", addr)
+// newChunkAssembler creates the assembler of messages chopped by the other side of one WaspConn connection
+func newChunkAssembler() *chunks.Assembler {
+	return chunks.NewAssembler(buffconn.MaxMessageSize-waspconn.ChunkMessageHeaderSize, maxChunkedSize, chunkTTL)
+}
+
+// goshimmerConnection is the ledger connection with the WaspConn plugin of Goshimmer nodes.
+// The node keeps connections with all configured Goshimmer nodes and uses one of them, the primary node,
+// for subscriptions and requests. The state of the connections is in the package variables
+type goshimmerConnection struct {
+	events *LedgerEvents
+}
+
+var goshimmerConn = &goshimmerConnection{events: newLedgerEvents()}
+
+func (c *goshimmerConnection) Events() *LedgerEvents {
+	return c.events
+}
+
+func (c *goshimmerConnection) Run(shutdownSignal <-chan struct{}) {
+	eps := endpoints
+	for _, ep := range eps {
+		go ep.connectLoop(shutdownSignal)
+	}
+	go keepSendingSubscriptionIfNeeded(shutdownSignal)
+	go keepCheckingHealth(shutdownSignal)
+
+	<-shutdownSignal
+
+	log.Info("Stopping node connection..")
+	go func() {
+		for _, ep := range eps {
+			ep.close()
+		}
+	}()
+}
+
+// connectLoop connects with the node and reconnects when the connection is lost
+func (ep *nodeEndpoint) connectLoop(shutdownSignal <-chan struct{}) {
+	for {
+		ep.connect()
+		log.Infof("will retry connecting to the node %s after %v", ep.address, retryAfter)
+		select {
+		case <-shutdownSignal:
+			return
+		case <-time.After(retryAfter):
+		}
+	}
+}
+
+// connect dials the node and reads from the connection until it is closed
+func (ep *nodeEndpoint) connect() {
+	log.Infof("connecting with node at %s", ep.address)
 
 	var conn net.Conn
 	if err := backoff.Retry(dialRetryPolicy, func() error {
 		var err error
-		conn, err = net.DialTimeout("tcp", addr, dialTimeout)
+		conn, err = net.DialTimeout("tcp", ep.address, dialTimeout)
 		if err != nil {
-			return fmt.Errorf("can't connect with the node: %v", err)
+			return fmt.Errorf("can't connect with the node %s: %v", ep.address, err)
 		}
 		return nil
 	}); err != nil {
 		log.Warn(err)
-
-		retryNodeConnect()
 		return
 	}
 
-	bconnMutex.Lock()
-	bconn = buffconn.NewBufferedConnection(conn)
-	bconnMutex.Unlock()
-
-	log.Debugf("established connection with node at %s", addr)
+	bconn := buffconn.NewBufferedConnection(conn)
+	log.Debugf("established connection with node at %s", ep.address)
 
 	dataReceivedClosure := events.NewClosure(func(data []byte) {
-		msgDataToEvent(data)
+		ep.msgDataToEvent(data)
 	})
-
 	bconn.Events.ReceiveMessage.Attach(dataReceivedClosure)
 	bconn.Events.Close.Attach(events.NewClosure(func() {
-		log.Errorf("lost connection with %s", addr)
+		log.Errorf("lost connection with %s", ep.address)
 		go func() {
-			bconnMutex.Lock()
-			bconnSave := bconn
-			bconn = nil
-			bconnMutex.Unlock()
-			bconnSave.Events.ReceiveMessage.Detach(dataReceivedClosure)
+			ep.clearConnection(bconn)
+			bconn.Events.ReceiveMessage.Detach(dataReceivedClosure)
+			selectPrimary(time.Now())
 		}()
 	}))
+	ep.setConnection(bconn)
 
-	if err := SendWaspIdToNode(); err == nil {
-		log.Debugf("sent own wasp id to node: %s", peering.MyNetworkId())
+	if err := ep.sendWaspId(); err == nil {
+		log.Debugf("sent own wasp id to node %s: %s", ep.address, peering.MyNetworkId())
 	} else {
-		log.Errorf("failed to send wasp id to node: %v", err)
+		log.Errorf("failed to send wasp id to node %s: %v", ep.address, err)
 	}
+	selectPrimary(time.Now())
 
 	// read loop
 	if err := bconn.Read(); err != nil {
 		if err != io.EOF && !strings.Contains(err.Error(), "use of closed network connection") {
-			log.Warnw("Permanent error", "err", err)
+			log.Warnw("Permanent error", "node", ep.address, "err", err)
 		}
 	}
-	// try to reconnect after some time
-	log.Debugf("disconnected from node. Will try to reconnect after %v", retryAfter)
-
-	retryNodeConnect()
+	// the Close event is not triggered when the node drops the connection
+	_ = bconn.Close()
+	log.Debugf("disconnected from node %s", ep.address)
 }
 
-func IsConnected() bool {
-	bconnMutex.RLock()
-	defer bconnMutex.RUnlock()
-	return bconn != nil
-}
-
-func retryNodeConnect() {
-	log.Infof("will retry connecting to the node after %v", retryAfter)
-	time.Sleep(retryAfter)
-	go nodeConnect()
+func (*goshimmerConnection) IsConnected() bool {
+	return getPrimary() != nil
 }
 
+// SendDataToNode sends data to the primary node
 func SendDataToNode(data []byte) error {
-	choppedData, chopped := chopper.ChopData(data, buffconn.MaxMessageSize-waspconn.ChunkMessageHeaderSize)
+	ep := getPrimary()
+	if ep == nil {
+		return fmt.Errorf("SendDataToNode: not connected to node")
+	}
+	return ep.sendData(data)
+}
 
-	bconnMutex.RLock()
-	defer bconnMutex.RUnlock()
+func (ep *nodeEndpoint) sendData(data []byte) error {
+	ep.mutex.RLock()
+	defer ep.mutex.RUnlock()
 
-	if bconn == nil {
-		return fmt.Errorf("SendDataToNode: not connected to node")
+	if ep.bconn == nil {
+		return fmt.Errorf("SendDataToNode: not connected to node %s", ep.address)
 	}
-	var err error
+	return writeChopped(ep.bconn, data)
+}
+
+// writeChopped writes the message to the connection, chopped into chunks if it doesn't fit into one buffconn message
+func writeChopped(bconn *buffconn.BufferedConnection, data []byte) error {
+	choppedData, chopped := chopper.ChopData(data, buffconn.MaxMessageSize-waspconn.ChunkMessageHeaderSize)
 	if !chopped {
-		_, err = bconn.Write(data)
-	} else {
-		for _, piece := range choppedData {
-			var d []byte
-			d, err = waspconn.EncodeMsg(&waspconn.WaspMsgChunk{
-				Data: piece,
-			})
-			if err != nil {
-				break
-			}
-			_, err = bconn.Write(d)
-			if err != nil {
-				break
-			}
+		_, err := bconn.Write(data)
+		return err
+	}
+	for _, piece := range choppedData {
+		d, err := waspconn.EncodeMsg(&waspconn.WaspMsgChunk{
+			Data: piece,
+		})
+		if err != nil {
+			return err
+		}
+		if _, err = bconn.Write(d); err != nil {
+			return err
 		}
 	}
-	return err
+	return nil
 }
@@ -0,0 +1,88 @@
+package peering
+
+import (
+	"sort"
+	"time"
+
+	"github.com/iotaledger/wasp/plugins/config"
+	"go.uber.org/atomic"
+)
+
+// Clocks of committee nodes must be close: the leader proposes the timestamp of the batch by its own clock
+// and the peers reject timestamps deviating too much from their clocks.
+// The node warns when the estimated clock offset of a peer exceeds maxClockOffset.
+// When the median offset of all peers exceeds it, most likely it is the own clock which drifts
+
+var (
+	maxClockOffset = 1 * time.Second
+	ownClockDrifts atomic.Bool
+)
+
+func loadMaxClockOffset() {
+	maxClockOffset = time.Duration(config.Node.GetInt(CfgPeeringMaxClockOffset)) * time.Millisecond
+}
+
+func exceedsMaxClockOffset(offset int64) bool {
+	return offset > int64(maxClockOffset) || offset < -int64(maxClockOffset)
+}
+
+// checkClockOffset logs when the clock offset of the peer starts or stops exceeding the maximum
+func (peer *tcpPeer) checkClockOffset() {
+	_, offset, ok := peer.clockEstimates()
+	if !ok {
+		return
+	}
+	drifts := exceedsMaxClockOffset(offset)
+	peer.Lock()
+	changed := peer.clockDrifts != drifts
+	peer.clockDrifts = drifts
+	peer.Unlock()
+
+	if changed {
+		if drifts {
+			log.Warnf("clock of the peer %s differs from own clock by %v", peer.remoteLocation, time.Duration(offset))
+		} else {
+			log.Infof("clock of the peer %s is in sync with own clock again", peer.remoteLocation)
+		}
+	}
+	checkOwnClock()
+}
+
+// checkOwnClock logs when the median clock offset of the peers starts or stops exceeding the maximum.
+// At least 2 peers are needed to tell the drifting own clock from the drifting clock of the peer
+func checkOwnClock() {
+	peersMutex.RLock()
+	offsets := make([]int64, 0, len(peers))
+	for _, peer := range peers {
+		if _, offset, ok := peer.clockEstimates(); ok {
+			offsets = append(offsets, offset)
+		}
+	}
+	peersMutex.RUnlock()
+
+	if len(offsets) < 2 {
+		return
+	}
+	median := medianClockOffset(offsets)
+	drifts := exceedsMaxClockOffset(median)
+	if ownClockDrifts.Swap(drifts) == drifts {
+		return
+	}
+	if drifts {
+		log.Warnf("own clock probably drifts: clocks of %d peers differ from it by %v (median). Check time synchronization",
+			len(offsets), time.Duration(median))
+	} else {
+		log.Infof("own clock is in sync with clocks of peers again")
+	}
+}
+
+func medianClockOffset(offsets []int64) int64 {
+	sorted := make([]int64, len(offsets))
+	copy(sorted, offsets)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
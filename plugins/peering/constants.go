@@ -6,6 +6,9 @@ const (
 	// equal and larger msg types are committee messages
 	// those with smaller are reserved by the package for heartbeat and handshake messages
 	FirstCommitteeMsgCode = byte(0x10)
+	// equal and larger msg types are messages of the distributed key generation.
+	// The address of such message is not used, the session is identified by the message data
+	FirstDKGMsgCode = byte(0x80)
 
 	MsgTypeHeartbeat = byte(0)
 	MsgTypeHandshake = byte(1)
@@ -10,7 +10,8 @@ import (
 // structure of the encoded PeerMessage:
 // Timestamp   8 bytes
 // MsgType type    1 byte
-//  -- if MsgType == 0 (heartbeat) --> the end of message
+//  -- if MsgType == 0 (heartbeat)
+// MsgData (optional echo of the timestamp received from the peer) --> end of message
 //  -- if MsgType == 1 (handshake)
 // MsgData (a string of peer network location) --> end of message
 //  -- if MsgType >= FirstCommitteeMsgCode
@@ -33,6 +34,7 @@ func encodeMessage(msg *PeerMessage) ([]byte, int64) {
 
 	case msg.MsgType == MsgTypeHeartbeat:
 		buf.WriteByte(MsgTypeHeartbeat)
+		buf.Write(msg.MsgData)
 
 	case msg.MsgType == MsgTypeHandshake:
 		buf.WriteByte(MsgTypeHandshake)
@@ -54,7 +56,8 @@ func encodeMessage(msg *PeerMessage) ([]byte, int64) {
 	return buf.Bytes(), ts
 }
 
-func decodeMessage(data []byte) (*PeerMessage, error) {
+// decodeMessage decodes the message. Data of committee messages can't be longer than maxMsgSize
+func decodeMessage(data []byte, maxMsgSize int) (*PeerMessage, error) {
 	if len(data) < 9 {
 		return nil, fmt.Errorf("too short message")
 	}
@@ -73,6 +76,7 @@ func decodeMessage(data []byte) (*PeerMessage, error) {
 	}
 	switch {
 	case ret.MsgType == MsgTypeHeartbeat:
+		ret.MsgData = rdr.Bytes()
 		return ret, nil
 
 	case ret.MsgType == MsgTypeHandshake:
@@ -91,9 +95,19 @@ func decodeMessage(data []byte) (*PeerMessage, error) {
 		if err = util.ReadUint16(rdr, &ret.SenderIndex); err != nil {
 			return nil, err
 		}
-		if ret.MsgData, err = util.ReadBytes32(rdr); err != nil {
+		var size uint32
+		if err = util.ReadUint32(rdr, &size); err != nil {
 			return nil, err
 		}
+		if int64(size) > int64(maxMsgSize) {
+			return nil, errTooLarge
+		}
+		if int(size) != rdr.Len() {
+			return nil, fmt.Errorf("wrong message data length")
+		}
+		// the data is copied because the buffer is reused for the next message
+		ret.MsgData = make([]byte, size)
+		copy(ret.MsgData, rdr.Bytes())
 		return ret, nil
 	}
 	return nil, fmt.Errorf("wrong message type = %d", ret.MsgType)
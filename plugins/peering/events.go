@@ -5,12 +5,18 @@ import (
 	"github.com/iotaledger/hive.go/events"
 )
 
-var EventPeerMessageReceived *events.Event
+// TransportEvents are events of the peering transport
+type TransportEvents struct {
+	// MessageReceived is triggered with committee and DKG messages received from peers
+	MessageReceived *events.Event
+}
 
-func init() {
-	EventPeerMessageReceived = events.NewEvent(func(handler interface{}, params ...interface{}) {
-		handler.(func(_ *PeerMessage))(params[0].(*PeerMessage))
-	})
+func newTransportEvents() *TransportEvents {
+	return &TransportEvents{
+		MessageReceived: events.NewEvent(func(handler interface{}, params ...interface{}) {
+			handler.(func(_ *PeerMessage))(params[0].(*PeerMessage))
+		}),
+	}
 }
 
 type PeerMessage struct {
@@ -19,4 +25,7 @@ type PeerMessage struct {
 	Timestamp   int64
 	MsgType     byte
 	MsgData     []byte
+	// network location of the peer the message is received from. It is set by the transport
+	// upon receipt from the authenticated connection and is not sent over the wire
+	SenderNetworkId string
 }
@@ -1,7 +1,10 @@
 package peering
 
 import (
+	"bytes"
 	"time"
+
+	"github.com/iotaledger/wasp/packages/util"
 )
 
 // message type is 1 byte
@@ -9,28 +12,33 @@ import (
 // these messages are processed by processHeartbeat method
 // the rest are forwarded to SC operator
 
-func (peer *Peer) initHeartbeats() {
+func (peer *tcpPeer) initHeartbeats() {
 	peer.lastHeartbeatSent = 0
 	peer.lastHeartbeatReceived = 0
 	peer.hbRingBufIdx = 0
 	for i := range peer.latencyRingBuf {
 		peer.latencyRingBuf[i] = 0
 	}
+	peer.lastRemoteTs = 0
+	peer.lastRemoteTsArrived = 0
+	peer.numEstimates = 0
 }
 
-func (peer *Peer) receiveHeartbeat(ts int64) {
+func (peer *tcpPeer) receiveHeartbeat(ts int64) {
 	peer.Lock()
 	peer.lastHeartbeatReceived = time.Now().UnixNano()
 	//log.Debugw("receiveHeartbeat", "id", peer.PeeringId(), "time", peer.lastHeartbeatReceived)
 	lagNano := peer.lastHeartbeatReceived - ts
 	peer.latencyRingBuf[peer.hbRingBufIdx] = lagNano
 	peer.hbRingBufIdx = (peer.hbRingBufIdx + 1) % numHeartbeatsToKeep
+	peer.lastRemoteTs = ts
+	peer.lastRemoteTsArrived = peer.lastHeartbeatReceived
 	peer.Unlock()
 
 	//log.Debugf("heartbeat received from %s, lag %f milisec", peer.remoteLocation.String(), float64(lagNano/10000)/100)
 }
 
-func (peer *Peer) scheduleNexHeartbeat() {
+func (peer *tcpPeer) scheduleNexHeartbeat() {
 	//log.Debugw("scheduleNexHeartbeat", "id", peer.PeeringId())
 
 	if peerAlive, _ := peer.IsAlive(); !peerAlive {
@@ -53,7 +61,7 @@ func (peer *Peer) scheduleNexHeartbeat() {
 		return
 	}
 	var hbMsgData []byte
-	hbMsgData, peer.lastHeartbeatSent = encodeMessage(nil)
+	hbMsgData, peer.lastHeartbeatSent = encodeMessage(peer.heartbeatMsg())
 
 	peer.Unlock()
 
@@ -67,8 +75,79 @@ func (peer *Peer) scheduleNexHeartbeat() {
 	}
 }
 
+// Heartbeat echoes the timestamp of the last message received from the peer and how long ago it was received.
+// The receiver of the heartbeat measures the round trip and the offset of clocks the same way NTP does:
+//   t1 - the timestamp echoed, by own clock
+//   t2 = t3 - hold, when the peer received the message, by the clock of the peer
+//   t3 - the timestamp of the heartbeat, by the clock of the peer
+//   t4 - when the heartbeat is received, by own clock
+//   round trip = (t4 - t1) - hold, offset = ((t2 - t1) + (t3 - t4)) / 2
+
+// heartbeatMsg returns heartbeat message with the echo. Must be called under the lock
+func (peer *tcpPeer) heartbeatMsg() *PeerMessage {
+	ret := &PeerMessage{MsgType: MsgTypeHeartbeat}
+	if peer.lastRemoteTs != 0 {
+		var buf bytes.Buffer
+		_ = util.WriteInt64(&buf, peer.lastRemoteTs)
+		_ = util.WriteInt64(&buf, time.Now().UnixNano()-peer.lastRemoteTsArrived)
+		ret.MsgData = buf.Bytes()
+	}
+	return ret
+}
+
+// receiveEcho takes the echo from the heartbeat with the timestamp ts and updates estimates of the round trip
+// and the clock offset of the peer
+func (peer *tcpPeer) receiveEcho(ts int64, data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	arrived := time.Now().UnixNano()
+	rdr := bytes.NewReader(data)
+	var echoTs, hold int64
+	if err := util.ReadInt64(rdr, &echoTs); err != nil {
+		return
+	}
+	if err := util.ReadInt64(rdr, &hold); err != nil {
+		return
+	}
+	roundTrip := arrived - echoTs - hold
+	if echoTs <= 0 || hold < 0 || roundTrip < 0 || roundTrip > int64(heartbeatEvery*isDeadAfterMissing) {
+		return
+	}
+	// the clock of the peer minus own clock
+	offset := ((ts - hold - echoTs) + (ts - arrived)) / 2
+
+	peer.Lock()
+	defer peer.Unlock()
+	i := peer.numEstimates % numHeartbeatsToKeep
+	peer.roundTripRingBuf[i] = roundTrip
+	peer.offsetRingBuf[i] = offset
+	peer.numEstimates++
+}
+
+// clockEstimates returns average round trip and clock offset of the peer (the peer's clock minus own clock)
+// in nanoseconds. Returns false if there are no estimates yet
+func (peer *tcpPeer) clockEstimates() (int64, int64, bool) {
+	peer.RLock()
+	defer peer.RUnlock()
+
+	n := peer.numEstimates
+	if n == 0 {
+		return 0, 0, false
+	}
+	if n > numHeartbeatsToKeep {
+		n = numHeartbeatsToKeep
+	}
+	var sumRoundTrip, sumOffset int64
+	for i := 0; i < n; i++ {
+		sumRoundTrip += peer.roundTripRingBuf[i]
+		sumOffset += peer.offsetRingBuf[i]
+	}
+	return sumRoundTrip / int64(n), sumOffset / int64(n), true
+}
+
 // return true if is alive and average latencyRingBuf in nanosec
-func (peer *Peer) IsAlive() (bool, int64) {
+func (peer *tcpPeer) IsAlive() (bool, int64) {
 	peer.RLock()
 	defer peer.RUnlock()
 	if peer.peerconn == nil || !peer.handshakeOk {
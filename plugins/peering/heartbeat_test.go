@@ -0,0 +1,62 @@
+package peering
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClockEstimates(t *testing.T) {
+	const peerClockOffset = int64(time.Second)
+
+	peer := &tcpPeer{RWMutex: &sync.RWMutex{}}
+	_, _, ok := peer.clockEstimates()
+	assert.False(t, ok)
+
+	// the peer received own message 10ms ago and held it for 2ms before sending the heartbeat
+	now := time.Now().UnixNano()
+	remote := &tcpPeer{RWMutex: &sync.RWMutex{}}
+	remote.lastRemoteTs = now - int64(10*time.Millisecond)
+	remote.lastRemoteTsArrived = time.Now().UnixNano() - int64(2*time.Millisecond)
+	data, _ := encodeMessage(remote.heartbeatMsg())
+	msg, err := decodeMessage(data, limits.maxMsgSize)
+	require.NoError(t, err)
+	require.EqualValues(t, MsgTypeHeartbeat, msg.MsgType)
+
+	// heartbeat sent 3ms ago by the clock of the peer
+	peer.receiveEcho(now+peerClockOffset-int64(3*time.Millisecond), msg.MsgData)
+	roundTrip, offset, ok := peer.clockEstimates()
+	require.True(t, ok)
+	assert.InDelta(t, 8*time.Millisecond, roundTrip, float64(2*time.Millisecond))
+	assert.InDelta(t, peerClockOffset, offset, float64(5*time.Millisecond))
+
+	// heartbeat without echo and impossible echoes are ignored
+	peer.receiveEcho(now, nil)
+	remote.lastRemoteTs = now + int64(time.Minute)
+	data, _ = encodeMessage(remote.heartbeatMsg())
+	msg, err = decodeMessage(data, limits.maxMsgSize)
+	require.NoError(t, err)
+	peer.receiveEcho(now, msg.MsgData)
+	assert.Equal(t, 1, peer.numEstimates)
+}
+
+func TestClockDrift(t *testing.T) {
+	assert.EqualValues(t, 2, medianClockOffset([]int64{5, -1, 2}))
+	assert.EqualValues(t, 3, medianClockOffset([]int64{5, -1, 2, 4}))
+
+	peer := &tcpPeer{RWMutex: &sync.RWMutex{}, remoteLocation: "node2:4000"}
+	peer.checkClockOffset()
+	assert.False(t, peer.clockDrifts)
+
+	peer.offsetRingBuf[0] = -int64(2 * maxClockOffset)
+	peer.numEstimates = 1
+	peer.checkClockOffset()
+	assert.True(t, peer.clockDrifts)
+
+	peer.offsetRingBuf[0] = int64(maxClockOffset / 2)
+	peer.checkClockOffset()
+	assert.False(t, peer.clockDrifts)
+}
@@ -0,0 +1,172 @@
+package peering
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/iotaledger/wasp/packages/registry"
+	"github.com/iotaledger/wasp/plugins/config"
+	"github.com/mr-tron/base58"
+)
+
+// identity of the node in the peering network. Peers authenticate each other with the key pair
+// during the handshake, the allow-list of public keys is in the registry
+var (
+	identity     ed25519.PrivateKey
+	identityCert tls.Certificate
+)
+
+const pemTypePrivateKey = "PRIVATE KEY"
+
+// MyPublicKey returns the public key of the node identity
+func MyPublicKey() ed25519.PublicKey {
+	return identity.Public().(ed25519.PublicKey)
+}
+
+// Identity signs messages with the identity key of the node and returns public keys trusted for network
+// locations of peers. Components take the identity along with the transport (see commiteeimpl and dkg.Node),
+// so they authenticate messages of peers independently of the transport
+type Identity interface {
+	// Sign signs the data with the private key of the node
+	Sign(data []byte) []byte
+	// TrustedPeerKey returns the public key trusted for the network location
+	TrustedPeerKey(netId string) (ed25519.PublicKey, bool, error)
+	// TrustedPeersVersion changes with each change of the allow-list, so keys taken from it can be invalidated
+	TrustedPeersVersion() uint64
+}
+
+// nodeIdentity is the identity key of the node with the allow-list in the registry
+type nodeIdentity struct{}
+
+func (nodeIdentity) Sign(data []byte) []byte {
+	return ed25519.Sign(identity, data)
+}
+
+func (nodeIdentity) TrustedPeerKey(netId string) (ed25519.PublicKey, bool, error) {
+	return registry.GetTrustedPeerKey(netId)
+}
+
+func (nodeIdentity) TrustedPeersVersion() uint64 {
+	return registry.TrustedPeersVersion()
+}
+
+// GetIdentity returns the identity of the node
+func GetIdentity() Identity {
+	return nodeIdentity{}
+}
+
+// staticIdentity is the identity with the fixed allow-list
+type staticIdentity struct {
+	key     ed25519.PrivateKey
+	trusted map[string]ed25519.PublicKey
+}
+
+func (id *staticIdentity) Sign(data []byte) []byte {
+	return ed25519.Sign(id.key, data)
+}
+
+func (id *staticIdentity) TrustedPeerKey(netId string) (ed25519.PublicKey, bool, error) {
+	key, ok := id.trusted[netId]
+	return key, ok, nil
+}
+
+func (id *staticIdentity) TrustedPeersVersion() uint64 {
+	return 0
+}
+
+// NewStaticIdentities generates identity keys of nodes at network locations, which trust each other.
+// Used with MemNetwork by nodes running in the same process
+func NewStaticIdentities(locations ...string) ([]Identity, error) {
+	keys := make([]ed25519.PrivateKey, len(locations))
+	trusted := make(map[string]ed25519.PublicKey)
+	for i, loc := range locations {
+		pubKey, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = key
+		trusted[loc] = pubKey
+	}
+	ret := make([]Identity, len(locations))
+	for i := range locations {
+		ret[i] = &staticIdentity{key: keys[i], trusted: trusted}
+	}
+	return ret, nil
+}
+
+// PublicKeyToString returns base58 encoding of the public key
+func PublicKeyToString(pubKey ed25519.PublicKey) string {
+	return base58.Encode(pubKey)
+}
+
+// PublicKeyFromString decodes base58 encoded public key
+func PublicKeyFromString(s string) (ed25519.PublicKey, error) {
+	data, err := base58.Decode(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("wrong public key size %d", len(data))
+	}
+	return data, nil
+}
+
+// loadIdentity reads the key pair from the key file. New key pair is generated and saved if the file doesn't exist
+func loadIdentity() error {
+	path := config.Node.GetString(CfgPeeringKeyFile)
+	data, err := ioutil.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		if identity, err = newIdentityFile(path); err != nil {
+			return err
+		}
+		log.Infof("generated new node identity in %s", path)
+	case err != nil:
+		return err
+	default:
+		if identity, err = decodeIdentity(data); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	identityCert, err = identityCertificate(identity)
+	return err
+}
+
+func newIdentityFile(path string) (ed25519.PrivateKey, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: pemTypePrivateKey, Bytes: der})
+	if err = ioutil.WriteFile(path, data, 0600); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func decodeIdentity(data []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != pemTypePrivateKey {
+		return nil, errors.New("PEM encoded private key expected")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	ret, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.New("ed25519 private key expected")
+	}
+	return ret, nil
+}
@@ -0,0 +1,119 @@
+package peering
+
+import (
+	"sync"
+	"time"
+
+	"github.com/iotaledger/wasp/packages/chunks"
+	"github.com/iotaledger/wasp/plugins/config"
+	"go.uber.org/atomic"
+)
+
+// Limits of the traffic accepted from one peer. A peer exceeding them can't exhaust memory or CPU of the node:
+//   - messages larger than maxMsgSize are rejected, also when reassembled from chunks
+//   - messages over the rate are dropped
+//   - received messages wait in the bounded inbound queue of the peer. When the queue is full,
+//     reading from the connection stops for up to inQueueTimeout, then the message is dropped
+type peerLimits struct {
+	maxMsgSize  int
+	msgRate     int // messages per second
+	msgBurst    int
+	inQueueSize int
+}
+
+const (
+	inQueueTimeout = 1 * time.Second
+	// chunks of unfinished message are dropped after chunkTTL
+	chunkTTL = 1 * time.Minute
+	// drops are logged once per dropLogEvery dropped messages
+	dropLogEvery = 100
+)
+
+var (
+	limits = peerLimits{
+		maxMsgSize:  1 << 20,
+		msgRate:     1000,
+		msgBurst:    2000,
+		inQueueSize: 1000,
+	}
+	errTooLarge = chunks.ErrTooLarge
+)
+
+func loadLimits() {
+	limits = peerLimits{
+		maxMsgSize:  config.Node.GetInt(CfgPeeringMaxMsgSize),
+		msgRate:     config.Node.GetInt(CfgPeeringMsgRate),
+		msgBurst:    config.Node.GetInt(CfgPeeringMsgBurst),
+		inQueueSize: config.Node.GetInt(CfgPeeringInQueueSize),
+	}
+}
+
+// DropCounters counts messages from the peer dropped because of the limits
+type DropCounters struct {
+	TooLarge    uint64 `json:"too_large"`
+	RateLimited uint64 `json:"rate_limited"`
+	QueueFull   uint64 `json:"queue_full"`
+}
+
+type dropCounters struct {
+	tooLarge    atomic.Uint64
+	rateLimited atomic.Uint64
+	queueFull   atomic.Uint64
+}
+
+func (c *dropCounters) snapshot() DropCounters {
+	return DropCounters{
+		TooLarge:    c.tooLarge.Load(),
+		RateLimited: c.rateLimited.Load(),
+		QueueFull:   c.queueFull.Load(),
+	}
+}
+
+// countDrop increments the counter and logs every dropLogEvery-th drop
+func countDrop(counter *atomic.Uint64, remoteLocation, reason string) {
+	if n := counter.Inc(); n%dropLogEvery == 1 {
+		log.Warnf("dropped message from %s: %s. Total dropped for the reason: %d", remoteLocation, reason, n)
+	}
+}
+
+// rateLimiter is a token bucket: it allows burst messages at once and rate messages per second on average.
+// Rate 0 means no limit
+type rateLimiter struct {
+	sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		rate:   float64(rate),
+		burst:  float64(burst),
+		tokens: float64(burst),
+	}
+}
+
+func (l *rateLimiter) allow(now time.Time) bool {
+	if l.rate <= 0 {
+		return true
+	}
+	l.Lock()
+	defer l.Unlock()
+
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
@@ -0,0 +1,88 @@
+package peering
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/iotaledger/hive.go/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	log = logger.NewExampleLogger(PluginName)
+}
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(10, 5)
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		assert.True(t, l.allow(now))
+	}
+	assert.False(t, l.allow(now))
+	// 10 per second, i.e. one per 100ms
+	assert.True(t, l.allow(now.Add(100*time.Millisecond)))
+	assert.False(t, l.allow(now.Add(150*time.Millisecond)))
+	// not more than burst after long pause
+	now = now.Add(time.Hour)
+	for i := 0; i < 5; i++ {
+		assert.True(t, l.allow(now))
+	}
+	assert.False(t, l.allow(now))
+
+	unlimited := newRateLimiter(0, 0)
+	for i := 0; i < 100; i++ {
+		assert.True(t, unlimited.allow(now))
+	}
+}
+
+func TestDecodeMessageLimit(t *testing.T) {
+	msg := &PeerMessage{
+		SenderIndex: 2,
+		MsgType:     FirstCommitteeMsgCode,
+		MsgData:     bytes.Repeat([]byte{1}, 1000),
+	}
+	data, _ := encodeMessage(msg)
+
+	back, err := decodeMessage(data, 1000)
+	require.NoError(t, err)
+	assert.Equal(t, msg.MsgData, back.MsgData)
+	assert.EqualValues(t, 2, back.SenderIndex)
+
+	_, err = decodeMessage(data, 999)
+	assert.Equal(t, errTooLarge, err)
+
+	// declared length doesn't match the data
+	_, err = decodeMessage(data[:len(data)-1], 1000)
+	assert.Error(t, err)
+}
+
+func TestInboundQueue(t *testing.T) {
+	peer := &tcpPeer{
+		RWMutex:        &sync.RWMutex{},
+		remoteLocation: "node2:4000",
+		events:         newTransportEvents(),
+		chIn:           make(chan *PeerMessage, 2),
+		chStop:         make(chan struct{}),
+		limiter:        newRateLimiter(0, 0),
+	}
+	for i := 0; i < 3; i++ {
+		peer.enqueue(&PeerMessage{MsgType: FirstCommitteeMsgCode})
+	}
+	assert.Len(t, peer.chIn, 2)
+	assert.EqualValues(t, 1, peer.dropped.queueFull.Load())
+
+	peer.limiter = newRateLimiter(1, 1)
+	<-peer.chIn
+	<-peer.chIn
+	peer.receiveMessage(&PeerMessage{MsgType: FirstCommitteeMsgCode})
+	peer.receiveMessage(&PeerMessage{MsgType: FirstCommitteeMsgCode})
+	assert.Len(t, peer.chIn, 1)
+	assert.Equal(t, DropCounters{RateLimited: 1, QueueFull: 1}, peer.dropped.snapshot())
+
+	// heartbeats are rate limited too
+	peer.receiveMessage(&PeerMessage{MsgType: MsgTypeHeartbeat})
+	assert.Equal(t, DropCounters{RateLimited: 2, QueueFull: 1}, peer.dropped.snapshot())
+}
@@ -0,0 +1,211 @@
+package peering
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// memQueueSize is the number of messages a node of the in-memory network can have undelivered
+const memQueueSize = 1000
+
+// MemNetwork connects in-memory transports of Wasp nodes running in the same process.
+// Used by integration tests and benchmarks instead of TCP connections
+type MemNetwork struct {
+	mutex sync.RWMutex
+	nodes map[string]*memTransport
+}
+
+type memTransport struct {
+	network     *MemNetwork
+	myNetworkId string
+	events      *TransportEvents
+	chIn        chan *PeerMessage
+	closeOnce   sync.Once
+	chClosed    chan struct{}
+	// number of users by peer location
+	peersMutex sync.Mutex
+	peers      map[string]int
+}
+
+type memPeer struct {
+	transport      *memTransport
+	remoteLocation string
+}
+
+// NewMemNetwork creates empty in-memory network
+func NewMemNetwork() *MemNetwork {
+	return &MemNetwork{
+		nodes: make(map[string]*memTransport),
+	}
+}
+
+// NewTransport adds the node with the network location to the network and returns its transport.
+// The node is removed from the network when the transport stops running
+func (n *MemNetwork) NewTransport(myNetworkId string) (Transport, error) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	if _, ok := n.nodes[myNetworkId]; ok {
+		return nil, fmt.Errorf("duplicate network location %s", myNetworkId)
+	}
+	ret := &memTransport{
+		network:     n,
+		myNetworkId: myNetworkId,
+		events:      newTransportEvents(),
+		chIn:        make(chan *PeerMessage, memQueueSize),
+		chClosed:    make(chan struct{}),
+		peers:       make(map[string]int),
+	}
+	n.nodes[myNetworkId] = ret
+	go ret.deliverLoop()
+	return ret, nil
+}
+
+func (n *MemNetwork) node(location string) (*memTransport, bool) {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+	ret, ok := n.nodes[location]
+	return ret, ok
+}
+
+func (n *MemNetwork) remove(location string) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	delete(n.nodes, location)
+}
+
+func (t *memTransport) MyNetworkId() string {
+	return t.myNetworkId
+}
+
+func (t *memTransport) Events() *TransportEvents {
+	return t.events
+}
+
+// UsePeer returns the peer. No connection is needed, the peer is alive while both nodes are in the network
+func (t *memTransport) UsePeer(remoteLocation string) Peer {
+	if remoteLocation == t.myNetworkId {
+		return nil
+	}
+	t.peersMutex.Lock()
+	t.peers[remoteLocation]++
+	t.peersMutex.Unlock()
+	return &memPeer{
+		transport:      t,
+		remoteLocation: remoteLocation,
+	}
+}
+
+func (t *memTransport) StopUsingPeer(remoteLocation string) {
+	t.peersMutex.Lock()
+	defer t.peersMutex.Unlock()
+
+	if t.peers[remoteLocation] <= 1 {
+		delete(t.peers, remoteLocation)
+		return
+	}
+	t.peers[remoteLocation]--
+}
+
+// PeerStatuses returns state of peers. The in-memory network doesn't exchange heartbeats,
+// so there are no round trip and clock offset measurements
+func (t *memTransport) PeerStatuses() []*PeerStatus {
+	t.peersMutex.Lock()
+	defer t.peersMutex.Unlock()
+
+	ret := make([]*PeerStatus, 0, len(t.peers))
+	for loc, numUsers := range t.peers {
+		_, isAlive := (&memPeer{transport: t, remoteLocation: loc}).target()
+		ret = append(ret, &PeerStatus{
+			RemoteLocation: loc,
+			IsConnected:    isAlive,
+			IsHandshaken:   isAlive,
+			IsAlive:        isAlive,
+			NumUsers:       numUsers,
+		})
+	}
+	return ret
+}
+
+// OwnClockDrifts is false: nodes of the in-memory network share the clock of the process
+func (t *memTransport) OwnClockDrifts() bool {
+	return false
+}
+
+func (t *memTransport) Run(shutdownSignal <-chan struct{}) {
+	<-shutdownSignal
+	t.close()
+}
+
+func (t *memTransport) close() {
+	t.closeOnce.Do(func() {
+		t.network.remove(t.myNetworkId)
+		close(t.chClosed)
+	})
+}
+
+func (t *memTransport) isClosed() bool {
+	select {
+	case <-t.chClosed:
+		return true
+	default:
+		return false
+	}
+}
+
+// deliverLoop triggers events for received messages in the order they were sent
+func (t *memTransport) deliverLoop() {
+	for {
+		select {
+		case msg := <-t.chIn:
+			t.events.MessageReceived.Trigger(msg)
+		case <-t.chClosed:
+			return
+		}
+	}
+}
+
+func (t *memTransport) receive(msg *PeerMessage) error {
+	select {
+	case t.chIn <- msg:
+		return nil
+	case <-t.chClosed:
+		return fmt.Errorf("no connection with %s", t.myNetworkId)
+	}
+}
+
+func (p *memPeer) RemoteLocation() string {
+	return p.remoteLocation
+}
+
+func (p *memPeer) target() (*memTransport, bool) {
+	if p.transport.isClosed() {
+		return nil, false
+	}
+	return p.transport.network.node(p.remoteLocation)
+}
+
+// SendMsg puts copy of the message to the queue of the target node. Blocks if the queue is full
+func (p *memPeer) SendMsg(msg *PeerMessage) error {
+	if msg.MsgType < FirstCommitteeMsgCode {
+		return fmt.Errorf("reserved message code")
+	}
+	if len(msg.MsgData) > limits.maxMsgSize {
+		return errTooLarge
+	}
+	target, ok := p.target()
+	if !ok {
+		return fmt.Errorf("no connection with %s", p.remoteLocation)
+	}
+	cpy := *msg
+	cpy.Timestamp = time.Now().UnixNano()
+	cpy.MsgData = append([]byte(nil), msg.MsgData...)
+	cpy.SenderNetworkId = p.transport.myNetworkId
+	return target.receive(&cpy)
+}
+
+func (p *memPeer) IsAlive() (bool, int64) {
+	_, ok := p.target()
+	return ok, 0
+}
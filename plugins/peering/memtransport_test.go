@@ -0,0 +1,93 @@
+package peering
+
+import (
+	"testing"
+	"time"
+
+	"github.com/iotaledger/hive.go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func receiveMessages(t Transport) chan *PeerMessage {
+	ret := make(chan *PeerMessage, 10)
+	t.Events().MessageReceived.Attach(events.NewClosure(func(msg *PeerMessage) {
+		ret <- msg
+	}))
+	return ret
+}
+
+func TestMemTransport(t *testing.T) {
+	network := NewMemNetwork()
+	t1, err := network.NewTransport("node1:4000")
+	require.NoError(t, err)
+	t2, err := network.NewTransport("node2:4000")
+	require.NoError(t, err)
+	_, err = network.NewTransport("node2:4000")
+	assert.Error(t, err)
+	assert.Equal(t, "node1:4000", t1.MyNetworkId())
+
+	assert.Nil(t, t1.UsePeer("node1:4000"))
+	peer := t1.UsePeer("node2:4000")
+	require.NotNil(t, peer)
+	assert.Equal(t, "node2:4000", peer.RemoteLocation())
+	alive, _ := peer.IsAlive()
+	assert.True(t, alive)
+	statuses := t1.PeerStatuses()
+	require.Len(t, statuses, 1)
+	assert.Equal(t, "node2:4000", statuses[0].RemoteLocation)
+	assert.True(t, statuses[0].IsAlive)
+	assert.False(t, statuses[0].HasClockEstimate)
+	assert.Equal(t, 1, statuses[0].NumUsers)
+
+	received := receiveMessages(t2)
+	for i := byte(0); i < 3; i++ {
+		require.NoError(t, peer.SendMsg(&PeerMessage{
+			SenderIndex: 1,
+			MsgType:     FirstCommitteeMsgCode + i,
+			MsgData:     []byte{i},
+		}))
+	}
+	for i := byte(0); i < 3; i++ {
+		select {
+		case msg := <-received:
+			assert.Equal(t, FirstCommitteeMsgCode+i, msg.MsgType)
+			assert.Equal(t, []byte{i}, msg.MsgData)
+			assert.EqualValues(t, 1, msg.SenderIndex)
+			assert.NotZero(t, msg.Timestamp)
+		case <-time.After(time.Second):
+			t.Fatal("message not received")
+		}
+	}
+	assert.Error(t, peer.SendMsg(&PeerMessage{MsgType: MsgTypeHandshake}))
+
+	// peer at the location which is not in the network yet
+	peer3 := t1.UsePeer("node3:4000")
+	alive, _ = peer3.IsAlive()
+	assert.False(t, alive)
+	assert.Error(t, peer3.SendMsg(&PeerMessage{MsgType: FirstCommitteeMsgCode}))
+	t3, err := network.NewTransport("node3:4000")
+	require.NoError(t, err)
+	alive, _ = peer3.IsAlive()
+	assert.True(t, alive)
+
+	// stopped node
+	shutdown := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		t3.Run(shutdown)
+		close(done)
+	}()
+	close(shutdown)
+	<-done
+	alive, _ = peer3.IsAlive()
+	assert.False(t, alive)
+	assert.Error(t, peer3.SendMsg(&PeerMessage{MsgType: FirstCommitteeMsgCode}))
+	assert.Nil(t, t3.UsePeer("node3:4000"))
+	alive, _ = t3.UsePeer("node1:4000").IsAlive()
+	assert.False(t, alive)
+
+	t1.StopUsingPeer("node2:4000")
+	t1.StopUsingPeer("node3:4000")
+	assert.Empty(t, t1.PeerStatuses())
+}
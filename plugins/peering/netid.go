@@ -9,7 +9,7 @@ import (
 
 // check if network location from the committee list represents current node
 func checkMyNetworkID() error {
-	shost, sport, err := net.SplitHostPort(MyNetworkId())
+	shost, sport, err := net.SplitHostPort(myTcpNetworkId())
 	if err != nil {
 		return err
 	}
@@ -18,7 +18,7 @@ func checkMyNetworkID() error {
 		return err
 	}
 	if port != config.Node.GetInt(CfgPeeringPort) {
-		return fmt.Errorf("wrong own network port in %s", MyNetworkId())
+		return fmt.Errorf("wrong own network port in %s", myTcpNetworkId())
 	}
 	myIPs, err := myIPs()
 	if err != nil {
@@ -35,7 +35,7 @@ func checkMyNetworkID() error {
 			}
 		}
 	}
-	return fmt.Errorf("network location %s doesn't represent current node", MyNetworkId())
+	return fmt.Errorf("network location %s doesn't represent current node", myTcpNetworkId())
 }
 
 func myIPs() ([]string, error) {
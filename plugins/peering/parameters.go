@@ -7,9 +7,26 @@ import (
 func init() {
 	flag.Int(CfgPeeringPort, 4000, "port for Wasp committee connection/peering")
 	flag.String(CfgMyNetId, "127.0.0.1:4000", "node host address as it is recognized by other peers")
+	flag.String(CfgPeeringKeyFile, "peering.key", "file with the private key of the node identity. Generated if it doesn't exist")
+	flag.Int(CfgPeeringMaxMsgSize, 1<<20, "maximum size of a message received from a peer, in bytes")
+	flag.Int(CfgPeeringMsgRate, 1000, "maximum number of messages per second accepted from a peer. 0 means no limit")
+	flag.Int(CfgPeeringMsgBurst, 2000, "number of messages a peer may send at once above the rate")
+	flag.Int(CfgPeeringInQueueSize, 1000, "size of the queue of messages received from a peer")
+	flag.Int(CfgPeeringMaxClockOffset, 1000, "milliseconds the clock of a peer may differ from own clock without a warning")
+	flag.Bool(CfgPeeringRequireTrustedPeers, false, "refuse to activate a committee with peers not in the trusted peer list. If false, only a warning is logged")
 }
 
 const (
-	CfgMyNetId     = "peering.netid"
-	CfgPeeringPort = "peering.port"
+	CfgMyNetId        = "peering.netid"
+	CfgPeeringPort    = "peering.port"
+	CfgPeeringKeyFile = "peering.keyFile"
+
+	CfgPeeringMaxMsgSize  = "peering.maxMsgSize"
+	CfgPeeringMsgRate     = "peering.msgRate"
+	CfgPeeringMsgBurst    = "peering.msgBurst"
+	CfgPeeringInQueueSize = "peering.inQueueSize"
+
+	CfgPeeringMaxClockOffset = "peering.maxClockOffset"
+
+	CfgPeeringRequireTrustedPeers = "peering.requireTrustedPeers"
 )
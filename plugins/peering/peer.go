@@ -1,13 +1,13 @@
 package peering
 
 import (
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"github.com/iotaledger/goshimmer/dapps/waspconn/packages/chopper"
 	"github.com/iotaledger/hive.go/backoff"
 	"github.com/iotaledger/hive.go/netutil/buffconn"
 	"go.uber.org/atomic"
-	"net"
 	"sync"
 	"time"
 )
@@ -15,10 +15,10 @@ import (
 // represents point-to-point TCP connection between two qnodes and another
 // it is used as transport for message exchange
 // Another end is always using the same connection
-// the Peer takes care about exchanging heartbeat messages.
+// the tcpPeer takes care about exchanging heartbeat messages.
 // It keeps last several received heartbeats as "lad" data to be able to calculate how synced/unsynced
 // clocks of peer are.
-type Peer struct {
+type tcpPeer struct {
 	*sync.RWMutex
 	isDismissed atomic.Bool       // to be GC-ed
 	peerconn    *peeredConnection // nil means not connected
@@ -28,40 +28,83 @@ type Peer struct {
 
 	startOnce *sync.Once
 	numUsers  int
+	events    *TransportEvents
+	// bounded queue of received messages and the limits of the traffic from the peer
+	chIn     chan *PeerMessage
+	chStop   chan struct{}
+	stopOnce sync.Once
+	limiter  *rateLimiter
+	dropped  dropCounters
 	// heartbeats and latencies
 	lastHeartbeatReceived int64
 	lastHeartbeatSent     int64
 	latencyRingBuf        [numHeartbeatsToKeep]int64
 	hbRingBufIdx          int
+	// timestamp of the last message from the peer, echoed back in heartbeats, and when it was received
+	lastRemoteTs        int64
+	lastRemoteTsArrived int64
+	// round trips and clock offsets estimated from echoed heartbeats
+	roundTripRingBuf [numHeartbeatsToKeep]int64
+	offsetRingBuf    [numHeartbeatsToKeep]int64
+	numEstimates     int
+	// the clock offset exceeds maxClockOffset
+	clockDrifts bool
 }
 
 // retry net.Dial once, on fail after 0.5s
 var dialRetryPolicy = backoff.ConstantBackOff(backoffDelay).With(backoff.MaxRetries(dialRetries))
 
 func isInbound(remoteLocation string) bool {
-	if remoteLocation == MyNetworkId() {
+	if remoteLocation == myTcpNetworkId() {
 		panic("remoteLocation == myLocation")
 	}
-	return remoteLocation < MyNetworkId()
+	return remoteLocation < myTcpNetworkId()
 }
 
-func (peer *Peer) isInbound() bool {
+func (peer *tcpPeer) isInbound() bool {
 	return isInbound(peer.remoteLocation)
 }
 
 func peeringId(remoteLocation string) string {
 	if isInbound(remoteLocation) {
-		return remoteLocation + "<" + MyNetworkId()
+		return remoteLocation + "<" + myTcpNetworkId()
 	} else {
-		return MyNetworkId() + "<" + remoteLocation
+		return myTcpNetworkId() + "<" + remoteLocation
 	}
 }
 
-func (peer *Peer) PeeringId() string {
+func (peer *tcpPeer) PeeringId() string {
 	return peeringId(peer.remoteLocation)
 }
 
-func (peer *Peer) connStatus() (bool, bool) {
+// RemoteLocation returns network location of the peer
+func (peer *tcpPeer) RemoteLocation() string {
+	return peer.remoteLocation
+}
+
+func (peer *tcpPeer) status() *PeerStatus {
+	isConnected, isHandshaken := peer.connStatus()
+	isAlive, _ := peer.IsAlive()
+	roundTrip, offset, hasEstimate := peer.clockEstimates()
+
+	peer.RLock()
+	defer peer.RUnlock()
+	return &PeerStatus{
+		RemoteLocation:   peer.remoteLocation,
+		IsInbound:        peer.isInbound(),
+		IsConnected:      isConnected,
+		IsHandshaken:     isHandshaken,
+		IsAlive:          isAlive,
+		LastHeartbeat:    peer.lastHeartbeatReceived,
+		AvgRoundTrip:     roundTrip,
+		ClockOffset:      offset,
+		HasClockEstimate: hasEstimate,
+		NumUsers:         peer.numUsers,
+		Dropped:          peer.dropped.snapshot(),
+	}
+}
+
+func (peer *tcpPeer) connStatus() (bool, bool) {
 	peer.RLock()
 	defer peer.RUnlock()
 	if peer.isDismissed.Load() {
@@ -70,7 +113,7 @@ func (peer *Peer) connStatus() (bool, bool) {
 	return peer.peerconn != nil, peer.handshakeOk
 }
 
-func (peer *Peer) closeConn() {
+func (peer *tcpPeer) closeConn() {
 	peer.Lock()
 	defer peer.Unlock()
 
@@ -82,8 +125,22 @@ func (peer *Peer) closeConn() {
 	}
 }
 
+// stop dismisses the peer removed from the peer map: loops of the peer stop and the connection is closed
+func (peer *tcpPeer) stop() {
+	peer.stopOnce.Do(func() {
+		peer.isDismissed.Store(true)
+		close(peer.chStop)
+
+		peer.Lock()
+		defer peer.Unlock()
+		if peer.peerconn != nil {
+			_ = peer.peerconn.Close()
+		}
+	})
+}
+
 // dials outbound address and established connection
-func (peer *Peer) runOutbound() {
+func (peer *tcpPeer) runOutbound() {
 	if peer.isDismissed.Load() {
 		return
 	}
@@ -108,11 +165,11 @@ func (peer *Peer) runOutbound() {
 		}()
 	}()
 
-	var conn net.Conn
+	var conn *tls.Conn
 
 	if err := backoff.Retry(dialRetryPolicy, func() error {
 		var err error
-		conn, err = net.DialTimeout("tcp", peer.remoteLocation, dialTimeout)
+		conn, err = dialTLS(peer.remoteLocation)
 		if err != nil {
 			return fmt.Errorf("dial %s failed: %w", peer.remoteLocation, err)
 		}
@@ -121,7 +178,7 @@ func (peer *Peer) runOutbound() {
 		log.Warn(err)
 		return
 	}
-	peer.peerconn = newPeeredConnection(conn, peer)
+	peer.peerconn = newPeeredConnection(conn, peerPublicKey(conn), peer)
 	if err := peer.sendHandshake(); err != nil {
 		log.Errorf("error during sendHandshake: %v", err)
 		return
@@ -139,22 +196,25 @@ func (peer *Peer) runOutbound() {
 }
 
 // sends handshake message. It contains myLocation
-func (peer *Peer) sendHandshake() error {
+func (peer *tcpPeer) sendHandshake() error {
 	data, _ := encodeMessage(&PeerMessage{
 		MsgType: MsgTypeHandshake,
 		MsgData: []byte(peer.PeeringId()),
 	})
 	_, err := peer.peerconn.Write(data)
-	log.Debugf("sendHandshake '%s' --> '%s', id = %s", MyNetworkId(), peer.remoteLocation, peer.PeeringId())
+	log.Debugf("sendHandshake '%s' --> '%s', id = %s", myTcpNetworkId(), peer.remoteLocation, peer.PeeringId())
 	return err
 }
 
-func (peer *Peer) SendMsg(msg *PeerMessage) error {
+func (peer *tcpPeer) SendMsg(msg *PeerMessage) error {
 	//log.Debugw("SendMsg", "id", peer.PeeringId(), "msgType", msg.MsgType)
 
 	if msg.MsgType < FirstCommitteeMsgCode {
 		return errors.New("reserved message code")
 	}
+	if len(msg.MsgData) > limits.maxMsgSize {
+		return errTooLarge
+	}
 	data, ts := encodeMessage(msg)
 
 	peer.lastHeartbeatSent = ts
@@ -170,7 +230,7 @@ func (peer *Peer) SendMsg(msg *PeerMessage) error {
 	return peer.sendChunks(choppedData)
 }
 
-func (peer *Peer) sendChunks(chopped [][]byte) error {
+func (peer *tcpPeer) sendChunks(chopped [][]byte) error {
 	for _, piece := range chopped {
 		d, _ := encodeMessage(&PeerMessage{
 			MsgType: MsgTypeMsgChunk,
@@ -183,38 +243,51 @@ func (peer *Peer) sendChunks(chopped [][]byte) error {
 	return nil
 }
 
-// sends same msg to all peers in the slice which are not nil
-// with the same timestamp
-func SendMsgToPeers(msg *PeerMessage, peers ...*Peer) (uint16, int64) {
-	if msg.MsgType < FirstCommitteeMsgCode {
-		return 0, 0
+// receiveMessage processes the message from the handshaken peer: heartbeats at once, other messages
+// through the inbound queue. Heartbeats count toward the rate limit as well, so the peer can't flood them
+func (peer *tcpPeer) receiveMessage(msg *PeerMessage) {
+	if !peer.limiter.allow(time.Now()) {
+		countDrop(&peer.dropped.rateLimited, peer.remoteLocation, "rate limit exceeded")
+		return
 	}
-	// timestamped here, once
-	data, ts := encodeMessage(msg)
-	choppedData, chopped := chopper.ChopData(data, buffconn.MaxMessageSize-ChunkMessageOverhead)
+	if msg.MsgType == MsgTypeHeartbeat {
+		peer.receiveEcho(msg.Timestamp, msg.MsgData)
+		peer.checkClockOffset()
+		return
+	}
+	peer.enqueue(msg)
+}
 
-	ret := uint16(0)
-	for _, peer := range peers {
-		if peer == nil {
-			continue
-		}
-		peer.RLock()
-		if !chopped {
-			peer.lastHeartbeatSent = ts
-			if err := peer.sendData(data); err == nil {
-				ret++
-			}
-		} else {
-			if err := peer.sendChunks(choppedData); err == nil {
-				ret++
-			}
+// enqueue puts received message into the inbound queue of the peer. If the queue is full, it blocks
+// reading from the connection for up to inQueueTimeout, so the sending peer is slowed down
+func (peer *tcpPeer) enqueue(msg *PeerMessage) {
+	msg.SenderNetworkId = peer.remoteLocation
+	select {
+	case peer.chIn <- msg:
+		return
+	default:
+	}
+	select {
+	case peer.chIn <- msg:
+	case <-peer.chStop:
+	case <-time.After(inQueueTimeout):
+		countDrop(&peer.dropped.queueFull, peer.remoteLocation, "inbound queue is full")
+	}
+}
+
+// deliverLoop triggers events for messages from the inbound queue until the peer is not used anymore
+func (peer *tcpPeer) deliverLoop() {
+	for {
+		select {
+		case msg := <-peer.chIn:
+			peer.events.MessageReceived.Trigger(msg)
+		case <-peer.chStop:
+			return
 		}
-		peer.RUnlock()
 	}
-	return ret, ts
 }
 
-func (peer *Peer) sendData(data []byte) error {
+func (peer *tcpPeer) sendData(data []byte) error {
 	if peer.peerconn == nil {
 		return fmt.Errorf("no connection with %s", peer.remoteLocation)
 	}
@@ -0,0 +1,20 @@
+package peering
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeerStop(t *testing.T) {
+	peer := &tcpPeer{RWMutex: &sync.RWMutex{}, chStop: make(chan struct{})}
+	peer.stop()
+	peer.stop()
+	assert.True(t, peer.isDismissed.Load())
+	select {
+	case <-peer.chStop:
+	default:
+		t.Fatal("peer is not stopped")
+	}
+}
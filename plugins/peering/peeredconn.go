@@ -1,10 +1,13 @@
 package peering
 
 import (
-	"github.com/iotaledger/goshimmer/dapps/waspconn/packages/chopper"
+	"crypto/ed25519"
+	"errors"
 	"github.com/iotaledger/hive.go/events"
 	"github.com/iotaledger/hive.go/netutil/buffconn"
+	"github.com/iotaledger/wasp/packages/chunks"
 	"net"
+	"time"
 )
 
 // extension of BufferedConnection from hive.go
@@ -13,15 +16,20 @@ import (
 // with peer (peers) according to the handshake information
 type peeredConnection struct {
 	*buffconn.BufferedConnection
-	peer        *Peer
+	peer        *tcpPeer
 	handshakeOk bool
+	// public key the remote side authenticated with in the TLS handshake
+	remotePubKey ed25519.PublicKey
+	chunks       *chunks.Assembler
 }
 
 // creates new peered connection and attach event handlers for received data and closing
-func newPeeredConnection(conn net.Conn, peer *Peer) *peeredConnection {
+func newPeeredConnection(conn net.Conn, remotePubKey ed25519.PublicKey, peer *tcpPeer) *peeredConnection {
 	bconn := &peeredConnection{
 		BufferedConnection: buffconn.NewBufferedConnection(conn),
 		peer:               peer,
+		remotePubKey:       remotePubKey,
+		chunks:             chunks.NewAssembler(buffconn.MaxMessageSize-ChunkMessageOverhead, limits.maxMsgSize, chunkTTL),
 	}
 	bconn.Events.ReceiveMessage.Attach(events.NewClosure(func(data []byte) {
 		bconn.receiveData(data)
@@ -40,33 +48,40 @@ func newPeeredConnection(conn net.Conn, peer *Peer) *peeredConnection {
 
 // receive data handler for peered connection
 func (bconn *peeredConnection) receiveData(data []byte) {
-	msg, err := decodeMessage(data)
+	msg, err := decodeMessage(data, limits.maxMsgSize)
 	if err != nil {
 		log.Errorf("decodeMessage: %v", err)
-		bconn.peer.closeConn()
+		if bconn.peer != nil && errors.Is(err, errTooLarge) {
+			countDrop(&bconn.peer.dropped.tooLarge, bconn.peer.remoteLocation, err.Error())
+		}
+		_ = bconn.Close()
 		return
 	}
 	if msg.MsgType == MsgTypeMsgChunk {
-		finalMsg, err := chopper.IncomingChunk(msg.MsgData, buffconn.MaxMessageSize-ChunkMessageOverhead)
+		if bconn.peer == nil || !bconn.peer.handshakeOk {
+			log.Errorf("unexpected message during handshake")
+			return
+		}
+		finalMsg, err := bconn.chunks.Incoming(msg.MsgData, time.Now())
 		if err != nil {
-			log.Errorf("decodeMessage: %v", err)
+			if errors.Is(err, errTooLarge) {
+				countDrop(&bconn.peer.dropped.tooLarge, bconn.peer.remoteLocation, "too large chunked message")
+			} else {
+				log.Errorf("incoming chunk: %v", err)
+			}
 			return
 		}
 		if finalMsg != nil {
 			bconn.receiveData(finalMsg)
 		}
+		return
 	}
 	if bconn.peer != nil {
 		// it is peered but maybe not handshaked yet (can only be outbound)
 		if bconn.peer.handshakeOk {
 			// it is handshake-ed
 			bconn.peer.receiveHeartbeat(msg.Timestamp)
-			if msg.MsgType == MsgTypeHeartbeat {
-				// heartbeat msg. No need for further processing
-				return
-			}
-			// trigger event to be processed
-			EventPeerMessageReceived.Trigger(msg)
+			bconn.peer.receiveMessage(msg)
 		} else {
 			// expected handshake msg
 			if msg.MsgType != MsgTypeHandshake {
@@ -123,6 +138,12 @@ func (bconn *peeredConnection) processHandShakeInbound(msg *PeerMessage) {
 		_ = bconn.Close()
 		return
 	}
+	// the peer must have authenticated with the key trusted for the location it claims
+	if err := verifyTrustedPeer(peer.remoteLocation, bconn.remotePubKey); err != nil {
+		log.Warnf("inbound connection with peer id %s rejected: %v", peeringId, err)
+		_ = bconn.Close()
+		return
+	}
 	bconn.peer = peer
 
 	peer.Lock()
@@ -12,7 +12,7 @@ import (
 )
 
 var (
-	peers      = make(map[string]*Peer)
+	peers      = make(map[string]*tcpPeer)
 	peersMutex = &sync.RWMutex{}
 )
 
@@ -63,10 +63,15 @@ func connectInboundLoop() {
 		}
 		log.Debugf("accepted connection from %s", conn.RemoteAddr().String())
 
-		bconn := newPeeredConnection(conn, nil)
-		go func() {
+		go func(conn net.Conn) {
+			tlsConn, err := acceptTLS(conn)
+			if err != nil {
+				log.Warnf("handshake with %s failed: %v", conn.RemoteAddr().String(), err)
+				return
+			}
+			bconn := newPeeredConnection(tlsConn, peerPublicKey(tlsConn), nil)
 			log.Debugf("starting reading inbound %s", conn.RemoteAddr().String())
-			err := bconn.Read()
+			err = bconn.Read()
 			log.Debugw("stopped reading inbound. Closing", "remote", conn.RemoteAddr(), "err", err)
 
 			//if err := bconn.Read(); err != nil {
@@ -75,7 +80,7 @@ func connectInboundLoop() {
 			//	}
 			//}
 			_ = bconn.Close()
-		}()
+		}(conn)
 	}
 }
 
@@ -25,6 +25,13 @@ func configure(_ *node.Plugin) {
 		return
 	}
 	log.Infof("my network Id = %s", MyNetworkId())
+	if err := loadIdentity(); err != nil {
+		log.Errorf("can't load node identity: %v", err)
+		return
+	}
+	log.Infof("my public key = %s", PublicKeyToString(MyPublicKey()))
+	loadLimits()
+	loadMaxClockOffset()
 	initialized.Store(true)
 }
 
@@ -32,17 +39,7 @@ func run(_ *node.Plugin) {
 	if !initialized.Load() {
 		return
 	}
-	if err := daemon.BackgroundWorker("WaspPeering", func(shutdownSignal <-chan struct{}) {
-
-		go connectOutboundLoop()
-		go connectInboundLoop()
-
-		<-shutdownSignal
-
-		log.Info("Closing all connections with peers...")
-		closeAll()
-		log.Info("Closing all connections with peers... done")
-	}, shutdown.PriorityPeering); err != nil {
+	if err := daemon.BackgroundWorker("WaspPeering", transport.Run, shutdown.PriorityPeering); err != nil {
 		panic(err)
 	}
 }
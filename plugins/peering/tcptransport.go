@@ -0,0 +1,106 @@
+package peering
+
+import (
+	"github.com/iotaledger/wasp/plugins/config"
+	"sync"
+)
+
+// tcpTransport is the transport of the node over TLS connections with other nodes.
+// The state of the connections is in the peer pool
+type tcpTransport struct {
+	events *TransportEvents
+}
+
+func myTcpNetworkId() string {
+	return config.Node.GetString(CfgMyNetId)
+}
+
+func (t *tcpTransport) MyNetworkId() string {
+	return myTcpNetworkId()
+}
+
+func (t *tcpTransport) Events() *TransportEvents {
+	return t.events
+}
+
+func (t *tcpTransport) OwnClockDrifts() bool {
+	return ownClockDrifts.Load()
+}
+
+func (t *tcpTransport) PeerStatuses() []*PeerStatus {
+	peersMutex.RLock()
+	defer peersMutex.RUnlock()
+
+	ret := make([]*PeerStatus, 0, len(peers))
+	for _, peer := range peers {
+		ret = append(ret, peer.status())
+	}
+	return ret
+}
+
+func (t *tcpTransport) Run(shutdownSignal <-chan struct{}) {
+	go connectOutboundLoop()
+	go connectInboundLoop()
+
+	<-shutdownSignal
+
+	log.Info("Closing all connections with peers...")
+	closeAll()
+	log.Info("Closing all connections with peers... done")
+}
+
+// adds new connection to the peer pool
+// if it already exists, returns existing
+// connection added to the pool is picked by loops which will try to establish connection
+func (t *tcpTransport) UsePeer(remoteLocation string) Peer {
+	if !initialized.Load() {
+		return nil
+	}
+	if remoteLocation == myTcpNetworkId() {
+		return nil
+	}
+	peersMutex.Lock()
+	defer peersMutex.Unlock()
+
+	if qconn, ok := peers[peeringId(remoteLocation)]; ok {
+		qconn.numUsers++
+		return qconn
+	}
+	ret := &tcpPeer{
+		RWMutex:        &sync.RWMutex{},
+		remoteLocation: remoteLocation,
+		startOnce:      &sync.Once{},
+		numUsers:       1,
+		events:         t.events,
+		chIn:           make(chan *PeerMessage, limits.inQueueSize),
+		chStop:         make(chan struct{}),
+		limiter:        newRateLimiter(limits.msgRate, limits.msgBurst),
+	}
+	go ret.deliverLoop()
+	peers[ret.PeeringId()] = ret
+	log.Debugf("added new peer id %s inbound = %v", ret.PeeringId(), ret.isInbound())
+	return ret
+}
+
+// decreases counter. The connection is closed when the peer is not used anymore
+func (t *tcpTransport) StopUsingPeer(remoteLocation string) {
+	if !initialized.Load() {
+		return
+	}
+	if remoteLocation == myTcpNetworkId() {
+		return
+	}
+	peerId := peeringId(remoteLocation)
+
+	peersMutex.Lock()
+	defer peersMutex.Unlock()
+
+	if peer, ok := peers[peerId]; ok {
+		peer.numUsers--
+		if peer.numUsers == 0 {
+			// removed under the same lock, so UsePeer never returns the stopped peer
+			delete(peers, peerId)
+			peer.stop()
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package peering
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"time"
+
+	"github.com/iotaledger/wasp/packages/registry"
+)
+
+// Connections between peers are TLS 1.3 with mutual authentication. Each node presents the self-signed
+// certificate of its identity key. In the TLS 1.3 handshake each side signs the transcript, which includes
+// random values of both sides, i.e. it signs the challenge. The certificate chain is not verified,
+// instead the public key is checked against the trusted peers in the registry:
+//   - outbound: the key must be the one trusted for the network location dialed
+//   - inbound: the key must be trusted. After the handshake message, it must be the key
+//     trusted for the network location the peer claims
+
+const handshakeTimeout = 5 * time.Second
+
+var errUntrustedPeer = errors.New("public key of the peer is not trusted")
+
+// identityCertificate creates self-signed certificate of the identity key
+func identityCertificate(key ed25519.PrivateKey) (tls.Certificate, error) {
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(10 * 365 * 24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+	}, nil
+}
+
+// tlsConfig returns the config of the TLS connection authenticated with the identity certificate.
+// The public key of the peer is checked by the verify function
+func tlsConfig(cert tls.Certificate, verify func(pubKey ed25519.PublicKey) error) *tls.Config {
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS13,
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAnyClientCert,
+		// certificates are self-signed, public keys are verified against trusted peers
+		InsecureSkipVerify: true,
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			pubKey, err := certificatePublicKey(rawCerts)
+			if err != nil {
+				return err
+			}
+			return verify(pubKey)
+		},
+	}
+}
+
+func certificatePublicKey(rawCerts [][]byte) (ed25519.PublicKey, error) {
+	if len(rawCerts) != 1 {
+		return nil, errors.New("exactly one certificate expected")
+	}
+	cert, err := x509.ParseCertificate(rawCerts[0])
+	if err != nil {
+		return nil, err
+	}
+	pubKey, ok := cert.PublicKey.(ed25519.PublicKey)
+	if !ok {
+		return nil, errors.New("ed25519 public key expected")
+	}
+	return pubKey, nil
+}
+
+// peerPublicKey returns the public key of the peer after the handshake
+func peerPublicKey(conn *tls.Conn) ed25519.PublicKey {
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil
+	}
+	pubKey, _ := certs[0].PublicKey.(ed25519.PublicKey)
+	return pubKey
+}
+
+// verifyTrustedPeer checks if the public key is trusted for the network location
+func verifyTrustedPeer(remoteLocation string, pubKey ed25519.PublicKey) error {
+	trusted, ok, err := registry.GetTrustedPeerKey(remoteLocation)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("peer %s is not trusted", remoteLocation)
+	}
+	if !bytes.Equal(trusted, pubKey) {
+		return fmt.Errorf("%s: %w", remoteLocation, errUntrustedPeer)
+	}
+	return nil
+}
+
+// verifyTrustedKey checks if the public key is trusted for any network location
+func verifyTrustedKey(pubKey ed25519.PublicKey) error {
+	peers, err := registry.GetTrustedPeers()
+	if err != nil {
+		return err
+	}
+	for _, p := range peers {
+		if bytes.Equal(p.PubKey, pubKey) {
+			return nil
+		}
+	}
+	return errUntrustedPeer
+}
+
+// CheckTrusted returns error if the identity doesn't trust one of the network locations
+// except its own location
+func CheckTrusted(identity Identity, myLocation string, remoteLocations ...string) error {
+	for _, loc := range remoteLocations {
+		if loc == myLocation {
+			continue
+		}
+		_, ok, err := identity.TrustedPeerKey(loc)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("peer %s is not trusted", loc)
+		}
+	}
+	return nil
+}
+
+// dialTLS opens the TLS connection to the peer and checks its public key
+func dialTLS(remoteLocation string) (*tls.Conn, error) {
+	conn, err := net.DialTimeout("tcp", remoteLocation, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	cfg := tlsConfig(identityCert, func(pubKey ed25519.PublicKey) error {
+		return verifyTrustedPeer(remoteLocation, pubKey)
+	})
+	return handshakeTLS(tls.Client(conn, cfg))
+}
+
+// acceptTLS runs the server side of the handshake on the inbound connection
+func acceptTLS(conn net.Conn) (*tls.Conn, error) {
+	return handshakeTLS(tls.Server(conn, tlsConfig(identityCert, verifyTrustedKey)))
+}
+
+func handshakeTLS(conn *tls.Conn) (*tls.Conn, error) {
+	_ = conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	if err := conn.Handshake(); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return conn, nil
+}
@@ -0,0 +1,139 @@
+package peering
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"errors"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestIdentity(t *testing.T) (ed25519.PrivateKey, tls.Certificate) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	cert, err := identityCertificate(key)
+	require.NoError(t, err)
+	return key, cert
+}
+
+func trustOnly(pubKey ed25519.PublicKey) func(ed25519.PublicKey) error {
+	return func(k ed25519.PublicKey) error {
+		if !bytes.Equal(k, pubKey) {
+			return errUntrustedPeer
+		}
+		return nil
+	}
+}
+
+// runs handshake between client and server over loopback connection and returns errors of both sides
+func handshake(t *testing.T, clientCfg, serverCfg *tls.Config) (*tls.Conn, *tls.Conn, error, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	type result struct {
+		conn *tls.Conn
+		err  error
+	}
+	chServer := make(chan result)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			chServer <- result{err: err}
+			return
+		}
+		server := tls.Server(conn, serverCfg)
+		_ = server.SetDeadline(time.Now().Add(5 * time.Second))
+		err = server.Handshake()
+		if err == nil {
+			// in TLS 1.3 the client certificate is checked after the client finished the handshake.
+			// The result reaches the client with the first read
+			_, err = server.Write([]byte{1})
+		}
+		_ = server.Close()
+		chServer <- result{conn: server, err: err}
+	}()
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	client := tls.Client(conn, clientCfg)
+	_ = client.SetDeadline(time.Now().Add(5 * time.Second))
+	errClient := client.Handshake()
+	if errClient == nil {
+		_, errClient = client.Read(make([]byte, 1))
+	}
+	_ = client.Close()
+	res := <-chServer
+	return client, res.conn, errClient, res.err
+}
+
+func TestHandshake(t *testing.T) {
+	key1, cert1 := newTestIdentity(t)
+	key2, cert2 := newTestIdentity(t)
+	pub1 := key1.Public().(ed25519.PublicKey)
+	pub2 := key2.Public().(ed25519.PublicKey)
+
+	client, server, errClient, errServer := handshake(t,
+		tlsConfig(cert1, trustOnly(pub2)),
+		tlsConfig(cert2, trustOnly(pub1)),
+	)
+	require.NoError(t, errClient)
+	require.NoError(t, errServer)
+	assert.EqualValues(t, tls.VersionTLS13, client.ConnectionState().Version)
+	assert.Equal(t, pub2, peerPublicKey(client))
+	assert.Equal(t, pub1, peerPublicKey(server))
+}
+
+func TestHandshakeUntrusted(t *testing.T) {
+	key1, cert1 := newTestIdentity(t)
+	key2, cert2 := newTestIdentity(t)
+	_, cert3 := newTestIdentity(t)
+	pub1 := key1.Public().(ed25519.PublicKey)
+	pub2 := key2.Public().(ed25519.PublicKey)
+
+	// the server presents another key
+	_, _, errClient, _ := handshake(t,
+		tlsConfig(cert1, trustOnly(pub2)),
+		tlsConfig(cert3, trustOnly(pub1)),
+	)
+	assert.True(t, errors.Is(errClient, errUntrustedPeer))
+
+	// the client presents another key
+	_, _, _, errServer := handshake(t,
+		tlsConfig(cert3, trustOnly(pub2)),
+		tlsConfig(cert2, trustOnly(pub1)),
+	)
+	assert.Error(t, errServer)
+}
+
+func TestIdentityEncoding(t *testing.T) {
+	key, _ := newTestIdentity(t)
+	pubKey := key.Public().(ed25519.PublicKey)
+
+	back, err := PublicKeyFromString(PublicKeyToString(pubKey))
+	require.NoError(t, err)
+	assert.Equal(t, pubKey, back)
+
+	_, err = PublicKeyFromString("abc")
+	assert.Error(t, err)
+
+	// key file
+	path := filepath.Join(t.TempDir(), "peering.key")
+	key, err = newIdentityFile(path)
+	require.NoError(t, err)
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	loaded, err := decodeIdentity(data)
+	require.NoError(t, err)
+	assert.Equal(t, key, loaded)
+
+	_, err = decodeIdentity([]byte("not a key"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,86 @@
+package peering
+
+// Transport is the network layer of the peering. It maintains connections with other Wasp nodes,
+// sends messages to them and delivers messages received from them.
+// The node uses the TCP transport. The in-memory transport (see MemNetwork) connects
+// several nodes in the same process without opening sockets
+type Transport interface {
+	// MyNetworkId returns the network location of the node as it is known to other nodes
+	MyNetworkId() string
+	// UsePeer returns the peer at the network location. The connection is established in the background
+	// and re-established when lost. Returns nil for the own location or if the transport is not available
+	UsePeer(remoteLocation string) Peer
+	// StopUsingPeer releases the peer returned by UsePeer. The connection is closed when it is not used anymore
+	StopUsingPeer(remoteLocation string)
+	// Events returns events of the transport
+	Events() *TransportEvents
+	// PeerStatuses returns state of all peers in use
+	PeerStatuses() []*PeerStatus
+	// OwnClockDrifts returns true if clock offsets of peers show that the own clock probably drifts
+	OwnClockDrifts() bool
+	// Run runs the transport until the shutdown signal, then closes all connections
+	Run(shutdownSignal <-chan struct{})
+}
+
+// Peer is the connection with another node as seen by the users of the transport
+type Peer interface {
+	// RemoteLocation returns network location of the peer
+	RemoteLocation() string
+	// SendMsg sends committee or DKG message to the peer
+	SendMsg(msg *PeerMessage) error
+	// IsAlive returns true if the peer is connected and the average latency in nanoseconds
+	IsAlive() (bool, int64)
+}
+
+// PeerStatus is the state of the connection with the peer
+type PeerStatus struct {
+	RemoteLocation string
+	IsInbound      bool
+	IsConnected    bool
+	IsHandshaken   bool
+	IsAlive        bool
+	// unix time in nanoseconds of the last message received, 0 if none
+	LastHeartbeat int64
+	// average of round trips measured with heartbeats, in nanoseconds
+	AvgRoundTrip int64
+	// estimated clock of the peer minus own clock, in nanoseconds
+	ClockOffset int64
+	// false if there are no round trip and clock offset measurements yet
+	HasClockEstimate bool
+	NumUsers         int
+	Dropped          DropCounters
+}
+
+// transport is the TCP transport of the Wasp node. Components take the transport as a dependency
+// (see commiteeimpl and dkg.Node), package functions below are shortcuts to the transport of the node
+var transport Transport = &tcpTransport{events: newTransportEvents()}
+
+// GetTransport returns the transport of the node
+func GetTransport() Transport {
+	return transport
+}
+
+// MyNetworkId returns the network location of the node
+func MyNetworkId() string {
+	return transport.MyNetworkId()
+}
+
+// UsePeer returns the peer at the network location using the transport of the node
+func UsePeer(remoteLocation string) Peer {
+	return transport.UsePeer(remoteLocation)
+}
+
+// StopUsingPeer releases the peer returned by UsePeer
+func StopUsingPeer(remoteLocation string) {
+	transport.StopUsingPeer(remoteLocation)
+}
+
+// PeerStatuses returns state of all peers of the node
+func PeerStatuses() []*PeerStatus {
+	return transport.PeerStatuses()
+}
+
+// Events returns events of the transport of the node
+func Events() *TransportEvents {
+	return transport.Events()
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/balance"
 	"github.com/iotaledger/wasp/packages/sctransaction/txbuilder"
 	"github.com/iotaledger/wasp/packages/vm"
+	"github.com/iotaledger/wasp/packages/vm/vmconst"
 	"github.com/iotaledger/wasp/packages/vm/vmtypes"
 	"github.com/iotaledger/wasp/plugins/config"
 	"time"
@@ -51,13 +52,8 @@ func run(_ *node.Plugin) {
 
 // RunComputationsAsync runs computations for the batch of requests in the background
 func RunComputationsAsync(ctx *vm.VMTask) error {
-	if len(ctx.Requests) == 0 {
-		return fmt.Errorf("must be at least 1 request")
-	}
-
-	txb, err := txbuilder.NewFromAddressBalances(&ctx.Address, ctx.Balances)
+	txb, err := newTaskTxBuilder(ctx)
 	if err != nil {
-		ctx.Log.Debugf("NewTxBuilder: %v\n%s", err, util.BalancesToString(ctx.Balances))
 		return err
 	}
 
@@ -71,6 +67,29 @@ func RunComputationsAsync(ctx *vm.VMTask) error {
 	return err
 }
 
+// RunComputations runs computations for the batch of requests in the calling goroutine.
+// OnFinish is called before it returns. Used by the committee simulator to keep runs reproducible
+func RunComputations(ctx *vm.VMTask) error {
+	txb, err := newTaskTxBuilder(ctx)
+	if err != nil {
+		return err
+	}
+	runTask(ctx, txb, nil)
+	return nil
+}
+
+func newTaskTxBuilder(ctx *vm.VMTask) (*txbuilder.Builder, error) {
+	if len(ctx.Requests) == 0 {
+		return nil, fmt.Errorf("must be at least 1 request")
+	}
+	txb, err := txbuilder.NewFromAddressBalances(&ctx.Address, ctx.Balances)
+	if err != nil {
+		ctx.Log.Debugf("NewTxBuilder: %v\n%s", err, util.BalancesToString(ctx.Balances))
+		return nil, err
+	}
+	return txb, nil
+}
+
 // runs batch
 func runTask(ctx *vm.VMTask, txb *txbuilder.Builder, shutdownSignal <-chan struct{}) {
 	ctx.Log.Debugw("runTask IN",
@@ -133,7 +152,17 @@ func runTask(ctx *vm.VMTask, txb *txbuilder.Builder, shutdownSignal <-chan struc
 		return
 	}
 
-	var err error
+	// the smart contract was rotated to the new committee:
+	// the smart contract token and all balances are moved to the address of the committee
+	newAddr, ok, err := vmctx.VirtualState.Variables().Codec().GetAddress(vmconst.VarNameCommitteeAddress)
+	if err != nil {
+		ctx.OnFinish(fmt.Errorf("RunVM: %v", err))
+		return
+	}
+	if ok && *newAddr != ctx.Address {
+		ctx.Log.Infof("smart contract is rotated to the committee %s", newAddr.String())
+		vmctx.TxBuilder.MoveAllToAddress(ctx.Address, *newAddr)
+	}
 
 	// create batch from state updates.
 	ctx.ResultBatch, err = state.NewBatch(stateUpdates)
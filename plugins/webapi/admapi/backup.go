@@ -0,0 +1,39 @@
+package admapi
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	"github.com/iotaledger/wasp/plugins/database"
+	"github.com/iotaledger/wasp/plugins/webapi/misc"
+	"github.com/labstack/echo"
+)
+
+type BackupRequest struct {
+	Addresses []string `json:"addresses"` //base58. Empty means all smart contracts
+	Registry  bool     `json:"registry"`
+}
+
+// HandlerBackup returns the backup archive as the response body
+func HandlerBackup(c echo.Context) error {
+	var req BackupRequest
+	if err := c.Bind(&req); err != nil {
+		return misc.OkJsonErr(c, err)
+	}
+	addrs := make([]address.Address, len(req.Addresses))
+	for i, s := range req.Addresses {
+		addr, err := address.FromBase58(s)
+		if err != nil {
+			return misc.OkJsonErr(c, err)
+		}
+		addrs[i] = addr
+	}
+	var buf bytes.Buffer
+	backup, err := database.WriteNodeBackup(&buf, addrs, req.Registry)
+	if err != nil {
+		return misc.OkJsonErr(c, err)
+	}
+	log.Infof("backup: %d partition(s), %d bytes", len(backup.Partitions), buf.Len())
+	return c.Blob(http.StatusOK, echo.MIMEOctetStream, buf.Bytes())
+}
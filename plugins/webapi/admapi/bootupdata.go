@@ -14,6 +14,8 @@ type BootupDataJsonable struct {
 	Color          string   `json:"color"`
 	CommitteeNodes []string `json:"committee_nodes"`
 	AccessNodes    []string `json:"access_nodes"`
+	OriginAddress  string   `json:"origin_address,omitempty"` // empty if the smart contract wasn't rotated to the committee
+	RotatedTo      string   `json:"rotated_to,omitempty"`
 }
 
 //----------------------------------------------------------
@@ -44,6 +46,12 @@ func HandlerPutSCData(c echo.Context) error {
 	rec.CommitteeNodes = req.CommitteeNodes
 	rec.AccessNodes = req.AccessNodes
 
+	if req.OriginAddress != "" {
+		if rec.OriginAddress, err = address.FromBase58(req.OriginAddress); err != nil {
+			return misc.OkJsonErr(c, err)
+		}
+	}
+
 	// TODO it is always overwritten!
 
 	if err = registry.SaveBootupData(&rec, true); err != nil {
@@ -95,14 +103,21 @@ func HandlerGetSCData(c echo.Context) error {
 	if !exists {
 		return misc.OkJson(c, &GetBootupDataResponse{Exists: false})
 	}
-	return misc.OkJson(c, &GetBootupDataResponse{
+	ret := &GetBootupDataResponse{
 		BootupDataJsonable: BootupDataJsonable{
 			Address:        bd.Address.String(),
 			CommitteeNodes: bd.CommitteeNodes,
 			AccessNodes:    bd.AccessNodes,
 		},
 		Exists: exists,
-	})
+	}
+	if *bd.StateAddress() != bd.Address {
+		ret.OriginAddress = bd.OriginAddress.String()
+	}
+	if bd.IsRotated() {
+		ret.RotatedTo = bd.RotatedTo.String()
+	}
+	return misc.OkJson(c, ret)
 }
 
 type GetScAddressesResponse struct {
@@ -0,0 +1,21 @@
+package admapi
+
+import (
+	"github.com/iotaledger/wasp/plugins/nodeconn"
+	"github.com/iotaledger/wasp/plugins/webapi/misc"
+	"github.com/labstack/echo"
+)
+
+type GetTxMetricsResponse struct {
+	IsConnected bool               `json:"is_connected"`
+	Metrics     nodeconn.TxMetrics `json:"metrics"`
+	Err         string             `json:"err"`
+}
+
+// HandlerGetTxMetrics returns counters and confirmation latencies of value transactions posted by the node
+func HandlerGetTxMetrics(c echo.Context) error {
+	return misc.OkJson(c, &GetTxMetricsResponse{
+		IsConnected: nodeconn.IsConnected(),
+		Metrics:     nodeconn.GetTxMetrics(),
+	})
+}
@@ -0,0 +1,31 @@
+package admapi
+
+import (
+	"net/http"
+
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	"github.com/iotaledger/wasp/plugins/committees"
+	"github.com/labstack/echo"
+)
+
+type PeerFaultsResponse struct {
+	Err          string   `json:"error"`
+	OwnPeerIndex uint16   `json:"own_peer_index"`
+	Faults       []uint32 `json:"faults"` // indexed by the peer index
+}
+
+// HandlerPeerFaults returns fault counters of committee peers of the smart contract, as seen by this node
+func HandlerPeerFaults(c echo.Context) error {
+	scAddress, err := address.FromBase58(c.Param("scaddress"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &PeerFaultsResponse{Err: err.Error()})
+	}
+	cmt := committees.CommitteeByAddress(scAddress)
+	if cmt == nil {
+		return c.JSON(http.StatusNotFound, &PeerFaultsResponse{Err: "committee not found"})
+	}
+	return c.JSON(http.StatusOK, &PeerFaultsResponse{
+		OwnPeerIndex: cmt.OwnPeerIndex(),
+		Faults:       cmt.PeerFaults(),
+	})
+}
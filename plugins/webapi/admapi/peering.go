@@ -0,0 +1,140 @@
+package admapi
+
+import (
+	"sort"
+
+	"github.com/iotaledger/wasp/packages/registry"
+	"github.com/iotaledger/wasp/plugins/committees"
+	"github.com/iotaledger/wasp/plugins/peering"
+	"github.com/iotaledger/wasp/plugins/webapi/misc"
+	"github.com/labstack/echo"
+)
+
+type TrustedPeerJsonable struct {
+	NetId  string `json:"net_id"`
+	PubKey string `json:"pub_key"` // base58
+}
+
+type GetTrustedPeersResponse struct {
+	Peers []*TrustedPeerJsonable `json:"peers"`
+	Err   string                 `json:"err"`
+}
+
+type PeerStatusJsonable struct {
+	NetId            string               `json:"net_id"`
+	IsInbound        bool                 `json:"is_inbound"`
+	IsConnected      bool                 `json:"is_connected"`
+	IsHandshaken     bool                 `json:"is_handshaken"`
+	IsAlive          bool                 `json:"is_alive"`
+	LastHeartbeat    int64                `json:"last_heartbeat"` // unix nano, 0 if none
+	AvgRoundTrip     int64                `json:"avg_round_trip"` // nanoseconds
+	ClockOffset      int64                `json:"clock_offset"`   // nanoseconds, clock of the peer minus own clock
+	HasClockEstimate bool                 `json:"has_clock_estimate"`
+	NumUsers         int                  `json:"num_users"`
+	Dropped          peering.DropCounters `json:"dropped"`
+	Committees       []string             `json:"committees"` // addresses of active committees served by the peer
+}
+
+type GetPeersResponse struct {
+	NetId string                `json:"net_id"`
+	Peers []*PeerStatusJsonable `json:"peers"`
+	Err   string                `json:"err"`
+}
+
+// HandlerGetPeeringIdentity returns network location and public key of the node in the peering network.
+// Other nodes must trust the public key to connect with the node
+func HandlerGetPeeringIdentity(c echo.Context) error {
+	return misc.OkJson(c, &TrustedPeerJsonable{
+		NetId:  peering.MyNetworkId(),
+		PubKey: peering.PublicKeyToString(peering.MyPublicKey()),
+	})
+}
+
+// HandlerPutTrustedPeers adds peers to the allow-list of the node or replaces their public keys
+func HandlerPutTrustedPeers(c echo.Context) error {
+	var req []*TrustedPeerJsonable
+
+	if err := c.Bind(&req); err != nil {
+		return misc.OkJsonErr(c, err)
+	}
+	for _, p := range req {
+		pubKey, err := peering.PublicKeyFromString(p.PubKey)
+		if err != nil {
+			return misc.OkJsonErr(c, err)
+		}
+		if err = registry.SaveTrustedPeer(&registry.TrustedPeer{NetId: p.NetId, PubKey: pubKey}); err != nil {
+			return misc.OkJsonErr(c, err)
+		}
+		log.Infof("trusted peer %s, public key %s", p.NetId, p.PubKey)
+	}
+	return misc.OkJsonErr(c, nil)
+}
+
+func HandlerGetTrustedPeers(c echo.Context) error {
+	peers, err := registry.GetTrustedPeers()
+	if err != nil {
+		return misc.OkJson(c, &GetTrustedPeersResponse{Err: err.Error()})
+	}
+	ret := make([]*TrustedPeerJsonable, len(peers))
+	for i, p := range peers {
+		ret[i] = &TrustedPeerJsonable{
+			NetId:  p.NetId,
+			PubKey: peering.PublicKeyToString(p.PubKey),
+		}
+	}
+	return misc.OkJson(c, &GetTrustedPeersResponse{Peers: ret})
+}
+
+// HandlerGetPeers returns state of connections with all peers of the node and the active committees they serve
+func HandlerGetPeers(c echo.Context) error {
+	committeesByPeer, err := activeCommitteesByPeer()
+	if err != nil {
+		return misc.OkJson(c, &GetPeersResponse{Err: err.Error()})
+	}
+	statuses := peering.PeerStatuses()
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].RemoteLocation < statuses[j].RemoteLocation
+	})
+	ret := make([]*PeerStatusJsonable, len(statuses))
+	for i, st := range statuses {
+		ret[i] = &PeerStatusJsonable{
+			NetId:            st.RemoteLocation,
+			IsInbound:        st.IsInbound,
+			IsConnected:      st.IsConnected,
+			IsHandshaken:     st.IsHandshaken,
+			IsAlive:          st.IsAlive,
+			LastHeartbeat:    st.LastHeartbeat,
+			AvgRoundTrip:     st.AvgRoundTrip,
+			ClockOffset:      st.ClockOffset,
+			HasClockEstimate: st.HasClockEstimate,
+			NumUsers:         st.NumUsers,
+			Dropped:          st.Dropped,
+			Committees:       committeesByPeer[st.RemoteLocation],
+		}
+	}
+	return misc.OkJson(c, &GetPeersResponse{
+		NetId: peering.MyNetworkId(),
+		Peers: ret,
+	})
+}
+
+// activeCommitteesByPeer returns addresses of active committees by network location of committee and access nodes
+func activeCommitteesByPeer() (map[string][]string, error) {
+	bootupRecords, err := registry.GetBootupRecords()
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[string][]string)
+	for _, bd := range bootupRecords {
+		if committees.CommitteeByAddress(bd.Address) == nil {
+			continue
+		}
+		for _, loc := range bd.CommitteeNodes {
+			ret[loc] = append(ret[loc], bd.Address.String())
+		}
+		for _, loc := range bd.AccessNodes {
+			ret[loc] = append(ret[loc], bd.Address.String())
+		}
+	}
+	return ret, nil
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	"github.com/iotaledger/wasp/packages/registry"
 	"github.com/iotaledger/wasp/packages/state"
 	"github.com/iotaledger/wasp/packages/table"
 	"github.com/labstack/echo"
@@ -22,7 +23,11 @@ func HandlerDumpSCState(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, &DumpSCStateResponse{Err: err.Error()})
 	}
 
-	virtualState, _, ok, err := state.LoadSolidState(&scAddress)
+	stateAddress, err := registry.GetStateAddress(&scAddress)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &DumpSCStateResponse{Err: err.Error()})
+	}
+	virtualState, _, ok, err := state.LoadSolidState(stateAddress)
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, &DumpSCStateResponse{Err: err.Error()})
 	}
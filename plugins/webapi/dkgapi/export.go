@@ -13,7 +13,8 @@ import (
 )
 
 type ExportDKShareRequest struct {
-	Address string `json:"address"` //base58
+	Address    string `json:"address"`    //base58
+	Passphrase string `json:"passphrase"` // if not empty, the blob is encrypted with the passphrase
 }
 
 type ExportDKShareResponse struct {
@@ -22,9 +23,13 @@ type ExportDKShareResponse struct {
 }
 
 type ImportDKShareRequest struct {
-	Blob string `json:"blob"` //base58
+	Blob       string `json:"blob"`       //base58
+	Passphrase string `json:"passphrase"` // needed if the blob is encrypted
 }
 
+// blob encrypted with the passphrase starts with the marker. The plain one starts with the version of BLS address
+const encryptedBlobMarker = byte(0xE2)
+
 type ImportDKShareResponse struct {
 	Err string `json:"err"`
 }
@@ -46,20 +51,27 @@ func HandlerExportDKShare(c echo.Context) error {
 	if !exist {
 		return c.JSON(http.StatusBadRequest, &ExportDKShareResponse{Err: "dkshare not found"})
 	}
-	blob, err := exportDKShare(dkshare)
+	blob, err := exportDKShare(dkshare, req.Passphrase)
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, &ExportDKShareResponse{Err: err.Error()})
 	}
 	return c.JSON(http.StatusOK, &ExportDKShareResponse{DKShare: blob})
 }
 
-func exportDKShare(dkshare *tcrypto.DKShare) (string, error) {
+func exportDKShare(dkshare *tcrypto.DKShare, passphrase string) (string, error) {
 	var buf bytes.Buffer
 	err := dkshare.Write(&buf)
 	if err != nil {
 		return "", err
 	}
-	return base58.Encode(buf.Bytes()), nil
+	if passphrase == "" {
+		return base58.Encode(buf.Bytes()), nil
+	}
+	data, err := tcrypto.SealWithPassphrase(passphrase, buf.Bytes())
+	if err != nil {
+		return "", err
+	}
+	return base58.Encode(append([]byte{encryptedBlobMarker}, data...)), nil
 }
 
 func HandlerImportDKShare(c echo.Context) error {
@@ -67,18 +79,26 @@ func HandlerImportDKShare(c echo.Context) error {
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, &ImportDKShareResponse{Err: err.Error()})
 	}
-	err := importDKShare(req.Blob)
+	err := importDKShare(req.Blob, req.Passphrase)
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, &ImportDKShareResponse{Err: err.Error()})
 	}
 	return c.JSON(http.StatusOK, &ImportDKShareResponse{})
 }
 
-func importDKShare(blob string) error {
+func importDKShare(blob string, passphrase string) error {
 	data, err := base58.Decode(blob)
 	if err != nil {
 		return err
 	}
+	if len(data) > 0 && data[0] == encryptedBlobMarker {
+		if passphrase == "" {
+			return fmt.Errorf("DKShare blob is encrypted: passphrase is required")
+		}
+		if data, err = tcrypto.OpenWithPassphrase(passphrase, data[1:]); err != nil {
+			return err
+		}
+	}
 	dks, err := tcrypto.UnmarshalDKShare(data, false)
 	if err != nil {
 		return err
@@ -89,11 +109,11 @@ func importDKShare(blob string) error {
 		return err
 	}
 	if exists {
-		oldBlob, err := exportDKShare(oldDks)
+		oldBlob, err := exportDKShare(oldDks, "")
 		if err != nil {
 			return err
 		}
-		if oldBlob != blob {
+		if oldBlob != base58.Encode(data) {
 			return fmt.Errorf("A different DKShare exists with same address %s", dks.Address)
 		}
 		log.Debugf("DKShare with address %s already imported", dks.Address)
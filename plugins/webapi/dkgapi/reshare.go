@@ -0,0 +1,159 @@
+package dkgapi
+
+import (
+	"time"
+
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	"github.com/iotaledger/wasp/packages/hashing"
+	"github.com/iotaledger/wasp/packages/registry"
+	"github.com/iotaledger/wasp/packages/tcrypto"
+	"github.com/iotaledger/wasp/plugins/dkg"
+	"github.com/iotaledger/wasp/plugins/peering"
+	"github.com/iotaledger/wasp/plugins/webapi/misc"
+	"github.com/labstack/echo"
+)
+
+//----------------------------------------------------------
+// Resharing of the existing key set to the new committee. The master public key and the address
+// of the key set remain the same. The POST handler implements 'adm/runreshare' API
+// Parameters (see RunReshareRequest struct):
+//     tmpId:              id of the resharing session. Must be the same for all nodes
+//     address:            address of the key set
+//     old_peer_locations: peering network locations of at least T nodes of the old committee
+//     new_peer_locations: peering network locations of nodes of the new committee. The position of the node
+//                         in the list is its index in the new key set
+//     t:                  quorum of the new committee
+//     timeout_msec:       timeout of the session. Default is 30 seconds
+//
+// The API must be called to all old and new nodes in parallel: the call returns only after the session is finished.
+// Old nodes deal shares of own private keys directly to new nodes over peering connections (see dkg.ReshareSession),
+// private shares are never sent to the caller.
+// The new node saves the reshared key share next to the key share in use and returns the hash of public shares
+// of the new key set. The caller must check if all new nodes returned the same hash.
+// The node switches to the reshared key share when the committee is activated with the new committee nodes.
+// Old key shares remain valid until old nodes which are not in the new committee erase them
+// with 'adm/eraseretireddkshare'
+
+func HandlerRunReshare(c echo.Context) error {
+	var req RunReshareRequest
+
+	if err := c.Bind(&req); err != nil {
+		return misc.OkJson(c, &RunReshareResponse{
+			Err: err.Error(),
+		})
+	}
+	return misc.OkJson(c, RunReshareReq(&req))
+}
+
+type RunReshareRequest struct {
+	TmpId            int      `json:"tmpId"`
+	Address          string   `json:"address"` //base58
+	OldPeerLocations []string `json:"old_peer_locations"`
+	NewPeerLocations []string `json:"new_peer_locations"`
+	T                uint16   `json:"t"`
+	TimeoutMsec      int      `json:"timeout_msec"`
+}
+
+type RunReshareResponse struct {
+	PubKeysHash string `json:"pub_keys_hash"` // empty if the node is not in the new committee
+	Err         string `json:"err"`
+}
+
+func RunReshareReq(req *RunReshareRequest) *RunReshareResponse {
+	addr, err := address.FromBase58(req.Address)
+	if err != nil {
+		return &RunReshareResponse{Err: err.Error()}
+	}
+	timeout := defaultDKGTimeout
+	if req.TimeoutMsec > 0 {
+		timeout = time.Duration(req.TimeoutMsec) * time.Millisecond
+	}
+	oldKS, ok, err := registry.GetDKShare(&addr)
+	if err != nil {
+		return &RunReshareResponse{Err: err.Error()}
+	}
+	if !ok {
+		oldKS = nil
+	}
+	ks, err := dkg.RunReshare(uint64(req.TmpId), &addr, oldKS, req.OldPeerLocations, req.NewPeerLocations, req.T, timeout)
+	if err != nil {
+		return &RunReshareResponse{Err: err.Error()}
+	}
+	if ks == nil {
+		log.Infow("Dealt own key share to the new committee", "address", addr.String())
+		return &RunReshareResponse{}
+	}
+	if err := registry.SaveResharedDKShare(ks); err != nil {
+		return &RunReshareResponse{Err: err.Error()}
+	}
+	h, err := pubKeysHash(ks)
+	if err != nil {
+		return &RunReshareResponse{Err: err.Error()}
+	}
+	log.Infow("Reshared key share peer-to-peer",
+		"address", ks.Address.String(),
+		"N", ks.N,
+		"T", ks.T,
+		"Index", ks.Index,
+	)
+	return &RunReshareResponse{
+		PubKeysHash: h.String(),
+	}
+}
+
+//----------------------------------------------------------
+// The POST handler implements 'adm/eraseretireddkshare' API
+// Parameters (see EraseRetiredDKShareRequest struct):
+//     address:            address of the reshared key set
+//     new_peer_locations: peering network locations of nodes of the new committee
+//
+// Must be called to old nodes which are not in the new committee after new nodes have switched to the
+// reshared key set. The node erases its key share of the address, so that old holders can't sign for it anymore.
+// The node in the new committee refuses the call
+
+func HandlerEraseRetiredDKShare(c echo.Context) error {
+	var req EraseRetiredDKShareRequest
+
+	if err := c.Bind(&req); err != nil {
+		return misc.OkJson(c, &EraseRetiredDKShareResponse{
+			Err: err.Error(),
+		})
+	}
+	return misc.OkJson(c, EraseRetiredDKShareReq(&req))
+}
+
+type EraseRetiredDKShareRequest struct {
+	Address          string   `json:"address"` //base58
+	NewPeerLocations []string `json:"new_peer_locations"`
+}
+
+type EraseRetiredDKShareResponse struct {
+	Erased bool   `json:"erased"` // false if the node had no key share of the address
+	Err    string `json:"err"`
+}
+
+func EraseRetiredDKShareReq(req *EraseRetiredDKShareRequest) *EraseRetiredDKShareResponse {
+	addr, err := address.FromBase58(req.Address)
+	if err != nil {
+		return &EraseRetiredDKShareResponse{Err: err.Error()}
+	}
+	erased, err := registry.EraseRetiredDKShare(&addr, req.NewPeerLocations, peering.MyNetworkId())
+	if err != nil {
+		return &EraseRetiredDKShareResponse{Err: err.Error()}
+	}
+	if erased {
+		log.Infow("Erased the key share retired by resharing", "address", addr.String())
+	}
+	return &EraseRetiredDKShareResponse{Erased: erased}
+}
+
+func pubKeysHash(ks *tcrypto.DKShare) (*hashing.HashValue, error) {
+	data := make([][]byte, len(ks.PubKeys))
+	for i, pk := range ks.PubKeys {
+		var err error
+		if data[i], err = pk.MarshalBinary(); err != nil {
+			return nil, err
+		}
+	}
+	return hashing.HashData(data...), nil
+}
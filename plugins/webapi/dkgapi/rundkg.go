@@ -0,0 +1,73 @@
+package dkgapi
+
+import (
+	"time"
+
+	"github.com/iotaledger/wasp/packages/registry"
+	"github.com/iotaledger/wasp/plugins/dkg"
+	"github.com/iotaledger/wasp/plugins/webapi/misc"
+	"github.com/labstack/echo"
+)
+
+//----------------------------------------------------------
+// The POST handler implements 'adm/rundkg' API
+// Parameters (see RunDKGRequest struct):
+//     tmpId:          id of the DKG session. Must be the same for all nodes
+//     peer_locations: peering network locations of all nodes of the committee. The position of the node
+//                     in the list is its index in the key set
+//     t:              required quorum
+//     timeout_msec:   timeout of the session. Default is 30 seconds
+//
+// The API must be called to all nodes in parallel: the call returns only after the session is finished.
+// Nodes run the distributed key generation over peering connections (see package 'dkg').
+// Private shares are never sent to the caller.
+// The node saves the key share and returns the address of the key set.
+// The caller must check if all nodes returned the same address
+
+const defaultDKGTimeout = 30 * time.Second
+
+func HandlerRunDKG(c echo.Context) error {
+	var req RunDKGRequest
+
+	if err := c.Bind(&req); err != nil {
+		return misc.OkJson(c, &RunDKGResponse{
+			Err: err.Error(),
+		})
+	}
+	return misc.OkJson(c, RunDKGReq(&req))
+}
+
+type RunDKGRequest struct {
+	TmpId         int      `json:"tmpId"`
+	PeerLocations []string `json:"peer_locations"`
+	T             uint16   `json:"t"`
+	TimeoutMsec   int      `json:"timeout_msec"`
+}
+
+type RunDKGResponse struct {
+	Address string `json:"address"` //base58
+	Err     string `json:"err"`
+}
+
+func RunDKGReq(req *RunDKGRequest) *RunDKGResponse {
+	timeout := defaultDKGTimeout
+	if req.TimeoutMsec > 0 {
+		timeout = time.Duration(req.TimeoutMsec) * time.Millisecond
+	}
+	ks, err := dkg.RunDKG(uint64(req.TmpId), req.PeerLocations, req.T, timeout)
+	if err != nil {
+		return &RunDKGResponse{Err: err.Error()}
+	}
+	if err := registry.SaveDKShareToRegistry(ks); err != nil {
+		return &RunDKGResponse{Err: err.Error()}
+	}
+	log.Infow("Generated new key share peer-to-peer",
+		"address", ks.Address.String(),
+		"N", ks.N,
+		"T", ks.T,
+		"Index", ks.Index,
+	)
+	return &RunDKGResponse{
+		Address: ks.Address.String(),
+	}
+}
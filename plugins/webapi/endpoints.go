@@ -12,9 +12,9 @@ import (
 func addEndpoints() {
 	Server.GET("/", IndexRequest)
 	// dkgapi
-	Server.POST("/adm/newdks", dkgapi.HandlerNewDks)
-	Server.POST("/adm/aggregatedks", dkgapi.HandlerAggregateDks)
-	Server.POST("/adm/commitdks", dkgapi.HandlerCommitDks)
+	Server.POST("/adm/rundkg", dkgapi.HandlerRunDKG)
+	Server.POST("/adm/runreshare", dkgapi.HandlerRunReshare)
+	Server.POST("/adm/eraseretireddkshare", dkgapi.HandlerEraseRetiredDKShare)
 	Server.POST("/adm/signdigest", dkgapi.HandlerSignDigest)
 	Server.POST("/adm/getpubkeyinfo", dkgapi.HandlerGetKeyPubInfo)
 	Server.POST("/adm/exportdkshare", dkgapi.HandlerExportDKShare)
@@ -26,8 +26,15 @@ func addEndpoints() {
 	Server.GET("/adm/shutdown", admapi.HandlerShutdown)
 	Server.POST("/adm/activatesc", admapi.HandlerActivateSC)
 	Server.GET("/adm/dumpscstate/:scaddress", admapi.HandlerDumpSCState)
+	Server.GET("/adm/peerfaults/:scaddress", admapi.HandlerPeerFaults)
 	Server.POST("/adm/putprogrammetadata", admapi.HandlerPutProgramMetaData)
 	Server.POST("/adm/getprogrammetadata", admapi.HandlerGetProgramMetadata)
+	Server.POST("/adm/backup", admapi.HandlerBackup)
+	Server.GET("/adm/getpeeringidentity", admapi.HandlerGetPeeringIdentity)
+	Server.POST("/adm/puttrustedpeers", admapi.HandlerPutTrustedPeers)
+	Server.GET("/adm/gettrustedpeers", admapi.HandlerGetTrustedPeers)
+	Server.GET("/adm/peers", admapi.HandlerGetPeers)
+	Server.GET("/adm/txmetrics", admapi.HandlerGetTxMetrics)
 	// redirect to goshimmer
 	Server.GET("/utxodb/outputs/:address", redirect.HandleRedirectGetAddressOutputs)
 	Server.POST("/utxodb/tx", redirect.HandleRedirectPostTransaction)
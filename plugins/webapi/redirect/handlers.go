@@ -16,15 +16,19 @@ func HandleRedirectGetAddressOutputs(c echo.Context) error {
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, &misc.SimpleResponse{Error: err.Error()})
 	}
+	if ledger := nodeconn.GetUtxoDBLedger(); ledger != nil {
+		return GetAddressOutputsFromLedger(c, ledger, addr)
+	}
 	nodeLocation := config.Node.GetString(nodeconn.CfgNodeAPIBind)
 	url := fmt.Sprintf("http://%s/utxodb/outputs/%s", nodeLocation, addr.String())
-	fmt.Printf("+++++++++++++++++ HandleRedirectGetAddressOutputs: %s\n", url)
 	return c.Redirect(http.StatusOK, url)
 }
 
 func HandleRedirectPostTransaction(c echo.Context) error {
+	if ledger := nodeconn.GetUtxoDBLedger(); ledger != nil {
+		return PostTransactionToLedger(c, ledger)
+	}
 	nodeLocation := config.Node.GetString(nodeconn.CfgNodeAPIBind)
 	url := fmt.Sprintf("http://%s/utxodb/tx", nodeLocation)
-	fmt.Printf("+++++++++++++++++ HandleRedirectPostTransaction: %s\n", url)
 	return c.Redirect(http.StatusOK, url)
 }
@@ -0,0 +1,53 @@
+package redirect
+
+import (
+	"net/http"
+
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	valuetransaction "github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/transaction"
+	"github.com/iotaledger/goshimmer/dapps/waspconn/packages/apilib"
+	"github.com/iotaledger/wasp/plugins/nodeconn"
+	"github.com/labstack/echo"
+	"github.com/mr-tron/base58"
+)
+
+// the same responses as Goshimmer gives, but from the embedded ledger of the node
+
+// GetAddressOutputsFromLedger responds to GET /utxodb/outputs/:address with outputs of the address in the ledger
+func GetAddressOutputsFromLedger(c echo.Context, ledger *nodeconn.UtxoDBLedger, addr address.Address) error {
+	out := make(map[string][]apilib.OutputBalance)
+	for outId, bals := range ledger.GetAddressOutputs(addr) {
+		outBals := make([]apilib.OutputBalance, len(bals))
+		for i, b := range bals {
+			outBals[i] = apilib.OutputBalance{
+				Value: b.Value,
+				Color: valuetransaction.ID(b.Color).String(),
+			}
+		}
+		out[outId.String()] = outBals
+	}
+	return c.JSON(http.StatusOK, &apilib.GetAccountOutputsResponse{
+		Address: addr.String(),
+		Outputs: out,
+	})
+}
+
+// PostTransactionToLedger responds to POST /utxodb/tx. The transaction is posted to the ledger
+func PostTransactionToLedger(c echo.Context, ledger *nodeconn.UtxoDBLedger) error {
+	var req apilib.PostTransactionRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, &apilib.PostTransactionResponse{Err: err.Error()})
+	}
+	txBytes, err := base58.Decode(req.Tx)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &apilib.PostTransactionResponse{Err: err.Error()})
+	}
+	tx, _, err := valuetransaction.FromBytes(txBytes)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &apilib.PostTransactionResponse{Err: err.Error()})
+	}
+	if err = ledger.PostTransaction(tx); err != nil {
+		return c.JSON(http.StatusConflict, &apilib.PostTransactionResponse{Err: err.Error()})
+	}
+	return c.JSON(http.StatusOK, &apilib.PostTransactionResponse{})
+}
@@ -3,6 +3,7 @@ package stateapi
 
 import (
 	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	"github.com/iotaledger/wasp/packages/registry"
 	"github.com/iotaledger/wasp/packages/state"
 	"github.com/iotaledger/wasp/packages/table"
 	"github.com/iotaledger/wasp/plugins/webapi/misc"
@@ -34,8 +35,14 @@ func HandlerQueryState(c echo.Context) error {
 			Error: err.Error(),
 		})
 	}
+	stateAddr, err := registry.GetStateAddress(&addr)
+	if err != nil {
+		return misc.OkJson(c, &QueryStateResponse{
+			Error: err.Error(),
+		})
+	}
 	// TODO serialize access to solid state
-	state, _, exist, err := state.LoadSolidState(&addr)
+	state, _, exist, err := state.LoadSolidState(stateAddr)
 	if err != nil {
 		return misc.OkJson(c, &QueryStateResponse{
 			Error: err.Error(),
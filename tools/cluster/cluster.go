@@ -27,6 +27,9 @@ import (
 	waspapi "github.com/iotaledger/wasp/packages/apilib"
 )
 
+// LedgerUtxoDB is the ledger of the cluster run by the utxodbnode tool instead of Goshimmer
+const LedgerUtxoDB = "utxodb"
+
 type SmartContractFinalConfig struct {
 	Address          string   `json:"address"`
 	Color            string   `json:"color"`
@@ -50,14 +53,22 @@ type WaspNodeConfig struct {
 	ApiPort     int    `json:"api_port"`
 	PeeringPort int    `json:"peering_port"`
 	NanomsgPort int    `json:"nanomsg_port"`
+	// comma separated byzantine behaviors of the node in all its committees, passed in WASP_BYZANTINE.
+	// The wasp binary must be built with the 'byzantine' tag
+	Byzantine string `json:"byzantine,omitempty"`
 }
 
 type ClusterConfig struct {
 	Nodes     []WaspNodeConfig `json:"nodes"`
 	Goshimmer struct {
 		BindAddress string `json:"bind_address"`
+		// "utxodb" starts the utxodbnode tool with the embedded ledger instead of the Goshimmer node.
+		// It reads the same config
+		Ledger string `json:"ledger,omitempty"`
 	} `json:"goshimmer"`
 	SmartContracts []SmartContractInitData `json:"smart_contracts"`
+	// if not empty, DKShares in keys.json are encrypted with the passphrase
+	DKSharePassphrase string `json:"dkshare_passphrase"`
 }
 
 type Cluster struct {
@@ -255,6 +266,13 @@ func (cluster *Cluster) Start() error {
 		return err
 	}
 
+	// identities are generated by nodes at first start, the allow-lists are set up each time
+	fmt.Printf("[cluster] exchanging peering identities of Wasp nodes...\n")
+	err = waspapi.TrustEachOther(cluster.ApiHosts())
+	if err != nil {
+		return err
+	}
+
 	keysExist, err := cluster.readKeysAndData()
 	if err != nil {
 		return err
@@ -277,7 +295,11 @@ func (cluster *Cluster) start() error {
 
 	initOk := make(chan bool, len(cluster.Config.Nodes))
 
-	err := cluster.startServer("goshimmer", cluster.GoshimmerDataPath(), "goshimmer", initOk, "WebAPI started")
+	command := "goshimmer"
+	if cluster.Config.Goshimmer.Ledger == LedgerUtxoDB {
+		command = "utxodbnode"
+	}
+	err := cluster.startServer(command, cluster.GoshimmerDataPath(), "goshimmer", nil, initOk, "WebAPI started")
 	if err != nil {
 		return err
 	}
@@ -289,8 +311,14 @@ func (cluster *Cluster) start() error {
 	}
 	fmt.Printf("[cluster] started goshimmer node\n")
 
-	for i, _ := range cluster.Config.Nodes {
-		err = cluster.startServer("wasp", cluster.WaspNodeDataPath(i), fmt.Sprintf("wasp %d", i), initOk, "nanomsg publisher is running")
+	for i, node := range cluster.Config.Nodes {
+		var env []string
+		if node.Byzantine != "" {
+			env = []string{"WASP_BYZANTINE=" + node.Byzantine}
+		}
+		// the web API is used right after the start to exchange peering identities
+		err = cluster.startServer("wasp", cluster.WaspNodeDataPath(i), fmt.Sprintf("wasp %d", i), env, initOk,
+			"nanomsg publisher is running", "WebAPI started")
 		if err != nil {
 			return err
 		}
@@ -307,9 +335,12 @@ func (cluster *Cluster) start() error {
 	return nil
 }
 
-func (cluster *Cluster) startServer(command string, cwd string, name string, initOk chan<- bool, initOkMsg string) error {
+func (cluster *Cluster) startServer(command string, cwd string, name string, env []string, initOk chan<- bool, initOkMsgs ...string) error {
 	cmd := exec.Command(command)
 	cmd.Dir = cwd
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
 	stdoutPipe, err := cmd.StdoutPipe()
 	if err != nil {
 		return err
@@ -331,7 +362,7 @@ func (cluster *Cluster) startServer(command string, cwd string, name string, ini
 	go scanLog(
 		stdoutPipe,
 		func(line string) { fmt.Printf("[ %s] %s\n", name, line) },
-		waitFor(initOkMsg, initOk),
+		waitFor(initOk, initOkMsgs...),
 	)
 
 	return nil
@@ -347,15 +378,23 @@ func scanLog(reader io.Reader, hooks ...func(string)) {
 	}
 }
 
-func waitFor(msg string, initOk chan<- bool) func(line string) {
-	found := false
+// waitFor signals initOk when all messages are found in the log, in any order
+func waitFor(initOk chan<- bool, msgs ...string) func(line string) {
+	pending := make(map[string]bool)
+	for _, msg := range msgs {
+		pending[msg] = true
+	}
 	return func(line string) {
-		if found {
+		if len(pending) == 0 {
 			return
 		}
-		if strings.Contains(line, msg) {
+		for msg := range pending {
+			if strings.Contains(line, msg) {
+				delete(pending, msg)
+			}
+		}
+		if len(pending) == 0 {
 			initOk <- true
-			found = true
 		}
 	}
 }
@@ -379,7 +418,7 @@ func (cluster *Cluster) importKeys() error {
 		fmt.Printf("[cluster] Importing DKShares for address %s...\n", scKeys.Address)
 		for nodeIndex, dks := range scKeys.DKShares {
 			url := fmt.Sprintf("%s:%d", cluster.Config.Nodes[nodeIndex].NetAddress, cluster.Config.Nodes[nodeIndex].ApiPort)
-			err := waspapi.ImportDKShare(url, dks)
+			err := waspapi.ImportDKShare(url, dks, cluster.Config.DKSharePassphrase)
 			if err != nil {
 				return err
 			}
@@ -562,3 +601,46 @@ func (cluster *Cluster) VerifySCState(sc *SmartContractFinalConfig, expectedInde
 	}
 	return pass
 }
+
+// WaitCommitteePeersAlive polls the peering status of the committee nodes until each of them sees
+// all other committee nodes alive. Until then a node takes itself for the leader
+func (cluster *Cluster) WaitCommitteePeersAlive(sc *SmartContractFinalConfig, timeout time.Duration) error {
+	peeringHosts := cluster.WaspHosts(sc.CommitteeNodes, (*WaspNodeConfig).PeeringHost)
+	deadline := time.Now().Add(timeout)
+	for _, host := range cluster.WaspHosts(sc.CommitteeNodes, (*WaspNodeConfig).ApiHost) {
+		for {
+			notAlive, err := notAlivePeers(host, peeringHosts)
+			if err != nil {
+				return err
+			}
+			if len(notAlive) == 0 {
+				break
+			}
+			if time.Now().After(deadline) {
+				return fmt.Errorf("%s: peers not alive after %v: %v", host, timeout, notAlive)
+			}
+			time.Sleep(200 * time.Millisecond)
+		}
+	}
+	fmt.Printf("[cluster] committee peers of %s are alive\n", sc.Address)
+	return nil
+}
+
+// notAlivePeers returns peering hosts which the node doesn't see alive. The node itself is skipped
+func notAlivePeers(apiHost string, peeringHosts []string) ([]string, error) {
+	resp, err := waspapi.GetPeers(apiHost)
+	if err != nil {
+		return nil, fmt.Errorf("GetPeers(%s): %v", apiHost, err)
+	}
+	alive := make(map[string]bool)
+	for _, p := range resp.Peers {
+		alive[p.NetId] = p.IsAlive
+	}
+	ret := make([]string, 0)
+	for _, h := range peeringHosts {
+		if h != resp.NetId && !alive[h] {
+			ret = append(ret, h)
+		}
+	}
+	return ret, nil
+}
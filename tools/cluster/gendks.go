@@ -27,10 +27,9 @@ func (cluster *Cluster) GenerateDKSets() error {
 	keys := make([]SmartContractFinalConfig, 0)
 
 	for i, sc := range cluster.Config.SmartContracts {
-		committee := cluster.WaspHosts(sc.CommitteeNodes, (*WaspNodeConfig).ApiHost)
-		addr, err := waspapi.GenerateNewDistributedKeySet(
-			committee,
-			uint16(len(committee)),
+		addr, err := waspapi.RunDistributedKeyGeneration(
+			cluster.WaspHosts(sc.CommitteeNodes, (*WaspNodeConfig).ApiHost),
+			cluster.WaspHosts(sc.CommitteeNodes, (*WaspNodeConfig).PeeringHost),
 			uint16(sc.Quorum),
 		)
 		if err != nil {
@@ -41,7 +40,7 @@ func (cluster *Cluster) GenerateDKSets() error {
 
 		dkShares := make([]string, 0)
 		for _, host := range cluster.ApiHosts() {
-			dks, err := waspapi.ExportDKShare(host, addr)
+			dks, err := waspapi.ExportDKShare(host, addr, cluster.Config.DKSharePassphrase)
 			if err != nil {
 				return err
 			}
@@ -0,0 +1,71 @@
+package wasptest
+
+import (
+	"fmt"
+
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	"github.com/iotaledger/goshimmer/dapps/waspconn/packages/utxodb"
+	waspapi "github.com/iotaledger/wasp/packages/apilib"
+	"github.com/iotaledger/wasp/packages/registry"
+	"github.com/iotaledger/wasp/packages/util"
+	"github.com/iotaledger/wasp/packages/vm/vmconst"
+	"github.com/iotaledger/wasp/tools/cluster"
+)
+
+// RotateCommittee generates the key set of the new committee, creates and activates its bootup records
+// and sends the request to rotate the smart contract. Returns the configuration of the smart contract
+// with the new address
+func RotateCommittee(clu *cluster.Cluster, sc *cluster.SmartContractFinalConfig, committeeNodes []int, quorum uint16) (*cluster.SmartContractFinalConfig, error) {
+	fmt.Printf("[cluster] rotating smart contract %s to nodes %v\n", sc.Address, committeeNodes)
+
+	origAddr, err := address.FromBase58(sc.Address)
+	if err != nil {
+		return nil, err
+	}
+	color, err := util.ColorFromString(sc.Color)
+	if err != nil {
+		return nil, err
+	}
+	originAddr := origAddr
+	if bd, exists, err := waspapi.GetSCData(clu.WaspHosts(sc.CommitteeNodes, (*cluster.WaspNodeConfig).ApiHost)[0], &origAddr); err == nil && exists {
+		originAddr = *bd.StateAddress()
+	}
+
+	apiHosts := clu.WaspHosts(committeeNodes, (*cluster.WaspNodeConfig).ApiHost)
+	peeringHosts := clu.WaspHosts(committeeNodes, (*cluster.WaspNodeConfig).PeeringHost)
+	newAddr, err := waspapi.RunDistributedKeyGeneration(apiHosts, peeringHosts, quorum)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("[cluster] new committee address: %s\n", newAddr.String())
+
+	ret := *sc
+	ret.Address = newAddr.String()
+	ret.CommitteeNodes = committeeNodes
+	ret.AccessNodes = nil
+	ret.DKShares = nil
+
+	for _, host := range apiHosts {
+		err = waspapi.PutSCData(host, registry.BootupData{
+			Address:        *newAddr,
+			Color:          color,
+			OwnerAddress:   utxodb.GetAddress(sc.OwnerIndexUtxodb),
+			CommitteeNodes: peeringHosts,
+			OriginAddress:  originAddr,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("apilib.PutSCData returned for host %s: %v", host, err)
+		}
+	}
+	if err = Activate1SC(clu, &ret); err != nil {
+		return nil, err
+	}
+
+	err = SendRequestNTimes(clu, sc, 1, vmconst.RequestCodeRotateCommittee, map[string]string{
+		vmconst.VarNameCommitteeAddress: newAddr.String(),
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &ret, nil
+}
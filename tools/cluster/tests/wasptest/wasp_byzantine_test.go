@@ -0,0 +1,69 @@
+package wasptest
+
+import (
+	"testing"
+	"time"
+
+	waspapi "github.com/iotaledger/wasp/packages/apilib"
+	"github.com/iotaledger/wasp/packages/vm/vmconst"
+	"github.com/iotaledger/wasp/tools/cluster"
+	"github.com/stretchr/testify/assert"
+)
+
+// node #3 of byzantine_cluster sends bad signature shares and equivocates when it is the leader.
+// Wasp must be built with the 'byzantine' tag: go install -tags byzantine
+func TestByzantineMember(t *testing.T) {
+	const byzantine = 3
+	clu := setup(t, "byzantine_cluster", "TestByzantineMember")
+
+	err := clu.ListenToMessages(map[string]int{
+		"bootuprec":           -1,
+		"active_committee":    -1,
+		"dismissed_committee": 0,
+		"request_in":          -1,
+		"request_out":         -1,
+		"state":               -1,
+	})
+	check(err, t)
+
+	sc := &clu.SmartContractConfig[0]
+	err = putScData(sc, clu)
+	check(err, t)
+	err = Activate1SC(clu, sc)
+	check(err, t)
+	// peers connect before the origin: a node which sees other peers dead takes itself as the leader of the state
+	err = clu.WaitCommitteePeersAlive(sc, 20*time.Second)
+	check(err, t)
+	err = CreateOrigin1SC(clu, sc)
+	check(err, t)
+
+	err = SendRequestNTimes(clu, sc, 3, vmconst.RequestCodeNOP, nil, 2*time.Second)
+	check(err, t)
+	clu.CollectMessages(30 * time.Second)
+
+	if !clu.Report() {
+		t.Fail()
+	}
+
+	honest := make([]int, 0, len(sc.CommitteeNodes)-1)
+	for _, i := range sc.CommitteeNodes {
+		if i != byzantine {
+			honest = append(honest, i)
+		}
+	}
+	var numFaults uint32
+	for _, host := range clu.WaspHosts(honest, (*cluster.WaspNodeConfig).ApiHost) {
+		// the honest quorum settles states of the requests after origin and init
+		state, err := waspapi.DumpSCState(host, sc.Address)
+		check(err, t)
+		assert.True(t, state.Exists)
+		assert.True(t, state.Index >= 2, "%s: state index %d", host, state.Index)
+
+		faults, err := waspapi.GetPeerFaults(host, sc.Address)
+		check(err, t)
+		numFaults += faults.Faults[byzantine]
+	}
+	// bad signature shares of the byzantine node are rejected and counted as its faults.
+	// Signature shares are sent to the leader only, so not every honest node sees them
+	assert.True(t, numFaults > 0, "faults of the byzantine node are not counted")
+}
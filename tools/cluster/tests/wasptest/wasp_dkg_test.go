@@ -0,0 +1,31 @@
+package wasptest
+
+import (
+	"testing"
+
+	waspapi "github.com/iotaledger/wasp/packages/apilib"
+	"github.com/iotaledger/wasp/tools/cluster"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunDKG(t *testing.T) {
+	// setup
+	wasps := setup(t, "test_cluster", "TestRunDKG")
+
+	// exercise
+	nodes := wasps.AllWaspNodes()
+	addr, err := waspapi.RunDistributedKeyGeneration(
+		wasps.WaspHosts(nodes, (*cluster.WaspNodeConfig).ApiHost),
+		wasps.WaspHosts(nodes, (*cluster.WaspNodeConfig).PeeringHost),
+		3,
+	)
+	check(err, t)
+
+	// verify
+	for i, resp := range waspapi.GetPublicKeyInfo(wasps.ApiHosts(), addr) {
+		assert.Equal(t, "", resp.Err)
+		assert.Equal(t, addr.String(), resp.Address)
+		assert.EqualValues(t, i, resp.Index)
+		assert.EqualValues(t, 3, resp.T)
+	}
+}
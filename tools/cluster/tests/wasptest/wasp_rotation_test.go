@@ -0,0 +1,74 @@
+package wasptest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	waspapi "github.com/iotaledger/wasp/packages/apilib"
+	"github.com/iotaledger/wasp/packages/table"
+	"github.com/iotaledger/wasp/packages/vm/vmconst"
+	"github.com/iotaledger/wasp/tools/cluster"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotateCommittee4To5(t *testing.T) {
+	clu := setup(t, "rotation_cluster", "TestRotateCommittee4To5")
+
+	// nodes of the old committee see two bootup records, the new node only one
+	err := clu.ListenToMessages(map[string]int{
+		"bootuprec":           -1,
+		"active_committee":    -1,
+		"rotated_committee":   -1,
+		"dismissed_committee": -1,
+		"request_in":          -1,
+		"request_out":         -1,
+		"state":               -1,
+	})
+	check(err, t)
+
+	sc := &clu.SmartContractConfig[0]
+	err = putScData(sc, clu)
+	check(err, t)
+	err = Activate1SC(clu, sc)
+	check(err, t)
+	err = CreateOrigin1SC(clu, sc)
+	check(err, t)
+	// the init request is processed before the NOP, so each request makes its own state
+	clu.CollectMessages(10 * time.Second)
+
+	err = SendRequestNTimes(clu, sc, 1, vmconst.RequestCodeNOP, nil, 0)
+	check(err, t)
+	clu.CollectMessages(10 * time.Second)
+
+	newSC, err := RotateCommittee(clu, sc, []int{0, 1, 2, 3, 4}, 4)
+	check(err, t)
+	clu.CollectMessages(20 * time.Second)
+
+	err = SendRequestNTimes(clu, newSC, 1, vmconst.RequestCodeNOP, nil, 0)
+	check(err, t)
+	clu.CollectMessages(20 * time.Second)
+
+	if !clu.Report() {
+		t.Fail()
+	}
+
+	// the old committee is dismissed and its bootup records are marked as rotated
+	oldAddr, err := address.FromBase58(sc.Address)
+	check(err, t)
+	newAddr, err := address.FromBase58(newSC.Address)
+	check(err, t)
+	for _, host := range clu.WaspHosts(sc.CommitteeNodes, (*cluster.WaspNodeConfig).ApiHost) {
+		bd, exists, err := waspapi.GetSCData(host, &oldAddr)
+		check(err, t)
+		assert.True(t, exists)
+		assert.Equal(t, newAddr, bd.RotatedTo)
+	}
+
+	// all 5 nodes of the new committee have the state: origin, init, NOP, rotation, NOP
+	if !clu.VerifySCState(newSC, 4, map[table.Key][]byte{
+		vmconst.VarNameCommitteeAddress: newAddr[:],
+	}) {
+		t.Fail()
+	}
+}
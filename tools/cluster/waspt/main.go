@@ -5,7 +5,10 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"text/tabwriter"
+	"time"
 
+	waspapi "github.com/iotaledger/wasp/packages/apilib"
 	"github.com/iotaledger/wasp/tools/cluster"
 )
 
@@ -26,7 +29,7 @@ func main() {
 	check(err)
 
 	if globalFlags.NArg() < 1 {
-		fmt.Printf("Usage: %s [options] [init|start|gendksets]\n", os.Args[0])
+		fmt.Printf("Usage: %s [options] [init|start|gendksets|peers]\n", os.Args[0])
 		globalFlags.PrintDefaults()
 		return
 	}
@@ -59,6 +62,50 @@ func main() {
 		err = wasps.GenerateDKSets()
 		check(err)
 		wasps.Stop()
+
+	case "peers":
+		printPeers(wasps.ApiHosts())
+	}
+}
+
+// printPeers prints connections of each node of the running cluster with its peers
+func printPeers(hosts []string) {
+	for _, host := range hosts {
+		resp, err := waspapi.GetPeers(host)
+		if err != nil {
+			fmt.Printf("%s: %v\n\n", host, err)
+			continue
+		}
+		fmt.Printf("%s (%s):\n", host, resp.NetId)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "  peer\tdirection\tstate\tlast heartbeat\tround trip\tclock offset\tdropped\tcommittees\n")
+		for _, p := range resp.Peers {
+			direction := "out"
+			if p.IsInbound {
+				direction = "in"
+			}
+			state := "disconnected"
+			switch {
+			case p.IsAlive:
+				state = "alive"
+			case p.IsHandshaken:
+				state = "handshaken"
+			case p.IsConnected:
+				state = "connected"
+			}
+			lastHeartbeat, roundTrip, offset := "-", "-", "-"
+			if p.LastHeartbeat != 0 {
+				lastHeartbeat = time.Since(time.Unix(0, p.LastHeartbeat)).Round(time.Millisecond).String() + " ago"
+			}
+			if p.HasClockEstimate {
+				roundTrip = time.Duration(p.AvgRoundTrip).String()
+				offset = time.Duration(p.ClockOffset).String()
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\t%s\t%d\t%d\n", p.NetId, direction, state, lastHeartbeat, roundTrip, offset,
+				p.Dropped.TooLarge+p.Dropped.RateLimited+p.Dropped.QueueFull, len(p.Committees))
+		}
+		_ = w.Flush()
+		fmt.Println()
 	}
 }
 
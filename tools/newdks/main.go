@@ -36,10 +36,20 @@ func main() {
 		panic("wrong assembly size parameters or number rof hosts")
 	}
 
+	// nodes run the key generation over peering connections, so they must trust each other
+	peeringHosts := make([]string, len(params.Hosts))
+	for i, host := range params.Hosts {
+		id, err := apilib.GetPeeringIdentity(host)
+		if err != nil {
+			panic(err)
+		}
+		peeringHosts[i] = id.NetId
+	}
+
 	params.Addresses = make([]string, 0, params.NumKeys)
 	numSuccess := 0
 	for i := 0; i < int(params.NumKeys); i++ {
-		addr, err := apilib.GenerateNewDistributedKeySet(params.Hosts, params.N, params.T)
+		addr, err := apilib.RunDistributedKeyGeneration(params.Hosts, peeringHosts, params.T)
 		if err == nil {
 			params.Addresses = append(params.Addresses, addr.String())
 			numSuccess++
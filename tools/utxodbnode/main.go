@@ -0,0 +1,117 @@
+// utxodbnode stands in for the Goshimmer node in test clusters. It keeps the utxodb ledger in memory
+// and serves the WaspConn protocol and the /utxodb endpoints of the web API from it, as the WaspConn plugin
+// of Goshimmer does. It reads the webapi.bindAddress, waspconn.port and utxodb.confirmationDelay (milliseconds)
+// keys of the Goshimmer config file, so the same config template serves both
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	"github.com/iotaledger/goshimmer/dapps/waspconn/packages/apilib"
+	"github.com/iotaledger/hive.go/logger"
+	"github.com/iotaledger/wasp/plugins/nodeconn"
+	"github.com/iotaledger/wasp/plugins/webapi/redirect"
+	"github.com/labstack/echo"
+)
+
+type config struct {
+	WebAPI struct {
+		BindAddress string `json:"bindAddress"`
+	} `json:"webapi"`
+	WaspConn struct {
+		Port int `json:"port"`
+	} `json:"waspconn"`
+	UtxoDB struct {
+		ConfirmationDelay int `json:"confirmationDelay"`
+	} `json:"utxodb"`
+}
+
+func check(err error) {
+	if err != nil {
+		fmt.Printf("utxodbnode error: %s. Exit...\n", err)
+		os.Exit(1)
+	}
+}
+
+func readConfig(fname string) (*config, error) {
+	data, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	ret := &config{}
+	if err = json.Unmarshal(data, ret); err != nil {
+		return nil, err
+	}
+	if ret.WebAPI.BindAddress == "" {
+		ret.WebAPI.BindAddress = "127.0.0.1:8080"
+	}
+	if ret.WaspConn.Port == 0 {
+		ret.WaspConn.Port = 5000
+	}
+	return ret, nil
+}
+
+func main() {
+	configFile := flag.String("config", "config.json", "Goshimmer config file")
+	flag.Parse()
+
+	cfg, err := readConfig(*configFile)
+	check(err)
+
+	log := logger.NewExampleLogger("utxodbnode")
+	ledger := nodeconn.NewUtxoDBLedger(time.Duration(cfg.UtxoDB.ConfirmationDelay)*time.Millisecond, log)
+
+	waspConnListener, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.WaspConn.Port))
+	check(err)
+	go func() {
+		_ = ledger.ServeWaspConn(waspConnListener)
+	}()
+	log.Infof("WaspConn started on port %d. Confirmation delay %d ms", cfg.WaspConn.Port, cfg.UtxoDB.ConfirmationDelay)
+
+	shutdown := make(chan struct{})
+	var shutdownOnce sync.Once
+	server := echo.New()
+	server.HideBanner = true
+	server.HidePort = true
+	server.GET("/utxodb/outputs/:address", func(c echo.Context) error {
+		addr, err := address.FromBase58(c.Param("address"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, &apilib.GetAccountOutputsResponse{Err: err.Error()})
+		}
+		return redirect.GetAddressOutputsFromLedger(c, ledger, addr)
+	})
+	server.POST("/utxodb/tx", func(c echo.Context) error {
+		return redirect.PostTransactionToLedger(c, ledger)
+	})
+	server.GET("/adm/shutdown", func(c echo.Context) error {
+		defer shutdownOnce.Do(func() { close(shutdown) })
+		return c.String(http.StatusOK, "Shutting down...")
+	})
+	server.Listener, err = net.Listen("tcp", cfg.WebAPI.BindAddress)
+	check(err)
+	go func() {
+		_ = server.Start("")
+	}()
+	log.Infof("WebAPI started on %s", cfg.WebAPI.BindAddress)
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	select {
+	case <-shutdown:
+	case <-interrupt:
+	}
+	log.Infof("shutting down")
+	_ = waspConnListener.Close()
+	_ = server.Close()
+}
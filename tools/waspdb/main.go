@@ -0,0 +1,125 @@
+// waspdb is an offline tool to back up and restore the database of the stopped Wasp node.
+// The running node locks the database, so the tool can't open it
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/iotaledger/goshimmer/dapps/valuetransfers/packages/address"
+	goshimmerdb "github.com/iotaledger/goshimmer/packages/database"
+	"github.com/iotaledger/wasp/plugins/database"
+)
+
+func check(err error) {
+	if err != nil {
+		fmt.Printf("waspdb error: %s. Exit...\n", err)
+		os.Exit(1)
+	}
+}
+
+func main() {
+	globalFlags := flag.NewFlagSet("", flag.ExitOnError)
+	engine := globalFlags.String("engine", database.EngineBadger, "Database engine")
+	dir := globalFlags.String("dir", "waspdb", "Database directory")
+	globalFlags.Parse(os.Args[1:])
+
+	if globalFlags.NArg() < 1 {
+		fmt.Printf("Usage: %s [options] [backup|restore|info]\n", os.Args[0])
+		globalFlags.PrintDefaults()
+		os.Exit(1)
+	}
+
+	switch globalFlags.Arg(0) {
+	case "backup":
+		backupFlags := flag.NewFlagSet("backup", flag.ExitOnError)
+		out := backupFlags.String("o", "wasp.backup", "Output file")
+		addrs := backupFlags.String("addresses", "", "Comma separated smart contract addresses. Empty means all")
+		withRegistry := backupFlags.Bool("registry", true, "Include the registry")
+		backupFlags.Parse(globalFlags.Args()[1:])
+
+		addrList, err := parseAddresses(*addrs)
+		check(err)
+		db, err := openDB(*engine, *dir)
+		check(err)
+		defer db.Close()
+
+		f, err := os.Create(*out)
+		check(err)
+		defer f.Close()
+		backup, err := database.WriteBackup(db.NewStore(), f, addrList, *withRegistry)
+		check(err)
+		printBackup(backup)
+
+	case "restore":
+		restoreFlags := flag.NewFlagSet("restore", flag.ExitOnError)
+		in := restoreFlags.String("i", "wasp.backup", "Input file")
+		force := restoreFlags.Bool("force", false, "Overwrite partitions of existing committees")
+		restoreFlags.Parse(globalFlags.Args()[1:])
+
+		backup := readBackup(*in)
+		// fails if the node is running
+		db, err := openDB(*engine, *dir)
+		check(err)
+		check(db.Close())
+
+		check(database.RestoreBackupToDir(*engine, *dir, backup, *force))
+		printBackup(backup)
+
+	case "info":
+		infoFlags := flag.NewFlagSet("info", flag.ExitOnError)
+		in := infoFlags.String("i", "wasp.backup", "Input file")
+		infoFlags.Parse(globalFlags.Args()[1:])
+
+		printBackup(readBackup(*in))
+
+	default:
+		check(fmt.Errorf("unknown command '%s'", globalFlags.Arg(0)))
+	}
+}
+
+func openDB(engine, dir string) (goshimmerdb.DB, error) {
+	db, err := database.NewDB(engine, dir)
+	if err != nil {
+		return nil, fmt.Errorf("can't open the database in '%s' (is the node running?): %v", dir, err)
+	}
+	return db, nil
+}
+
+func parseAddresses(s string) ([]address.Address, error) {
+	ret := make([]address.Address, 0)
+	for _, a := range strings.Split(s, ",") {
+		if a = strings.TrimSpace(a); a == "" {
+			continue
+		}
+		addr, err := address.FromBase58(a)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, addr)
+	}
+	return ret, nil
+}
+
+func readBackup(fname string) *database.Backup {
+	f, err := os.Open(fname)
+	check(err)
+	defer f.Close()
+	backup, err := database.ReadBackup(f)
+	check(err)
+	return backup
+}
+
+func printBackup(backup *database.Backup) {
+	fmt.Printf("database schema version: %d\n", backup.DBVersion)
+	fmt.Printf("timestamp: %v\n", backup.Timestamp)
+	for _, part := range backup.Partitions {
+		name := part.Address.String()
+		if backup.IsRegistry(part) {
+			name = "registry"
+		}
+		fmt.Printf("    %s: %d record(s)\n", name, part.NumRecords)
+	}
+}